@@ -6,7 +6,7 @@ import (
 )
 
 // PolicyFilter represents a Pomerium policy that can be attached to a particular route defined
-// via the Kubernetes Gateway API.
+// via the Kubernetes Gateway API, or to an Ingress using the policy_ref annotation.
 //
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -0,0 +1,61 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GatewayClassConfig holds Pomerium-specific defaults for all routes of Gateways of a particular
+// GatewayClass. It is referenced from the GatewayClass spec.parametersRef field.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+type GatewayClassConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the route defaults.
+	Spec GatewayClassConfigSpec `json:"spec,omitempty"`
+}
+
+// GatewayClassConfigSpec defines defaults for HTTPRoutes and GRPCRoutes.
+type GatewayClassConfigSpec struct {
+	// DefaultPolicyFilter references a PolicyFilter to apply to any route rule that does not
+	// reference a PolicyFilter of its own.
+	//
+	// +optional
+	DefaultPolicyFilter *PolicyFilterReference `json:"defaultPolicyFilter,omitempty"`
+
+	// Timeout sets the default upstream timeout for routes.
+	//
+	// +optional
+	// +kubebuilder:validation:Format=duration
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// IdleTimeout sets the default upstream idle timeout for routes.
+	//
+	// +optional
+	// +kubebuilder:validation:Format=duration
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+}
+
+// PolicyFilterReference identifies a PolicyFilter.
+type PolicyFilterReference struct {
+	// Namespace of the PolicyFilter.
+	Namespace string `json:"namespace"`
+
+	// Name of the PolicyFilter.
+	Name string `json:"name"`
+}
+
+//+kubebuilder:object:root=true
+
+// GatewayClassConfigList is a list of GatewayClassConfigs.
+type GatewayClassConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GatewayClassConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GatewayClassConfig{}, &GatewayClassConfigList{})
+}
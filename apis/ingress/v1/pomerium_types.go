@@ -385,8 +385,9 @@ type PomeriumSpec struct {
 
 	// CertificateAutoProvision sets the certificate auto provision settings.
 	// This is a fallback for routes that are not defined via Ingress or
-	// Gateway resources. When configured, cert-manager certificate resources
-	// will be created for any routes which have no matching TLS certificate.
+	// Gateway resources, unless kubernetesRoutes is set. When configured,
+	// cert-manager certificate resources will be created for any routes which
+	// have no matching TLS certificate.
 	//
 	// +kubebuilder:validation:Optional
 	CertificateAutoProvision *CertificateAutoProvision `json:"certificateAutoProvision,omitzero"`
@@ -596,6 +597,41 @@ type CertificateAutoProvision struct {
 	// +kubebuilder:validation:MinLength=1
 	// +kubebuilder:validation:Format="namespace/name"
 	Issuer *string `json:"issuer"`
+	// Strategy determines how DNS names are grouped into certificates:
+	// <ul>
+	// <li><code>PerName</code> (the default) provisions a certificate for each DNS name.</li>
+	// <li><code>Wildcard</code> provisions a wildcard certificate for each parent domain
+	// with at least <code>wildcardThreshold</code> DNS names, and a certificate for each
+	// other DNS name. Wildcard certificates are only provisioned if the issuer is not
+	// an ACME issuer, or has a DNS-01 challenge solver.</li>
+	// <li><code>SAN</code> provisions certificates with up to <code>maxNames</code> DNS names each.</li>
+	// </ul>
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=PerName;Wildcard;SAN
+	Strategy *string `json:"strategy,omitempty"`
+	// WildcardThreshold is the number of DNS names that must share a parent domain
+	// for the <code>Wildcard</code> strategy to provision a wildcard certificate for it.
+	// Defaults to 3.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=2
+	WildcardThreshold *int32 `json:"wildcardThreshold,omitempty"`
+	// MaxNames is the maximum number of DNS names in each certificate provisioned
+	// by the <code>SAN</code> strategy. Defaults to 100, the Let's Encrypt limit.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	MaxNames *int32 `json:"maxNames,omitempty"`
+	// KubernetesRoutes, if true, also provisions certificates for Ingress hosts not
+	// covered by a <code>tls</code> entry with a <code>secretName</code>, and for Gateway
+	// HTTPS listeners without <code>certificateRefs</code> (which must set
+	// <code>tls.options</code> instead to pass validation). These certificates are created
+	// in the namespace of the Ingress or Gateway, and their Secrets are used for its routes.
+	// An Issuer is only used for the Ingresses and Gateways in its own namespace.
+	//
+	// +kubebuilder:validation:Optional
+	KubernetesRoutes *bool `json:"kubernetesRoutes,omitempty"`
 }
 
 // ResourceStatus represents the outcome of the latest attempt to reconcile
@@ -622,15 +658,55 @@ type CertificateAutoProvisionStatus struct {
 	DataBrokerLastUpdated metav1.Time `json:"dataBrokerLastUpdated,omitzero"`
 }
 
+// CertificateSummary summarizes the TLS certificates synced to Pomerium. Each certificate about
+// to expire, or expired, is reported by warning Events on its Secret and on the Pomerium CRD.
+type CertificateSummary struct {
+	// Total is the number of TLS certificates synced to Pomerium.
+	Total int32 `json:"total"`
+	// Expiring is the number of certificates that expire within the expiry warning period.
+	Expiring int32 `json:"expiring"`
+	// Expired is the number of certificates that have expired.
+	Expired int32 `json:"expired"`
+	// EarliestExpiry is when the certificate that expires first does, or did.
+	// +optional
+	EarliestExpiry *metav1.Time `json:"earliestExpiry,omitempty"`
+	// EarliestExpirySecret is the <code>namespace/name</code> of the Secret holding
+	// the certificate that expires first.
+	// +optional
+	EarliestExpirySecret string `json:"earliestExpirySecret,omitempty"`
+}
+
+// IngressStatusCounts counts the Ingresses by the outcome of their latest reconciliation.
+// The status of each Ingress is recorded in a RouteStatus of the same name and namespace.
+type IngressStatusCounts struct {
+	// Reconciled is the number of Ingresses that were synced with Pomerium.
+	Reconciled int32 `json:"reconciled"`
+	// Failed is the number of Ingresses that could not be synced with Pomerium.
+	Failed int32 `json:"failed"`
+}
+
 // PomeriumStatus represents configuration and Ingress status.
 type PomeriumStatus struct {
 	// Status of certificate auto provisioning.
 	// +optional
 	CertificateAutoProvisionStatus *CertificateAutoProvisionStatus `json:"certificateAutoProvisionStatus,omitzero"`
-	// Routes provide per-Ingress status.
+	// CertificateSummary summarizes the TLS certificates synced to Pomerium, as of the latest check.
+	// +optional
+	CertificateSummary *CertificateSummary `json:"certificateSummary,omitempty"`
+	// Ingresses counts the Ingresses by the outcome of their latest reconciliation.
+	// +optional
+	Ingresses *IngressStatusCounts `json:"ingresses,omitempty"`
+	// Routes is deprecated: per-Ingress status is now recorded in RouteStatus objects,
+	// and this map is cleared on startup.
+	// +optional
 	Routes map[string]ResourceStatus `json:"ingress,omitempty"`
 	// SettingsStatus represent most recent main configuration reconciliation status.
 	SettingsStatus *ResourceStatus `json:"settingsStatus,omitempty"`
+	// Conditions describe the state of the configuration synced via the unified API.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
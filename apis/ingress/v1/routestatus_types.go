@@ -0,0 +1,35 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=routestatuses
+//+kubebuilder:resource:scope=Namespaced
+//+kubebuilder:printcolumn:name="Reconciled",type=boolean,JSONPath=`.status.reconciled`
+//+kubebuilder:printcolumn:name="Error",type=string,JSONPath=`.status.error`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RouteStatus records the outcome of the latest attempt to reconcile an Ingress
+// with Pomerium. It has the same name and namespace as the Ingress, and is owned by it.
+type RouteStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Status of the Ingress.
+	Status ResourceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RouteStatusList contains a list of RouteStatus
+type RouteStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RouteStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RouteStatus{}, &RouteStatusList{})
+}
@@ -37,6 +37,26 @@ func (in *CertificateAutoProvision) DeepCopyInto(out *CertificateAutoProvision)
 		*out = new(string)
 		**out = **in
 	}
+	if in.Strategy != nil {
+		in, out := &in.Strategy, &out.Strategy
+		*out = new(string)
+		**out = **in
+	}
+	if in.WildcardThreshold != nil {
+		in, out := &in.WildcardThreshold, &out.WildcardThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxNames != nil {
+		in, out := &in.MaxNames, &out.MaxNames
+		*out = new(int32)
+		**out = **in
+	}
+	if in.KubernetesRoutes != nil {
+		in, out := &in.KubernetesRoutes, &out.KubernetesRoutes
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateAutoProvision.
@@ -65,6 +85,25 @@ func (in *CertificateAutoProvisionStatus) DeepCopy() *CertificateAutoProvisionSt
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateSummary) DeepCopyInto(out *CertificateSummary) {
+	*out = *in
+	if in.EarliestExpiry != nil {
+		in, out := &in.EarliestExpiry, &out.EarliestExpiry
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateSummary.
+func (in *CertificateSummary) DeepCopy() *CertificateSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CircuitBreakerThresholds) DeepCopyInto(out *CircuitBreakerThresholds) {
 	*out = *in
@@ -317,6 +356,21 @@ func (in *IdentityProvider) DeepCopy() *IdentityProvider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressStatusCounts) DeepCopyInto(out *IngressStatusCounts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressStatusCounts.
+func (in *IngressStatusCounts) DeepCopy() *IngressStatusCounts {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressStatusCounts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MatchSubjectAltNames) DeepCopyInto(out *MatchSubjectAltNames) {
 	*out = *in
@@ -659,6 +713,16 @@ func (in *PomeriumStatus) DeepCopyInto(out *PomeriumStatus) {
 		*out = new(CertificateAutoProvisionStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.CertificateSummary != nil {
+		in, out := &in.CertificateSummary, &out.CertificateSummary
+		*out = new(CertificateSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ingresses != nil {
+		in, out := &in.Ingresses, &out.Ingresses
+		*out = new(IngressStatusCounts)
+		**out = **in
+	}
 	if in.Routes != nil {
 		in, out := &in.Routes, &out.Routes
 		*out = make(map[string]ResourceStatus, len(*in))
@@ -671,6 +735,13 @@ func (in *PomeriumStatus) DeepCopyInto(out *PomeriumStatus) {
 		*out = new(ResourceStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PomeriumStatus.
@@ -751,6 +822,64 @@ func (in *ResourceStatus) DeepCopy() *ResourceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteStatus) DeepCopyInto(out *RouteStatus) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteStatus.
+func (in *RouteStatus) DeepCopy() *RouteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RouteStatus) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouteStatusList) DeepCopyInto(out *RouteStatusList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RouteStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouteStatusList.
+func (in *RouteStatusList) DeepCopy() *RouteStatusList {
+	if in == nil {
+		return nil
+	}
+	out := new(RouteStatusList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RouteStatusList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SSH) DeepCopyInto(out *SSH) {
 	*out = *in
@@ -22,6 +22,7 @@ import (
 	controllerconfig "sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/pomerium/ingress-controller/controllers"
 	"github.com/pomerium/ingress-controller/controllers/gateway"
@@ -72,8 +73,14 @@ type allCmdParam struct {
 	dumpConfigDiff          bool
 	syncAPIURL              string
 	syncAPINamespaceID      string
+	syncAPIOptions          []pomerium.APIReconcilerOption
 	syncAPIToken            string
+	syncAPITokenSecret      *types.NamespacedName
 	syncAPIBootstrap        bool
+	syncAPIGCInterval       time.Duration
+	syncAPIGCDryRun         bool
+	syncAPIDriftInterval    time.Duration
+	syncAPIDriftMode        pomerium.DriftMode
 
 	// bootstrapMetricsAddr for bootstrap configuration controller metrics
 	bootstrapMetricsAddr string
@@ -86,6 +93,9 @@ type allCmdParam struct {
 	configControllerShutdownTimeout time.Duration
 
 	certificateControllerName string
+	certificateExpiryWarning  time.Duration
+	webhookOptions            *webhook.Options
+	webhookWarnOnly           bool
 
 	cfg config.Config
 }
@@ -201,6 +211,11 @@ func (s *allCmdOptions) getParam(ctx context.Context) (*allCmdParam, error) {
 		return nil, fmt.Errorf("options: %w", err)
 	}
 
+	apiTokenSecret, err := s.getSyncAPITokenSecret()
+	if err != nil {
+		return nil, fmt.Errorf("options: %w", err)
+	}
+
 	p := &allCmdParam{
 		settings:                        *settings,
 		ingressOpts:                     opts,
@@ -210,9 +225,18 @@ func (s *allCmdOptions) getParam(ctx context.Context) (*allCmdParam, error) {
 		configControllerShutdownTimeout: s.configControllerShutdownTimeout,
 		syncAPIURL:                      s.SyncAPIURL,
 		syncAPINamespaceID:              s.SyncAPINamespaceID,
+		syncAPIOptions:                  s.getAPIReconcilerOptions(),
 		syncAPIToken:                    s.SyncAPIToken,
+		syncAPITokenSecret:              apiTokenSecret,
 		syncAPIBootstrap:                s.syncAPIIngress != "",
+		syncAPIGCInterval:               s.SyncAPIGCInterval,
+		syncAPIGCDryRun:                 s.SyncAPIGCDryRun,
+		syncAPIDriftInterval:            s.SyncAPIDriftInterval,
+		syncAPIDriftMode:                pomerium.DriftMode(s.SyncAPIDriftMode),
 		certificateControllerName:       s.CertificateControllerOptions.Name,
+		certificateExpiryWarning:        s.CertificateExpiryWarning,
+		webhookOptions:                  s.getWebhookOptions(),
+		webhookWarnOnly:                 s.WebhookWarnOnly,
 	}
 	if err := p.makeBootstrapConfig(ctx, *s); err != nil {
 		return nil, fmt.Errorf("bootstrap: %w", err)
@@ -430,7 +454,8 @@ func (s *allCmdParam) buildController(ctx context.Context, cfg *config.Config) (
 			}
 			dialAddressOverride = net.JoinHostPort("localhost", port)
 		}
-		reconciler, err = pomerium.NewAPIReconciler(s.syncAPIURL, s.syncAPINamespaceID, s.syncAPIToken, s.cfg.Options, dialAddressOverride)
+		reconciler, err = pomerium.NewAPIReconciler(s.syncAPIURL, s.syncAPINamespaceID, s.syncAPIToken, s.cfg.Options, dialAddressOverride,
+			s.syncAPIOptions...)
 		if err != nil {
 			return nil, err
 		}
@@ -454,6 +479,14 @@ func (s *allCmdParam) buildController(ctx context.Context, cfg *config.Config) (
 		GlobalSettings:            &s.settings,
 		GatewayControllerConfig:   s.gatewayConfig,
 		CertificateControllerName: s.certificateControllerName,
+		GarbageCollectionInterval: s.syncAPIGCInterval,
+		GarbageCollectionDryRun:   s.syncAPIGCDryRun,
+		DriftCheckInterval:        s.syncAPIDriftInterval,
+		DriftMode:                 s.syncAPIDriftMode,
+		APITokenSecret:            s.syncAPITokenSecret,
+		CertificateExpiryWarning:  s.certificateExpiryWarning,
+		Webhook:                   s.webhookOptions,
+		WebhookWarnOnly:           s.webhookWarnOnly,
 	}
 
 	return c, nil
@@ -483,7 +516,7 @@ func (s *allCmdParam) runBootstrapConfigController(ctx context.Context, reconcil
 	if host, err := os.Hostname(); err == nil {
 		name = fmt.Sprintf("%s pod/%s", name, host)
 	}
-	if err := settings.NewSettingsController(mgr, reconciler, s.settings, name, false, health_ctrl.SettingsBootstrapReconciler); err != nil {
+	if err := settings.NewSettingsController(mgr, reconciler, s.settings, name, false, health_ctrl.SettingsBootstrapReconciler, nil); err != nil {
 		return fmt.Errorf("settings controller: %w", err)
 	}
 	return mgr.Start(ctx)
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"net/url"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"k8s.io/apiserver/pkg/server/healthz"
@@ -39,7 +41,10 @@ type controllerCmd struct {
 
 	sharedSecret string
 
-	debug bool
+	debug  bool
+	dryRun bool
+	// onConfigChange is passed the changes not made in dry-run mode, instead of logging them.
+	onConfigChange pomerium.DryRunFunc
 
 	cobra.Command
 	pomerium.IngressReconciler
@@ -68,12 +73,28 @@ const (
 	tlsOverrideCertificateName = "databroker-tls-override-certificate-name"
 	leaderElectionID           = "leader-election-id"
 	leaderElectionNamespace    = "leader-election-namespace"
+	dryRun                     = "dry-run"
 )
 
 func (s *controllerCmd) setupFlags() error {
 	flags := s.PersistentFlags()
 	flags.StringVar(&s.metricsAddr, metricsBindAddress, ":9090", "The address the metric endpoint binds to.")
 	flags.StringVar(&s.probeAddr, healthProbeBindAddress, ":8081", "The address the probe endpoint binds to.")
+	flags.StringVar(&s.leaderElectionID, leaderElectionID, "pomerium-ingress-controller", "leader election lease name")
+	flags.StringVar(&s.leaderElectionNamespace, leaderElectionNamespace, "", "leader election lease namespace")
+	flags.BoolVar(&s.dryRun, dryRun, false,
+		"only log the changes to the Pomerium configuration and Kubernetes objects, without making them, "+
+			"and without taking over from a running controller. Kubernetes Events are still recorded")
+	if err := s.setupConnectionFlags(flags); err != nil {
+		return err
+	}
+
+	s.ingressControllerOpts.setupFlags(flags)
+	return viperWalk(flags)
+}
+
+// setupConnectionFlags sets up the flags for the databroker connection, and the debug flag.
+func (s *controllerCmd) setupConnectionFlags(flags *pflag.FlagSet) error {
 	flags.StringVar(&s.databrokerServiceURL, databrokerServiceURL, "http://localhost:5443",
 		"the databroker service url")
 	flags.StringVar(&s.tlsCAFile, databrokerTLSCAFile, "", "tls CA file path")
@@ -82,18 +103,11 @@ func (s *controllerCmd) setupFlags() error {
 		"disable remote hosts TLS certificate chain and hostname check for the databroker connection")
 	flags.StringVar(&s.tlsOverrideCertificateName, tlsOverrideCertificateName, "",
 		"override the certificate name used for the databroker connection")
-	flags.StringVar(&s.leaderElectionID, leaderElectionID, "pomerium-ingress-controller", "leader election lease name")
-	flags.StringVar(&s.leaderElectionNamespace, leaderElectionNamespace, "", "leader election lease namespace")
 
 	flags.StringVar(&s.sharedSecret, sharedSecret, "",
 		"base64-encoded shared secret for signing JWTs")
 	flags.BoolVar(&s.debug, debug, false, "enable debug logging")
-	if err := flags.MarkHidden("debug"); err != nil {
-		return err
-	}
-
-	s.ingressControllerOpts.setupFlags(flags)
-	return viperWalk(flags)
+	return flags.MarkHidden("debug")
 }
 
 func (s *controllerCmd) exec(*cobra.Command, []string) error {
@@ -105,9 +119,16 @@ func (s *controllerCmd) exec(*cobra.Command, []string) error {
 		return fmt.Errorf("build controller: %w", err)
 	}
 
+	readyChecks := []healthz.HealthChecker{healthz.NamedCheck("acquire-lease", c.ReadyzCheck)}
+	if hc, ok := c.Reconciler.(pomerium.APIHealthChecker); ok {
+		readyChecks = append(readyChecks, healthz.NamedCheck("sync-api", func(*http.Request) error {
+			return hc.APIHealthError()
+		}))
+	}
+
 	eg, ctx := errgroup.WithContext(ctx)
 	eg.Go(func() error {
-		return runHealthz(ctx, s.probeAddr, healthz.NamedCheck("acquire-lease", c.ReadyzCheck))
+		return runHealthz(ctx, s.probeAddr, readyChecks...)
 	})
 	eg.Go(func() error { return c.Run(ctx) })
 
@@ -154,6 +175,11 @@ func (s *controllerCmd) buildController(ctx context.Context) (*controllers.Contr
 		return nil, err
 	}
 
+	apiTokenSecret, err := s.getSyncAPITokenSecret()
+	if err != nil {
+		return nil, err
+	}
+
 	c := &controllers.Controller{
 		MgrOpts: ctrl.Options{
 			Scheme:  scheme,
@@ -162,18 +188,40 @@ func (s *controllerCmd) buildController(ctx context.Context) (*controllers.Contr
 				SkipNameValidation: new(true),
 			},
 		},
-		IngressCtrlOpts:         opts,
-		GatewayControllerConfig: gatewayConfig,
-		GlobalSettings:          globalSettings,
+		IngressCtrlOpts:          opts,
+		GatewayControllerConfig:  gatewayConfig,
+		GlobalSettings:           globalSettings,
+		CertificateExpiryWarning: s.CertificateExpiryWarning,
+		Webhook:                  s.getWebhookOptions(),
+		WebhookWarnOnly:          s.WebhookWarnOnly,
+		DryRun:                   s.dryRun,
+	}
+
+	onConfigChange := s.onConfigChange
+	if s.dryRun {
+		c.MgrOpts.Client.DryRun = new(true)
+		if onConfigChange == nil {
+			onConfigChange = pomerium.LogConfigChange
+		}
 	}
 
 	if s.SyncAPIURL != "" {
+		apiOpts := s.getAPIReconcilerOptions()
+		if s.dryRun {
+			apiOpts = append(apiOpts, pomerium.WithDryRun(onConfigChange))
+		}
 		c.Reconciler, err = pomerium.NewAPIReconciler(
-			s.SyncAPIURL, s.SyncAPINamespaceID, s.SyncAPIToken, pomerium_config.NewDefaultOptions(), "")
+			s.SyncAPIURL, s.SyncAPINamespaceID, s.SyncAPIToken, pomerium_config.NewDefaultOptions(), "",
+			apiOpts...)
 		if err != nil {
 			return nil, err
 		}
-		c.MgrOpts.LeaderElection = true
+		c.GarbageCollectionInterval = s.SyncAPIGCInterval
+		c.GarbageCollectionDryRun = s.SyncAPIGCDryRun
+		c.DriftCheckInterval = s.SyncAPIDriftInterval
+		c.DriftMode = pomerium.DriftMode(s.SyncAPIDriftMode)
+		c.APITokenSecret = apiTokenSecret
+		c.MgrOpts.LeaderElection = !s.dryRun
 		c.MgrOpts.LeaderElectionID = s.leaderElectionID
 		c.MgrOpts.LeaderElectionNamespace = s.leaderElectionNamespace
 		return c, nil
@@ -187,6 +235,11 @@ func (s *controllerCmd) buildController(ctx context.Context) (*controllers.Contr
 	}
 
 	c.DataBrokerServiceClient = databroker.NewDataBrokerServiceClient(conn)
-	c.Reconciler = pomerium.NewDataBrokerReconciler(c.DataBrokerServiceClient, s.debug)
+	if s.dryRun {
+		c.Reconciler = pomerium.NewDataBrokerReconciler(
+			pomerium.NewDryRunDataBrokerClient(c.DataBrokerServiceClient, onConfigChange), s.debug)
+	} else {
+		c.Reconciler = pomerium.NewDataBrokerReconciler(c.DataBrokerServiceClient, s.debug)
+	}
 	return c, nil
 }
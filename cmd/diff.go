@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/pomerium/ingress-controller/controllers"
+	"github.com/pomerium/ingress-controller/controllers/apitoken"
+	"github.com/pomerium/ingress-controller/controllers/gateway"
+	"github.com/pomerium/ingress-controller/controllers/ingress"
+	"github.com/pomerium/ingress-controller/controllers/settings"
+	"github.com/pomerium/ingress-controller/pomerium"
+)
+
+type diffCmd struct {
+	controllerCmd
+
+	output string
+}
+
+// DiffCommand creates command that prints the changes the ingress controller would make
+func DiffCommand() (*cobra.Command, error) {
+	cmd := diffCmd{}
+	cmd.Command = cobra.Command{
+		Use:   "diff",
+		Short: "prints the changes the ingress controller would make to the Pomerium configuration",
+		Long: `Computes the Pomerium configuration for the Ingresses, Gateway API objects and Pomerium settings in
+the cluster, as the controller with the same flags would on startup, and prints how it differs from the
+configuration in the databroker or the unified API. Nothing is changed.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.RunE = cmd.exec
+	if err := cmd.setupFlags(); err != nil {
+		return nil, err
+	}
+	return &cmd.Command, nil
+}
+
+const diffOutput = "output"
+
+func (s *diffCmd) setupFlags() error {
+	flags := s.PersistentFlags()
+	flags.StringVarP(&s.output, diffOutput, "o", "text", "output format: text, yaml or json")
+	if err := s.setupConnectionFlags(flags); err != nil {
+		return err
+	}
+
+	s.ingressControllerOpts.setupFlags(flags)
+	return viperWalk(flags)
+}
+
+func (s *diffCmd) exec(cmd *cobra.Command, _ []string) error {
+	setupLogger(s.debug)
+	ctx := cmd.Context()
+
+	if !slices.Contains([]string{"text", "yaml", "json"}, s.output) {
+		return fmt.Errorf("%s=%s: must be text, yaml or json", diffOutput, s.output)
+	}
+
+	var mu sync.Mutex
+	var changes []pomerium.ConfigChange
+	s.dryRun = true
+	s.onConfigChange = func(_ context.Context, change pomerium.ConfigChange) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change)
+	}
+
+	c, err := s.buildController(ctx)
+	if err != nil {
+		return fmt.Errorf("build controller: %w", err)
+	}
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return fmt.Errorf("get k8s api config: %w", err)
+	}
+	k8sClient, err := client.New(cfg, client.Options{Scheme: c.MgrOpts.Scheme, DryRun: new(true)})
+	if err != nil {
+		return fmt.Errorf("k8s client: %w", err)
+	}
+
+	err = diff(ctx, c, k8sClient)
+	slices.SortStableFunc(changes, compareConfigChanges)
+	if writeErr := writeConfigChanges(cmd.OutOrStdout(), changes, s.output); writeErr != nil {
+		return writeErr
+	}
+	// the changes that could be computed are printed even if some objects could not
+	return err
+}
+
+// diff passes the configuration for the objects in the cluster to the reconcilers of c, as
+// their controllers do on startup. c must be in dry-run mode.
+func diff(ctx context.Context, c *controllers.Controller, k8sClient client.Client) error {
+	if ar, ok := c.Reconciler.(interface{ SetK8sClient(client client.Client) }); ok {
+		ar.SetK8sClient(k8sClient)
+	}
+	if c.APITokenSecret != nil {
+		if err := loadAPIToken(ctx, c, k8sClient); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	if c.GlobalSettings != nil {
+		cfg, err := settings.FetchConfig(ctx, k8sClient, *c.GlobalSettings)
+		if err == nil {
+			_, err = c.Reconciler.SetConfig(ctx, cfg)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("settings: %w", err))
+		}
+	}
+
+	// as on the initial sync, Ingresses are only synced if all of them could be fetched, as the
+	// others would otherwise appear to be deleted
+	ics, err := ingress.FetchManagedIngresses(ctx, k8sClient, c.IngressCtrlOpts...)
+	if err == nil {
+		_, err = c.Reconciler.Set(ctx, ics)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ingresses: %w", err))
+	}
+
+	if c.GatewayControllerConfig != nil {
+		gc, err := gateway.FetchGatewayConfig(ctx, k8sClient, *c.GatewayControllerConfig)
+		if err == nil {
+			_, err = c.Reconciler.SetGatewayConfig(ctx, gc)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gateway: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadAPIToken passes the unified API token from the Secret named by c.APITokenSecret to the
+// reconciler, as the API token controller would.
+func loadAPIToken(ctx context.Context, c *controllers.Controller, k8sClient client.Client) error {
+	updater, ok := c.Reconciler.(pomerium.APITokenUpdater)
+	if !ok {
+		return fmt.Errorf("reconciler %T does not support API token updates", c.Reconciler)
+	}
+	secret := new(corev1.Secret)
+	if err := k8sClient.Get(ctx, *c.APITokenSecret, secret); err != nil {
+		return fmt.Errorf("get api token secret: %w", err)
+	}
+	token := strings.TrimSpace(string(secret.Data[apitoken.TokenKey]))
+	if token == "" {
+		return fmt.Errorf("secret %s has no %q key", c.APITokenSecret, apitoken.TokenKey)
+	}
+	updater.SetAPIToken(token)
+	return nil
+}
+
+// compareConfigChanges orders changes by record, kind and name, as unified API writes may be
+// made concurrently.
+func compareConfigChanges(a, b pomerium.ConfigChange) int {
+	return cmp.Or(
+		cmp.Compare(a.Record, b.Record),
+		cmp.Compare(a.Kind, b.Kind),
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.ID, b.ID),
+	)
+}
+
+// writeConfigChanges writes changes as text, YAML or JSON.
+func writeConfigChanges(w io.Writer, changes []pomerium.ConfigChange, format string) error {
+	if changes == nil {
+		changes = []pomerium.ConfigChange{}
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case "yaml":
+		data, err = yaml.Marshal(changes)
+	case "json":
+		data, err = json.MarshalIndent(changes, "", "  ")
+		data = append(data, '\n')
+	default:
+		data = formatConfigChanges(changes)
+	}
+	if err != nil {
+		return fmt.Errorf("marshal changes: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// formatConfigChanges formats changes for reading, with a heading line for each change followed
+// by its indented diff.
+func formatConfigChanges(changes []pomerium.ConfigChange) []byte {
+	if len(changes) == 0 {
+		return []byte("no changes\n")
+	}
+
+	var b strings.Builder
+	for _, change := range changes {
+		if change.Record != "" {
+			fmt.Fprintf(&b, "%s: ", change.Record)
+		}
+		fmt.Fprintf(&b, "%s %s", change.Action, change.Kind)
+		if change.Name != "" {
+			fmt.Fprintf(&b, " %s", change.Name)
+		}
+		if change.ID != "" {
+			fmt.Fprintf(&b, " (id %s)", change.ID)
+		}
+		b.WriteByte('\n')
+		for line := range strings.Lines(change.Diff) {
+			fmt.Fprintf(&b, "  %s", line)
+		}
+		if !strings.HasSuffix(change.Diff, "\n") && change.Diff != "" {
+			b.WriteByte('\n')
+		}
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pomerium/ingress-controller/pomerium"
+)
+
+func TestWriteConfigChanges(t *testing.T) {
+	changes := []pomerium.ConfigChange{
+		{Action: pomerium.ConfigChangeUpdate, Kind: "settings", Record: "pomerium-crd", Diff: "-a\n+b\n"},
+		{Action: pomerium.ConfigChangeCreate, Kind: "route", Record: "ingress-controller", Name: "b", Diff: "+b"},
+		{Action: pomerium.ConfigChangeDelete, Kind: "route", Record: "ingress-controller", Name: "a", ID: "id-a"},
+	}
+	slices.SortStableFunc(changes, compareConfigChanges)
+
+	var out bytes.Buffer
+	require.NoError(t, writeConfigChanges(&out, changes, "text"))
+	assert.Equal(t, `ingress-controller: delete route a (id id-a)
+ingress-controller: create route b
+  +b
+pomerium-crd: update settings
+  -a
+  +b
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, writeConfigChanges(&out, nil, "text"))
+	assert.Equal(t, "no changes\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeConfigChanges(&out, nil, "json"))
+	assert.Equal(t, "[]\n", out.String())
+}
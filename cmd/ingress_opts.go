@@ -2,28 +2,50 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	validate "github.com/go-playground/validator/v10"
 	"github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/apitoken"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/controllers/gateway"
 	"github.com/pomerium/ingress-controller/controllers/ingress"
+	"github.com/pomerium/ingress-controller/pomerium"
 	"github.com/pomerium/ingress-controller/util"
 )
 
 type ingressControllerOpts struct {
-	ClassName               string `validate:"required"`
-	GatewayAPIEnabled       bool
-	GatewayClassName        string `validate:"required"`
-	AnnotationPrefix        string `validate:"required"`
-	Namespaces              []string
-	UpdateStatusFromService string ``
-	GlobalSettings          string `validate:"required"`
-	SyncAPIURL              string
-	SyncAPINamespaceID      string
-	SyncAPIToken            string
+	ClassName                string `validate:"required"`
+	GatewayAPIEnabled        bool
+	GatewayClassName         string `validate:"required"`
+	AnnotationPrefix         string `validate:"required"`
+	Namespaces               []string
+	UpdateStatusFromService  string ``
+	GlobalSettings           string `validate:"required"`
+	SyncAPIURL               string
+	SyncAPINamespaceID       string
+	SyncAPINamespaceMap      map[string]string
+	SyncAPINamespaceLabels   bool
+	SyncAPIToken             string
+	SyncAPITokenSecret       string
+	SyncAPIInstallID         string
+	SyncAPIGCInterval        time.Duration
+	SyncAPIGCDryRun          bool
+	SyncAPIDriftInterval     time.Duration
+	SyncAPIDriftMode         string  `validate:"oneof=revert report ignore"`
+	SyncAPIRateLimit         float64 `validate:"gte=0"`
+	SyncAPIBurst             int     `validate:"gte=1"`
+	SyncAPIMaxRetries        uint64
+	SyncAPIBreakerThreshold  int           `validate:"gte=0"`
+	SyncAPIBreakerTimeout    time.Duration `validate:"gt=0"`
+	CertificateExpiryWarning time.Duration `validate:"gte=0"`
+	WebhookPort              int           `validate:"gte=0"`
+	WebhookCertDir           string
+	WebhookWarnOnly          bool
 }
 
 const (
@@ -38,7 +60,24 @@ const (
 	syncAPIIngress             = "sync-api-ingress"
 	syncAPIURL                 = "sync-api-url"
 	syncAPINamespaceID         = "sync-api-namespace-id"
-	syncAPIToken               = "sync-api-token" //nolint:gosec
+	syncAPINamespaceMap        = "sync-api-namespace-map"
+	syncAPINamespaceLabels     = "sync-api-namespace-labels"
+	syncAPIToken               = "sync-api-token"        //nolint:gosec
+	syncAPITokenSecret         = "sync-api-token-secret" //nolint:gosec
+	syncAPIInstallID           = "sync-api-install-id"
+	syncAPIGCInterval          = "sync-api-gc-interval"
+	syncAPIGCDryRun            = "sync-api-gc-dry-run"
+	syncAPIDriftInterval       = "sync-api-drift-interval"
+	syncAPIDriftMode           = "sync-api-drift-mode"
+	syncAPIRateLimit           = "sync-api-rate-limit"
+	syncAPIBurst               = "sync-api-burst"
+	syncAPIMaxRetries          = "sync-api-max-retries"
+	syncAPIBreakerThreshold    = "sync-api-circuit-breaker-threshold"
+	syncAPIBreakerTimeout      = "sync-api-circuit-breaker-timeout"
+	certificateExpiryWarning   = "certificate-expiry-warning"
+	webhookPort                = "webhook-port"
+	webhookCertDir             = "webhook-cert-dir"
+	webhookWarnOnly            = "webhook-warn-only"
 )
 
 func (s *ingressControllerOpts) setupFlags(flags *pflag.FlagSet) {
@@ -50,9 +89,52 @@ func (s *ingressControllerOpts) setupFlags(flags *pflag.FlagSet) {
 	flags.StringVar(&s.UpdateStatusFromService, updateStatusFromService, "", "update ingress status from given service status (pomerium-proxy)")
 	flags.StringVar(&s.GlobalSettings, globalSettings, "",
 		fmt.Sprintf("namespace/name to a resource of type %s/Settings", icsv1.GroupVersion.Group))
-	flags.StringVar(&s.SyncAPIURL, syncAPIURL, "", "unified API sync URL")
+	flags.StringVar(&s.SyncAPIURL, syncAPIURL, "",
+		"unified API sync URL; writes are not atomic, and are undone on a best-effort basis if an Ingress cannot be synced in full")
 	flags.StringVar(&s.SyncAPINamespaceID, syncAPINamespaceID, "", "unified API sync namespace ID")
+	flags.StringToStringVar(&s.SyncAPINamespaceMap, syncAPINamespaceMap, nil,
+		"Kubernetes namespace=unified API namespace ID pairs, to sync the Ingresses in a Kubernetes namespace to another unified API namespace")
+	flags.BoolVar(&s.SyncAPINamespaceLabels, syncAPINamespaceLabels, false,
+		fmt.Sprintf("sync the Ingresses in Kubernetes namespaces labeled %s to the given unified API namespace", pomerium.APINamespaceLabel))
 	flags.StringVar(&s.SyncAPIToken, syncAPIToken, "", "unified API sync token")
+	flags.StringVar(&s.SyncAPITokenSecret, syncAPITokenSecret, "",
+		fmt.Sprintf("namespace/name of a Secret holding the unified API sync token in its %q key, reloaded when changed", apitoken.TokenKey))
+	flags.StringVar(&s.SyncAPIInstallID, syncAPIInstallID, "",
+		"ID unique to this controller install, recorded on the unified API objects it creates; required for garbage collection to remove any")
+	flags.DurationVar(&s.SyncAPIGCInterval, syncAPIGCInterval, time.Hour,
+		"interval between removals of unified API routes, policies and keypairs no longer referenced by any object, or 0 to disable")
+	flags.BoolVar(&s.SyncAPIGCDryRun, syncAPIGCDryRun, true,
+		"only log the unified API routes, policies and keypairs that would be removed as unreferenced")
+	flags.DurationVar(&s.SyncAPIDriftInterval, syncAPIDriftInterval, 10*time.Minute,
+		"interval between checks for changes to synced unified API objects made by other means, or 0 to disable")
+	flags.StringVar(&s.SyncAPIDriftMode, syncAPIDriftMode, string(pomerium.DriftModeReport),
+		"default handling of changes to synced unified API objects: revert, report or ignore")
+	apiClientDefaults := pomerium.DefaultAPIClientOptions()
+	flags.Float64Var(&s.SyncAPIRateLimit, syncAPIRateLimit, apiClientDefaults.RateLimit,
+		"maximum sustained rate of unified API requests per second, or 0 for no limit")
+	flags.IntVar(&s.SyncAPIBurst, syncAPIBurst, apiClientDefaults.Burst,
+		"maximum number of unified API requests that may be made at once, within the rate limit")
+	flags.Uint64Var(&s.SyncAPIMaxRetries, syncAPIMaxRetries, apiClientDefaults.MaxRetries,
+		"maximum number of retries of a unified API request failing with a retryable error")
+	flags.IntVar(&s.SyncAPIBreakerThreshold, syncAPIBreakerThreshold, apiClientDefaults.BreakerThreshold,
+		"number of consecutive failed unified API requests after which further requests fail immediately, or 0 to disable")
+	flags.DurationVar(&s.SyncAPIBreakerTimeout, syncAPIBreakerTimeout, apiClientDefaults.BreakerTimeout,
+		"how long unified API requests fail immediately, once the circuit breaker threshold is reached, before a trial request")
+	flags.DurationVar(&s.CertificateExpiryWarning, certificateExpiryWarning, certificate.DefaultExpiryWarning,
+		"how long before a synced TLS certificate expires to emit warning Events, or 0 to disable")
+	flags.IntVar(&s.WebhookPort, webhookPort, 0,
+		"port to serve validating admission webhooks for Ingresses, PolicyFilters and the Pomerium CRD on, or 0 to disable")
+	flags.StringVar(&s.WebhookCertDir, webhookCertDir, "",
+		"directory with the tls.crt and tls.key of the admission webhook server, instead of the default")
+	flags.BoolVar(&s.WebhookWarnOnly, webhookWarnOnly, false,
+		"admit objects failing admission webhook validation with a warning, instead of rejecting them")
+}
+
+func (s *ingressControllerOpts) getWebhookOptions() *webhook.Options {
+	if s.WebhookPort == 0 {
+		return nil
+	}
+	return &webhook.Options{Port: s.WebhookPort, CertDir: s.WebhookCertDir}
 }
 
 func (s *ingressControllerOpts) Validate() error {
@@ -71,12 +153,50 @@ func (s *ingressControllerOpts) getGlobalSettings() (*types.NamespacedName, erro
 	return name, nil
 }
 
+func (s *ingressControllerOpts) getAPIReconcilerOptions() []pomerium.APIReconcilerOption {
+	clientOpts := pomerium.DefaultAPIClientOptions()
+	clientOpts.RateLimit = s.SyncAPIRateLimit
+	clientOpts.Burst = s.SyncAPIBurst
+	clientOpts.MaxRetries = s.SyncAPIMaxRetries
+	clientOpts.BreakerThreshold = s.SyncAPIBreakerThreshold
+	clientOpts.BreakerTimeout = s.SyncAPIBreakerTimeout
+	opts := []pomerium.APIReconcilerOption{pomerium.WithAPIClientOptions(clientOpts)}
+	if len(s.SyncAPINamespaceMap) > 0 {
+		opts = append(opts, pomerium.WithAPINamespaceMap(s.SyncAPINamespaceMap))
+	}
+	if s.SyncAPIInstallID != "" {
+		opts = append(opts, pomerium.WithInstallID(s.SyncAPIInstallID))
+	}
+	return opts
+}
+
+func (s *ingressControllerOpts) getSyncAPITokenSecret() (*types.NamespacedName, error) {
+	if s.SyncAPITokenSecret == "" {
+		return nil, nil
+	}
+	if s.SyncAPIToken != "" {
+		return nil, fmt.Errorf("only one of %s and %s may be set", syncAPIToken, syncAPITokenSecret)
+	}
+	if s.SyncAPIURL == "" {
+		return nil, fmt.Errorf("%s requires %s", syncAPITokenSecret, syncAPIURL)
+	}
+
+	name, err := util.ParseNamespacedName(s.SyncAPITokenSecret)
+	if err != nil {
+		return nil, fmt.Errorf("%s=%s: %w", syncAPITokenSecret, s.SyncAPITokenSecret, err)
+	}
+	return name, nil
+}
+
 func (s *ingressControllerOpts) getIngressControllerOptions() ([]ingress.Option, error) {
 	opts := []ingress.Option{
 		ingress.WithNamespaces(s.Namespaces),
 		ingress.WithAnnotationPrefix(s.AnnotationPrefix),
 		ingress.WithControllerName(s.ClassName),
 	}
+	if s.SyncAPINamespaceLabels {
+		opts = append(opts, ingress.WithNamespaceMetadata())
+	}
 	if name, err := s.getGlobalSettings(); err != nil {
 		return nil, err
 	} else if name != nil {
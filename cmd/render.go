@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+	"sigs.k8s.io/yaml"
+
+	pom_cfg "github.com/pomerium/pomerium/config"
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/gateway"
+	"github.com/pomerium/ingress-controller/controllers/ingress"
+	"github.com/pomerium/ingress-controller/controllers/reporter"
+	"github.com/pomerium/ingress-controller/controllers/settings"
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+type renderCmd struct {
+	filenames        []string
+	className        string
+	gatewayClassName string
+	annotationPrefix string
+	namespaces       []string
+	globalSettings   string
+	defaultNamespace string
+	output           string
+	redact           bool
+	debug            bool
+
+	cobra.Command
+}
+
+// RenderCommand renders the Pomerium configuration for Kubernetes manifests, without a cluster
+func RenderCommand() (*cobra.Command, error) {
+	cmd := renderCmd{
+		Command: cobra.Command{
+			Use:   "render",
+			Short: "renders the Pomerium configuration for Kubernetes manifests",
+			Long: `Reads Ingress, Gateway API, Pomerium and related objects from manifest files, and prints
+the Pomerium configuration the controller would apply for them. Objects of other kinds are ignored.`,
+			Args: cobra.NoArgs,
+		},
+	}
+	cmd.RunE = cmd.exec
+	if err := cmd.setupFlags(); err != nil {
+		return nil, err
+	}
+	return &cmd.Command, nil
+}
+
+const (
+	renderFilename         = "filename"
+	renderDefaultNamespace = "default-namespace"
+	renderOutput           = "output"
+	renderRedact           = "redact"
+)
+
+func (s *renderCmd) setupFlags() error {
+	flags := s.PersistentFlags()
+	flags.StringSliceVarP(&s.filenames, renderFilename, "f", []string{"-"},
+		"manifest files or directories to read objects from, or - for stdin")
+	flags.StringVar(&s.className, ingressClassControllerName, ingress.DefaultClassControllerName, "IngressClass controller name")
+	flags.StringVar(&s.gatewayClassName, gatewayClassControllerName, gateway.DefaultClassControllerName, "GatewayClass controller name")
+	flags.StringVar(&s.annotationPrefix, annotationPrefix, ingress.DefaultAnnotationPrefix, "Ingress annotation prefix")
+	flags.StringSliceVar(&s.namespaces, namespaces, nil, "namespaces to watch, or none to watch all namespaces")
+	flags.StringVar(&s.globalSettings, globalSettings, "",
+		fmt.Sprintf("name of the %s/Pomerium object to render the settings of, if there is more than one", icsv1.GroupVersion.Group))
+	flags.StringVar(&s.defaultNamespace, renderDefaultNamespace, metav1.NamespaceDefault,
+		"namespace of the namespaced objects that do not specify one")
+	flags.StringVarP(&s.output, renderOutput, "o", "yaml", "output format: yaml or json")
+	flags.BoolVar(&s.redact, renderRedact, true, "replace private keys and credentials copied from Secrets in the output")
+	flags.BoolVar(&s.debug, debug, false, "enable debug logging")
+	if err := flags.MarkHidden("debug"); err != nil {
+		return err
+	}
+	return viperWalk(flags)
+}
+
+func (s *renderCmd) exec(cmd *cobra.Command, _ []string) error {
+	setupLogger(s.debug)
+	ctx := cmd.Context()
+
+	if s.output != "yaml" && s.output != "json" {
+		return fmt.Errorf("%s=%s: must be yaml or json", renderOutput, s.output)
+	}
+
+	scheme, err := getScheme()
+	if err != nil {
+		return fmt.Errorf("scheme: %w", err)
+	}
+	objs, err := s.readObjects(cmd.InOrStdin(), scheme)
+	if err != nil {
+		return err
+	}
+	c, err := newRenderClient(ctx, scheme, objs)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := s.render(ctx, c)
+	if cfg == nil {
+		return err
+	}
+	out, marshalErr := marshalRenderedConfig(cfg, s.output)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	if _, writeErr := cmd.OutOrStdout().Write(out); writeErr != nil {
+		return writeErr
+	}
+	// the configuration that could be rendered is printed even if some objects could not
+	return err
+}
+
+// render returns the configuration for all the objects in c. The objects that could not be
+// rendered are left out, and returned as errors along with the configuration.
+func (s *renderCmd) render(ctx context.Context, c client.Client) (*pb.Config, error) {
+	opts := pomerium.RenderOptions{Redact: s.redact}
+	var errs []error
+
+	var err error
+	opts.Settings, err = s.fetchSettings(ctx, c)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	opts.Ingresses, err = ingress.FetchManagedIngresses(ctx, c,
+		ingress.WithNamespaces(s.namespaces),
+		ingress.WithAnnotationPrefix(s.annotationPrefix),
+		ingress.WithControllerName(s.className),
+	)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("ingresses: %w", err))
+	}
+	opts.Gateway, err = gateway.FetchGatewayConfig(ctx, c, gateway.ControllerConfig{
+		ControllerName: s.gatewayClassName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gateway: %w", err)
+	}
+
+	ctx = util.WithBin[pom_cfg.FieldMsg](ctx)
+	cfg, err := pomerium.Render(ctx, opts)
+	for _, warning := range reporter.ConfigWarnings(ctx) {
+		fmt.Fprintln(s.ErrOrStderr(), "warning:", warning)
+	}
+	return cfg, errors.Join(append(errs, err)...)
+}
+
+// fetchSettings returns the settings of the Pomerium object named by the globalSettings flag, or
+// of the only Pomerium object if it is not set. Without any Pomerium objects, there are no settings.
+func (s *renderCmd) fetchSettings(ctx context.Context, c client.Client) (*model.Config, error) {
+	var pom icsv1.Pomerium
+	if s.globalSettings != "" {
+		name, err := util.ParseNamespacedName(s.globalSettings, util.WithClusterScope())
+		if err != nil {
+			return nil, fmt.Errorf("%s=%s: %w", globalSettings, s.globalSettings, err)
+		}
+		if err := c.Get(ctx, *name, &pom); err != nil {
+			return nil, fmt.Errorf("get settings %s: %w", name.Name, err)
+		}
+	} else {
+		var list icsv1.PomeriumList
+		if err := c.List(ctx, &list); err != nil {
+			return nil, fmt.Errorf("list settings: %w", err)
+		}
+		switch len(list.Items) {
+		case 0:
+			return nil, nil
+		case 1:
+			pom = list.Items[0]
+		default:
+			return nil, fmt.Errorf("found %d Pomerium objects, use --%s to select one", len(list.Items), globalSettings)
+		}
+	}
+
+	cfg, err := settings.FetchConfigFor(ctx, c, &pom)
+	if err != nil {
+		return nil, fmt.Errorf("settings %s: %w", pom.Name, err)
+	}
+	return cfg, nil
+}
+
+// readObjects decodes all objects of the kinds known to scheme from the manifest files.
+func (s *renderCmd) readObjects(stdin io.Reader, scheme *runtime.Scheme) ([]client.Object, error) {
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	var objs []client.Object
+	for _, filename := range s.filenames {
+		paths, err := manifestPaths(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			fileObjs, err := readManifestFile(decoder, path, stdin)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			objs = append(objs, fileObjs...)
+		}
+	}
+
+	for _, obj := range objs {
+		if obj.GetNamespace() == "" && !isClusterScoped(obj.GetObjectKind().GroupVersionKind().GroupKind()) {
+			obj.SetNamespace(s.defaultNamespace)
+		}
+	}
+	return objs, nil
+}
+
+// manifestPaths returns the YAML and JSON files of a directory, or the filename itself.
+func manifestPaths(filename string) ([]string, error) {
+	if filename == "-" {
+		return []string{filename}, nil
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{filename}, nil
+	}
+
+	var paths []string
+	err = filepath.WalkDir(filename, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+			if !d.IsDir() {
+				paths = append(paths, path)
+			}
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func readManifestFile(decoder runtime.Decoder, path string, stdin io.Reader) ([]client.Object, error) {
+	if path == "-" {
+		return decodeManifests(decoder, stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeManifests(decoder, f)
+}
+
+func decodeManifests(decoder runtime.Decoder, r io.Reader) ([]client.Object, error) {
+	var objs []client.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docObjs, err := decodeManifest(decoder, doc)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, docObjs...)
+	}
+}
+
+func decodeManifest(decoder runtime.Decoder, doc []byte) ([]client.Object, error) {
+	doc, err := utilyaml.ToJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(doc)) == 0 || bytes.Equal(doc, []byte("null")) {
+		return nil, nil
+	}
+
+	obj, gvk, err := decoder.Decode(doc, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if list, ok := obj.(*corev1.List); ok {
+		var objs []client.Object
+		for _, item := range list.Items {
+			itemObjs, err := decodeManifest(decoder, item.Raw)
+			if err != nil {
+				return nil, err
+			}
+			objs = append(objs, itemObjs...)
+		}
+		return objs, nil
+	}
+
+	cobj, ok := obj.(client.Object)
+	if !ok {
+		return nil, nil
+	}
+	cobj.GetObjectKind().SetGroupVersionKind(*gvk)
+	return []client.Object{cobj}, nil
+}
+
+// clusterScopedKinds are the cluster-scoped kinds that may be relevant to rendering.
+var clusterScopedKinds = []schema.GroupKind{
+	{Group: corev1.GroupName, Kind: "Namespace"},
+	{Group: networkingv1.GroupName, Kind: "IngressClass"},
+	{Group: gateway_v1.GroupName, Kind: "GatewayClass"},
+	{Group: icsv1.GroupVersion.Group, Kind: "Pomerium"},
+	{Group: icgv1alpha1.GroupVersion.Group, Kind: "GatewayClassConfig"},
+	{Group: certmanager_v1.SchemeGroupVersion.Group, Kind: certmanager_v1.ClusterIssuerKind},
+}
+
+func isClusterScoped(gk schema.GroupKind) bool {
+	return slices.Contains(clusterScopedKinds, gk)
+}
+
+// newRenderClient returns an in-memory client holding objs, and a Namespace for each namespace
+// that objs reference but do not include, for the controllers to fetch objects from. All the
+// kinds of scheme are served, as if their CRDs were installed.
+func newRenderClient(ctx context.Context, scheme *runtime.Scheme, objs []client.Object) (client.Client, error) {
+	mapper := meta.NewDefaultRESTMapper(scheme.PrioritizedVersionsAllGroups())
+	for gvk := range scheme.AllKnownTypes() {
+		scope := meta.RESTScopeNamespace
+		if isClusterScoped(gvk.GroupKind()) {
+			scope = meta.RESTScopeRoot
+		}
+		mapper.Add(gvk, scope)
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithRESTMapper(mapper).
+		WithIndex(&corev1.Secret{}, gateway.SecretTypeIndex, gateway.SecretType).
+		WithStatusSubresource(
+			&networkingv1.Ingress{},
+			&icsv1.Pomerium{},
+			&icgv1alpha1.PolicyFilter{},
+			&gateway_v1.GatewayClass{},
+			&gateway_v1.Gateway{},
+			&gateway_v1.HTTPRoute{},
+			&gateway_v1.GRPCRoute{},
+			&gateway_v1.TCPRoute{},
+			&gateway_v1.UDPRoute{},
+			&gateway_v1.BackendTLSPolicy{},
+		).
+		Build()
+
+	namespaces := make(map[string]bool)
+	for _, obj := range objs {
+		if ns := obj.GetNamespace(); ns != "" {
+			namespaces[ns] = true
+		}
+		if _, ok := obj.(*corev1.Namespace); ok {
+			namespaces[obj.GetName()] = false
+		}
+	}
+	for name, missing := range namespaces {
+		if missing {
+			objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+	}
+
+	for _, obj := range objs {
+		obj.SetResourceVersion("")
+		if err := c.Create(ctx, obj); err != nil {
+			return nil, fmt.Errorf("%s %s: %w", obj.GetObjectKind().GroupVersionKind().Kind,
+				util.GetNamespacedName(obj), err)
+		}
+	}
+	return c, nil
+}
+
+// marshalRenderedConfig formats cfg as indented JSON or as YAML, with a stable layout so that the
+// output of different runs may be compared.
+func marshalRenderedConfig(cfg *pb.Config, format string) ([]byte, error) {
+	data, err := protojson.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("marshal config: %w", err)
+	}
+	if format == "yaml" {
+		return yaml.JSONToYAML(data)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, fmt.Errorf("format config: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
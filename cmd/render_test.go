@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+const renderTestManifests = `
+apiVersion: networking.k8s.io/v1
+kind: IngressClass
+metadata:
+  name: pomerium
+  annotations:
+    ingressclass.kubernetes.io/is-default-class: "true"
+spec:
+  controller: pomerium.io/ingress-controller
+---
+apiVersion: example.com/v1
+kind: Unknown
+metadata:
+  name: ignored
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: app
+    spec:
+      ports:
+        - port: 80
+  - apiVersion: networking.k8s.io/v1
+    kind: Ingress
+    metadata:
+      name: app
+      annotations:
+        ingress.pomerium.io/allow_any_authenticated_user: "true"
+        ingress.pomerium.io/service_proxy_upstream: "true"
+    spec:
+      rules:
+        - host: app.localhost.pomerium.io
+          http:
+            paths:
+              - path: /
+                pathType: Prefix
+                backend:
+                  service:
+                    name: app
+                    port:
+                      number: 80
+`
+
+func TestRender(t *testing.T) {
+	cmd, err := RenderCommand()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	cmd.SetIn(strings.NewReader(renderTestManifests))
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--" + renderDefaultNamespace, "apps"})
+	require.NoError(t, cmd.Execute())
+
+	var cfg struct {
+		Routes []struct {
+			From string   `json:"from"`
+			To   []string `json:"to"`
+		} `json:"routes"`
+	}
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &cfg))
+	require.Len(t, cfg.Routes, 1)
+	assert.Equal(t, "https://app.localhost.pomerium.io", cfg.Routes[0].From)
+	assert.Equal(t, []string{"http://app.apps.svc.cluster.local:80"}, cfg.Routes[0].To)
+}
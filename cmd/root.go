@@ -23,6 +23,8 @@ func RootCommand() (*cobra.Command, error) {
 		"gen-secrets": GenSecretsCommand,
 		"controller":  ControllerCommand,
 		"all-in-one":  AllInOneCommand,
+		"render":      RenderCommand,
+		"diff":        DiffCommand,
 		"stress-test": stress_cmd.Command,
 	} {
 		cmd, err := fn()
@@ -0,0 +1,175 @@
+// Package apitoken implements a controller that loads the unified API token from a Secret.
+package apitoken
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/pomerium"
+)
+
+const (
+	// TokenKey is the key of the Secret data holding the unified API token.
+	TokenKey = "token"
+
+	// APIAuthenticatedCondition is the Pomerium CRD status condition reporting whether the
+	// unified API token has been loaded and not rejected.
+	APIAuthenticatedCondition = "APIAuthenticated"
+
+	// statusRefreshInterval is how often the status condition is refreshed, to reflect the
+	// outcome of recent unified API requests.
+	statusRefreshInterval = time.Minute
+
+	controllerName = "api-token"
+)
+
+type tokenController struct {
+	// secrets caches just the token Secret
+	secrets client.Reader
+	// Client is used to update the Pomerium CRD status
+	client.Client
+	pomerium.APITokenUpdater
+
+	secretName types.NamespacedName
+	// globalSettings, if set, is the Pomerium CRD to report the token status on
+	globalSettings *types.NamespacedName
+
+	// token is the token last loaded
+	token string
+}
+
+// NewController creates and registers a controller that loads the unified API token from the
+// given Secret whenever it changes. Unless the reconciler was created with a token, unified API
+// requests wait until the token is first loaded. If globalSettings is set, whether the token is
+// loaded and accepted by the unified API is reported as a condition of the Pomerium CRD status.
+func NewController(
+	mgr ctrl.Manager,
+	updater pomerium.APITokenUpdater,
+	secretName types.NamespacedName,
+	globalSettings *types.NamespacedName,
+) error {
+	// Watch just the one Secret, which need not be in one of the watched namespaces.
+	secrets, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:            mgr.GetScheme(),
+		Mapper:            mgr.GetRESTMapper(),
+		DefaultNamespaces: map[string]cache.Config{secretName.Namespace: {}},
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}: {Field: fields.OneTermEqualSelector("metadata.name", secretName.Name)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("create secret cache: %w", err)
+	}
+	if err := mgr.Add(secrets); err != nil {
+		return fmt.Errorf("add secret cache: %w", err)
+	}
+
+	tc := &tokenController{
+		secrets:         secrets,
+		Client:          mgr.GetClient(),
+		APITokenUpdater: updater,
+		secretName:      secretName,
+		globalSettings:  globalSettings,
+	}
+
+	// Reconcile once at startup, even if the Secret does not exist yet.
+	initial := source.Func(func(_ context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		q.Add(reconcile.Request{NamespacedName: secretName})
+		return nil
+	})
+
+	err = ctrl.NewControllerManagedBy(mgr).
+		Named(controllerName).
+		WatchesRawSource(source.Kind(secrets, &corev1.Secret{}, &handler.TypedEnqueueRequestForObject[*corev1.Secret]{})).
+		WatchesRawSource(initial).
+		Complete(tc)
+	if err != nil {
+		return fmt.Errorf("build controller: %w", err)
+	}
+	return nil
+}
+
+// Reconcile loads the token from the Secret, if changed, and refreshes the status condition.
+func (c *tokenController) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	var secret corev1.Secret
+	var tokenErr error
+	if err := c.secrets.Get(ctx, c.secretName, &secret); apierrors.IsNotFound(err) {
+		tokenErr = fmt.Errorf("secret %s not found", c.secretName)
+	} else if err != nil {
+		return ctrl.Result{}, fmt.Errorf("get secret %s: %w", c.secretName, err)
+	} else {
+		tokenErr = c.loadToken(ctx, &secret)
+	}
+
+	if err := c.updateStatus(ctx, tokenErr); err != nil {
+		return ctrl.Result{}, fmt.Errorf("update status: %w", err)
+	}
+	return ctrl.Result{RequeueAfter: statusRefreshInterval}, nil
+}
+
+// loadToken passes the token from the Secret to the API reconciler if it has changed.
+// While the Secret has no token, the token last loaded remains in use.
+func (c *tokenController) loadToken(ctx context.Context, secret *corev1.Secret) error {
+	token := strings.TrimSpace(string(secret.Data[TokenKey]))
+	if token == "" {
+		return fmt.Errorf("secret %s has no %q key", c.secretName, TokenKey)
+	}
+	if token != c.token {
+		log.FromContext(ctx).Info("loaded unified API token", "secret", c.secretName)
+		c.SetAPIToken(token)
+		c.token = token
+	}
+	return nil
+}
+
+func (c *tokenController) updateStatus(ctx context.Context, tokenErr error) error {
+	if c.globalSettings == nil {
+		return nil
+	}
+
+	condition := metav1.Condition{
+		Type:    APIAuthenticatedCondition,
+		Status:  metav1.ConditionTrue,
+		Reason:  "TokenLoaded",
+		Message: fmt.Sprintf("token loaded from secret %s", c.secretName),
+	}
+	if tokenErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TokenUnavailable"
+		condition.Message = tokenErr.Error()
+	} else if err := c.APIAuthError(); err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "TokenRejected"
+		condition.Message = err.Error()
+	}
+
+	var pom icsv1.Pomerium
+	if err := c.Get(ctx, *c.globalSettings, &pom); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	original := pom.DeepCopy()
+	condition.ObservedGeneration = pom.Generation
+	if !meta.SetStatusCondition(&pom.Status.Conditions, condition) {
+		return nil
+	}
+	return c.Status().Patch(ctx, &pom,
+		client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+}
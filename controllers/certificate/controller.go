@@ -12,7 +12,6 @@ import (
 
 	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
 	certmanager_meta_v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
-	"github.com/hashicorp/go-set/v3"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
@@ -22,7 +21,6 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/apimachinery/pkg/util/rand"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -77,7 +75,7 @@ func (c *certificateController) reconcile(ctx context.Context) error {
 		return fmt.Errorf("error retrieving pomerium settings: %w", err)
 	}
 
-	var namespace string
+	var namespace, issuerNamespace string
 	var issuer certmanager_meta_v1.IssuerReference
 	if settings.Spec.CertificateAutoProvision != nil && settings.Spec.CertificateAutoProvision.ClusterIssuer != nil {
 		issuer = certmanager_meta_v1.IssuerReference{
@@ -90,6 +88,7 @@ func (c *certificateController) reconcile(ctx context.Context) error {
 			return fmt.Errorf("error parsing certificate auto provision issuer: %w", err)
 		}
 		namespace = name.Namespace
+		issuerNamespace = name.Namespace
 		issuer = certmanager_meta_v1.IssuerReference{
 			Kind: "Issuer",
 			Name: name.Name,
@@ -127,8 +126,22 @@ func (c *certificateController) reconcile(ctx context.Context) error {
 		return fmt.Errorf("error listing secrets: %w", err)
 	}
 
+	strategy := getProvisionStrategy(settings.Spec.CertificateAutoProvision)
+	if strategy.wildcardThreshold > 0 {
+		ok, err := c.issuerSupportsWildcards(ctx, issuer, issuerNamespace)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.FromContext(ctx).Info("certificate-controller: issuer has no DNS-01 challenge solver, not provisioning wildcard certificates",
+				"issuer-kind", issuer.Kind,
+				"issuer-name", issuer.Name)
+			strategy.wildcardThreshold = 0
+		}
+	}
+
 	return errors.Join(
-		c.reconcileCertificates(ctx, namespace, issuer, cl.Items),
+		c.reconcileCertificates(ctx, namespace, issuer, strategy, cl.Items),
 		c.reconcileSecrets(ctx, sl.Items),
 	)
 }
@@ -137,6 +150,7 @@ func (c *certificateController) reconcileCertificates(
 	ctx context.Context,
 	namespace string,
 	issuer certmanager_meta_v1.IssuerReference,
+	strategy provisionStrategy,
 	certificates []certmanager_v1.Certificate,
 ) error {
 	// delete any certificates with a different cluster issuer
@@ -161,28 +175,25 @@ func (c *certificateController) reconcileCertificates(
 	if err := c.dataBrokerCollector.Sync(); err != nil {
 		return fmt.Errorf("error syncing databroker data: %w", err)
 	}
-	missingNames := set.From(c.dataBrokerCollector.MissingNames())
-
-	// remove any missing names for which we've already created certificates
-	for _, cert := range certificatesForIssuer {
-		used := false
-		for _, name := range cert.Spec.DNSNames {
-			if missingNames.Contains(name) {
-				missingNames.Remove(name)
-				used = true
-			}
+	plan := planCertificates(strategy, c.dataBrokerCollector.MissingNames(), certificatesForIssuer)
+
+	// delete any certificates which are no longer needed, or don't conform to the strategy
+	for _, cert := range plan.delete {
+		if err := c.deleteCertificate(ctx, cert); err != nil {
+			return err
 		}
-		// if the certificate is not being used, remove it
-		if !used {
-			if err := c.deleteCertificate(ctx, &cert); err != nil {
-				return err
-			}
+	}
+
+	// update any certificates whose names have changed
+	for _, cert := range plan.update {
+		if err := c.updateCertificate(ctx, cert); err != nil {
+			return err
 		}
 	}
 
 	// create any certificates for any missing names
-	for name := range missingNames.Items() {
-		if err := c.createCertificate(ctx, namespace, issuer, name); err != nil {
+	for _, dnsNames := range plan.create {
+		if err := c.createCertificate(ctx, namespace, issuer, strategy, dnsNames); err != nil {
 			return err
 		}
 	}
@@ -200,6 +211,7 @@ func (c *certificateController) reconcileSecrets(
 	})
 
 	cfg := new(configpb.Config)
+	var synced []*core_v1.Secret
 	for _, s := range secrets {
 		certPEM := s.Data["tls.crt"]
 		keyPEM := s.Data["tls.key"]
@@ -211,10 +223,15 @@ func (c *certificateController) reconcileSecrets(
 				CertBytes: certPEM,
 				KeyBytes:  keyPEM,
 			})
+			synced = append(synced, &s)
 		}
 	}
 
-	return c.upsertConfig(ctx, cfg)
+	if err := c.upsertConfig(ctx, cfg); err != nil {
+		return err
+	}
+	c.cfg.monitor.Observe(ctx, "certificate auto provisioning", synced)
+	return nil
 }
 
 func (c *certificateController) run(mgr controllerruntime.Manager) {
@@ -248,6 +265,8 @@ func (c *certificateController) run(mgr controllerruntime.Manager) {
 		Watches(new(core_v1.Secret), &handler.EnqueueRequestForObject{}).
 		Watches(new(pomerium_ingress_v1.Pomerium), &handler.EnqueueRequestForObject{}).
 		Watches(new(certmanager_v1.Certificate), &handler.EnqueueRequestForObject{}).
+		Watches(new(certmanager_v1.ClusterIssuer), &handler.EnqueueRequestForObject{}).
+		Watches(new(certmanager_v1.Issuer), &handler.EnqueueRequestForObject{}).
 		Complete(c)
 	if err != nil {
 		log.FromContext(ctx).Error(err, "error building certificate controller")
@@ -258,9 +277,10 @@ func (c *certificateController) createCertificate(
 	ctx context.Context,
 	namespace string,
 	issuer certmanager_meta_v1.IssuerReference,
-	dnsName string,
+	strategy provisionStrategy,
+	dnsNames []string,
 ) error {
-	k8sName := "pomerium-certificate-" + rand.String(16)
+	k8sName := certificateName(dnsNames)
 	cert := &certmanager_v1.Certificate{
 		ObjectMeta: meta_v1.ObjectMeta{
 			Name:      k8sName,
@@ -276,21 +296,36 @@ func (c *certificateController) createCertificate(
 					managedByLabelName: managedByLabelValue,
 				},
 			},
-			DNSNames:  []string{dnsName},
+			DNSNames:  dnsNames,
 			IssuerRef: issuer,
 		},
 	}
 	log.FromContext(ctx).Info("certificate-controller: creating certificate",
+		"name", k8sName,
 		"namespace", namespace,
 		"issuer-kind", issuer.Kind,
 		"issuer-name", issuer.Name,
-		"dns-name", dnsName)
-	if err := c.kubernetesClient.Create(ctx, cert); err != nil {
+		"strategy", strategy.name,
+		"dns-names", dnsNames)
+	// the certificate may already exist if it was created by a previous reconcile that isn't yet
+	// reflected in the cache
+	if err := c.kubernetesClient.Create(ctx, cert); err != nil && !apierrors.IsAlreadyExists(err) {
 		return fmt.Errorf("error creating certificate: %w", err)
 	}
 	return nil
 }
 
+func (c *certificateController) updateCertificate(ctx context.Context, cert *certmanager_v1.Certificate) error {
+	log.FromContext(ctx).Info("certificate-controller: updating certificate",
+		"name", cert.Name,
+		"namespace", cert.Namespace,
+		"dns-names", cert.Spec.DNSNames)
+	if err := c.kubernetesClient.Update(ctx, cert); err != nil {
+		return fmt.Errorf("error updating certificate (%s/%s): %w", cert.Namespace, cert.Name, err)
+	}
+	return nil
+}
+
 func (c *certificateController) deleteCertificate(ctx context.Context, cert *certmanager_v1.Certificate) error {
 	log.FromContext(ctx).Info("certificate-controller: deleting certificate",
 		"name", cert.Name,
@@ -11,6 +11,8 @@ type controllerConfig struct {
 	// if not set, discover the namespace from the issuer or the pod where the
 	// controller is running
 	namespace *string
+	// if set, tracks the expiry of the provisioned certificates
+	monitor *Monitor
 }
 
 // An Option customizes the config.
@@ -37,6 +39,13 @@ func WithNamespace(namespace string) Option {
 	}
 }
 
+// WithMonitor sets the certificate monitor in the config.
+func WithMonitor(monitor *Monitor) Option {
+	return func(cfg *controllerConfig) {
+		cfg.monitor = monitor
+	}
+}
+
 func getControllerConfig(options ...Option) *controllerConfig {
 	cfg := new(controllerConfig)
 	WithControllerName(DefaultControllerName)(cfg)
@@ -26,6 +26,7 @@ func TestReconcileCertificates(t *testing.T) {
 	ctx := context.Background()
 	namespace := "default"
 	issuer := certmanager_meta_v1.IssuerReference{Kind: "ClusterIssuer", Name: "letsencrypt"}
+	perName := getProvisionStrategy(nil)
 
 	scheme := runtime.NewScheme()
 	require.NoError(t, clientgoscheme.AddToScheme(scheme))
@@ -79,7 +80,7 @@ func TestReconcileCertificates(t *testing.T) {
 
 	t.Run("no issuer skips provisioning", func(t *testing.T) {
 		c := newController(t)
-		err := c.reconcileCertificates(ctx, namespace, certmanager_meta_v1.IssuerReference{}, nil)
+		err := c.reconcileCertificates(ctx, namespace, certmanager_meta_v1.IssuerReference{}, perName, nil)
 		assert.NoError(t, err)
 		assert.Empty(t, listCerts(t, c))
 	})
@@ -90,7 +91,7 @@ func TestReconcileCertificates(t *testing.T) {
 		secret := &core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: "a-secret"}}
 		c := newController(t, cert, secret)
 
-		err := c.reconcileCertificates(ctx, namespace, certmanager_meta_v1.IssuerReference{}, []certmanager_v1.Certificate{*cert})
+		err := c.reconcileCertificates(ctx, namespace, certmanager_meta_v1.IssuerReference{}, perName, []certmanager_v1.Certificate{*cert})
 		require.NoError(t, err)
 
 		assert.Empty(t, listCerts(t, c), "mismatched cert should be deleted")
@@ -105,7 +106,7 @@ func TestReconcileCertificates(t *testing.T) {
 			{"r", "1"}: {"a.example.com"},
 		})
 
-		err := c.reconcileCertificates(ctx, namespace, issuer, []certmanager_v1.Certificate{*cert})
+		err := c.reconcileCertificates(ctx, namespace, issuer, perName, []certmanager_v1.Certificate{*cert})
 		require.NoError(t, err)
 
 		remaining := listCerts(t, c)
@@ -121,7 +122,7 @@ func TestReconcileCertificates(t *testing.T) {
 			{"r", "1"}: {"b.example.com"},
 		})
 
-		err := c.reconcileCertificates(ctx, namespace, issuer, []certmanager_v1.Certificate{*certA, *certB})
+		err := c.reconcileCertificates(ctx, namespace, issuer, perName, []certmanager_v1.Certificate{*certA, *certB})
 		require.NoError(t, err)
 
 		remaining := listCerts(t, c)
@@ -135,12 +136,13 @@ func TestReconcileCertificates(t *testing.T) {
 			{"r", "1"}: {"new.example.com"},
 		})
 
-		err := c.reconcileCertificates(ctx, namespace, issuer, nil)
+		err := c.reconcileCertificates(ctx, namespace, issuer, perName, nil)
 		require.NoError(t, err)
 
 		created := listCerts(t, c)
 		require.Len(t, created, 1)
 		got := created[0]
+		assert.Equal(t, certificateName([]string{"new.example.com"}), got.Name, "name should be deterministic")
 		assert.Equal(t, namespace, got.Namespace)
 		assert.Equal(t, []string{"new.example.com"}, got.Spec.DNSNames)
 		assert.Equal(t, issuer, got.Spec.IssuerRef)
@@ -159,7 +161,7 @@ func TestReconcileCertificates(t *testing.T) {
 			{"r", "1"}: {"a.example.com"},
 		})
 
-		err := c.reconcileCertificates(ctx, namespace, issuer, []certmanager_v1.Certificate{*cert})
+		err := c.reconcileCertificates(ctx, namespace, issuer, perName, []certmanager_v1.Certificate{*cert})
 		require.NoError(t, err)
 
 		created := listCerts(t, c)
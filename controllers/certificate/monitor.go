@@ -0,0 +1,325 @@
+package certificate
+
+import (
+	"cmp"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	core_v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/internal/certificate"
+)
+
+const (
+	// DefaultExpiryWarning is how long before a certificate expires warning Events are emitted
+	// by default.
+	DefaultExpiryWarning = 14 * 24 * time.Hour
+
+	monitorCheckInterval = time.Hour
+
+	reasonCertificateExpiring = "CertificateExpiring"
+	reasonCertificateExpired  = "CertificateExpired"
+)
+
+var certificateExpirySeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "pomerium_ingress_controller",
+	Subsystem: "certificate",
+	Name:      "expiry_seconds",
+	Help:      "Seconds until a TLS certificate synced to Pomerium expires as of the latest check, negative once expired, by Secret.",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(certificateExpirySeconds)
+}
+
+// A Monitor tracks the expiry of the TLS certificates synced to Pomerium. Controllers report the
+// Secrets they sync for each source, and the Monitor periodically summarizes the certificates in
+// the status of the Pomerium CRD, emits warning Events for certificates about to expire and
+// exports their remaining validity as a metric.
+//
+// All methods may be called on a nil Monitor, which does nothing.
+type Monitor struct {
+	client         client.Client
+	recorder       record.EventRecorder
+	globalSettings *types.NamespacedName
+	expiryWarning  time.Duration
+
+	mu      sync.Mutex
+	sources map[string][]observedCertificate
+	warned  map[types.NamespacedName]time.Time
+	changed chan struct{}
+}
+
+type observedCertificate struct {
+	secret   types.NamespacedName
+	notAfter time.Time
+	issuer   string
+	dnsNames []string
+}
+
+// NewMonitor creates a new Monitor. Certificates are summarized in the status of the
+// globalSettings Pomerium CRD if set, and warning Events are emitted once a certificate expires
+// within expiryWarning, or never if it is zero.
+func NewMonitor(
+	c client.Client,
+	recorder record.EventRecorder,
+	globalSettings *types.NamespacedName,
+	expiryWarning time.Duration,
+) *Monitor {
+	return &Monitor{
+		client:         c,
+		recorder:       recorder,
+		globalSettings: globalSettings,
+		expiryWarning:  expiryWarning,
+		sources:        make(map[string][]observedCertificate),
+		warned:         make(map[types.NamespacedName]time.Time),
+		changed:        make(chan struct{}, 1),
+	}
+}
+
+// Observe records the TLS Secrets synced for a source, such as "Ingress default/example",
+// replacing any previously observed for it. Secrets without a server certificate are ignored.
+func (m *Monitor) Observe(ctx context.Context, source string, secrets []*core_v1.Secret) {
+	if m == nil {
+		return
+	}
+
+	var observed []observedCertificate
+	for _, secret := range secrets {
+		if secret == nil || secret.Type != core_v1.SecretTypeTLS {
+			continue
+		}
+		name := types.NamespacedName{Namespace: secret.Namespace, Name: secret.Name}
+		cert := leafCertificate(secret.Data[core_v1.TLSCertKey])
+		if cert == nil {
+			log.FromContext(ctx).V(1).Info("no server certificate found in secret", "secret", name, "source", source)
+			continue
+		}
+		observed = append(observed, observedCertificate{
+			secret:   name,
+			notAfter: cert.NotAfter,
+			issuer:   cert.Issuer.String(),
+			dnsNames: slices.Clone(cert.DNSNames),
+		})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.EqualFunc(m.sources[source], observed, observedCertificate.equal) {
+		return
+	}
+	if len(observed) == 0 {
+		delete(m.sources, source)
+	} else {
+		m.sources[source] = observed
+	}
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Forget removes the TLS Secrets observed for a source.
+func (m *Monitor) Forget(ctx context.Context, source string) {
+	m.Observe(ctx, source, nil)
+}
+
+// Start checks the observed certificates whenever they change, and at least hourly, until the
+// context is canceled. It implements manager.Runnable, so it only runs while leader.
+func (m *Monitor) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("certificate-monitor")
+	ticker := time.NewTicker(monitorCheckInterval)
+	defer ticker.Stop()
+	// another replica exports the metrics once this one is no longer leader
+	defer certificateExpirySeconds.Reset()
+
+	for {
+		if err := m.Check(ctx); err != nil {
+			logger.Error(err, "certificate check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-m.changed:
+		}
+	}
+}
+
+// Check updates the metrics, Events and Pomerium CRD status for the observed certificates.
+func (m *Monitor) Check(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+
+	now := time.Now()
+	certs := m.certificates()
+
+	certificateExpirySeconds.Reset()
+	for _, cert := range certs {
+		certificateExpirySeconds.
+			WithLabelValues(cert.secret.Namespace, cert.secret.Name).
+			Set(cert.notAfter.Sub(now).Seconds())
+	}
+
+	var settings *pomerium_ingress_v1.Pomerium
+	if m.globalSettings != nil {
+		settings = new(pomerium_ingress_v1.Pomerium)
+		if err := m.client.Get(ctx, *m.globalSettings, settings); apierrors.IsNotFound(err) {
+			settings = nil
+		} else if err != nil {
+			return fmt.Errorf("get settings %s: %w", m.globalSettings.Name, err)
+		}
+	}
+
+	m.warn(ctx, settings, certs, now)
+
+	if settings == nil {
+		return nil
+	}
+	return m.updateStatus(ctx, settings, certs, now)
+}
+
+// warn emits warning Events, on the Secret and the Pomerium CRD, for certificates that expire
+// within the warning period, at most once per check interval for each.
+func (m *Monitor) warn(ctx context.Context, settings *pomerium_ingress_v1.Pomerium, certs []monitoredCertificate, now time.Time) {
+	if m.expiryWarning <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	warned := make(map[types.NamespacedName]time.Time)
+	for _, cert := range certs {
+		remaining := cert.notAfter.Sub(now)
+		if remaining > m.expiryWarning {
+			continue
+		}
+		if last, ok := m.warned[cert.secret]; ok && now.Sub(last) < monitorCheckInterval {
+			warned[cert.secret] = last
+			continue
+		}
+		warned[cert.secret] = now
+
+		reason, msg := reasonCertificateExpiring,
+			fmt.Sprintf("certificate for %v expires in %s, at %s",
+				cert.dnsNames, remaining.Round(time.Minute), cert.notAfter.UTC().Format(time.RFC3339))
+		if remaining <= 0 {
+			reason, msg = reasonCertificateExpired,
+				fmt.Sprintf("certificate for %v expired at %s", cert.dnsNames, cert.notAfter.UTC().Format(time.RFC3339))
+		}
+		log.FromContext(ctx).Info(msg, "secret", cert.secret, "sources", cert.sources)
+		m.recorder.Event(&core_v1.Secret{ObjectMeta: meta_v1.ObjectMeta{
+			Namespace: cert.secret.Namespace,
+			Name:      cert.secret.Name,
+		}}, core_v1.EventTypeWarning, reason, msg)
+		if settings != nil {
+			m.recorder.Event(settings, core_v1.EventTypeWarning, reason,
+				fmt.Sprintf("secret %s: %s", cert.secret, msg))
+		}
+	}
+	m.warned = warned
+}
+
+// updateStatus records a summary of the certificates in the Pomerium CRD status. The state of
+// each certificate is only reported by the metric, and by the Events emitted by warn, so that
+// the status does not grow with the number of certificates.
+func (m *Monitor) updateStatus(
+	ctx context.Context,
+	settings *pomerium_ingress_v1.Pomerium,
+	certs []monitoredCertificate,
+	now time.Time,
+) error {
+	var summary *pomerium_ingress_v1.CertificateSummary
+	if len(certs) > 0 {
+		summary = &pomerium_ingress_v1.CertificateSummary{Total: int32(len(certs))}
+	}
+	for _, cert := range certs {
+		switch remaining := cert.notAfter.Sub(now); {
+		case remaining <= 0:
+			summary.Expired++
+		case remaining <= m.expiryWarning:
+			summary.Expiring++
+		}
+		if summary.EarliestExpiry == nil || cert.notAfter.Before(summary.EarliestExpiry.Time) {
+			summary.EarliestExpiry = new(meta_v1.NewTime(cert.notAfter))
+			summary.EarliestExpirySecret = cert.secret.String()
+		}
+	}
+	if apiequality.Semantic.DeepEqual(settings.Status.CertificateSummary, summary) {
+		return nil
+	}
+
+	patch := client.MergeFrom(settings.DeepCopy())
+	settings.Status.CertificateSummary = summary
+	if err := m.client.Status().Patch(ctx, settings, patch); err != nil {
+		return fmt.Errorf("update settings status: %w", err)
+	}
+	return nil
+}
+
+// monitoredCertificate is an observed certificate with all of the sources it was observed for.
+type monitoredCertificate struct {
+	observedCertificate
+	sources []string
+}
+
+// certificates returns the observed certificates, sorted by Secret.
+func (m *Monitor) certificates() []monitoredCertificate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bySecret := make(map[types.NamespacedName]*monitoredCertificate)
+	for _, source := range slices.Sorted(maps.Keys(m.sources)) {
+		for _, cert := range m.sources[source] {
+			if mc, ok := bySecret[cert.secret]; ok {
+				mc.sources = append(mc.sources, source)
+				continue
+			}
+			bySecret[cert.secret] = &monitoredCertificate{observedCertificate: cert, sources: []string{source}}
+		}
+	}
+
+	certs := make([]monitoredCertificate, 0, len(bySecret))
+	for _, mc := range bySecret {
+		certs = append(certs, *mc)
+	}
+	slices.SortFunc(certs, func(x, y monitoredCertificate) int {
+		return cmp.Or(
+			cmp.Compare(x.secret.Namespace, y.secret.Namespace),
+			cmp.Compare(x.secret.Name, y.secret.Name))
+	})
+	return certs
+}
+
+func (c observedCertificate) equal(other observedCertificate) bool {
+	return c.secret == other.secret &&
+		c.notAfter.Equal(other.notAfter) &&
+		c.issuer == other.issuer &&
+		slices.Equal(c.dnsNames, other.dnsNames)
+}
+
+// leafCertificate returns the first server certificate in PEM data, which is the leaf of a chain.
+func leafCertificate(data []byte) *x509.Certificate {
+	for cert := range certificate.IterateServerCertificatesFromPEM(data) {
+		return cert
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+)
+
+func TestMonitor(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, pomerium_ingress_v1.AddToScheme(scheme))
+
+	settings := &pomerium_ingress_v1.Pomerium{ObjectMeta: meta_v1.ObjectMeta{Name: "global"}}
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(settings).
+		WithStatusSubresource(settings).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	m := NewMonitor(cl, recorder, &types.NamespacedName{Name: "global"}, 7*24*time.Hour)
+
+	now := time.Now()
+	expiring := newTLSSecret(t, "default", "expiring", now.Add(24*time.Hour), "a.example.com")
+	valid := newTLSSecret(t, "default", "valid", now.Add(60*24*time.Hour), "b.example.com")
+	opaque := &core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "opaque"},
+		Type:       core_v1.SecretTypeOpaque,
+	}
+
+	m.Observe(ctx, "Ingress default/a", []*core_v1.Secret{expiring, opaque})
+	m.Observe(ctx, "Ingress default/b", []*core_v1.Secret{expiring, valid})
+	require.NoError(t, m.Check(ctx))
+
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(settings), settings))
+	summary := settings.Status.CertificateSummary
+	require.NotNil(t, summary)
+	assert.EqualValues(t, 2, summary.Total)
+	assert.EqualValues(t, 1, summary.Expiring)
+	assert.EqualValues(t, 0, summary.Expired)
+	assert.Equal(t, "default/expiring", summary.EarliestExpirySecret)
+	if assert.NotNil(t, summary.EarliestExpiry) {
+		assert.WithinDuration(t, now.Add(24*time.Hour), summary.EarliestExpiry.Time, time.Second)
+	}
+
+	// the expiring certificate is reported on its Secret and the settings
+	assert.Len(t, recorder.Events, 2)
+	for range 2 {
+		assert.Contains(t, <-recorder.Events, "Warning "+reasonCertificateExpiring)
+	}
+	require.NoError(t, m.Check(ctx))
+	assert.Empty(t, recorder.Events, "warnings should not be repeated within the check interval")
+
+	m.Forget(ctx, "Ingress default/b")
+	require.NoError(t, m.Check(ctx))
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(settings), settings))
+	require.NotNil(t, settings.Status.CertificateSummary)
+	assert.EqualValues(t, 1, settings.Status.CertificateSummary.Total)
+
+	expired := newTLSSecret(t, "default", "expired", now.Add(-time.Hour), "c.example.com")
+	m.Observe(ctx, "Ingress default/c", []*core_v1.Secret{expired})
+	require.NoError(t, m.Check(ctx))
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(settings), settings))
+	assert.Equal(t, &pomerium_ingress_v1.CertificateSummary{
+		Total:                2,
+		Expiring:             1,
+		Expired:              1,
+		EarliestExpiry:       settings.Status.CertificateSummary.EarliestExpiry,
+		EarliestExpirySecret: "default/expired",
+	}, settings.Status.CertificateSummary)
+	for range 2 {
+		assert.Contains(t, <-recorder.Events, "Warning "+reasonCertificateExpired)
+	}
+
+	m.Forget(ctx, "Ingress default/a")
+	m.Forget(ctx, "Ingress default/c")
+	require.NoError(t, m.Check(ctx))
+	require.NoError(t, cl.Get(ctx, client.ObjectKeyFromObject(settings), settings))
+	assert.Nil(t, settings.Status.CertificateSummary)
+
+	var nilMonitor *Monitor
+	nilMonitor.Observe(ctx, "Ingress default/a", []*core_v1.Secret{expiring})
+	assert.NoError(t, nilMonitor.Check(ctx))
+}
+
+func newTLSSecret(t *testing.T, namespace, name string, notAfter time.Time, dnsNames ...string) *core_v1.Secret {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	parent := &x509.Certificate{Subject: pkix.Name{CommonName: "test-ca"}}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &core_v1.Secret{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       core_v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			core_v1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			core_v1.TLSPrivateKeyKey: []byte("unused"),
+		},
+	}
+}
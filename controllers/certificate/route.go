@@ -0,0 +1,134 @@
+package certificate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanager_meta_v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	core_v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+// RouteCertificateLabel is set on the Certificates provisioned for Ingresses and Gateways, and
+// their Secrets, to the kind of the object the certificate was provisioned for.
+const RouteCertificateLabel = "ingress.pomerium.io/route-certificate"
+
+// RouteIssuer returns the issuer to provision certificates for the Ingresses and Gateways in the
+// given namespace with, if the settings enable it. As a Certificate may only reference an Issuer in
+// its own namespace, an Issuer is only used for Ingresses and Gateways in its namespace.
+func RouteIssuer(settings *pomerium_ingress_v1.Pomerium, namespace string) (certmanager_meta_v1.IssuerReference, bool) {
+	p := settings.Spec.CertificateAutoProvision
+	if p == nil || p.KubernetesRoutes == nil || !*p.KubernetesRoutes {
+		return certmanager_meta_v1.IssuerReference{}, false
+	}
+	if p.ClusterIssuer != nil {
+		return certmanager_meta_v1.IssuerReference{Kind: "ClusterIssuer", Name: *p.ClusterIssuer}, true
+	}
+	if p.Issuer != nil {
+		name, err := util.ParseNamespacedName(*p.Issuer)
+		if err != nil || name.Namespace != namespace {
+			return certmanager_meta_v1.IssuerReference{}, false
+		}
+		return certmanager_meta_v1.IssuerReference{Kind: "Issuer", Name: name.Name}, true
+	}
+	return certmanager_meta_v1.IssuerReference{}, false
+}
+
+// RouteCertificateName returns the name of the Certificate, and its Secret, provisioned for an
+// object (or one of its parts, such as a Gateway listener).
+func RouteCertificateName(parts ...string) string {
+	name := strings.Join(parts, "-") + "-pomerium-tls"
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+	h := sha256.Sum256([]byte(name))
+	suffix := "-" + hex.EncodeToString(h[:8])
+	return strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-len(suffix)], "-.") + suffix
+}
+
+// EnsureRouteCertificate creates or updates a Certificate for the DNS names, owned by owner and
+// in its namespace. The Secret of the Certificate has the same name.
+func EnsureRouteCertificate(
+	ctx context.Context,
+	c client.Client,
+	owner client.Object,
+	name string,
+	issuer certmanager_meta_v1.IssuerReference,
+	dnsNames []string,
+) error {
+	kind, err := c.GroupVersionKindFor(owner)
+	if err != nil {
+		return err
+	}
+	cert := &certmanager_v1.Certificate{
+		ObjectMeta: meta_v1.ObjectMeta{Name: name, Namespace: owner.GetNamespace()},
+	}
+	op, err := controllerutil.CreateOrUpdate(ctx, c, cert, func() error {
+		if cert.ResourceVersion != "" && !meta_v1.IsControlledBy(cert, owner) {
+			return fmt.Errorf("certificate %s/%s already exists and was not provisioned for %s %s",
+				cert.Namespace, cert.Name, kind.Kind, owner.GetName())
+		}
+		if err := controllerutil.SetControllerReference(owner, cert, c.Scheme()); err != nil {
+			return err
+		}
+		labels := map[string]string{RouteCertificateLabel: kind.Kind}
+		cert.Labels = labels
+		cert.Spec.SecretName = name
+		cert.Spec.SecretTemplate = &certmanager_v1.CertificateSecretTemplate{Labels: labels}
+		cert.Spec.DNSNames = slices.Sorted(slices.Values(dnsNames))
+		cert.Spec.IssuerRef = issuer
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("provision certificate %s/%s: %w", cert.Namespace, cert.Name, err)
+	}
+	if op != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("provisioned certificate",
+			"name", name,
+			"namespace", cert.Namespace,
+			"operation", op,
+			"issuer-kind", issuer.Kind,
+			"issuer-name", issuer.Name,
+			"dns-names", cert.Spec.DNSNames)
+	}
+	return nil
+}
+
+// RemoveRouteCertificate deletes a Certificate provisioned for owner, if there is one.
+func RemoveRouteCertificate(ctx context.Context, c client.Client, owner client.Object, name string) error {
+	cert := new(certmanager_v1.Certificate)
+	err := c.Get(ctx, client.ObjectKey{Namespace: owner.GetNamespace(), Name: name}, cert)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get certificate %s/%s: %w", owner.GetNamespace(), name, err)
+	}
+	if !meta_v1.IsControlledBy(cert, owner) {
+		return nil
+	}
+	log.FromContext(ctx).Info("removing provisioned certificate", "name", name, "namespace", cert.Namespace)
+	if err := c.Delete(ctx, cert); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("delete certificate %s/%s: %w", cert.Namespace, cert.Name, err)
+	}
+	return nil
+}
+
+// IsIssued reports whether a Secret holds an issued certificate and key.
+func IsIssued(secret *core_v1.Secret) bool {
+	return secret.Type == core_v1.SecretTypeTLS &&
+		len(secret.Data[core_v1.TLSCertKey]) > 0 &&
+		len(secret.Data[core_v1.TLSPrivateKeyKey]) > 0
+}
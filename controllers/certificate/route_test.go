@@ -0,0 +1,119 @@
+package certificate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	certmanager_api "github.com/cert-manager/cert-manager/pkg/api"
+	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanager_meta_v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+)
+
+func TestRouteIssuer(t *testing.T) {
+	t.Parallel()
+
+	settings := func(p *pomerium_ingress_v1.CertificateAutoProvision) *pomerium_ingress_v1.Pomerium {
+		return &pomerium_ingress_v1.Pomerium{
+			Spec: pomerium_ingress_v1.PomeriumSpec{CertificateAutoProvision: p},
+		}
+	}
+
+	_, ok := RouteIssuer(settings(nil), "default")
+	assert.False(t, ok)
+	_, ok = RouteIssuer(settings(&pomerium_ingress_v1.CertificateAutoProvision{
+		ClusterIssuer: new("letsencrypt"),
+	}), "default")
+	assert.False(t, ok, "provisioning for routes should be opt-in")
+
+	issuer, ok := RouteIssuer(settings(&pomerium_ingress_v1.CertificateAutoProvision{
+		ClusterIssuer:    new("letsencrypt"),
+		KubernetesRoutes: new(true),
+	}), "default")
+	assert.True(t, ok)
+	assert.Equal(t, certmanager_meta_v1.IssuerReference{Kind: "ClusterIssuer", Name: "letsencrypt"}, issuer)
+
+	namespaced := settings(&pomerium_ingress_v1.CertificateAutoProvision{
+		Issuer:           new("team-a/ca"),
+		KubernetesRoutes: new(true),
+	})
+	issuer, ok = RouteIssuer(namespaced, "team-a")
+	assert.True(t, ok)
+	assert.Equal(t, certmanager_meta_v1.IssuerReference{Kind: "Issuer", Name: "ca"}, issuer)
+	_, ok = RouteIssuer(namespaced, "team-b")
+	assert.False(t, ok, "an Issuer may only be used in its own namespace")
+}
+
+func TestRouteCertificateName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "gateway-https-pomerium-tls", RouteCertificateName("gateway", "https"))
+
+	long := strings.Repeat("a", validation.DNS1123SubdomainMaxLength)
+	name := RouteCertificateName(long)
+	assert.Len(t, name, validation.DNS1123SubdomainMaxLength)
+	assert.Empty(t, validation.IsDNS1123Subdomain(name))
+	assert.NotEqual(t, name, RouteCertificateName(long+"b"))
+}
+
+func TestEnsureRouteCertificate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, certmanager_api.AddToScheme(scheme))
+
+	ingress := &networkingv1.Ingress{ObjectMeta: meta_v1.ObjectMeta{
+		Name: "my-ingress", Namespace: "default", UID: "ingress-uid",
+	}}
+	other := &networkingv1.Ingress{ObjectMeta: meta_v1.ObjectMeta{
+		Name: "other", Namespace: "default", UID: "other-uid",
+	}}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ingress, other).Build()
+	issuer := certmanager_meta_v1.IssuerReference{Kind: "ClusterIssuer", Name: "letsencrypt"}
+	name := RouteCertificateName(ingress.Name)
+	get := func() (*certmanager_v1.Certificate, error) {
+		cert := new(certmanager_v1.Certificate)
+		return cert, cl.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, cert)
+	}
+
+	require.NoError(t, EnsureRouteCertificate(ctx, cl, ingress, name, issuer, []string{"b.example.com", "a.example.com"}))
+	cert, err := get()
+	require.NoError(t, err)
+	assert.True(t, meta_v1.IsControlledBy(cert, ingress))
+	assert.Equal(t, name, cert.Spec.SecretName)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cert.Spec.DNSNames)
+	assert.Equal(t, issuer, cert.Spec.IssuerRef)
+	assert.Equal(t, "Ingress", cert.Labels[RouteCertificateLabel])
+	require.NotNil(t, cert.Spec.SecretTemplate)
+	assert.Equal(t, "Ingress", cert.Spec.SecretTemplate.Labels[RouteCertificateLabel])
+
+	require.NoError(t, EnsureRouteCertificate(ctx, cl, ingress, name, issuer, []string{"a.example.com"}))
+	cert, err = get()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example.com"}, cert.Spec.DNSNames)
+
+	assert.Error(t, EnsureRouteCertificate(ctx, cl, other, name, issuer, []string{"c.example.com"}),
+		"a certificate provisioned for another object should not be taken over")
+	require.NoError(t, RemoveRouteCertificate(ctx, cl, other, name))
+	_, err = get()
+	require.NoError(t, err, "a certificate provisioned for another object should not be removed")
+
+	require.NoError(t, RemoveRouteCertificate(ctx, cl, ingress, name))
+	_, err = get()
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.NoError(t, RemoveRouteCertificate(ctx, cl, ingress, name))
+}
@@ -0,0 +1,282 @@
+package certificate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	certmanager_acme_v1 "github.com/cert-manager/cert-manager/pkg/apis/acme/v1"
+	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanager_meta_v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/hashicorp/go-set/v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+)
+
+// Certificate provisioning strategies, see pomerium_ingress_v1.CertificateAutoProvision.
+const (
+	strategyPerName  = "PerName"
+	strategyWildcard = "Wildcard"
+	strategySAN      = "SAN"
+
+	defaultWildcardThreshold = 3
+	defaultMaxNames          = 100
+)
+
+// A provisionStrategy determines how DNS names are grouped into certificates.
+type provisionStrategy struct {
+	name string
+	// wildcardThreshold is the number of names that must share a parent domain for a wildcard
+	// certificate to be provisioned, or 0 if wildcard certificates should not be provisioned.
+	wildcardThreshold int
+	maxNames          int
+}
+
+func getProvisionStrategy(p *pomerium_ingress_v1.CertificateAutoProvision) provisionStrategy {
+	s := provisionStrategy{name: strategyPerName, maxNames: 1}
+	if p == nil || p.Strategy == nil {
+		return s
+	}
+	switch *p.Strategy {
+	case strategyWildcard:
+		s.name = strategyWildcard
+		s.wildcardThreshold = defaultWildcardThreshold
+		if p.WildcardThreshold != nil {
+			s.wildcardThreshold = max(int(*p.WildcardThreshold), 2)
+		}
+	case strategySAN:
+		s.name = strategySAN
+		s.maxNames = defaultMaxNames
+		if p.MaxNames != nil {
+			s.maxNames = max(int(*p.MaxNames), 1)
+		}
+	}
+	return s
+}
+
+// issuerSupportsWildcards returns whether the issuer can issue wildcard certificates: ACME
+// issuers need a DNS-01 challenge solver, other issuers (such as CA issuers) can sign any name.
+func (c *certificateController) issuerSupportsWildcards(
+	ctx context.Context,
+	issuer certmanager_meta_v1.IssuerReference,
+	issuerNamespace string,
+) (bool, error) {
+	var spec certmanager_v1.IssuerSpec
+	switch issuer.Kind {
+	case "ClusterIssuer":
+		var ci certmanager_v1.ClusterIssuer
+		if err := c.kubernetesClient.Get(ctx, client.ObjectKey{Name: issuer.Name}, &ci); err != nil {
+			return false, fmt.Errorf("error retrieving cluster issuer %s: %w", issuer.Name, err)
+		}
+		spec = ci.Spec
+	default:
+		var i certmanager_v1.Issuer
+		if err := c.kubernetesClient.Get(ctx, client.ObjectKey{Namespace: issuerNamespace, Name: issuer.Name}, &i); err != nil {
+			return false, fmt.Errorf("error retrieving issuer %s/%s: %w", issuerNamespace, issuer.Name, err)
+		}
+		spec = i.Spec
+	}
+	if spec.ACME == nil {
+		return true, nil
+	}
+	return slices.ContainsFunc(spec.ACME.Solvers, func(s certmanager_acme_v1.ACMEChallengeSolver) bool {
+		return s.DNS01 != nil
+	}), nil
+}
+
+// A certificatePlan describes the changes needed to provision certificates for the missing names.
+type certificatePlan struct {
+	// create holds the DNS names of each certificate to create.
+	create [][]string
+	// update holds existing certificates whose DNS names have changed.
+	update []*certmanager_v1.Certificate
+	delete []*certmanager_v1.Certificate
+}
+
+// planCertificates groups the missing names into certificates according to the strategy.
+//
+// To avoid needlessly re-issuing certificates, existing certificates are kept as long as they
+// conform to the strategy, with names that are no longer missing removed from them, and new
+// names are added to existing SAN certificates with room for them before new certificates are
+// created. Certificates for names covered by a wildcard certificate are only deleted once the
+// wildcard certificate is ready.
+func planCertificates(
+	s provisionStrategy,
+	missingNames []string,
+	existing []certmanager_v1.Certificate,
+) certificatePlan {
+	var plan certificatePlan
+	needed := set.From(missingNames)
+
+	existing = slices.SortedFunc(slices.Values(existing), func(x, y certmanager_v1.Certificate) int {
+		return cmp.Compare(x.Name, y.Name)
+	})
+
+	// wildcards holds the parent domains of the wildcard certificates to keep or create, mapped to
+	// whether the certificate is ready.
+	wildcards := make(map[string]bool)
+	var rest []*certmanager_v1.Certificate
+	for i := range existing {
+		cert := &existing[i]
+		parent, ok := consolidatedWildcard(cert, needed)
+		switch {
+		case !s.allows(cert):
+			plan.delete = append(plan.delete, cert)
+		case !ok:
+			rest = append(rest, cert)
+		case s.wildcardThreshold == 0 || !coversAny(parent, needed):
+			plan.delete = append(plan.delete, cert)
+		default:
+			if _, dup := wildcards[parent]; dup {
+				plan.delete = append(plan.delete, cert)
+				continue
+			}
+			wildcards[parent] = isReady(cert)
+		}
+	}
+	var newWildcards []string
+	if s.wildcardThreshold > 0 {
+		for parent, names := range groupByParent(needed) {
+			if _, ok := wildcards[parent]; !ok && len(names) >= s.wildcardThreshold {
+				wildcards[parent] = false
+				newWildcards = append(newWildcards, parent)
+			}
+		}
+	}
+	covered := func(name string) (byWildcard, ready bool) {
+		ready, byWildcard = wildcards[parentDomain(name)]
+		return byWildcard, ready
+	}
+
+	// keep the names of existing certificates which are still needed
+	claimed := set.New[string](needed.Size())
+	var kept []*certmanager_v1.Certificate
+	for _, cert := range rest {
+		var names []string
+		for _, name := range cert.Spec.DNSNames {
+			if _, ready := covered(name); ready || !needed.Contains(name) || !claimed.Insert(name) {
+				continue
+			}
+			names = append(names, name)
+		}
+		switch {
+		case len(names) == 0:
+			plan.delete = append(plan.delete, cert)
+		case len(names) != len(cert.Spec.DNSNames):
+			cert = cert.DeepCopy()
+			cert.Spec.DNSNames = names
+			plan.update = append(plan.update, cert)
+			kept = append(kept, cert)
+		default:
+			kept = append(kept, cert)
+		}
+	}
+
+	var unclaimed []string
+	for name := range needed.Items() {
+		if byWildcard, _ := covered(name); !byWildcard && !claimed.Contains(name) {
+			unclaimed = append(unclaimed, name)
+		}
+	}
+	unclaimed = slices.SortedFunc(slices.Values(unclaimed), compareNames)
+
+	for _, parent := range slices.Sorted(slices.Values(newWildcards)) {
+		plan.create = append(plan.create, []string{"*." + parent})
+	}
+
+	// add names to existing certificates with room for them
+	if s.maxNames > 1 {
+		for _, cert := range kept {
+			n := min(s.maxNames-len(cert.Spec.DNSNames), len(unclaimed))
+			if n <= 0 {
+				continue
+			}
+			if !slices.Contains(plan.update, cert) {
+				cert = cert.DeepCopy()
+				plan.update = append(plan.update, cert)
+			}
+			cert.Spec.DNSNames = append(cert.Spec.DNSNames, unclaimed[:n]...)
+			unclaimed = unclaimed[n:]
+		}
+	}
+	for names := range slices.Chunk(unclaimed, s.maxNames) {
+		plan.create = append(plan.create, names)
+	}
+
+	return plan
+}
+
+// allows returns whether a certificate conforms to the strategy.
+func (s provisionStrategy) allows(cert *certmanager_v1.Certificate) bool {
+	n := len(cert.Spec.DNSNames)
+	return n > 0 && n <= s.maxNames
+}
+
+// consolidatedWildcard returns the parent domain of a certificate provisioned to cover the names
+// under it, rather than a certificate for a wildcard name that is itself needed.
+func consolidatedWildcard(cert *certmanager_v1.Certificate, needed *set.Set[string]) (string, bool) {
+	if len(cert.Spec.DNSNames) != 1 {
+		return "", false
+	}
+	name := cert.Spec.DNSNames[0]
+	parent, ok := strings.CutPrefix(name, "*.")
+	if !ok || needed.Contains(name) {
+		return "", false
+	}
+	return parent, true
+}
+
+func coversAny(parent string, names *set.Set[string]) bool {
+	for name := range names.Items() {
+		if parentDomain(name) == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByParent groups names by their parent domain. Wildcard names, and names directly under a
+// top-level domain, are not included, as no wildcard certificate can cover them.
+func groupByParent(names *set.Set[string]) map[string][]string {
+	groups := make(map[string][]string)
+	for name := range names.Items() {
+		if parent := parentDomain(name); strings.Contains(parent, ".") {
+			groups[parent] = append(groups[parent], name)
+		}
+	}
+	return groups
+}
+
+// parentDomain returns the domain a wildcard certificate must be for to cover the name, or "" if
+// the name is itself a wildcard.
+func parentDomain(name string) string {
+	if strings.HasPrefix(name, "*.") {
+		return ""
+	}
+	_, parent, _ := strings.Cut(name, ".")
+	return parent
+}
+
+// compareNames orders names by their parent domain first, so that SAN certificates tend to group
+// related names.
+func compareNames(x, y string) int {
+	return cmp.Or(cmp.Compare(parentDomain(x), parentDomain(y)), cmp.Compare(x, y))
+}
+
+func isReady(cert *certmanager_v1.Certificate) bool {
+	return slices.ContainsFunc(cert.Status.Conditions, func(c certmanager_v1.CertificateCondition) bool {
+		return c.Type == certmanager_v1.CertificateConditionReady && c.Status == certmanager_meta_v1.ConditionTrue
+	})
+}
+
+// certificateName returns a deterministic name for a certificate for the given DNS names, so that
+// certificates are not created twice if the controller restarts or its cache is out of date.
+func certificateName(dnsNames []string) string {
+	h := sha256.Sum256([]byte(strings.Join(slices.Sorted(slices.Values(dnsNames)), ",")))
+	return "pomerium-certificate-" + hex.EncodeToString(h[:8])
+}
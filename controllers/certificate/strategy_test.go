@@ -0,0 +1,133 @@
+package certificate
+
+import (
+	"testing"
+
+	certmanager_v1 "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	certmanager_meta_v1 "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	"github.com/stretchr/testify/assert"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pomerium_ingress_v1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+)
+
+func TestPlanCertificates(t *testing.T) {
+	t.Parallel()
+
+	wildcard := getProvisionStrategy(&pomerium_ingress_v1.CertificateAutoProvision{
+		Strategy:          new(strategyWildcard),
+		WildcardThreshold: new(int32(2)),
+	})
+	san := getProvisionStrategy(&pomerium_ingress_v1.CertificateAutoProvision{
+		Strategy: new(strategySAN),
+		MaxNames: new(int32(3)),
+	})
+
+	makeCert := func(name string, ready bool, dnsNames ...string) certmanager_v1.Certificate {
+		cert := certmanager_v1.Certificate{
+			ObjectMeta: meta_v1.ObjectMeta{Name: name},
+			Spec:       certmanager_v1.CertificateSpec{DNSNames: dnsNames},
+		}
+		if ready {
+			cert.Status.Conditions = []certmanager_v1.CertificateCondition{{
+				Type:   certmanager_v1.CertificateConditionReady,
+				Status: certmanager_meta_v1.ConditionTrue,
+			}}
+		}
+		return cert
+	}
+	names := func(certs []*certmanager_v1.Certificate) map[string][]string {
+		m := make(map[string][]string)
+		for _, cert := range certs {
+			m[cert.Name] = cert.Spec.DNSNames
+		}
+		return m
+	}
+
+	t.Run("per name", func(t *testing.T) {
+		plan := planCertificates(getProvisionStrategy(nil),
+			[]string{"a.example.com", "b.example.com", "c.example.com"},
+			[]certmanager_v1.Certificate{
+				makeCert("a", false, "a.example.com"),
+				makeCert("ab", false, "a.example.com", "b.example.com"),
+				makeCert("d", false, "d.example.com"),
+			})
+		assert.Equal(t, [][]string{{"b.example.com"}, {"c.example.com"}}, plan.create)
+		assert.Empty(t, plan.update)
+		assert.Equal(t, map[string][]string{
+			"ab": {"a.example.com", "b.example.com"},
+			"d":  {"d.example.com"},
+		}, names(plan.delete), "certificates with several names, or no longer needed names, should be deleted")
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		plan := planCertificates(wildcard,
+			[]string{"a.example.com", "b.example.com", "c.example.com", "x.other.com", "*.wild.com"},
+			[]certmanager_v1.Certificate{
+				makeCert("a", false, "a.example.com"),
+				makeCert("wild", false, "*.wild.com"),
+			})
+		assert.Equal(t, [][]string{{"*.example.com"}, {"x.other.com"}}, plan.create)
+		assert.Empty(t, plan.update)
+		assert.Empty(t, plan.delete, "certificates should be kept until the wildcard certificate is ready")
+
+		plan = planCertificates(wildcard,
+			[]string{"a.example.com", "b.example.com", "c.example.com"},
+			[]certmanager_v1.Certificate{
+				makeCert("a", false, "a.example.com"),
+				makeCert("wildcard", true, "*.example.com"),
+			})
+		assert.Empty(t, plan.create)
+		assert.Equal(t, map[string][]string{"a": {"a.example.com"}}, names(plan.delete),
+			"certificates covered by a ready wildcard certificate should be deleted")
+
+		plan = planCertificates(wildcard,
+			[]string{"a.example.com"},
+			[]certmanager_v1.Certificate{makeCert("wildcard", true, "*.example.com")})
+		assert.Empty(t, plan.create)
+		assert.Empty(t, plan.delete, "a wildcard certificate should be kept while it covers any name")
+
+		plan = planCertificates(getProvisionStrategy(nil),
+			[]string{"a.example.com"},
+			[]certmanager_v1.Certificate{makeCert("wildcard", true, "*.example.com")})
+		assert.Equal(t, [][]string{{"a.example.com"}}, plan.create)
+		assert.Equal(t, map[string][]string{"wildcard": {"*.example.com"}}, names(plan.delete),
+			"wildcard certificates should be deleted when the strategy changes")
+	})
+
+	t.Run("san", func(t *testing.T) {
+		plan := planCertificates(san,
+			[]string{"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "x.other.com"},
+			[]certmanager_v1.Certificate{
+				makeCert("ab", false, "a.example.com", "b.example.com", "gone.example.com"),
+			})
+		assert.Equal(t, map[string][]string{
+			"ab": {"a.example.com", "b.example.com", "c.example.com"},
+		}, names(plan.update), "names should be removed from and added to existing certificates")
+		assert.Equal(t, [][]string{{"d.example.com", "e.example.com", "x.other.com"}}, plan.create)
+		assert.Empty(t, plan.delete)
+
+		plan = planCertificates(san,
+			[]string{"a.example.com", "b.example.com"},
+			[]certmanager_v1.Certificate{
+				makeCert("ab", false, "a.example.com", "b.example.com"),
+				makeCert("b", false, "b.example.com"),
+				makeCert("wide", false, "c.example.com", "d.example.com", "e.example.com", "f.example.com"),
+			})
+		assert.Empty(t, plan.create)
+		assert.Empty(t, plan.update)
+		assert.Equal(t, map[string][]string{
+			"b":    {"b.example.com"},
+			"wide": {"c.example.com", "d.example.com", "e.example.com", "f.example.com"},
+		}, names(plan.delete), "duplicate and oversized certificates should be deleted")
+	})
+}
+
+func TestCertificateName(t *testing.T) {
+	t.Parallel()
+
+	name := certificateName([]string{"b.example.com", "a.example.com"})
+	assert.Equal(t, name, certificateName([]string{"a.example.com", "b.example.com"}))
+	assert.NotEqual(t, name, certificateName([]string{"a.example.com"}))
+	assert.Len(t, name, len("pomerium-certificate-")+16)
+}
@@ -6,17 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"sync/atomic"
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	runtime_ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/pomerium/pomerium/pkg/databrokerutil"
 	"github.com/pomerium/pomerium/pkg/grpc/databroker"
 
+	"github.com/pomerium/ingress-controller/controllers/apitoken"
 	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/controllers/gateway"
 	"github.com/pomerium/ingress-controller/controllers/ingress"
@@ -50,15 +56,39 @@ type Controller struct {
 	GlobalSettings *types.NamespacedName
 	// CertificateControllerName is the name of the certificate controller.
 	CertificateControllerName string
+	// GarbageCollectionInterval, if non-zero and the Reconciler supports it, is the interval
+	// between garbage collection passes. The first pass runs at startup.
+	GarbageCollectionInterval time.Duration
+	// GarbageCollectionDryRun only logs any configuration that would be garbage collected.
+	GarbageCollectionDryRun bool
+	// DriftCheckInterval, if non-zero and the Reconciler supports it, is the interval between
+	// checks for changes made to the synced configuration by other means.
+	DriftCheckInterval time.Duration
+	// DriftMode is the default handling of any changes found by a drift check.
+	DriftMode pomerium.DriftMode
+	// APITokenSecret, if set, is a Secret holding the unified API token, which is reloaded
+	// whenever the Secret changes. The Reconciler must support token updates.
+	APITokenSecret *types.NamespacedName
+	// CertificateExpiryWarning is how long before a synced TLS certificate expires to emit
+	// warning Events, or zero to not emit any.
+	CertificateExpiryWarning time.Duration
+	// Webhook, if set, configures a server for validating admission webhooks for Ingresses,
+	// PolicyFilters and the Pomerium CRD.
+	Webhook *webhook.Options
+	// WebhookWarnOnly admits objects failing validation with a warning, instead of rejecting them.
+	WebhookWarnOnly bool
+	// DryRun runs without a lease, so as not to take over from the controller that holds it.
+	// The Reconciler and the manager client are expected not to make any changes.
+	DryRun bool
 
 	running int32
 }
 
 // Run runs controller using lease
 func (c *Controller) Run(ctx context.Context) error {
-	if c.MgrOpts.LeaderElection {
-		// If we're using k8s leader election, we don't need to acquire a
-		// databroker lease.
+	if c.MgrOpts.LeaderElection || c.DryRun {
+		// If we're using k8s leader election, or only doing a dry run,
+		// we don't need to acquire a databroker lease.
 		return c.RunLeased(ctx)
 	}
 	leaser := databrokerutil.NewLeaser("ingress-controller", leaseDuration, c)
@@ -78,7 +108,12 @@ func (c *Controller) RunLeased(ctx context.Context) (err error) {
 	if err != nil {
 		return fmt.Errorf("get k8s api config: %w", err)
 	}
-	mgr, err := runtime_ctrl.NewManager(cfg, c.MgrOpts)
+	mgrOpts := c.MgrOpts
+	if c.Webhook != nil {
+		// webhooks may only be registered once with a server, so each run needs a new one
+		mgrOpts.WebhookServer = webhook.NewServer(*c.Webhook)
+	}
+	mgr, err := runtime_ctrl.NewManager(cfg, mgrOpts)
 	if err != nil {
 		return fmt.Errorf("unable to create controller manager: %w", err)
 	}
@@ -86,28 +121,77 @@ func (c *Controller) RunLeased(ctx context.Context) (err error) {
 	if ar, ok := c.Reconciler.(interface{ SetK8sClient(client client.Client) }); ok {
 		ar.SetK8sClient(mgr.GetClient())
 	}
+	if c.APITokenSecret != nil {
+		updater, ok := c.Reconciler.(pomerium.APITokenUpdater)
+		if !ok {
+			return fmt.Errorf("reconciler %T does not support API token updates", c.Reconciler)
+		}
+		if err = apitoken.NewController(mgr, updater, *c.APITokenSecret, c.GlobalSettings); err != nil {
+			return fmt.Errorf("create api token controller: %w", err)
+		}
+	}
+
+	monitor := certificate.NewMonitor(mgr.GetClient(), mgr.GetEventRecorderFor("pomerium-certificate-monitor"),
+		c.GlobalSettings, c.CertificateExpiryWarning)
+	if err = mgr.Add(monitor); err != nil {
+		return fmt.Errorf("add certificate monitor: %w", err)
+	}
 
-	if err = ingress.NewIngressController(mgr, c.Reconciler, c.getIngressOpts(mgr)...); err != nil {
+	// Objects whose synced configuration the drift check finds deleted are synced again.
+	resync := make(chan event.GenericEvent, driftResyncBufferSize)
+	ingressOpts, routeStatusReporter := c.getIngressOpts(mgr)
+	ingressOpts = append(slices.Clip(ingressOpts),
+		ingress.WithCertificateMonitor(monitor), ingress.WithResync(resync))
+	if err = ingress.NewIngressController(mgr, c.Reconciler, ingressOpts...); err != nil {
 		return fmt.Errorf("create ingress controller: %w", err)
 	}
 	if c.GlobalSettings != nil {
-		if err = settings.NewSettingsController(mgr, c.Reconciler, *c.GlobalSettings, "pomerium-crd", true, health_ctrl.SettingsReconciler); err != nil {
+		if err = settings.NewSettingsController(mgr, c.Reconciler, *c.GlobalSettings, "pomerium-crd", true, health_ctrl.SettingsReconciler, monitor); err != nil {
 			return fmt.Errorf("create settings controller: %w", err)
 		}
+		if err = mgr.Add(c.clearLegacyIngressStatus(mgr.GetClient())); err != nil {
+			return fmt.Errorf("add legacy ingress status cleanup: %w", err)
+		}
+		if err = mgr.Add(runPeriodically("ingress-status-counts", ingressStatusCountsInterval, func(ctx context.Context) error {
+			return routeStatusReporter.UpdateCounts(ctx, mgr.GetAPIReader())
+		})); err != nil {
+			return fmt.Errorf("add ingress status counts: %w", err)
+		}
 		certificate.NewCertificateController(mgr, c.DataBrokerServiceClient,
 			certificate.WithControllerName(c.CertificateControllerName),
-			certificate.WithGlobalSettingsName(*c.GlobalSettings))
+			certificate.WithGlobalSettingsName(*c.GlobalSettings),
+			certificate.WithMonitor(monitor))
 	} else {
 		log.FromContext(ctx).V(1).Info("no Pomerium CRD")
 	}
 
 	if c.GatewayControllerConfig != nil {
-		err := gateway.NewControllers(ctx, mgr, c.Reconciler, *c.GatewayControllerConfig)
+		gatewayConfig := *c.GatewayControllerConfig
+		gatewayConfig.GlobalSettings = c.GlobalSettings
+		gatewayConfig.CertificateMonitor = monitor
+		err := gateway.NewControllers(ctx, mgr, c.Reconciler, gatewayConfig)
 		if err != nil {
 			return err
 		}
 	}
 
+	if c.Webhook != nil {
+		if err = c.setupWebhooks(mgr); err != nil {
+			return err
+		}
+	}
+
+	if gc, ok := c.Reconciler.(pomerium.GarbageCollector); ok && c.GarbageCollectionInterval > 0 {
+		if err = mgr.Add(c.garbageCollection(gc, mgr.GetAPIReader())); err != nil {
+			return fmt.Errorf("add garbage collection: %w", err)
+		}
+	}
+	if dd, ok := c.Reconciler.(pomerium.DriftDetector); ok && c.DriftCheckInterval > 0 {
+		if err = mgr.Add(c.driftCheck(dd, mgr.GetEventRecorderFor("pomerium-drift"), resync)); err != nil {
+			return fmt.Errorf("add drift check: %w", err)
+		}
+	}
+
 	c.setRunning(true)
 	if err = mgr.Start(ctx); err != nil {
 		return fmt.Errorf("running controller: %w", err)
@@ -115,6 +199,99 @@ func (c *Controller) RunLeased(ctx context.Context) (err error) {
 	return nil
 }
 
+// setupWebhooks registers the validating admission webhooks for the objects the controllers
+// reconcile.
+func (c *Controller) setupWebhooks(mgr runtime_ctrl.Manager) error {
+	if err := ingress.NewIngressWebhook(mgr, c.WebhookWarnOnly, c.IngressCtrlOpts...); err != nil {
+		return fmt.Errorf("create ingress webhook: %w", err)
+	}
+	if c.GlobalSettings != nil {
+		if err := settings.NewSettingsWebhook(mgr, c.WebhookWarnOnly); err != nil {
+			return fmt.Errorf("create settings webhook: %w", err)
+		}
+	}
+	if c.GatewayControllerConfig != nil {
+		if err := gateway.NewPolicyFilterWebhook(mgr, c.WebhookWarnOnly); err != nil {
+			return fmt.Errorf("create PolicyFilter webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+// clearLegacyIngressStatus returns a runnable that removes the per-Ingress status that earlier
+// versions kept in the Pomerium CRD, now that it is recorded in RouteStatus objects.
+func (c *Controller) clearLegacyIngressStatus(k8sClient client.Client) manager.RunnableFunc {
+	return func(ctx context.Context) error {
+		if err := reporter.ClearLegacyIngressStatus(ctx, k8sClient, *c.GlobalSettings); err != nil {
+			log.FromContext(ctx).Error(err, "clear legacy ingress status")
+		}
+		return nil
+	}
+}
+
+// garbageCollection returns a runnable that periodically removes any Pomerium configuration left
+// behind by deleted Kubernetes objects.
+func (c *Controller) garbageCollection(gc pomerium.GarbageCollector, reader client.Reader) manager.RunnableFunc {
+	opts := pomerium.GarbageCollectionOptions{
+		GatewayAPI: c.GatewayControllerConfig != nil,
+		DryRun:     c.GarbageCollectionDryRun,
+	}
+	return runPeriodically("garbage-collection", c.GarbageCollectionInterval, func(ctx context.Context) error {
+		_, err := gc.CollectGarbage(ctx, reader, opts)
+		return err
+	})
+}
+
+// ingressStatusCountsInterval is the interval between updates of the Ingress counts in the
+// Pomerium CRD status, when any Ingress status changed.
+const ingressStatusCountsInterval = 10 * time.Second
+
+// driftResyncBufferSize is the number of objects that may be queued to be synced again after
+// their synced configuration is found deleted. Any more are skipped until the next drift check.
+const driftResyncBufferSize = 100
+
+// driftCheck returns a runnable that periodically reverts or reports any changes made to the
+// synced Pomerium configuration by other means. Objects whose synced configuration was deleted
+// are sent to resync.
+func (c *Controller) driftCheck(
+	dd pomerium.DriftDetector, recorder record.EventRecorder, resync chan<- event.GenericEvent,
+) manager.RunnableFunc {
+	opts := pomerium.DriftCheckOptions{
+		DefaultMode:   c.DriftMode,
+		EventRecorder: recorder,
+		Resync: func(owner client.Object) {
+			select {
+			case resync <- event.GenericEvent{Object: owner}:
+			default:
+			}
+		},
+	}
+	return runPeriodically("drift-check", c.DriftCheckInterval, func(ctx context.Context) error {
+		_, err := dd.CheckDrift(ctx, opts)
+		return err
+	})
+}
+
+// runPeriodically returns a runnable that calls fn at startup and then at every interval, logging
+// any errors. As a manager runnable, it runs only while leader.
+func runPeriodically(name string, interval time.Duration, fn func(context.Context) error) manager.RunnableFunc {
+	return func(ctx context.Context) error {
+		logger := log.FromContext(ctx).WithName(name)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := fn(ctx); err != nil {
+				logger.Error(err, name+" failed")
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
 func (c *Controller) setRunning(running bool) {
 	if running {
 		atomic.StoreInt32(&c.running, 1)
@@ -132,22 +309,25 @@ func (c *Controller) ReadyzCheck(_ *http.Request) error {
 	return nil
 }
 
-func (c *Controller) getIngressOpts(mgr runtime_ctrl.Manager) []ingress.Option {
+// getIngressOpts returns the ingress controller options, along with the reporter recording the
+// ingress status in RouteStatus objects, if there is a Pomerium CRD.
+func (c *Controller) getIngressOpts(mgr runtime_ctrl.Manager) ([]ingress.Option, *reporter.IngressRouteStatusReporter) {
 	if c.GlobalSettings == nil {
-		return c.IngressCtrlOpts
+		return c.IngressCtrlOpts, nil
 	}
 
 	rep := reporter.SettingsReporter{
 		NamespacedName: *c.GlobalSettings,
 		Client:         mgr.GetClient(),
 	}
+	routeStatusReporter := &reporter.IngressRouteStatusReporter{
+		SettingsReporter: rep,
+	}
 
 	return append(c.IngressCtrlOpts, ingress.WithIngressStatusReporter(
-		&reporter.IngressSettingsReporter{
-			SettingsReporter: rep,
-		},
+		routeStatusReporter,
 		&reporter.IngressSettingsEventReporter{
 			EventRecorder:    mgr.GetEventRecorderFor("pomerium-ingress"),
 			SettingsReporter: rep,
-		}))
+		})), routeStatusReporter
 }
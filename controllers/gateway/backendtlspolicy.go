@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	context "context"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-set/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// caCertificateKey is the ConfigMap key holding CA certificates referenced by a BackendTLSPolicy.
+const caCertificateKey = "ca.crt"
+
+// backendTLSPolicyInfo holds a BackendTLSPolicy together with its processing results.
+type backendTLSPolicyInfo struct {
+	policy         *gateway_v1.BackendTLSPolicy
+	originalStatus *gateway_v1.PolicyStatus
+
+	// config is nil if the policy is not valid.
+	config *model.BackendTLSConfig
+
+	// Conditions to set for each ancestor Gateway.
+	conditions []metav1.Condition
+
+	// ancestors holds the Gateways with attached routes that use a Service targeted by the policy.
+	ancestors set.Collection[refKey]
+}
+
+// processBackendTLSPolicies resolves all BackendTLSPolicies and adds the upstream TLS settings of
+// all valid policies to the GatewayConfig.
+func processBackendTLSPolicies(config *model.GatewayConfig, o *objects) {
+	config.BackendTLS = make(map[model.BackendTLSKey]*model.BackendTLSConfig)
+
+	// From the spec: "If a conflict occurs [...] the Policy with the oldest creation timestamp
+	// takes precedence, then the Policy appearing first in alphabetical order by
+	// {namespace}/{name}."
+	slices.SortFunc(o.BackendTLSPolicies, func(a, b *backendTLSPolicyInfo) int {
+		if c := a.policy.CreationTimestamp.Compare(b.policy.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(
+			a.policy.Namespace+"/"+a.policy.Name,
+			b.policy.Namespace+"/"+b.policy.Name)
+	})
+
+	for _, p := range o.BackendTLSPolicies {
+		var resolvedRefs metav1.Condition
+		p.config, resolvedRefs = resolveBackendTLSPolicy(o, p.policy)
+
+		accepted := metav1.Condition{
+			Type:   string(gateway_v1.PolicyConditionAccepted),
+			Status: metav1.ConditionTrue,
+			Reason: string(gateway_v1.PolicyReasonAccepted),
+		}
+		switch {
+		case len(p.policy.Spec.Validation.SubjectAltNames) > 0:
+			p.config = nil
+			accepted.Status = metav1.ConditionFalse
+			accepted.Reason = string(gateway_v1.PolicyReasonInvalid)
+			accepted.Message = "subjectAltNames are not supported"
+		case p.config == nil:
+			accepted.Status = metav1.ConditionFalse
+			accepted.Reason = string(gateway_v1.BackendTLSPolicyReasonNoValidCACertificate)
+		}
+
+		if p.config != nil {
+			var conflicted []string
+			for _, t := range p.policy.Spec.TargetRefs {
+				if t.Group != corev1.GroupName || t.Kind != "Service" {
+					continue
+				}
+				k := model.BackendTLSKey{
+					NamespacedName: types.NamespacedName{Namespace: p.policy.Namespace, Name: string(t.Name)},
+				}
+				if t.SectionName != nil {
+					k.SectionName = string(*t.SectionName)
+				}
+				if _, exists := config.BackendTLS[k]; exists {
+					conflicted = append(conflicted, string(t.Name))
+					continue
+				}
+				config.BackendTLS[k] = p.config
+			}
+			if len(conflicted) > 0 {
+				accepted.Status = metav1.ConditionFalse
+				accepted.Reason = string(gateway_v1.PolicyReasonConflicted)
+				accepted.Message = "targets already have a policy: " + strings.Join(conflicted, ", ")
+			}
+		}
+
+		p.conditions = []metav1.Condition{accepted, resolvedRefs}
+	}
+}
+
+// resolveBackendTLSPolicy resolves the CA certificate references of a BackendTLSPolicy. It returns
+// nil settings if there are no valid CA certificates.
+func resolveBackendTLSPolicy(
+	o *objects,
+	p *gateway_v1.BackendTLSPolicy,
+) (*model.BackendTLSConfig, metav1.Condition) {
+	validation := &p.Spec.Validation
+	cfg := &model.BackendTLSConfig{Hostname: string(validation.Hostname)}
+
+	invalidRefs := make(map[gateway_v1.PolicyConditionReason][]string)
+	for _, ref := range validation.CACertificateRefs {
+		if ref.Group != corev1.GroupName || ref.Kind != "ConfigMap" {
+			invalidRefs[gateway_v1.BackendTLSPolicyReasonInvalidKind] = append(
+				invalidRefs[gateway_v1.BackendTLSPolicyReasonInvalidKind], string(ref.Name))
+			continue
+		}
+		cm := o.ConfigMaps[types.NamespacedName{Namespace: p.Namespace, Name: string(ref.Name)}]
+		if cm == nil || !x509.NewCertPool().AppendCertsFromPEM([]byte(cm.Data[caCertificateKey])) {
+			invalidRefs[gateway_v1.BackendTLSPolicyReasonInvalidCACertificateRef] = append(
+				invalidRefs[gateway_v1.BackendTLSPolicyReasonInvalidCACertificateRef], string(ref.Name))
+			continue
+		}
+		cfg.CACertificates = append(cfg.CACertificates, cm.Data[caCertificateKey]...)
+		if !strings.HasSuffix(cm.Data[caCertificateKey], "\n") {
+			cfg.CACertificates = append(cfg.CACertificates, '\n')
+		}
+	}
+
+	resolvedRefs := metav1.Condition{
+		Type:   string(gateway_v1.BackendTLSPolicyConditionResolvedRefs),
+		Status: metav1.ConditionTrue,
+		Reason: string(gateway_v1.BackendTLSPolicyReasonResolvedRefs),
+	}
+	var messages []string
+	for reason, refNames := range invalidRefs {
+		resolvedRefs.Status = metav1.ConditionFalse
+		resolvedRefs.Reason = string(reason) // if multiple reasons apply this will set one arbitrarily
+		messages = append(messages, "invalid CA certificate refs ("+string(reason)+"): "+strings.Join(refNames, ", "))
+	}
+	resolvedRefs.Message = strings.Join(messages, "; ")
+
+	// Either the well-known system CA certificates or at least one valid CA certificate is required.
+	if len(cfg.CACertificates) == 0 && validation.WellKnownCACertificates == nil {
+		return nil, resolvedRefs
+	}
+	return cfg, resolvedRefs
+}
+
+// addBackendTLSAncestor records gatewayKey as an ancestor of any BackendTLSPolicies targeting a
+// Service referenced by the backendRefs of an attached route.
+func (o *objects) addBackendTLSAncestor(gatewayKey refKey, backendRefs backendRefSet) {
+	for _, p := range o.BackendTLSPolicies {
+		for _, t := range p.policy.Spec.TargetRefs {
+			k := refKey{
+				Group:     corev1.GroupName,
+				Kind:      "Service",
+				Namespace: p.policy.Namespace,
+				Name:      string(t.Name),
+			}
+			if t.Group == corev1.GroupName && t.Kind == "Service" && backendRefs.containsObject(k) {
+				p.ancestors.Insert(gatewayKey)
+				break
+			}
+		}
+	}
+}
+
+// updateBackendTLSPolicyStatus sets the status for each ancestor Gateway of each BackendTLSPolicy,
+// and updates any modified status.
+func (c *gatewayController) updateBackendTLSPolicyStatus(ctx context.Context, o *objects) error {
+	for _, p := range o.BackendTLSPolicies {
+		existing := make(map[refKey]gateway_v1.PolicyAncestorStatus)
+		var ancestors []gateway_v1.PolicyAncestorStatus
+		for _, a := range p.policy.Status.Ancestors {
+			if a.ControllerName != gateway_v1.GatewayController(c.ControllerName) {
+				// Preserve the status set by any other controllers.
+				ancestors = append(ancestors, a)
+				continue
+			}
+			existing[refKeyForParentRef(p.policy, &a.AncestorRef)] = a
+		}
+
+		keys := p.ancestors.Slice()
+		slices.SortFunc(keys, func(a, b refKey) int {
+			return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+		})
+		for _, k := range keys {
+			a, ok := existing[k]
+			if !ok {
+				a = gateway_v1.PolicyAncestorStatus{
+					AncestorRef: gateway_v1.ParentReference{
+						Group:     new(gateway_v1.Group(gateway_v1.GroupName)),
+						Kind:      new(gateway_v1.Kind("Gateway")),
+						Namespace: new(gateway_v1.Namespace(k.Namespace)),
+						Name:      gateway_v1.ObjectName(k.Name),
+					},
+					ControllerName: gateway_v1.GatewayController(c.ControllerName),
+				}
+			}
+			upsertConditions(&a.Conditions, p.policy.Generation, p.conditions...)
+			ancestors = append(ancestors, a)
+		}
+		p.policy.Status.Ancestors = ancestors
+
+		if !equality.Semantic.DeepEqual(&p.policy.Status, p.originalStatus) {
+			if err := c.Status().Update(ctx, p.policy); err != nil {
+				return fmt.Errorf("couldn't update status for BackendTLSPolicy %q: %w", p.policy.Name, err)
+			}
+		}
+	}
+	return nil
+}
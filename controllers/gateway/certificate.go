@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	context "context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
+)
+
+// provisionCertificates provisions a certificate for each HTTPS listener without certificateRefs,
+// if the global settings enable it, and records the Secrets to use for these listeners.
+func (c *gatewayController) provisionCertificates(ctx context.Context, o *objects) error {
+	o.ProvisionedCertificates = make(map[listenerKey]refKey)
+	if c.GlobalSettings == nil {
+		return nil
+	}
+
+	var settings icsv1.Pomerium
+	if err := c.Get(ctx, *c.GlobalSettings, &settings); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get settings %s: %w", c.GlobalSettings.Name, err)
+	}
+
+	for key, g := range o.Gateways {
+		issuer, ok := certificate.RouteIssuer(&settings, g.Namespace)
+		if !ok {
+			continue
+		}
+		for i := range g.Spec.Listeners {
+			l := &g.Spec.Listeners[i]
+			name := certificate.RouteCertificateName(g.Name, string(l.Name))
+			if !needsProvisionedCertificate(l) {
+				if err := certificate.RemoveRouteCertificate(ctx, c, g, name); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := certificate.EnsureRouteCertificate(ctx, c, g, name, issuer,
+				[]string{string(*l.Hostname)}); err != nil {
+				return err
+			}
+			o.ProvisionedCertificates[listenerKey{key, l.Name}] = refKey{
+				Group:     corev1.GroupName,
+				Kind:      "Secret",
+				Namespace: g.Namespace,
+				Name:      name,
+			}
+		}
+	}
+	return nil
+}
+
+// needsProvisionedCertificate reports whether a listener terminates TLS without any
+// certificateRefs, for a hostname a certificate can be issued for.
+func needsProvisionedCertificate(l *gateway_v1.Listener) bool {
+	return l.Protocol == gateway_v1.HTTPSProtocolType &&
+		l.Hostname != nil && *l.Hostname != "" &&
+		l.TLS != nil && len(l.TLS.CertificateRefs) == 0 &&
+		(l.TLS.Mode == nil || *l.TLS.Mode == gateway_v1.TLSModeTerminate)
+}
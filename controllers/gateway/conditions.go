@@ -41,3 +41,14 @@ func upsertCondition(
 	*conditions = append(*conditions, condition)
 	return true
 }
+
+func removeCondition(conditions *[]metav1.Condition, conditionType string) (modified bool) {
+	conds := *conditions
+	for i := range conds {
+		if conds[i].Type == conditionType {
+			*conditions = append(conds[:i], conds[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
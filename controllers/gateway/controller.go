@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -17,7 +18,10 @@ import (
 	gateway_v1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/pomerium"
+	"github.com/pomerium/ingress-controller/util"
 )
 
 // DefaultClassControllerName is the default GatewayClass ControllerName.
@@ -29,6 +33,11 @@ type ControllerConfig struct {
 	ControllerName string
 	// Gateway addresses are determined from this service.
 	ServiceName types.NamespacedName
+	// GlobalSettings, if set, is the Pomerium CRD whose certificate auto provision settings
+	// determine whether certificates are provisioned for HTTPS listeners without certificateRefs.
+	GlobalSettings *types.NamespacedName
+	// CertificateMonitor, if set, tracks the expiry of the certificates of all Gateway listeners.
+	CertificateMonitor *certificate.Monitor
 }
 
 // NewControllers sets up GatewayClass and Gateway controllers.
@@ -53,6 +62,13 @@ type gatewayController struct {
 	ControllerConfig
 
 	extensionFilters map[refKey]objectAndFilter
+
+	// TCPRoute and UDPRoute CRDs may not be installed.
+	tcpRoutesInstalled bool
+	udpRoutesInstalled bool
+
+	// The BackendTLSPolicy CRD may not be installed.
+	backendTLSPoliciesInstalled bool
 }
 
 // NewGatewayController creates and registers a new controller for Gateway objects.
@@ -69,24 +85,63 @@ func NewGatewayController(
 		extensionFilters:  make(map[refKey]objectAndFilter),
 	}
 
-	err := mgr.GetFieldIndexer().IndexField(ctx, &corev1.Secret{}, "type",
-		func(o client.Object) []string { return []string{string(o.(*corev1.Secret).Type)} })
+	err := mgr.GetFieldIndexer().IndexField(ctx, &corev1.Secret{}, SecretTypeIndex, SecretType)
 	if err != nil {
 		return fmt.Errorf("couldn't create index on Secret type: %w", err)
 	}
 
-	// All updates will trigger the same reconcile request.
+	// All updates will trigger the same reconcile request. Only the routes affected by a change
+	// will be translated and synced again (see routeFingerprint).
+	requests := []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name: config.ControllerName,
+		},
+	}}
 	enqueueRequest := handler.EnqueueRequestsFromMapFunc(
 		func(_ context.Context, _ client.Object) []reconcile.Request {
-			return []reconcile.Request{{
-				NamespacedName: types.NamespacedName{
-					Name: config.ControllerName,
-				},
-			}}
+			return requests
 		})
+	// Changes to commonly-used object kinds should only trigger a reconcile if the object is
+	// referenced by any Gateway API object.
+	enqueueRequestIf := func(referenced func(context.Context, client.Object) bool) handler.EventHandler {
+		return handler.EnqueueRequestsFromMapFunc(
+			func(ctx context.Context, obj client.Object) []reconcile.Request {
+				if !referenced(ctx, obj) {
+					return nil
+				}
+				return requests
+			})
+	}
 
-	err = ctrl.NewControllerManagedBy(mgr).
+	gtc.tcpRoutesInstalled, err = util.IsKindInstalled(mgr.GetClient(), &gateway_v1.TCPRoute{})
+	if err != nil {
+		return fmt.Errorf("couldn't check for TCPRoute kind: %w", err)
+	}
+	gtc.udpRoutesInstalled, err = util.IsKindInstalled(mgr.GetClient(), &gateway_v1.UDPRoute{})
+	if err != nil {
+		return fmt.Errorf("couldn't check for UDPRoute kind: %w", err)
+	}
+	gtc.backendTLSPoliciesInstalled, err = util.IsKindInstalled(mgr.GetClient(), &gateway_v1.BackendTLSPolicy{})
+	if err != nil {
+		return fmt.Errorf("couldn't check for BackendTLSPolicy kind: %w", err)
+	}
+
+	gatewayClassConfigsInstalled, err := util.IsKindInstalled(mgr.GetClient(), &icgv1alpha1.GatewayClassConfig{})
+	if err != nil {
+		return fmt.Errorf("couldn't check for GatewayClassConfig kind: %w", err)
+	}
+
+	if err := gtc.createIndexes(ctx, mgr.GetFieldIndexer()); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named("gateway").
+		Watches(
+			&gateway_v1.GatewayClass{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
 		Watches(
 			&gateway_v1.Gateway{},
 			enqueueRequest,
@@ -97,13 +152,58 @@ func NewGatewayController(
 			enqueueRequest,
 			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
 		).
-		Watches(&corev1.Secret{}, enqueueRequest).
-		Watches(&corev1.Namespace{}, enqueueRequest).
-		Watches(&corev1.Service{}, enqueueRequest).
+		Watches(
+			&gateway_v1.GRPCRoute{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Watches(&corev1.Secret{}, enqueueRequestIf(gtc.secretReferenced)).
+		Watches(&corev1.Namespace{}, enqueueRequestIf(gtc.namespaceReferenced)).
+		Watches(&corev1.Service{}, enqueueRequestIf(gtc.serviceReferenced)).
+		Watches(&discoveryv1.EndpointSlice{}, enqueueRequestIf(gtc.endpointSliceReferenced)).
 		Watches(&gateway_v1beta1.ReferenceGrant{}, enqueueRequest).
-		Watches(&icgv1alpha1.PolicyFilter{}, enqueueRequest).
-		Complete(gtc)
-	if err != nil {
+		Watches(&icgv1alpha1.PolicyFilter{}, enqueueRequest)
+	if gatewayClassConfigsInstalled {
+		b = b.Watches(
+			&icgv1alpha1.GatewayClassConfig{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		)
+	}
+	if gtc.tcpRoutesInstalled {
+		b = b.Watches(
+			&gateway_v1.TCPRoute{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		)
+	}
+	if gtc.udpRoutesInstalled {
+		b = b.Watches(
+			&gateway_v1.UDPRoute{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		)
+	}
+	if config.GlobalSettings != nil {
+		b = b.Watches(
+			&icsv1.Pomerium{},
+			enqueueRequest,
+			builder.WithPredicates(
+				predicate.GenerationChangedPredicate{},
+				predicate.NewPredicateFuncs(func(obj client.Object) bool {
+					return obj.GetName() == config.GlobalSettings.Name
+				}),
+			),
+		)
+	}
+	if gtc.backendTLSPoliciesInstalled {
+		b = b.Watches(
+			&gateway_v1.BackendTLSPolicy{},
+			enqueueRequest,
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).Watches(&corev1.ConfigMap{}, enqueueRequestIf(gtc.configMapReferenced))
+	}
+	if err := b.Complete(gtc); err != nil {
 		return fmt.Errorf("build controller: %w", err)
 	}
 
@@ -116,6 +216,10 @@ func (c *gatewayController) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl
 		return ctrl.Result{}, err
 	}
 
+	if err := c.provisionCertificates(ctx, o); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	config, err := c.processGateways(ctx, o)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -125,6 +229,7 @@ func (c *gatewayController) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl
 	if err != nil {
 		return ctrl.Result{}, err
 	}
+	c.CertificateMonitor.Observe(ctx, "Gateway API", config.Certificates)
 
 	return ctrl.Result{}, nil
 }
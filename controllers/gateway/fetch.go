@@ -2,33 +2,57 @@ package gateway
 
 import (
 	context "context"
+	"errors"
 
 	"github.com/hashicorp/go-set/v3"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
 	gateway_v1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	"github.com/pomerium/ingress-controller/model"
 	"github.com/pomerium/ingress-controller/util"
 )
 
 // objects holds all relevant Gateway objects and their dependencies.
 type objects struct {
-	Gateways                map[refKey]*gateway_v1.Gateway
-	HTTPRoutesByGateway     map[refKey][]httpRouteInfo
-	OriginalHTTPRouteStatus []httpRouteAndOriginalStatus
-	Namespaces              map[string]*corev1.Namespace
-	ReferenceGrants         referenceGrantMap
-	TLSSecrets              map[refKey]*corev1.Secret
-	Services                map[types.NamespacedName]*corev1.Service
-	PolicyFilters           map[types.NamespacedName]*icgv1alpha1.PolicyFilter
+	GatewayClasses        map[string]gatewayClassInfo
+	Gateways              map[refKey]*gateway_v1.Gateway
+	HTTPRoutesByGateway   map[refKey][]httpRouteInfo
+	GRPCRoutesByGateway   map[refKey][]grpcRouteInfo
+	TunnelRoutesByGateway map[refKey][]tunnelRouteInfo
+	OriginalRouteStatus   []routeAndOriginalStatus
+	Namespaces            map[string]*corev1.Namespace
+	ReferenceGrants       referenceGrantMap
+	TLSSecrets            map[refKey]*corev1.Secret
+	Services              map[types.NamespacedName]*corev1.Service
+	EndpointSlices        map[types.NamespacedName][]*discoveryv1.EndpointSlice
+	PolicyFilters         map[types.NamespacedName]*icgv1alpha1.PolicyFilter
+	BackendTLSPolicies    []*backendTLSPolicyInfo
+	ConfigMaps            map[types.NamespacedName]*corev1.ConfigMap
+
+	// ProvisionedCertificates holds the Secrets of the certificates provisioned for HTTPS
+	// listeners without certificateRefs.
+	ProvisionedCertificates map[listenerKey]refKey
+}
+
+// gatewayClassInfo holds the route defaults from the parameters of a GatewayClass, or a message
+// describing why the parameters are invalid.
+type gatewayClassInfo struct {
+	defaults          *model.GatewayRouteDefaults
+	invalidParameters string
 }
 
-type httpRouteAndOriginalStatus struct {
-	route          *gateway_v1.HTTPRoute
-	originalStatus *gateway_v1.HTTPRouteStatus
+// routeAndOriginalStatus holds a route of any kind, together with a snapshot of its status taken
+// before processing, to determine whether the status needs to be updated.
+type routeAndOriginalStatus struct {
+	route          client.Object
+	status         *gateway_v1.RouteStatus
+	originalStatus *gateway_v1.RouteStatus
 }
 
 // fetchObjects fetches all relevant Gateway objects.
@@ -41,10 +65,22 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 		return nil, err
 	}
 	gcNames := set.New[string](0)
+	o.GatewayClasses = make(map[string]gatewayClassInfo)
 	for i := range gcl.Items {
 		gc := &gcl.Items[i]
-		if gc.Spec.ControllerName == gateway_v1.GatewayController(c.ControllerName) {
-			gcNames.Insert(gc.Name)
+		if gc.Spec.ControllerName != gateway_v1.GatewayController(c.ControllerName) {
+			continue
+		}
+		gcNames.Insert(gc.Name)
+
+		defaults, err := resolveGatewayClassParameters(ctx, c, gc)
+		var invalid *invalidParametersError
+		if errors.As(err, &invalid) {
+			o.GatewayClasses[gc.Name] = gatewayClassInfo{invalidParameters: invalid.Error()}
+		} else if err != nil {
+			return nil, err
+		} else {
+			o.GatewayClasses[gc.Name] = gatewayClassInfo{defaults: defaults}
 		}
 	}
 
@@ -69,9 +105,12 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 	o.HTTPRoutesByGateway = make(map[refKey][]httpRouteInfo)
 	for i := range hrl.Items {
 		hr := &hrl.Items[i]
-		o.OriginalHTTPRouteStatus = append(o.OriginalHTTPRouteStatus,
-			httpRouteAndOriginalStatus{route: hr, originalStatus: hr.Status.DeepCopy()})
-		ensureRouteParentStatusExists(hr, c.ControllerName)
+		o.OriginalRouteStatus = append(o.OriginalRouteStatus, routeAndOriginalStatus{
+			route:          hr,
+			status:         &hr.Status.RouteStatus,
+			originalStatus: hr.Status.RouteStatus.DeepCopy(),
+		})
+		ensureRouteParentStatusExists(&hr.Spec.CommonRouteSpec, &hr.Status.RouteStatus, c.ControllerName)
 		for j := range hr.Spec.ParentRefs {
 			pr := &hr.Spec.ParentRefs[j]
 			key := refKeyForParentRef(hr, pr)
@@ -82,6 +121,67 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 		}
 	}
 
+	// Fetch all GRPCRoutes and filter by Gateway parentRef.
+	var grl gateway_v1.GRPCRouteList
+	if err := c.List(ctx, &grl); err != nil {
+		return nil, err
+	}
+	o.GRPCRoutesByGateway = make(map[refKey][]grpcRouteInfo)
+	for i := range grl.Items {
+		gr := &grl.Items[i]
+		o.OriginalRouteStatus = append(o.OriginalRouteStatus, routeAndOriginalStatus{
+			route:          gr,
+			status:         &gr.Status.RouteStatus,
+			originalStatus: gr.Status.RouteStatus.DeepCopy(),
+		})
+		ensureRouteParentStatusExists(&gr.Spec.CommonRouteSpec, &gr.Status.RouteStatus, c.ControllerName)
+		for j := range gr.Spec.ParentRefs {
+			pr := &gr.Spec.ParentRefs[j]
+			key := refKeyForParentRef(gr, pr)
+			if _, ok := o.Gateways[key]; ok {
+				o.GRPCRoutesByGateway[key] = append(o.GRPCRoutesByGateway[key],
+					grpcRouteInfo{gr, pr, &gr.Status.Parents[j]})
+			}
+		}
+	}
+
+	// Fetch all TCPRoutes and UDPRoutes, if these kinds are installed.
+	o.TunnelRoutesByGateway = make(map[refKey][]tunnelRouteInfo)
+	if c.tcpRoutesInstalled {
+		var trl gateway_v1.TCPRouteList
+		if err := c.List(ctx, &trl); err != nil {
+			return nil, err
+		}
+		for i := range trl.Items {
+			tr := &trl.Items[i]
+			var backendRefs []*gateway_v1.BackendRef
+			for j := range tr.Spec.Rules {
+				for k := range tr.Spec.Rules[j].BackendRefs {
+					backendRefs = append(backendRefs, &tr.Spec.Rules[j].BackendRefs[k])
+				}
+			}
+			o.addTunnelRoute(tr, "TCPRoute", &tr.Spec.CommonRouteSpec, &tr.Status.RouteStatus,
+				backendRefs, c.ControllerName)
+		}
+	}
+	if c.udpRoutesInstalled {
+		var udprl gateway_v1.UDPRouteList
+		if err := c.List(ctx, &udprl); err != nil {
+			return nil, err
+		}
+		for i := range udprl.Items {
+			ur := &udprl.Items[i]
+			var backendRefs []*gateway_v1.BackendRef
+			for j := range ur.Spec.Rules {
+				for k := range ur.Spec.Rules[j].BackendRefs {
+					backendRefs = append(backendRefs, &ur.Spec.Rules[j].BackendRefs[k])
+				}
+			}
+			o.addTunnelRoute(ur, "UDPRoute", &ur.Spec.CommonRouteSpec, &ur.Status.RouteStatus,
+				backendRefs, c.ControllerName)
+		}
+	}
+
 	// Fetch all Namespaces (the labels may be needed for the allowedRoutes restrictions).
 	var nl corev1.NamespaceList
 	if err := c.List(ctx, &nl); err != nil {
@@ -102,7 +202,7 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 
 	// Fetch all TLS secrets.
 	var sl corev1.SecretList
-	if err := c.List(ctx, &sl, client.MatchingFields{"type": string(corev1.SecretTypeTLS)}); err != nil {
+	if err := c.List(ctx, &sl, client.MatchingFields{SecretTypeIndex: string(corev1.SecretTypeTLS)}); err != nil {
 		return nil, err
 	}
 	o.TLSSecrets = make(map[refKey]*corev1.Secret)
@@ -122,6 +222,23 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 		o.Services[util.GetNamespacedName(s)] = s
 	}
 
+	// Fetch all EndpointSlices (needed for named ports of headless Services, and for routes
+	// using endpoint upstreams).
+	var esl discoveryv1.EndpointSliceList
+	if err := c.List(ctx, &esl); err != nil {
+		return nil, err
+	}
+	o.EndpointSlices = make(map[types.NamespacedName][]*discoveryv1.EndpointSlice)
+	for i := range esl.Items {
+		es := &esl.Items[i]
+		serviceName := es.Labels[discoveryv1.LabelServiceName]
+		if serviceName == "" {
+			continue
+		}
+		k := types.NamespacedName{Namespace: es.Namespace, Name: serviceName}
+		o.EndpointSlices[k] = append(o.EndpointSlices[k], es)
+	}
+
 	// Fetch all PolicyFilters.
 	var pfl icgv1alpha1.PolicyFilterList
 	if err := c.List(ctx, &pfl); err != nil {
@@ -133,6 +250,40 @@ func (c *gatewayController) fetchObjects(ctx context.Context) (*objects, error)
 		o.PolicyFilters[util.GetNamespacedName(pf)] = pf
 	}
 
+	// Fetch all BackendTLSPolicies and their CA certificate ConfigMaps, if this kind is installed.
+	o.ConfigMaps = make(map[types.NamespacedName]*corev1.ConfigMap)
+	if c.backendTLSPoliciesInstalled {
+		var btpl gateway_v1.BackendTLSPolicyList
+		if err := c.List(ctx, &btpl); err != nil {
+			return nil, err
+		}
+		for i := range btpl.Items {
+			p := &btpl.Items[i]
+			o.BackendTLSPolicies = append(o.BackendTLSPolicies, &backendTLSPolicyInfo{
+				policy:         p,
+				originalStatus: p.Status.DeepCopy(),
+				ancestors:      set.New[refKey](0),
+			})
+			for _, ref := range p.Spec.Validation.CACertificateRefs {
+				if ref.Group != corev1.GroupName || ref.Kind != "ConfigMap" {
+					continue
+				}
+				name := types.NamespacedName{Namespace: p.Namespace, Name: string(ref.Name)}
+				if _, ok := o.ConfigMaps[name]; ok {
+					continue
+				}
+				var cm corev1.ConfigMap
+				if err := c.Get(ctx, name, &cm); apierrors.IsNotFound(err) {
+					o.ConfigMaps[name] = nil
+				} else if err != nil {
+					return nil, err
+				} else {
+					o.ConfigMaps[name] = &cm
+				}
+			}
+		}
+	}
+
 	return &o, nil
 }
 
@@ -141,3 +292,9 @@ type httpRouteInfo struct {
 	parent *gateway_v1.ParentReference
 	status *gateway_v1.RouteParentStatus
 }
+
+type grpcRouteInfo struct {
+	route  *gateway_v1.GRPCRoute
+	parent *gateway_v1.ParentReference
+	status *gateway_v1.RouteParentStatus
+}
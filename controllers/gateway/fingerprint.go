@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// routeFingerprint computes a digest of all inputs used to translate a single route into Pomerium
+// routes: the route spec, the listener addresses it is attached to, and the backend Services,
+// EndpointSlices, BackendTLSPolicy settings and PolicyFilters it refers to. Reconcilers may skip
+// translating and syncing a route whose fingerprint has not changed.
+func routeFingerprint(
+	config *model.GatewayConfig,
+	o *objects,
+	route client.Object,
+	addresses []model.GatewayListenerAddress,
+	backendRefs []*gateway_v1.BackendRef,
+	validBackendRefs backendRefSet,
+	extensionRefs []*gateway_v1.LocalObjectReference,
+	defaults *model.GatewayRouteDefaults,
+) string {
+	h := sha256.New()
+	write := func(format string, args ...any) {
+		fmt.Fprintf(h, format+"\n", args...)
+	}
+
+	write("route %s %d %t %q", route.GetUID(), route.GetGeneration(), route.GetDeletionTimestamp() != nil,
+		route.GetAnnotations()[model.GatewayEndpointUpstreamAnnotation])
+	for _, a := range addresses {
+		write("address %s %s %d", a.Protocol, a.Hostname, a.Port)
+	}
+
+	endpointUpstream := route.GetAnnotations()[model.GatewayEndpointUpstreamAnnotation] == "true"
+	for _, br := range backendRefs {
+		if !validBackendRefs.Valid(route, br) {
+			write("backend invalid")
+			continue
+		}
+		k := refKeyForBackendRef(route, &br.BackendObjectReference)
+		serviceName := types.NamespacedName{Namespace: k.Namespace, Name: k.Name}
+		svc := o.Services[serviceName]
+		if svc == nil {
+			write("backend %s missing", serviceName)
+			continue
+		}
+		write("backend %s %s", serviceName, svc.ResourceVersion)
+
+		// Endpoints are needed only for endpoint upstreams and for headless Services, and change
+		// frequently otherwise.
+		if endpointUpstream || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+			writeEndpointSlices(h, o.EndpointSlices[serviceName])
+		}
+		writeBackendTLS(h, config.BackendTLS, serviceName)
+	}
+
+	for _, ref := range extensionRefs {
+		var pf *icgv1alpha1.PolicyFilter
+		if ref.Kind == "PolicyFilter" {
+			pf = o.PolicyFilters[types.NamespacedName{Namespace: route.GetNamespace(), Name: string(ref.Name)}]
+		}
+		writePolicyFilter(write, string(ref.Kind), string(ref.Name), pf)
+	}
+
+	if defaults != nil {
+		write("defaults %v %v", durationString(defaults.Timeout), durationString(defaults.IdleTimeout))
+		if k := defaults.PolicyFilter; k != nil {
+			pf := o.PolicyFilters[types.NamespacedName{Namespace: k.Namespace, Name: k.Name}]
+			writePolicyFilter(write, k.Kind, k.Namespace+"/"+k.Name, pf)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writePolicyFilter(
+	write func(format string, args ...any),
+	kind, name string,
+	pf *icgv1alpha1.PolicyFilter,
+) {
+	if pf == nil {
+		write("filter %s/%s missing", kind, name)
+		return
+	}
+	write("filter %s/%s %s %d %t", kind, name, pf.UID, pf.Generation, pf.DeletionTimestamp != nil)
+}
+
+func durationString(d *time.Duration) string {
+	if d == nil {
+		return "-"
+	}
+	return d.String()
+}
+
+func writeEndpointSlices(h hash.Hash, endpointSlices []*discoveryv1.EndpointSlice) {
+	versions := make([]string, len(endpointSlices))
+	for i, es := range endpointSlices {
+		versions[i] = es.Name + "@" + es.ResourceVersion
+	}
+	slices.Sort(versions)
+	fmt.Fprintf(h, "endpoints %s\n", strings.Join(versions, " "))
+}
+
+func writeBackendTLS(
+	h hash.Hash,
+	backendTLS map[model.BackendTLSKey]*model.BackendTLSConfig,
+	serviceName types.NamespacedName,
+) {
+	var keys []model.BackendTLSKey
+	for k := range backendTLS {
+		if k.NamespacedName == serviceName {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b model.BackendTLSKey) int {
+		return strings.Compare(a.SectionName, b.SectionName)
+	})
+	for _, k := range keys {
+		cfg := backendTLS[k]
+		sum := sha256.Sum256(cfg.CACertificates)
+		fmt.Fprintf(h, "tls %q %s %x\n", k.SectionName, cfg.Hostname, sum)
+	}
+}
+
+// httpRouteExtensionRefs returns the extension filter references of all HTTPRoute rules.
+func httpRouteExtensionRefs(route *gateway_v1.HTTPRoute) []*gateway_v1.LocalObjectReference {
+	var refs []*gateway_v1.LocalObjectReference
+	for i := range route.Spec.Rules {
+		for j := range route.Spec.Rules[i].Filters {
+			if ref := route.Spec.Rules[i].Filters[j].ExtensionRef; ref != nil {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// grpcRouteExtensionRefs returns the extension filter references of all GRPCRoute rules.
+func grpcRouteExtensionRefs(route *gateway_v1.GRPCRoute) []*gateway_v1.LocalObjectReference {
+	var refs []*gateway_v1.LocalObjectReference
+	for i := range route.Spec.Rules {
+		for j := range route.Spec.Rules[i].Filters {
+			if ref := route.Spec.Rules[i].Filters[j].ExtensionRef; ref != nil {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
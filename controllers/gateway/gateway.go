@@ -24,13 +24,20 @@ func (c *gatewayController) processGateways(
 		return nil, err
 	}
 
+	processBackendTLSPolicies(&config, o)
+
+	conflicts := findListenerConflicts(o)
 	for key := range o.Gateways {
-		if err := c.processGateway(ctx, &config, o, key); err != nil {
+		if err := c.processGateway(ctx, &config, o, key, conflicts); err != nil {
 			return nil, err
 		}
 	}
 
-	if err := c.updateModifiedHTTPRouteStatus(ctx, o.OriginalHTTPRouteStatus); err != nil {
+	if err := c.updateModifiedRouteStatus(ctx, o.OriginalRouteStatus); err != nil {
+		return nil, err
+	}
+
+	if err := c.updateBackendTLSPolicyStatus(ctx, o); err != nil {
 		return nil, err
 	}
 
@@ -44,12 +51,32 @@ func (c *gatewayController) processGateway(
 	config *model.GatewayConfig,
 	o *objects,
 	gatewayKey refKey,
+	conflicts map[listenerKey]metav1.Condition,
 ) error {
 	gateway := o.Gateways[gatewayKey]
 
 	// Snapshot the existing status, then compare after updates to determine if it has changed.
 	previousStatus := gateway.Status.DeepCopy()
 
+	// A Gateway cannot be programmed if the parameters of its GatewayClass are invalid.
+	class := o.GatewayClasses[string(gateway.Spec.GatewayClassName)]
+	if class.invalidParameters != "" {
+		upsertGatewayConditions(gateway,
+			metav1.Condition{
+				Type:    string(gateway_v1.GatewayConditionAccepted),
+				Status:  metav1.ConditionFalse,
+				Reason:  string(gateway_v1.GatewayReasonInvalidParameters),
+				Message: "GatewayClass parameters are invalid: " + class.invalidParameters,
+			},
+			metav1.Condition{
+				Type:   string(gateway_v1.GatewayConditionProgrammed),
+				Status: metav1.ConditionFalse,
+				Reason: string(gateway_v1.GatewayReasonInvalid),
+			},
+		)
+		return c.updateGatewayStatus(ctx, gateway, previousStatus)
+	}
+
 	// We need to preserve any existing ListenerStatus conditions, to avoid modifying the
 	// LastTransitionTime incorrectly.
 	ensureListenerStatusExists(gateway)
@@ -61,26 +88,88 @@ func (c *gatewayController) processGateway(
 		status := &gateway.Status.Listeners[i]
 		l := listenerAndStatus{listener, status, gateway.Generation}
 
-		processListener(config, o, gatewayKey, l)
+		processListener(config, o, gatewayKey, l, conflicts)
 
-		// Filter out any listeners that do not support HTTPRoutes.
-		if len(status.SupportedKinds) > 0 {
+		// Filter out any listeners that do not support any route kinds, or that conflict with
+		// another listener.
+		_, conflicted := conflicts[listenerKey{gatewayKey, listener.Name}]
+		if len(status.SupportedKinds) > 0 && !conflicted {
 			listenersByName[string(listener.Name)] = l
 		}
 
-		// Reset AttachedRoutes because processHTTPRoute() will increment these counts.
+		// Reset AttachedRoutes because the process*Route() functions will increment these counts.
 		status.AttachedRoutes = 0
 	}
 
-	for _, r := range o.HTTPRoutesByGateway[gatewayKey] {
-		result := processHTTPRoute(o, gateway, listenersByName, r)
-		if len(result.Hostnames) > 0 {
-			config.Routes = append(config.Routes, model.GatewayHTTPRouteConfig{
-				HTTPRoute:        r.route,
-				Hostnames:        result.Hostnames,
+	// The routes translated from each route are validated as Pomerium does, so that any routes
+	// left out of the configuration are reported in the route status.
+	httpRoutes := o.HTTPRoutesByGateway[gatewayKey]
+	methodConflicts := findMethodMatchConflicts(httpRoutes)
+	for i, r := range httpRoutes {
+		result := processHTTPRoute(o, gateway, listenersByName, r, methodConflicts[i])
+		rc := model.GatewayHTTPRouteConfig{
+			HTTPRoute:        r.route,
+			Addresses:        result.Addresses,
+			ValidBackendRefs: result.ValidBackendRefs,
+			Services:         o.Services,
+			EndpointSlices:   o.EndpointSlices,
+			Defaults:         class.defaults,
+		}
+		if validateHTTPRouteConfig(ctx, config, &rc, r, result) && len(result.Addresses) > 0 {
+			o.addBackendTLSAncestor(gatewayKey, result.ValidBackendRefs)
+			rc.Fingerprint = routeFingerprint(config, o, r.route, result.Addresses, result.BackendRefs,
+				result.ValidBackendRefs, httpRouteExtensionRefs(r.route), class.defaults)
+			config.Routes = append(config.Routes, rc)
+		}
+	}
+
+	for _, r := range o.GRPCRoutesByGateway[gatewayKey] {
+		result := processGRPCRoute(o, gateway, listenersByName, r)
+		rc := model.GatewayGRPCRouteConfig{
+			GRPCRoute:        r.route,
+			Addresses:        result.Addresses,
+			ValidBackendRefs: result.ValidBackendRefs,
+			Services:         o.Services,
+			EndpointSlices:   o.EndpointSlices,
+			Defaults:         class.defaults,
+		}
+		if validateGRPCRouteConfig(ctx, config, &rc, r, result) && len(result.Addresses) > 0 {
+			o.addBackendTLSAncestor(gatewayKey, result.ValidBackendRefs)
+			rc.Fingerprint = routeFingerprint(config, o, r.route, result.Addresses, result.BackendRefs,
+				result.ValidBackendRefs, grpcRouteExtensionRefs(r.route), class.defaults)
+			config.GRPCRoutes = append(config.GRPCRoutes, rc)
+		}
+	}
+
+	for _, r := range o.TunnelRoutesByGateway[gatewayKey] {
+		result := processTunnelRoute(o, gateway, listenersByName, r)
+		switch route := r.route.(type) {
+		case *gateway_v1.TCPRoute:
+			rc := model.GatewayTCPRouteConfig{
+				TCPRoute:         route,
+				Addresses:        result.Addresses,
 				ValidBackendRefs: result.ValidBackendRefs,
 				Services:         o.Services,
-			})
+				EndpointSlices:   o.EndpointSlices,
+			}
+			if validateTCPRouteConfig(&rc, r) && len(result.Addresses) > 0 {
+				rc.Fingerprint = routeFingerprint(config, o, r.route, result.Addresses,
+					r.backendRefs, result.ValidBackendRefs, nil, nil)
+				config.TCPRoutes = append(config.TCPRoutes, rc)
+			}
+		case *gateway_v1.UDPRoute:
+			rc := model.GatewayUDPRouteConfig{
+				UDPRoute:         route,
+				Addresses:        result.Addresses,
+				ValidBackendRefs: result.ValidBackendRefs,
+				Services:         o.Services,
+				EndpointSlices:   o.EndpointSlices,
+			}
+			if validateUDPRouteConfig(&rc, r) && len(result.Addresses) > 0 {
+				rc.Fingerprint = routeFingerprint(config, o, r.route, result.Addresses,
+					r.backendRefs, result.ValidBackendRefs, nil, nil)
+				config.UDPRoutes = append(config.UDPRoutes, rc)
+			}
 		}
 	}
 
@@ -99,6 +188,15 @@ func (c *gatewayController) processGateway(
 		},
 	)
 
+	return c.updateGatewayStatus(ctx, gateway, previousStatus)
+}
+
+// updateGatewayStatus updates the status of a Gateway if it differs from previousStatus.
+func (c *gatewayController) updateGatewayStatus(
+	ctx context.Context,
+	gateway *gateway_v1.Gateway,
+	previousStatus *gateway_v1.GatewayStatus,
+) error {
 	if !equality.Semantic.DeepEqual(gateway.Status, previousStatus) {
 		if err := c.Status().Update(ctx, gateway); err != nil {
 			return fmt.Errorf("couldn't update status for gateway %q: %w", gateway.Name, err)
@@ -170,13 +268,13 @@ func upsertGatewayConditions(g *gateway_v1.Gateway, conditions ...metav1.Conditi
 	return upsertConditions(&g.Status.Conditions, g.Generation, conditions...)
 }
 
-func (c *gatewayController) updateModifiedHTTPRouteStatus(
-	ctx context.Context, s []httpRouteAndOriginalStatus,
+func (c *gatewayController) updateModifiedRouteStatus(
+	ctx context.Context, s []routeAndOriginalStatus,
 ) error {
 	for _, r := range s {
-		if !equality.Semantic.DeepEqual(r.route.Status, r.originalStatus) {
+		if !equality.Semantic.DeepEqual(r.status, r.originalStatus) {
 			if err := c.Status().Update(ctx, r.route); err != nil {
-				return fmt.Errorf("couldn't update status for route %q: %w", r.route.Name, err)
+				return fmt.Errorf("couldn't update status for route %q: %w", r.route.GetName(), err)
 			}
 		}
 	}
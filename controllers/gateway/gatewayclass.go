@@ -2,11 +2,22 @@ package gateway
 
 import (
 	context "context"
+	"errors"
+	"fmt"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/util"
 )
 
 type gatewayClassController struct {
@@ -15,7 +26,8 @@ type gatewayClassController struct {
 }
 
 // NewGatewayClassController creates and registers a new controller for GatewayClass objects.
-// This controller does just one thing: it sets the "Accepted" status condition.
+// This controller does just one thing: it sets the "Accepted" status condition, according to
+// whether the parametersRef (if any) is valid.
 func NewGatewayClassController(
 	mgr ctrl.Manager,
 	controllerName string,
@@ -25,10 +37,43 @@ func NewGatewayClassController(
 		controllerName: controllerName,
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named("gateway-class").
 		For(&gateway_v1.GatewayClass{}).
-		Complete(gtcc)
+		Watches(&icgv1alpha1.PolicyFilter{}, handler.EnqueueRequestsFromMapFunc(gtcc.classesWithParameters))
+
+	installed, err := util.IsKindInstalled(mgr.GetClient(), &icgv1alpha1.GatewayClassConfig{})
+	if err != nil {
+		return fmt.Errorf("couldn't check for GatewayClassConfig kind: %w", err)
+	}
+	if installed {
+		b = b.Watches(&icgv1alpha1.GatewayClassConfig{},
+			handler.EnqueueRequestsFromMapFunc(gtcc.classesWithParameters))
+	}
+
+	return b.Complete(gtcc)
+}
+
+// classesWithParameters returns a reconcile request for each GatewayClass of this controller with
+// a parametersRef, as the validity of the parameters depends on other objects.
+func (c *gatewayClassController) classesWithParameters(
+	ctx context.Context, _ client.Object,
+) []reconcile.Request {
+	var gcl gateway_v1.GatewayClassList
+	if err := c.List(ctx, &gcl); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range gcl.Items {
+		gc := &gcl.Items[i]
+		if gc.Spec.ControllerName == gateway_v1.GatewayController(c.controllerName) &&
+			gc.Spec.ParametersRef != nil {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: gc.Name},
+			})
+		}
+	}
+	return requests
 }
 
 func (c *gatewayClassController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -41,7 +86,13 @@ func (c *gatewayClassController) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
-	if setGatewayClassAccepted(&gc) {
+	_, err := resolveGatewayClassParameters(ctx, c, &gc)
+	var invalid *invalidParametersError
+	if err != nil && !errors.As(err, &invalid) {
+		return ctrl.Result{}, err
+	}
+
+	if setGatewayClassAccepted(&gc, invalid) {
 		// Condition changed, need to update status.
 		if err := c.Status().Update(ctx, &gc); err != nil {
 			return ctrl.Result{}, err
@@ -51,10 +102,89 @@ func (c *gatewayClassController) Reconcile(ctx context.Context, req ctrl.Request
 	return ctrl.Result{}, nil
 }
 
-func setGatewayClassAccepted(gc *gateway_v1.GatewayClass) (modified bool) {
+func setGatewayClassAccepted(gc *gateway_v1.GatewayClass, invalid *invalidParametersError) (modified bool) {
+	if invalid != nil {
+		return upsertCondition(&gc.Status.Conditions, gc.Generation, metav1.Condition{
+			Type:    string(gateway_v1.GatewayClassConditionStatusAccepted),
+			Status:  metav1.ConditionFalse,
+			Reason:  string(gateway_v1.GatewayClassReasonInvalidParameters),
+			Message: invalid.Error(),
+		})
+	}
 	return upsertCondition(&gc.Status.Conditions, gc.Generation, metav1.Condition{
 		Type:   string(gateway_v1.GatewayClassConditionStatusAccepted),
 		Status: metav1.ConditionTrue,
 		Reason: string(gateway_v1.GatewayClassReasonAccepted),
 	})
 }
+
+// invalidParametersError indicates that the parametersRef of a GatewayClass cannot be resolved.
+type invalidParametersError struct {
+	message string
+}
+
+func (e *invalidParametersError) Error() string {
+	return e.message
+}
+
+func invalidParameters(format string, args ...any) error {
+	return &invalidParametersError{fmt.Sprintf(format, args...)}
+}
+
+// resolveGatewayClassParameters fetches the GatewayClassConfig referenced by a GatewayClass and
+// returns the corresponding route defaults, or nil if there is no parametersRef. An error of type
+// *invalidParametersError is returned if the reference or the parameters are not valid.
+func resolveGatewayClassParameters(
+	ctx context.Context,
+	c client.Reader,
+	gc *gateway_v1.GatewayClass,
+) (*model.GatewayRouteDefaults, error) {
+	ref := gc.Spec.ParametersRef
+	if ref == nil {
+		return nil, nil
+	}
+	if string(ref.Group) != icgv1alpha1.GroupVersion.Group || ref.Kind != "GatewayClassConfig" {
+		return nil, invalidParameters("unsupported parametersRef kind %q in group %q", ref.Kind, ref.Group)
+	}
+	if ref.Namespace != nil {
+		// GatewayClassConfig is cluster-scoped.
+		return nil, invalidParameters("parametersRef namespace must not be set for GatewayClassConfig")
+	}
+
+	var cfg icgv1alpha1.GatewayClassConfig
+	err := c.Get(ctx, types.NamespacedName{Name: ref.Name}, &cfg)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil, invalidParameters("GatewayClassConfig %q not found", ref.Name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var defaults model.GatewayRouteDefaults
+	if t := cfg.Spec.Timeout; t != nil {
+		if t.Duration < 0 {
+			return nil, invalidParameters("GatewayClassConfig %q: timeout must not be negative", ref.Name)
+		}
+		defaults.Timeout = new(t.Duration)
+	}
+	if t := cfg.Spec.IdleTimeout; t != nil {
+		if t.Duration < 0 {
+			return nil, invalidParameters("GatewayClassConfig %q: idleTimeout must not be negative", ref.Name)
+		}
+		defaults.IdleTimeout = new(t.Duration)
+	}
+	if pf := cfg.Spec.DefaultPolicyFilter; pf != nil {
+		name := types.NamespacedName{Namespace: pf.Namespace, Name: pf.Name}
+		var obj icgv1alpha1.PolicyFilter
+		if err := c.Get(ctx, name, &obj); apierrors.IsNotFound(err) {
+			return nil, invalidParameters("GatewayClassConfig %q: default PolicyFilter %s not found", ref.Name, name)
+		} else if err != nil {
+			return nil, err
+		}
+		defaults.PolicyFilter = &model.ExtensionFilterKey{
+			Kind:      "PolicyFilter",
+			Namespace: pf.Namespace,
+			Name:      pf.Name,
+		}
+	}
+	return &defaults, nil
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"context"
+
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
+)
+
+type grpcRouteResult struct {
+	Addresses        []model.GatewayListenerAddress
+	BackendRefs      []*gateway_v1.BackendRef
+	ValidBackendRefs backendRefSet
+	// UnsupportedRules describes the unsupported rules and matches of the route, if any.
+	UnsupportedRules string
+}
+
+// processGRPCRoute checks the validity of a GRPCRoute, updates its status accordingly, and
+// computes the listener addresses it matches (with "all" hostnames represented as "*").
+func processGRPCRoute(
+	o *objects,
+	g *gateway_v1.Gateway,
+	listeners map[string]listenerAndStatus,
+	r grpcRouteInfo,
+) grpcRouteResult {
+	var result grpcRouteResult
+
+	rp := routeParent{
+		route:     r.route,
+		kind:      "GRPCRoute",
+		hostnames: r.route.Spec.Hostnames,
+		parent:    r.parent,
+		status:    r.status,
+	}
+
+	// Reject this route early if it includes any per-backendRef filters as we don't support these.
+	if anyGRPCBackendRefHasFilters(r.route.Spec.Rules) {
+		setRouteStatusUnsupported(rp, "backendRef filters are not supported")
+		return result
+	}
+
+	// As for HTTPRoutes, reject this route if any of its matches cannot be represented as
+	// Pomerium routes, or if none of its rules can, and report any other unsupported rules as
+	// partially invalid.
+	unsupported := gateway.ValidateGRPCRules(r.route.Spec.Rules)
+	msg, dropped := unsupportedRulesMessage(unsupported)
+	if anyUnsupportedMatch(unsupported) || (dropped > 0 && dropped == len(r.route.Spec.Rules)) {
+		setRouteStatusUnsupported(rp, msg)
+		return result
+	}
+	result.UnsupportedRules = msg
+
+	for i := range r.route.Spec.Rules {
+		rule := &r.route.Spec.Rules[i]
+		for j := range rule.BackendRefs {
+			result.BackendRefs = append(result.BackendRefs, &rule.BackendRefs[j].BackendRef)
+		}
+	}
+	result.ValidBackendRefs = validateBackendRefsResolved(o, r.route, r.status, result.BackendRefs)
+
+	result.Addresses = attachedAddresses(attachRoute(o, g, listeners, rp))
+	return result
+}
+
+// validateGRPCRouteConfig reports the unsupported rules of a GRPCRoute, and any of the Pomerium
+// routes translated from it that fail validation, in the route status (see
+// setRouteStatusDropped). It reports whether any of the translated routes are valid.
+func validateGRPCRouteConfig(
+	ctx context.Context,
+	config *model.GatewayConfig,
+	rc *model.GatewayGRPCRouteConfig,
+	r grpcRouteInfo,
+	result grpcRouteResult,
+) bool {
+	return setRouteStatusDropped(routeParent{route: r.route, status: r.status},
+		result.UnsupportedRules, gateway.ValidateGRPCRoutes(ctx, config, rc))
+}
+
+func anyGRPCBackendRefHasFilters(rules []gateway_v1.GRPCRouteRule) bool {
+	for i := range rules {
+		rule := &rules[i]
+		for i := range rule.BackendRefs {
+			if len(rule.BackendRefs[i].Filters) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,80 +1,93 @@
 package gateway
 
 import (
+	"context"
+	"fmt"
+	"slices"
 	"strings"
 
-	"github.com/hashicorp/go-set/v3"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
 )
 
 type httpRouteResult struct {
-	Hostnames        []gateway_v1.Hostname
+	Addresses        []model.GatewayListenerAddress
+	BackendRefs      []*gateway_v1.BackendRef
 	ValidBackendRefs backendRefSet
+	// UnsupportedRules describes the unsupported rules and matches of the route, if any.
+	UnsupportedRules string
 }
 
 // processHTTPRoute checks the validity of an HTTPRoute, updates its status accordingly, and
-// computes its matching hostnames (with "all" represented as "*").
+// computes the listener addresses it matches (with "all" hostnames represented as "*").
 func processHTTPRoute(
 	o *objects,
 	g *gateway_v1.Gateway,
 	listeners map[string]listenerAndStatus,
 	r httpRouteInfo,
+	methodConflicts string,
 ) httpRouteResult {
 	var result httpRouteResult
 
+	rp := routeParent{
+		route:     r.route,
+		kind:      "HTTPRoute",
+		hostnames: r.route.Spec.Hostnames,
+		parent:    r.parent,
+		status:    r.status,
+	}
+
 	// Reject this route early if it includes any per-backendRef filters as we don't support these.
 	if anyBackendRefHasFilters(r.route.Spec.Rules) {
-		upsertCondition(&r.status.Conditions, r.route.Generation, metav1.Condition{
-			Type:    string(gateway_v1.RouteConditionAccepted),
-			Status:  metav1.ConditionFalse,
-			Reason:  string(gateway_v1.RouteReasonUnsupportedValue),
-			Message: "backendRef filters are not supported",
-		})
+		setRouteStatusUnsupported(rp, "backendRef filters are not supported")
 		return result
 	}
 
-	result.ValidBackendRefs = validateHTTPRouteBackendRefsResolved(o, r)
-
-	// An HTTPRoute may specify a listener name directly. In this case we should check for route
-	// attachment with just the one listener.
-	if r.parent.SectionName != nil {
-		l, ok := listeners[string(*r.parent.SectionName)]
-		if !ok {
-			setRouteStatusAccepted(r, gateway_v1.RouteReasonNoMatchingParent)
-			return result
-		}
-		ra := processHTTPRouteForListener(o, g, l, r.route)
-		setRouteStatusAccepted(r, ra.reason)
-		result.Hostnames = ra.hostnames
+	// Reject this route if any of its matches cannot be represented as Pomerium routes, or if none
+	// of its rules can. Any other unsupported rules are left out, and reported as partially
+	// invalid (see setRouteStatusDropped).
+	unsupported := gateway.ValidateRules(r.route.Spec.Rules)
+	msg, dropped := unsupportedRulesMessage(unsupported)
+	if methodConflicts != "" && msg != "" {
+		msg += "; " + methodConflicts
+	} else if methodConflicts != "" {
+		msg = methodConflicts
+	}
+	if methodConflicts != "" || anyUnsupportedMatch(unsupported) ||
+		(dropped > 0 && dropped == len(r.route.Spec.Rules)) {
+		setRouteStatusUnsupported(rp, msg)
 		return result
 	}
+	result.UnsupportedRules = msg
 
-	// Otherwise check for route attachment with all listeners.
-	hostnamesSet := set.New[gateway_v1.Hostname](0)
-	var reason gateway_v1.RouteConditionReason
-	for _, l := range listeners {
-		ra := processHTTPRouteForListener(o, g, l, r.route)
-		hostnamesSet.InsertSlice(ra.hostnames)
-		// If the route attaches to any listener, we consider the route accepted.
-		// Otherwise we'll return the reason associated with the first listener.
-		if reason == "" || ra.reason == gateway_v1.RouteReasonAccepted {
-			reason = ra.reason
+	for i := range r.route.Spec.Rules {
+		rule := &r.route.Spec.Rules[i]
+		for j := range rule.BackendRefs {
+			result.BackendRefs = append(result.BackendRefs, &rule.BackendRefs[j].BackendRef)
 		}
 	}
-	if reason == "" {
-		reason = gateway_v1.RouteReasonNoMatchingParent // no listeners at all
-	}
-	setRouteStatusAccepted(r, reason)
-	result.Hostnames = hostnamesSet.Slice()
+	result.ValidBackendRefs = validateBackendRefsResolved(o, r.route, r.status, result.BackendRefs)
+
+	result.Addresses = attachedAddresses(attachRoute(o, g, listeners, rp))
 	return result
 }
 
+// validateHTTPRouteConfig reports the unsupported rules of an HTTPRoute, and any of the Pomerium
+// routes translated from it that fail validation, in the route status (see
+// setRouteStatusDropped). It reports whether any of the translated routes are valid.
+func validateHTTPRouteConfig(
+	ctx context.Context,
+	config *model.GatewayConfig,
+	rc *model.GatewayHTTPRouteConfig,
+	r httpRouteInfo,
+	result httpRouteResult,
+) bool {
+	return setRouteStatusDropped(routeParent{route: r.route, status: r.status},
+		result.UnsupportedRules, gateway.ValidateRoutes(ctx, config, rc))
+}
+
 func anyBackendRefHasFilters(rules []gateway_v1.HTTPRouteRule) bool {
 	for i := range rules {
 		rule := &rules[i]
@@ -87,217 +100,100 @@ func anyBackendRefHasFilters(rules []gateway_v1.HTTPRouteRule) bool {
 	return false
 }
 
-func validateHTTPRouteBackendRefsResolved(
-	o *objects,
-	r httpRouteInfo,
-) (validRefs backendRefSet) {
-	// Check that all backendRefs can be resolved.
-	invalidRefs := make(map[gateway_v1.RouteConditionReason][]string)
-	invalid := func(reason gateway_v1.RouteConditionReason, name string) {
-		invalidRefs[reason] = append(invalidRefs[reason], name)
-	}
-	for i := range r.route.Spec.Rules {
-		rule := &r.route.Spec.Rules[i]
-		for i := range rule.BackendRefs {
-			refKey := refKeyForBackendRef(r.route, &rule.BackendRefs[i].BackendObjectReference)
-			if refKey.Group != corev1.GroupName || refKey.Kind != "Service" {
-				invalid(gateway_v1.RouteReasonInvalidKind, refKey.Name)
-				continue
+// findMethodMatchConflicts returns, for each of the HTTPRoutes attached to a Gateway, a message
+// listing any of its method matches with the same path match as a match of another of the routes,
+// if the routes may be attached to the same listener with overlapping hostnames. A method match is
+// translated into a policy denying requests with any other method, so the Pomerium route
+// translated from it would deny the requests meant for the other route. Conflicts between the
+// matches of a single route are reported by gateway.ValidateRules.
+func findMethodMatchConflicts(routes []httpRouteInfo) []string {
+	type match struct {
+		route, rule, match int
+		method             bool
+	}
+	byPath := make(map[gateway.PathMatchKey][]match)
+	for i, r := range routes {
+		for j := range r.route.Spec.Rules {
+			rule := &r.route.Spec.Rules[j]
+			if len(rule.Matches) == 0 {
+				k := gateway.NewPathMatchKey(nil)
+				byPath[k] = append(byPath[k], match{i, j, -1, false})
 			}
-			if !o.ReferenceGrants.allowed(r.route, refKey) {
-				invalid(gateway_v1.RouteReasonRefNotPermitted, refKey.Name)
-				continue
-			}
-			if o.Services[types.NamespacedName{Namespace: refKey.Namespace, Name: refKey.Name}] == nil {
-				invalid(gateway_v1.RouteReasonBackendNotFound, refKey.Name)
-				continue
+			for k := range rule.Matches {
+				m := &rule.Matches[k]
+				if gateway.ValidateMatch(m) != nil {
+					continue
+				}
+				pk := gateway.NewPathMatchKey(m.Path)
+				byPath[pk] = append(byPath[pk], match{i, j, k, m.Method != nil})
 			}
-			validRefs.insert(r.route, &rule.BackendRefs[i].BackendRef)
 		}
 	}
 
-	resolvedRefs := metav1.Condition{
-		Type:   string(gateway_v1.RouteConditionResolvedRefs),
-		Status: metav1.ConditionTrue,
-		Reason: string(gateway_v1.RouteReasonResolvedRefs),
-	}
-
-	var messages []string
-	for reason, refNames := range invalidRefs {
-		resolvedRefs.Status = metav1.ConditionFalse
-		resolvedRefs.Reason = string(reason) // if multiple reasons apply this will set one arbitrarily
-		messages = append(messages, "invalid refs ("+string(reason)+"): "+strings.Join(refNames, ", "))
-	}
-	resolvedRefs.Message = strings.Join(messages, "; ")
-
-	upsertCondition(&r.status.Conditions, r.route.Generation, resolvedRefs)
-
-	return validRefs
-}
-
-type routeAttachment struct {
-	// Resolved hostnames, with "all" represented as "*".
-	hostnames []gateway_v1.Hostname
-
-	// "Accepted" condition status reason.
-	reason gateway_v1.RouteConditionReason
-}
-
-func processHTTPRouteForListener(
-	o *objects,
-	g *gateway_v1.Gateway,
-	l listenerAndStatus,
-	r *gateway_v1.HTTPRoute,
-) routeAttachment {
-	if !isHTTPRouteAllowed(o, l.listener.AllowedRoutes, r, g.Namespace) {
-		return routeAttachment{reason: gateway_v1.RouteReasonNotAllowedByListeners}
-	}
-
-	hostnames := routeHostnames(l.listener.Hostname, r.Spec.Hostnames)
-	if len(hostnames) == 0 {
-		return routeAttachment{reason: gateway_v1.RouteReasonNoMatchingListenerHostname}
+	conflicts := make([][]string, len(routes))
+	for _, matches := range byPath {
+		for _, m := range matches {
+			if !m.method {
+				continue
+			}
+			for _, other := range matches {
+				a, b := routes[m.route], routes[other.route]
+				if a.route == b.route || !routesMayOverlap(a, b) {
+					continue
+				}
+				conflicts[m.route] = append(conflicts[m.route], fmt.Sprintf(
+					"rule %d: match %d: method match has the same path match as a match of HTTPRoute %s/%s",
+					m.rule, m.match, b.route.Namespace, b.route.Name))
+				break
+			}
+		}
 	}
 
-	l.status.AttachedRoutes++
-
-	return routeAttachment{
-		hostnames: hostnames,
-		reason:    gateway_v1.RouteReasonAccepted,
+	messages := make([]string, len(routes))
+	for i := range conflicts {
+		slices.Sort(conflicts[i])
+		messages[i] = strings.Join(conflicts[i], "; ")
 	}
+	return messages
 }
 
-func isHTTPRouteAllowed(
-	o *objects,
-	allowed *gateway_v1.AllowedRoutes,
-	r *gateway_v1.HTTPRoute,
-	gatewayNamespace string,
-) bool {
-	// This assumes we've already checked SupportedKinds in processGateway(), so we need only
-	// check that the route namespace is allowed.
-	from := gateway_v1.NamespacesFromSame
-	if allowed.Namespaces != nil && allowed.Namespaces.From != nil {
-		from = *allowed.Namespaces.From
-	}
-	switch from {
-	case gateway_v1.NamespacesFromAll:
-		return true
-	case gateway_v1.NamespacesFromSame:
-		return r.Namespace == gatewayNamespace
-	case gateway_v1.NamespacesFromSelector:
-		selector, err := metav1.LabelSelectorAsSelector(allowed.Namespaces.Selector)
-		if err != nil {
-			return false
-		}
-		routeNamespace := o.Namespaces[r.Namespace]
-		return selector.Matches(labels.Set(routeNamespace.Labels))
-	default:
+// routesMayOverlap reports whether two routes attached to the same Gateway may match requests
+// for the same listener and hostname.
+func routesMayOverlap(a, b httpRouteInfo) bool {
+	if a.parent.SectionName != nil && b.parent.SectionName != nil &&
+		*a.parent.SectionName != *b.parent.SectionName {
 		return false
 	}
-}
-
-func routeHostnames(
-	listenerHostname *gateway_v1.Hostname,
-	routeHostnames []gateway_v1.Hostname,
-) []gateway_v1.Hostname {
-	// If the listener does not specify a hostname, it accepts any hostname.
-	if listenerHostname == nil {
-		// If the route also does not specify a hostname, it matches all hostnames.
-		if len(routeHostnames) == 0 {
-			return []gateway_v1.Hostname{"*"}
-		}
-		return routeHostnames
-	}
-
-	// If the listener specifies a hostname and the route does not, only the listener hostname matches.
-	if len(routeHostnames) == 0 {
-		return []gateway_v1.Hostname{*listenerHostname}
+	if len(a.route.Spec.Hostnames) == 0 || len(b.route.Spec.Hostnames) == 0 {
+		return true
 	}
-
-	// If both the listener and route specify hostnames, compute the intersection.
-	var matching []gateway_v1.Hostname
-	for _, rh := range routeHostnames {
-		if h := hostnameIntersection(string(*listenerHostname), string(rh)); h != "" {
-			matching = append(matching, gateway_v1.Hostname(h))
+	for _, ha := range a.route.Spec.Hostnames {
+		for _, hb := range b.route.Spec.Hostnames {
+			if hostnameIntersection(string(ha), string(hb)) != "" {
+				return true
+			}
 		}
 	}
-	return matching
-}
-
-func hostnameIntersection(a, b string) string {
-	// Simplest case: exact match.
-	if a == b {
-		return a
-	}
-
-	// From the spec:
-	//
-	// "Hostnames that are prefixed with a wildcard label (`*.`) are interpreted as
-	// a suffix match. That means that a match for `*.example.com` would match both
-	//`test.example.com`, and `foo.test.example.com`, but not `example.com`."
-	if strings.HasPrefix(a, "*.") && strings.HasSuffix(b, a[1:]) {
-		return b
-	}
-	if strings.HasPrefix(b, "*.") && strings.HasSuffix(a, b[1:]) {
-		return a
-	}
-
-	return "" // no intersection
+	return false
 }
 
-func setRouteStatusAccepted(
-	r httpRouteInfo,
-	acceptedReason gateway_v1.RouteConditionReason,
-) (modified bool) {
-	acceptedStatus := metav1.ConditionTrue
-	if acceptedReason != gateway_v1.RouteReasonAccepted {
-		acceptedStatus = metav1.ConditionFalse
-	}
-	return upsertCondition(&r.status.Conditions, r.route.Generation, metav1.Condition{
-		Type:   string(gateway_v1.RouteConditionAccepted),
-		Status: acceptedStatus,
-		Reason: string(acceptedReason),
+// anyUnsupportedMatch reports whether any of the unsupported rules of a route have unsupported
+// matches.
+func anyUnsupportedMatch(unsupported []gateway.UnsupportedRule) bool {
+	return slices.ContainsFunc(unsupported, func(u gateway.UnsupportedRule) bool {
+		return u.UnsupportedMatch
 	})
 }
 
-type backendRefSet struct {
-	c set.Collection[refKey]
-}
-
-func (b *backendRefSet) insert(obj client.Object, r *gateway_v1.BackendRef) {
-	if b.c == nil {
-		b.c = set.New[refKey](1)
-	}
-	b.c.Insert(refKeyForBackendRef(obj, &r.BackendObjectReference))
-}
-
-func (b backendRefSet) Valid(obj client.Object, r *gateway_v1.BackendRef) bool {
-	if b.c == nil {
-		return false
-	}
-	return b.c.Contains(refKeyForBackendRef(obj, &r.BackendObjectReference))
-}
-
-// ensureRouteParentStatusExists ensures that the elements of r.Status.Parents correspond to the
-// elements of r.Spec.ParentRef.
-func ensureRouteParentStatusExists(r *gateway_v1.HTTPRoute, controllerName string) {
-	// Check to see if the parent status items already match.
-	if len(r.Status.Parents) == len(r.Spec.ParentRefs) {
-		ok := true
-		for i := range r.Spec.ParentRefs {
-			if !equality.Semantic.DeepEqual(r.Spec.ParentRefs[i], r.Status.Parents[i].ParentRef) {
-				ok = false
-				break
-			}
-		}
-		if ok {
-			return
+// unsupportedRulesMessage returns a status message listing the unsupported rules of a route, and
+// the number of rules that are dropped entirely.
+func unsupportedRulesMessage(unsupported []gateway.UnsupportedRule) (msg string, dropped int) {
+	var messages []string
+	for _, u := range unsupported {
+		if u.Dropped {
+			dropped++
 		}
+		messages = append(messages, fmt.Sprintf("rule %d: %v", u.Index, u.Err))
 	}
-
-	// Allocate new parent status items.
-	r.Status.Parents = make([]gateway_v1.RouteParentStatus, len(r.Spec.ParentRefs))
-	for i := range r.Status.Parents {
-		r.Status.Parents[i].ParentRef = r.Spec.ParentRefs[i]
-		r.Status.Parents[i].ControllerName = gateway_v1.GatewayController(controllerName)
-	}
+	return strings.Join(messages, "; "), dropped
 }
@@ -0,0 +1,218 @@
+package gateway
+
+import (
+	context "context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/controllers/certificate"
+)
+
+// SecretTypeIndex indexes Secrets by type. The client of the Gateway controller must provide it,
+// as TLS Secrets are listed using this index.
+const SecretTypeIndex = "type"
+
+// SecretType returns the type of a Secret, for SecretTypeIndex.
+func SecretType(obj client.Object) []string {
+	return []string{string(obj.(*corev1.Secret).Type)}
+}
+
+// Field indexes used to determine whether a changed object is referenced by any Gateway API
+// object, so that changes to unrelated objects do not trigger a reconcile.
+const (
+	// backendServiceIndex indexes routes by the "namespace/name" of each backend Service.
+	backendServiceIndex = "backendService"
+	// certificateSecretIndex indexes Gateways by the "namespace/name" of each certificate Secret.
+	certificateSecretIndex = "certificateSecret"
+	// namespaceSelectorIndex indexes Gateways with any listener selecting routes by Namespace labels.
+	namespaceSelectorIndex = "namespaceSelector"
+	// caConfigMapIndex indexes BackendTLSPolicies by the "namespace/name" of each CA ConfigMap.
+	caConfigMapIndex = "caConfigMap"
+)
+
+// routeIndexObjects returns the (empty) route objects and lists of all installed route kinds.
+func (c *gatewayController) routeIndexObjects() ([]client.Object, []client.ObjectList) {
+	objs := []client.Object{&gateway_v1.HTTPRoute{}, &gateway_v1.GRPCRoute{}}
+	lists := []client.ObjectList{&gateway_v1.HTTPRouteList{}, &gateway_v1.GRPCRouteList{}}
+	if c.tcpRoutesInstalled {
+		objs = append(objs, &gateway_v1.TCPRoute{})
+		lists = append(lists, &gateway_v1.TCPRouteList{})
+	}
+	if c.udpRoutesInstalled {
+		objs = append(objs, &gateway_v1.UDPRoute{})
+		lists = append(lists, &gateway_v1.UDPRouteList{})
+	}
+	return objs, lists
+}
+
+// createIndexes registers the field indexes used by the watch map functions.
+func (c *gatewayController) createIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	routeObjs, _ := c.routeIndexObjects()
+	for _, obj := range routeObjs {
+		if err := indexer.IndexField(ctx, obj, backendServiceIndex, backendServiceNames); err != nil {
+			return fmt.Errorf("couldn't create index on %T backend Services: %w", obj, err)
+		}
+	}
+	err := indexer.IndexField(ctx, &gateway_v1.Gateway{}, certificateSecretIndex, certificateSecretNames)
+	if err != nil {
+		return fmt.Errorf("couldn't create index on Gateway certificate Secrets: %w", err)
+	}
+	err = indexer.IndexField(ctx, &gateway_v1.Gateway{}, namespaceSelectorIndex, usesNamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("couldn't create index on Gateway namespace selectors: %w", err)
+	}
+	if c.backendTLSPoliciesInstalled {
+		err = indexer.IndexField(ctx, &gateway_v1.BackendTLSPolicy{}, caConfigMapIndex, caConfigMapNames)
+		if err != nil {
+			return fmt.Errorf("couldn't create index on BackendTLSPolicy CA ConfigMaps: %w", err)
+		}
+	}
+	return nil
+}
+
+// routeBackendRefs returns all backendRefs of a route of any supported kind.
+func routeBackendRefs(obj client.Object) []*gateway_v1.BackendObjectReference {
+	var refs []*gateway_v1.BackendObjectReference
+	switch r := obj.(type) {
+	case *gateway_v1.HTTPRoute:
+		for i := range r.Spec.Rules {
+			for j := range r.Spec.Rules[i].BackendRefs {
+				refs = append(refs, &r.Spec.Rules[i].BackendRefs[j].BackendObjectReference)
+			}
+		}
+	case *gateway_v1.GRPCRoute:
+		for i := range r.Spec.Rules {
+			for j := range r.Spec.Rules[i].BackendRefs {
+				refs = append(refs, &r.Spec.Rules[i].BackendRefs[j].BackendObjectReference)
+			}
+		}
+	case *gateway_v1.TCPRoute:
+		for i := range r.Spec.Rules {
+			for j := range r.Spec.Rules[i].BackendRefs {
+				refs = append(refs, &r.Spec.Rules[i].BackendRefs[j].BackendObjectReference)
+			}
+		}
+	case *gateway_v1.UDPRoute:
+		for i := range r.Spec.Rules {
+			for j := range r.Spec.Rules[i].BackendRefs {
+				refs = append(refs, &r.Spec.Rules[i].BackendRefs[j].BackendObjectReference)
+			}
+		}
+	}
+	return refs
+}
+
+func backendServiceNames(obj client.Object) []string {
+	var names []string
+	for _, ref := range routeBackendRefs(obj) {
+		k := refKeyForBackendRef(obj, ref)
+		if k.Group == corev1.GroupName && k.Kind == "Service" {
+			names = append(names, k.Namespace+"/"+k.Name)
+		}
+	}
+	return names
+}
+
+func certificateSecretNames(obj client.Object) []string {
+	g := obj.(*gateway_v1.Gateway)
+	var names []string
+	for i := range g.Spec.Listeners {
+		tls := g.Spec.Listeners[i].TLS
+		if tls == nil {
+			continue
+		}
+		for j := range tls.CertificateRefs {
+			k := refKeyForCertificateRef(g, &tls.CertificateRefs[j])
+			if k.Group == corev1.GroupName && k.Kind == "Secret" {
+				names = append(names, k.Namespace+"/"+k.Name)
+			}
+		}
+	}
+	return names
+}
+
+func usesNamespaceSelector(obj client.Object) []string {
+	g := obj.(*gateway_v1.Gateway)
+	for i := range g.Spec.Listeners {
+		ar := g.Spec.Listeners[i].AllowedRoutes
+		if ar != nil && ar.Namespaces != nil && ar.Namespaces.From != nil &&
+			*ar.Namespaces.From == gateway_v1.NamespacesFromSelector {
+			return []string{"true"}
+		}
+	}
+	return nil
+}
+
+func caConfigMapNames(obj client.Object) []string {
+	p := obj.(*gateway_v1.BackendTLSPolicy)
+	var names []string
+	for _, ref := range p.Spec.Validation.CACertificateRefs {
+		if ref.Group == corev1.GroupName && ref.Kind == "ConfigMap" {
+			names = append(names, p.Namespace+"/"+string(ref.Name))
+		}
+	}
+	return names
+}
+
+// anyIndexed reports whether any object of the given list types matches value in the named index.
+func (c *gatewayController) anyIndexed(
+	ctx context.Context, index, value string, lists ...client.ObjectList,
+) bool {
+	for _, l := range lists {
+		if err := c.List(ctx, l, client.MatchingFields{index: value}, client.Limit(1)); err != nil {
+			// Err on the side of reconciling.
+			log.FromContext(ctx).Error(err, "couldn't list indexed objects", "index", index)
+			return true
+		}
+		if meta.LenList(l) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceReferenced reports whether a Service is the proxy Service or a route backend.
+func (c *gatewayController) serviceReferenced(ctx context.Context, obj client.Object) bool {
+	if obj.GetNamespace() == c.ServiceName.Namespace && obj.GetName() == c.ServiceName.Name {
+		return true
+	}
+	_, lists := c.routeIndexObjects()
+	return c.anyIndexed(ctx, backendServiceIndex, obj.GetNamespace()+"/"+obj.GetName(), lists...)
+}
+
+// endpointSliceReferenced reports whether an EndpointSlice belongs to a route backend Service.
+func (c *gatewayController) endpointSliceReferenced(ctx context.Context, obj client.Object) bool {
+	serviceName := obj.GetLabels()[discoveryv1.LabelServiceName]
+	if serviceName == "" {
+		return false
+	}
+	_, lists := c.routeIndexObjects()
+	return c.anyIndexed(ctx, backendServiceIndex, obj.GetNamespace()+"/"+serviceName, lists...)
+}
+
+// secretReferenced reports whether a Secret is referenced as a Gateway listener certificate, or
+// holds a certificate provisioned for a Gateway listener.
+func (c *gatewayController) secretReferenced(ctx context.Context, obj client.Object) bool {
+	if obj.GetLabels()[certificate.RouteCertificateLabel] == "Gateway" {
+		return true
+	}
+	return c.anyIndexed(ctx, certificateSecretIndex, obj.GetNamespace()+"/"+obj.GetName(),
+		&gateway_v1.GatewayList{})
+}
+
+// namespaceReferenced reports whether Namespace labels may affect which routes are allowed.
+func (c *gatewayController) namespaceReferenced(ctx context.Context, _ client.Object) bool {
+	return c.anyIndexed(ctx, namespaceSelectorIndex, "true", &gateway_v1.GatewayList{})
+}
+
+// configMapReferenced reports whether a ConfigMap is referenced as a BackendTLSPolicy CA certificate.
+func (c *gatewayController) configMapReferenced(ctx context.Context, obj client.Object) bool {
+	return c.anyIndexed(ctx, caConfigMapIndex, obj.GetNamespace()+"/"+obj.GetName(),
+		&gateway_v1.BackendTLSPolicyList{})
+}
@@ -3,6 +3,7 @@ package gateway
 import (
 	"crypto/tls"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/hashicorp/go-set/v3"
@@ -20,6 +21,12 @@ type listenerAndStatus struct {
 	generation int64
 }
 
+// listenerKey identifies a single listener of a single Gateway.
+type listenerKey struct {
+	gateway refKey
+	name    gateway_v1.SectionName
+}
+
 // processListener adds routes and certificates associated with a single Listener to the
 // GatewayConfig and updates the ListenerStatus.
 func processListener(
@@ -27,6 +34,7 @@ func processListener(
 	o *objects,
 	gatewayKey refKey,
 	l listenerAndStatus,
+	conflicts map[listenerKey]metav1.Condition,
 ) {
 	l.status.Name = l.listener.Name
 
@@ -52,27 +60,128 @@ func processListener(
 		},
 	)
 	setListenerStatusSupportedKinds(l)
-	processCertificateRefs(config, o, g, l)
+
+	// The certificates of a conflicted listener must not be used.
+	if conflicted, ok := conflicts[listenerKey{gatewayKey, l.listener.Name}]; ok {
+		setListenerStatusConflicted(l, conflicted)
+		return
+	}
+	upsertCondition(&l.status.Conditions, l.generation, metav1.Condition{
+		Type:   string(gateway_v1.ListenerConditionConflicted),
+		Status: metav1.ConditionFalse,
+		Reason: string(gateway_v1.ListenerReasonNoConflicts),
+	})
+	processCertificateRefs(config, o, gatewayKey, l)
+}
+
+// setListenerStatusConflicted sets the "Conflicted" condition of a conflicted listener, and marks
+// it as not programmed.
+func setListenerStatusConflicted(l listenerAndStatus, conflicted metav1.Condition) {
+	upsertConditions(&l.status.Conditions, l.generation,
+		conflicted,
+		metav1.Condition{
+			Type:    string(gateway_v1.ListenerConditionProgrammed),
+			Status:  metav1.ConditionFalse,
+			Reason:  string(gateway_v1.ListenerReasonInvalid),
+			Message: conflicted.Message,
+		},
+	)
+}
+
+// findListenerConflicts returns a "Conflicted" condition for each listener that cannot be
+// configured alongside another listener of the same Gateway. Conflicts are resolved in favor of
+// the listener appearing first in the Gateway spec.
+func findListenerConflicts(o *objects) map[listenerKey]metav1.Condition {
+	conflicts := make(map[listenerKey]metav1.Condition)
+	for key, g := range o.Gateways {
+		acceptedByPort := make(map[gateway_v1.PortNumber][]*gateway_v1.Listener)
+	listeners:
+		for i := range g.Spec.Listeners {
+			l := &g.Spec.Listeners[i]
+			for _, a := range acceptedByPort[l.Port] {
+				if !listenerProtocolsCompatible(a.Protocol, l.Protocol) {
+					conflicts[listenerKey{key, l.Name}] = metav1.Condition{
+						Type:    string(gateway_v1.ListenerConditionConflicted),
+						Status:  metav1.ConditionTrue,
+						Reason:  string(gateway_v1.ListenerReasonProtocolConflict),
+						Message: fmt.Sprintf("conflicts with listener %q", a.Name),
+					}
+					continue listeners
+				}
+			}
+			acceptedByPort[l.Port] = append(acceptedByPort[l.Port], l)
+		}
+	}
+	return conflicts
+}
+
+// listenerProtocolsCompatible reports whether listeners with the given protocols may share a port.
+// Pomerium serves plain HTTP and TLS on separate ports, so HTTP and HTTPS cannot share a port.
+// TCP and UDP ports are distinct, so a TCP and a UDP listener may share a port number.
+func listenerProtocolsCompatible(a, b gateway_v1.ProtocolType) bool {
+	if a == b {
+		return true
+	}
+	isTunnel := func(p gateway_v1.ProtocolType) bool {
+		return p == gateway_v1.TCPProtocolType || p == gateway_v1.UDPProtocolType
+	}
+	return isTunnel(a) && isTunnel(b)
+}
+
+// supportedRouteKinds returns the route kinds that can be attached to a Listener with the given
+// protocol. TCPRoutes and UDPRoutes are exposed as Pomerium TCP and UDP tunnels. Other protocols
+// (such as TLS passthrough) are not supported.
+func supportedRouteKinds(protocol gateway_v1.ProtocolType) []string {
+	switch protocol {
+	case gateway_v1.HTTPProtocolType, gateway_v1.HTTPSProtocolType:
+		return []string{"HTTPRoute", "GRPCRoute"}
+	case gateway_v1.TCPProtocolType:
+		return []string{"TCPRoute"}
+	case gateway_v1.UDPProtocolType:
+		return []string{"UDPRoute"}
+	default:
+		return nil
+	}
 }
 
 // setListenerStatusSupportedKinds sets the status SupportedKinds and updates the conditions if any
-// allowedRoutes kinds are unsupported. (Currently the only supported kind is HTTPRoute.)
+// allowedRoutes kinds are unsupported.
 func setListenerStatusSupportedKinds(l listenerAndStatus) {
+	supportedKinds := supportedRouteKinds(l.listener.Protocol)
+	if len(supportedKinds) == 0 {
+		l.status.SupportedKinds = []gateway_v1.RouteGroupKind{}
+		upsertConditions(&l.status.Conditions, l.generation,
+			metav1.Condition{
+				Type:    string(gateway_v1.ListenerConditionAccepted),
+				Status:  metav1.ConditionFalse,
+				Reason:  string(gateway_v1.ListenerReasonUnsupportedProtocol),
+				Message: fmt.Sprintf("unsupported protocol %q", l.listener.Protocol),
+			},
+			metav1.Condition{
+				Type:   string(gateway_v1.ListenerConditionProgrammed),
+				Status: metav1.ConditionFalse,
+				Reason: string(gateway_v1.ListenerReasonInvalid),
+			},
+		)
+		return
+	}
+
 	// If allowedRoutes is unset, there is no restriction on allowed route kinds.
 	allowed := l.listener.AllowedRoutes
 	if allowed == nil || len(allowed.Kinds) == 0 {
-		l.status.SupportedKinds = []gateway_v1.RouteGroupKind{{Kind: "HTTPRoute"}}
+		l.status.SupportedKinds = make([]gateway_v1.RouteGroupKind, len(supportedKinds))
+		for i, kind := range supportedKinds {
+			l.status.SupportedKinds[i] = gateway_v1.RouteGroupKind{Kind: gateway_v1.Kind(kind)}
+		}
 		return
 	}
 
 	supported := make([]gateway_v1.RouteGroupKind, 0)
 	unsupportedKinds := set.New[string](0)
 	for _, k := range allowed.Kinds {
-		if groupKindFromRouteGroupKind(&k) == (schema.GroupKind{
-			Group: gateway_v1.GroupName,
-			Kind:  "HTTPRoute",
-		}) {
-			supported = []gateway_v1.RouteGroupKind{k}
+		gk := groupKindFromRouteGroupKind(&k)
+		if gk.Group == gateway_v1.GroupName && slices.Contains(supportedKinds, gk.Kind) {
+			supported = append(supported, k)
 		} else {
 			unsupportedKinds.Insert(string(k.Kind))
 		}
@@ -85,8 +194,8 @@ func setListenerStatusSupportedKinds(l listenerAndStatus) {
 				Type:   string(gateway_v1.ListenerConditionResolvedRefs),
 				Status: metav1.ConditionFalse,
 				Reason: string(gateway_v1.ListenerReasonInvalidRouteKinds),
-				Message: fmt.Sprintf("unsupported route kinds: %s (only HTTPRoute is supported)",
-					strings.Join(unsupportedKinds.Slice(), ", ")),
+				Message: fmt.Sprintf("unsupported route kinds: %s (supported kinds: %s)",
+					strings.Join(unsupportedKinds.Slice(), ", "), strings.Join(supportedKinds, ", ")),
 			},
 			metav1.Condition{
 				Type:   string(gateway_v1.ListenerConditionProgrammed),
@@ -102,12 +211,28 @@ func setListenerStatusSupportedKinds(l listenerAndStatus) {
 func processCertificateRefs(
 	config *model.GatewayConfig,
 	o *objects,
-	g *gateway_v1.Gateway,
+	gatewayKey refKey,
 	l listenerAndStatus,
 ) {
 	if l.listener.TLS == nil {
 		return
 	}
+	g := o.Gateways[gatewayKey]
+
+	// A listener without certificateRefs may use a provisioned certificate, once it is issued.
+	if k, ok := o.ProvisionedCertificates[listenerKey{gatewayKey, l.listener.Name}]; ok {
+		if secret, ok := o.TLSSecrets[k]; ok && validateTLSSecret(secret) == nil {
+			config.Certificates = append(config.Certificates, secret)
+			return
+		}
+		upsertCondition(&l.status.Conditions, l.generation, metav1.Condition{
+			Type:    string(gateway_v1.ListenerConditionProgrammed),
+			Status:  metav1.ConditionFalse,
+			Reason:  string(gateway_v1.ListenerReasonPending),
+			Message: "waiting for certificate " + k.Name + " to be issued",
+		})
+		return
+	}
 
 	var hasValidRef bool
 	invalidRefs := make(map[gateway_v1.ListenerConditionReason][]string)
@@ -0,0 +1,42 @@
+package gateway
+
+import (
+	context "context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+// FetchGatewayConfig returns the Gateway-defined configuration that the Gateway controller would
+// reconcile. Optional Gateway API kinds that are not served by c are skipped. Certificates are
+// not provisioned, so HTTPS listeners without certificateRefs are left without a certificate.
+//
+// The status of the Gateway API objects is updated through c as on reconcile, so c would
+// usually be an in-memory or dry-run client. It must provide SecretTypeIndex.
+func FetchGatewayConfig(ctx context.Context, c client.Client, config ControllerConfig) (*model.GatewayConfig, error) {
+	gtc := &gatewayController{
+		Client:           c,
+		ControllerConfig: config,
+		extensionFilters: make(map[refKey]objectAndFilter),
+	}
+
+	var err error
+	if gtc.tcpRoutesInstalled, err = util.IsKindInstalled(c, &gateway_v1.TCPRoute{}); err != nil {
+		return nil, err
+	}
+	if gtc.udpRoutesInstalled, err = util.IsKindInstalled(c, &gateway_v1.UDPRoute{}); err != nil {
+		return nil, err
+	}
+	if gtc.backendTLSPoliciesInstalled, err = util.IsKindInstalled(c, &gateway_v1.BackendTLSPolicy{}); err != nil {
+		return nil, err
+	}
+
+	o, err := gtc.fetchObjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return gtc.processGateways(ctx, o)
+}
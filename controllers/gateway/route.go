@@ -0,0 +1,437 @@
+package gateway
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-set/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
+)
+
+// routeParent holds the parts of a route (of any kind) needed to attach it to a single parent
+// Gateway.
+type routeParent struct {
+	route client.Object
+	kind  string
+
+	// Hostnames from the route spec.
+	hostnames []gateway_v1.Hostname
+
+	// exactHostname requires the route to resolve to non-wildcard hostnames only. This is the
+	// case for routes exposed as Pomerium tunnels, where the hostname is part of the route "From".
+	exactHostname bool
+
+	parent *gateway_v1.ParentReference
+	status *gateway_v1.RouteParentStatus
+}
+
+// listenerAttachment describes a route attached to a single listener.
+type listenerAttachment struct {
+	listener *gateway_v1.Listener
+
+	// Resolved hostnames, with "all" represented as "*".
+	hostnames []gateway_v1.Hostname
+}
+
+// attachRoute checks for route attachment with the Gateway listeners, updates the "Accepted"
+// status condition accordingly, and returns the listeners the route is attached to.
+func attachRoute(
+	o *objects,
+	g *gateway_v1.Gateway,
+	listeners map[string]listenerAndStatus,
+	r routeParent,
+) []listenerAttachment {
+	// A route may specify a listener name directly. In this case we should check for route
+	// attachment with just the one listener.
+	if r.parent.SectionName != nil {
+		l, ok := listeners[string(*r.parent.SectionName)]
+		if !ok {
+			setRouteStatusAccepted(r, gateway_v1.RouteReasonNoMatchingParent)
+			return nil
+		}
+		ra := processRouteForListener(o, g, l, r)
+		setRouteStatusAccepted(r, ra.reason)
+		if ra.reason != gateway_v1.RouteReasonAccepted {
+			return nil
+		}
+		return []listenerAttachment{{l.listener, ra.hostnames}}
+	}
+
+	// Otherwise check for route attachment with all listeners.
+	var attachments []listenerAttachment
+	var reason gateway_v1.RouteConditionReason
+	for _, l := range listeners {
+		ra := processRouteForListener(o, g, l, r)
+		if ra.reason == gateway_v1.RouteReasonAccepted {
+			attachments = append(attachments, listenerAttachment{l.listener, ra.hostnames})
+		}
+		// If the route attaches to any listener, we consider the route accepted.
+		// Otherwise we'll return the reason associated with the first listener.
+		if reason == "" || ra.reason == gateway_v1.RouteReasonAccepted {
+			reason = ra.reason
+		}
+	}
+	if reason == "" {
+		reason = gateway_v1.RouteReasonNoMatchingParent // no listeners at all
+	}
+	setRouteStatusAccepted(r, reason)
+	return attachments
+}
+
+// attachedAddresses returns the distinct listener addresses of all listener attachments, in a
+// stable order.
+func attachedAddresses(attachments []listenerAttachment) []model.GatewayListenerAddress {
+	addressSet := set.New[model.GatewayListenerAddress](0)
+	for _, a := range attachments {
+		for _, h := range a.hostnames {
+			addressSet.Insert(model.GatewayListenerAddress{
+				Protocol: a.listener.Protocol,
+				Hostname: h,
+				Port:     a.listener.Port,
+			})
+		}
+	}
+	addresses := addressSet.Slice()
+	slices.SortFunc(addresses, func(a, b model.GatewayListenerAddress) int {
+		return cmp.Or(
+			cmp.Compare(a.Hostname, b.Hostname),
+			cmp.Compare(a.Port, b.Port),
+			cmp.Compare(a.Protocol, b.Protocol),
+		)
+	})
+	return addresses
+}
+
+type routeAttachment struct {
+	// Resolved hostnames, with "all" represented as "*".
+	hostnames []gateway_v1.Hostname
+
+	// "Accepted" condition status reason.
+	reason gateway_v1.RouteConditionReason
+}
+
+func processRouteForListener(
+	o *objects,
+	g *gateway_v1.Gateway,
+	l listenerAndStatus,
+	r routeParent,
+) routeAttachment {
+	if !isRouteKindSupported(l, r.kind) ||
+		!isRouteAllowed(o, l.listener.AllowedRoutes, r.route, g.Namespace) {
+		return routeAttachment{reason: gateway_v1.RouteReasonNotAllowedByListeners}
+	}
+
+	hostnames := routeHostnames(l.listener.Hostname, r.hostnames)
+	if r.exactHostname {
+		hostnames = slices.DeleteFunc(slices.Clone(hostnames), func(h gateway_v1.Hostname) bool {
+			return strings.HasPrefix(string(h), "*")
+		})
+	}
+	if len(hostnames) == 0 {
+		return routeAttachment{reason: gateway_v1.RouteReasonNoMatchingListenerHostname}
+	}
+
+	l.status.AttachedRoutes++
+
+	return routeAttachment{
+		hostnames: hostnames,
+		reason:    gateway_v1.RouteReasonAccepted,
+	}
+}
+
+// isRouteKindSupported checks the route kind against the listener SupportedKinds status, as
+// computed by setListenerStatusSupportedKinds().
+func isRouteKindSupported(l listenerAndStatus, kind string) bool {
+	want := schema.GroupKind{Group: gateway_v1.GroupName, Kind: kind}
+	return slices.ContainsFunc(l.status.SupportedKinds, func(k gateway_v1.RouteGroupKind) bool {
+		return groupKindFromRouteGroupKind(&k) == want
+	})
+}
+
+func isRouteAllowed(
+	o *objects,
+	allowed *gateway_v1.AllowedRoutes,
+	r client.Object,
+	gatewayNamespace string,
+) bool {
+	// The route kind is checked separately, so we need only check that the route namespace is
+	// allowed.
+	from := gateway_v1.NamespacesFromSame
+	if allowed.Namespaces != nil && allowed.Namespaces.From != nil {
+		from = *allowed.Namespaces.From
+	}
+	switch from {
+	case gateway_v1.NamespacesFromAll:
+		return true
+	case gateway_v1.NamespacesFromSame:
+		return r.GetNamespace() == gatewayNamespace
+	case gateway_v1.NamespacesFromSelector:
+		selector, err := metav1.LabelSelectorAsSelector(allowed.Namespaces.Selector)
+		if err != nil {
+			return false
+		}
+		routeNamespace := o.Namespaces[r.GetNamespace()]
+		return selector.Matches(labels.Set(routeNamespace.Labels))
+	default:
+		return false
+	}
+}
+
+func routeHostnames(
+	listenerHostname *gateway_v1.Hostname,
+	routeHostnames []gateway_v1.Hostname,
+) []gateway_v1.Hostname {
+	// If the listener does not specify a hostname, it accepts any hostname.
+	if listenerHostname == nil {
+		// If the route also does not specify a hostname, it matches all hostnames.
+		if len(routeHostnames) == 0 {
+			return []gateway_v1.Hostname{"*"}
+		}
+		return routeHostnames
+	}
+
+	// If the listener specifies a hostname and the route does not, only the listener hostname matches.
+	if len(routeHostnames) == 0 {
+		return []gateway_v1.Hostname{*listenerHostname}
+	}
+
+	// If both the listener and route specify hostnames, compute the intersection.
+	var matching []gateway_v1.Hostname
+	for _, rh := range routeHostnames {
+		if h := hostnameIntersection(string(*listenerHostname), string(rh)); h != "" {
+			matching = append(matching, gateway_v1.Hostname(h))
+		}
+	}
+	return matching
+}
+
+func hostnameIntersection(a, b string) string {
+	// Simplest case: exact match.
+	if a == b {
+		return a
+	}
+
+	// From the spec:
+	//
+	// "Hostnames that are prefixed with a wildcard label (`*.`) are interpreted as
+	// a suffix match. That means that a match for `*.example.com` would match both
+	//`test.example.com`, and `foo.test.example.com`, but not `example.com`."
+	if strings.HasPrefix(a, "*.") && strings.HasSuffix(b, a[1:]) {
+		return b
+	}
+	if strings.HasPrefix(b, "*.") && strings.HasSuffix(a, b[1:]) {
+		return a
+	}
+
+	return "" // no intersection
+}
+
+func setRouteStatusAccepted(
+	r routeParent,
+	acceptedReason gateway_v1.RouteConditionReason,
+) (modified bool) {
+	acceptedStatus := metav1.ConditionTrue
+	if acceptedReason != gateway_v1.RouteReasonAccepted {
+		acceptedStatus = metav1.ConditionFalse
+	}
+	return upsertCondition(&r.status.Conditions, r.route.GetGeneration(), metav1.Condition{
+		Type:   string(gateway_v1.RouteConditionAccepted),
+		Status: acceptedStatus,
+		Reason: string(acceptedReason),
+	})
+}
+
+// validateBackendRefsResolved checks that all backendRefs of a route can be resolved, sets the
+// "ResolvedRefs" status condition accordingly, and returns the set of valid backendRefs.
+func validateBackendRefsResolved(
+	o *objects,
+	route client.Object,
+	status *gateway_v1.RouteParentStatus,
+	backendRefs []*gateway_v1.BackendRef,
+) (validRefs backendRefSet) {
+	invalidRefs := make(map[gateway_v1.RouteConditionReason][]string)
+	invalid := func(reason gateway_v1.RouteConditionReason, name string) {
+		invalidRefs[reason] = append(invalidRefs[reason], name)
+	}
+	for _, br := range backendRefs {
+		refKey := refKeyForBackendRef(route, &br.BackendObjectReference)
+		if refKey.Group != corev1.GroupName || refKey.Kind != "Service" {
+			invalid(gateway_v1.RouteReasonInvalidKind, refKey.Name)
+			continue
+		}
+		if !o.ReferenceGrants.allowed(route, refKey) {
+			invalid(gateway_v1.RouteReasonRefNotPermitted, refKey.Name)
+			continue
+		}
+		serviceName := types.NamespacedName{Namespace: refKey.Namespace, Name: refKey.Name}
+		svc := o.Services[serviceName]
+		if svc == nil {
+			invalid(gateway_v1.RouteReasonBackendNotFound, refKey.Name)
+			continue
+		}
+		if br.Port == nil {
+			invalid(gateway_v1.RouteReasonUnsupportedValue, refKey.Name+" (port is required)")
+			continue
+		}
+		_, _, err := gateway.ResolveServicePort(svc, o.EndpointSlices[serviceName], *br.Port)
+		if err != nil {
+			invalid(gateway_v1.RouteReasonBackendNotFound, fmt.Sprintf("%s (%v)", refKey.Name, err))
+			continue
+		}
+		validRefs.insert(route, br)
+	}
+
+	resolvedRefs := metav1.Condition{
+		Type:   string(gateway_v1.RouteConditionResolvedRefs),
+		Status: metav1.ConditionTrue,
+		Reason: string(gateway_v1.RouteReasonResolvedRefs),
+	}
+
+	var messages []string
+	for reason, refNames := range invalidRefs {
+		resolvedRefs.Status = metav1.ConditionFalse
+		resolvedRefs.Reason = string(reason) // if multiple reasons apply this will set one arbitrarily
+		messages = append(messages, "invalid refs ("+string(reason)+"): "+strings.Join(refNames, ", "))
+	}
+	resolvedRefs.Message = strings.Join(messages, "; ")
+
+	upsertCondition(&status.Conditions, route.GetGeneration(), resolvedRefs)
+
+	return validRefs
+}
+
+type backendRefSet struct {
+	c set.Collection[backendRefKey]
+}
+
+// backendRefKey identifies a single port of a backend object.
+type backendRefKey struct {
+	refKey
+	port gateway_v1.PortNumber
+}
+
+func newBackendRefKey(obj client.Object, r *gateway_v1.BackendRef) backendRefKey {
+	k := backendRefKey{refKey: refKeyForBackendRef(obj, &r.BackendObjectReference)}
+	if r.Port != nil {
+		k.port = *r.Port
+	}
+	return k
+}
+
+func (b *backendRefSet) insert(obj client.Object, r *gateway_v1.BackendRef) {
+	if b.c == nil {
+		b.c = set.New[backendRefKey](1)
+	}
+	b.c.Insert(newBackendRefKey(obj, r))
+}
+
+func (b backendRefSet) Valid(obj client.Object, r *gateway_v1.BackendRef) bool {
+	if b.c == nil {
+		return false
+	}
+	return b.c.Contains(newBackendRefKey(obj, r))
+}
+
+// containsObject reports whether any port of the given backend object is in the set.
+func (b backendRefSet) containsObject(k refKey) bool {
+	if b.c == nil {
+		return false
+	}
+	for brk := range b.c.Items() {
+		if brk.refKey == k {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureRouteParentStatusExists ensures that the elements of status.Parents correspond to the
+// elements of spec.ParentRefs.
+func ensureRouteParentStatusExists(
+	spec *gateway_v1.CommonRouteSpec,
+	status *gateway_v1.RouteStatus,
+	controllerName string,
+) {
+	// Check to see if the parent status items already match.
+	if len(status.Parents) == len(spec.ParentRefs) {
+		ok := true
+		for i := range spec.ParentRefs {
+			if !equality.Semantic.DeepEqual(spec.ParentRefs[i], status.Parents[i].ParentRef) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return
+		}
+	}
+
+	// Allocate new parent status items.
+	status.Parents = make([]gateway_v1.RouteParentStatus, len(spec.ParentRefs))
+	for i := range status.Parents {
+		status.Parents[i].ParentRef = spec.ParentRefs[i]
+		status.Parents[i].ControllerName = gateway_v1.GatewayController(controllerName)
+	}
+}
+
+// setRouteStatusUnsupported sets the "Accepted" status condition to indicate that the route uses
+// some feature that cannot be represented as Pomerium configuration.
+func setRouteStatusUnsupported(r routeParent, message string) (modified bool) {
+	return upsertCondition(&r.status.Conditions, r.route.GetGeneration(), metav1.Condition{
+		Type:    string(gateway_v1.RouteConditionAccepted),
+		Status:  metav1.ConditionFalse,
+		Reason:  string(gateway_v1.RouteReasonUnsupportedValue),
+		Message: message,
+	})
+}
+
+// setRouteStatusDropped sets the "PartiallyInvalid" status condition of an accepted route from
+// its unsupported rules (see unsupportedRulesMessage) and from any of the Pomerium routes
+// translated from it that fail validation (see gateway.InvalidRoutesError), or removes it if
+// there are neither. It reports whether any of the translated routes are valid.
+func setRouteStatusDropped(r routeParent, unsupportedRules string, invalidRoutes error) (anyValid bool) {
+	var messages []string
+	if unsupportedRules != "" {
+		messages = append(messages, unsupportedRules)
+	}
+	anyValid = true
+	if invalidRoutes != nil {
+		var ire *gateway.InvalidRoutesError
+		if errors.As(invalidRoutes, &ire) && ire.Valid == 0 {
+			anyValid = false
+			messages = append(messages, "no translated route is valid: "+invalidRoutes.Error())
+		} else {
+			messages = append(messages, "invalid translated routes: "+invalidRoutes.Error())
+		}
+	}
+	setRouteStatusPartiallyInvalid(r, strings.Join(messages, "; "))
+	return anyValid
+}
+
+// setRouteStatusPartiallyInvalid sets the "PartiallyInvalid" status condition if message is
+// non-empty, or removes it otherwise.
+func setRouteStatusPartiallyInvalid(r routeParent, message string) (modified bool) {
+	if message == "" {
+		return removeCondition(&r.status.Conditions, string(gateway_v1.RouteConditionPartiallyInvalid))
+	}
+	return upsertCondition(&r.status.Conditions, r.route.GetGeneration(), metav1.Condition{
+		Type:   string(gateway_v1.RouteConditionPartiallyInvalid),
+		Status: metav1.ConditionTrue,
+		Reason: string(gateway_v1.RouteReasonUnsupportedValue),
+		// From the spec: the message "MUST start with the prefix 'Dropped Rule'".
+		Message: "Dropped Rule(s), matches or routes: " + message,
+	})
+}
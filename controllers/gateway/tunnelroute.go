@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
+)
+
+// tunnelRouteInfo holds a TCPRoute or UDPRoute together with a single parent reference. These
+// routes are exposed as Pomerium TCP or UDP tunnels.
+type tunnelRouteInfo struct {
+	route client.Object
+	kind  string
+
+	// BackendRefs of all route rules.
+	backendRefs []*gateway_v1.BackendRef
+
+	parent *gateway_v1.ParentReference
+	status *gateway_v1.RouteParentStatus
+}
+
+type tunnelRouteResult struct {
+	Addresses        []model.GatewayListenerAddress
+	ValidBackendRefs backendRefSet
+}
+
+// processTunnelRoute checks the validity of a TCPRoute or UDPRoute, updates its status
+// accordingly, and computes the listener addresses it should be exposed on.
+func processTunnelRoute(
+	o *objects,
+	g *gateway_v1.Gateway,
+	listeners map[string]listenerAndStatus,
+	r tunnelRouteInfo,
+) tunnelRouteResult {
+	var result tunnelRouteResult
+
+	result.ValidBackendRefs = validateBackendRefsResolved(o, r.route, r.status, r.backendRefs)
+
+	// A Pomerium tunnel route is identified by hostname and port, so the listener must specify
+	// a hostname (this is implementation-specific, as the spec ignores the hostname of TCP and
+	// UDP listeners).
+	result.Addresses = attachedAddresses(attachRoute(o, g, listeners, routeParent{
+		route:         r.route,
+		kind:          r.kind,
+		exactHostname: true,
+		parent:        r.parent,
+		status:        r.status,
+	}))
+	return result
+}
+
+// validateTCPRouteConfig reports any of the Pomerium routes translated from a TCPRoute that fail
+// validation in the route status (see setRouteStatusDropped). It reports whether any of the
+// translated routes are valid.
+func validateTCPRouteConfig(rc *model.GatewayTCPRouteConfig, r tunnelRouteInfo) bool {
+	return setRouteStatusDropped(routeParent{route: r.route, status: r.status}, "", gateway.ValidateTCPRoutes(rc))
+}
+
+// validateUDPRouteConfig is the UDPRoute equivalent of validateTCPRouteConfig.
+func validateUDPRouteConfig(rc *model.GatewayUDPRouteConfig, r tunnelRouteInfo) bool {
+	return setRouteStatusDropped(routeParent{route: r.route, status: r.status}, "", gateway.ValidateUDPRoutes(rc))
+}
+
+// addTunnelRoute records a TCPRoute or UDPRoute for each parent Gateway it references.
+func (o *objects) addTunnelRoute(
+	route client.Object,
+	kind string,
+	spec *gateway_v1.CommonRouteSpec,
+	status *gateway_v1.RouteStatus,
+	backendRefs []*gateway_v1.BackendRef,
+	controllerName string,
+) {
+	o.OriginalRouteStatus = append(o.OriginalRouteStatus, routeAndOriginalStatus{
+		route:          route,
+		status:         status,
+		originalStatus: status.DeepCopy(),
+	})
+	ensureRouteParentStatusExists(spec, status, controllerName)
+	for j := range spec.ParentRefs {
+		pr := &spec.ParentRefs[j]
+		key := refKeyForParentRef(route, pr)
+		if _, ok := o.Gateways[key]; ok {
+			o.TunnelRoutesByGateway[key] = append(o.TunnelRoutesByGateway[key],
+				tunnelRouteInfo{route, kind, backendRefs, pr, &status.Parents[j]})
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package gateway
+
+import (
+	context "context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+// NewPolicyFilterWebhook registers a validating admission webhook for PolicyFilter objects. The
+// policy of each is parsed as it would be on reconcile, and rejected with the parse error, or
+// admitted with a warning if warnOnly.
+func NewPolicyFilterWebhook(mgr ctrl.Manager, warnOnly bool) error {
+	err := ctrl.NewWebhookManagedBy(mgr, new(icgv1alpha1.PolicyFilter)).
+		WithValidator(&policyFilterValidator{warnOnly: warnOnly}).
+		Complete()
+	if err != nil {
+		return fmt.Errorf("build PolicyFilter webhook: %w", err)
+	}
+	return nil
+}
+
+type policyFilterValidator struct {
+	warnOnly bool
+}
+
+var _ admission.Validator[*icgv1alpha1.PolicyFilter] = (*policyFilterValidator)(nil)
+
+// ValidateCreate implements admission.Validator
+func (v *policyFilterValidator) ValidateCreate(_ context.Context, obj *icgv1alpha1.PolicyFilter) (admission.Warnings, error) {
+	return v.validate(obj)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *policyFilterValidator) ValidateUpdate(_ context.Context, _, obj *icgv1alpha1.PolicyFilter) (admission.Warnings, error) {
+	return v.validate(obj)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *policyFilterValidator) ValidateDelete(context.Context, *icgv1alpha1.PolicyFilter) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *policyFilterValidator) validate(obj *icgv1alpha1.PolicyFilter) (admission.Warnings, error) {
+	if obj.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	_, err := gateway.NewPolicyFilter(obj)
+	return util.AdmissionResult(err, v.warnOnly)
+}
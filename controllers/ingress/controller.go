@@ -1,22 +1,29 @@
 package ingress
 
 import (
+	"fmt"
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/controllers/reporter"
 	"github.com/pomerium/ingress-controller/model"
 	"github.com/pomerium/ingress-controller/pomerium"
+	"github.com/pomerium/ingress-controller/util"
 	"github.com/pomerium/ingress-controller/util/generic"
 )
 
@@ -55,13 +62,25 @@ type ingressController struct {
 	// globalSettings defines which global settings object to watch
 	globalSettings *types.NamespacedName
 
+	// fetchNamespace makes the Namespace of each ingress available to the reconciler,
+	// and reconciles the ingresses in a namespace whenever the Namespace changes
+	fetchNamespace bool
+
+	// certificateMonitor, if set, tracks the expiry of the TLS certificates of each ingress
+	certificateMonitor *certificate.Monitor
+
+	// resync, if set, delivers objects whose synced Pomerium configuration was deleted out-of-band,
+	// so that the dependent ingresses are reconciled and the configuration recreated
+	resync <-chan event.GenericEvent
+
 	// object Kinds are frequently used, do not change and are cached
-	endpointsKind    string
 	ingressKind      string
 	ingressClassKind string
 	secretKind       string
 	serviceKind      string
 	settingsKind     string
+	namespaceKind    string
+	policyFilterKind string
 
 	initComplete *once
 }
@@ -112,6 +131,14 @@ func WithUpdateIngressStatusFromService(name types.NamespacedName) Option {
 	}
 }
 
+// WithNamespaceMetadata makes ingress controller fetch the Namespace of each Ingress,
+// for reconcilers that depend on Namespace labels or annotations
+func WithNamespaceMetadata() Option {
+	return func(ic *ingressController) {
+		ic.fetchNamespace = true
+	}
+}
+
 // WithWatchSettings specifies which global settings to watch
 func WithWatchSettings(name types.NamespacedName) Option {
 	return func(ic *ingressController) {
@@ -119,6 +146,20 @@ func WithWatchSettings(name types.NamespacedName) Option {
 	}
 }
 
+// WithCertificateMonitor reports the TLS certificates synced for each ingress to a monitor
+func WithCertificateMonitor(m *certificate.Monitor) Option {
+	return func(ic *ingressController) {
+		ic.certificateMonitor = m
+	}
+}
+
+// WithResync reconciles the ingresses that depend on each object received from ch
+func WithResync(ch <-chan event.GenericEvent) Option {
+	return func(ic *ingressController) {
+		ic.resync = ch
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager
 func (r *ingressController) SetupWithManager(mgr ctrl.Manager) error {
 	r.Client = mgr.GetClient()
@@ -129,10 +170,17 @@ func (r *ingressController) SetupWithManager(mgr ctrl.Manager) error {
 	r.ingressKind = generic.GVKForType[*networkingv1.Ingress](r.Scheme).Kind
 	r.serviceKind = generic.GVKForType[*corev1.Service](r.Scheme).Kind
 	r.settingsKind = generic.GVKForType[*icsv1.Pomerium](r.Scheme).Kind
-	r.endpointsKind = generic.GVKForType[*corev1.Endpoints](r.Scheme).Kind
 	r.ingressClassKind = generic.GVKForType[*networkingv1.IngressClass](r.Scheme).Kind
+	r.namespaceKind = generic.GVKForType[*corev1.Namespace](r.Scheme).Kind
+	r.policyFilterKind = generic.GVKForType[*icgv1alpha1.PolicyFilter](r.Scheme).Kind
+
+	// PolicyFilters may be referenced with the policy_ref annotation, if the CRD is installed
+	policyFiltersInstalled, err := util.IsKindInstalled(r.Client, &icgv1alpha1.PolicyFilter{})
+	if err != nil {
+		return fmt.Errorf("check whether PolicyFilter CRD is installed: %w", err)
+	}
 
-	err := ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		Named(controllerName).
 		For(&networkingv1.Ingress{}).
 		Watches(
@@ -144,13 +192,28 @@ func (r *ingressController) SetupWithManager(mgr ctrl.Manager) error {
 		).
 		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.getDependantIngressFn(r.secretKind))).
 		Watches(&corev1.Service{}, handler.EnqueueRequestsFromMapFunc(r.getDependantIngressFn(r.serviceKind))).
-		Watches(&corev1.Endpoints{}, handler.EnqueueRequestsFromMapFunc(r.getDependantIngressFn(r.endpointsKind))).
-		WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
-		Complete(r)
-	if err != nil {
-		return err
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(r.getEndpointSliceDependantIngressFn()))
+	if policyFiltersInstalled {
+		// ignore status updates, which are made by the gateway controller
+		b = b.Watches(&icgv1alpha1.PolicyFilter{},
+			handler.EnqueueRequestsFromMapFunc(r.getDependantIngressFn(r.policyFilterKind)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	if r.fetchNamespace {
+		b = b.Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.getNamespaceDependantIngressFn()))
 	}
-	return nil
+	if r.globalSettings != nil {
+		// ingresses depend on the settings for certificate auto provisioning; ignore status updates,
+		// which are made when ingresses are reconciled
+		b = b.Watches(&icsv1.Pomerium{},
+			handler.EnqueueRequestsFromMapFunc(r.getDependantIngressFn(r.settingsKind)),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	if r.resync != nil {
+		b = b.WatchesRawSource(source.Channel(r.resync, handler.EnqueueRequestsFromMapFunc(r.getResyncIngressFn())))
+	}
+	return b.WithEventFilter(predicate.ResourceVersionChangedPredicate{}).
+		Complete(r)
 }
 
 func (r *ingressController) isWatching(obj client.Object) bool {
@@ -163,5 +226,10 @@ func (r *ingressController) isWatching(obj client.Object) bool {
 		return true
 	}
 
+	if (r.globalSettings != nil) &&
+		(*r.globalSettings == types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}) {
+		return true
+	}
+
 	return r.namespaces[obj.GetNamespace()]
 }
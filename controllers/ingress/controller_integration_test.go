@@ -19,7 +19,9 @@ import (
 	"go.uber.org/zap/zaptest"
 	"google.golang.org/protobuf/proto"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,6 +34,7 @@ import (
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
 	ingress_controller "github.com/pomerium/ingress-controller/controllers/ingress"
 	"github.com/pomerium/ingress-controller/controllers/reporter"
@@ -152,6 +155,7 @@ func (s *ControllerTestSuite) SetupSuite() {
 	scheme := runtime.NewScheme()
 	s.NoError(clientgoscheme.AddToScheme(scheme))
 	s.NoError(icsv1.AddToScheme(scheme))
+	s.NoError(icgv1alpha1.AddToScheme(scheme))
 
 	useExistingCluster := false
 	s.Environment = &envtest.Environment{
@@ -203,11 +207,24 @@ func (s *ControllerTestSuite) deleteAll() {
 		s.NoError(s.Client.Delete(ctx, &secrets.Items[i]))
 	}
 
+	policyFilters := new(icgv1alpha1.PolicyFilterList)
+	s.NoError(s.Client.List(ctx, policyFilters))
+	for i := range policyFilters.Items {
+		s.NoError(s.Client.Delete(ctx, &policyFilters.Items[i]))
+	}
+
 	settings := new(icsv1.PomeriumList)
 	s.NoError(s.Client.List(ctx, settings))
 	for i := range settings.Items {
 		s.NoError(s.Client.Delete(ctx, &settings.Items[i]))
 	}
+
+	// there is no garbage collector in the test environment to delete them along with their Ingresses
+	routeStatuses := new(icsv1.RouteStatusList)
+	s.NoError(s.Client.List(ctx, routeStatuses))
+	for i := range routeStatuses.Items {
+		s.NoError(s.Client.Delete(ctx, &routeStatuses.Items[i]))
+	}
 }
 
 func (s *ControllerTestSuite) TearDownTest() {
@@ -252,7 +269,7 @@ func (s *ControllerTestSuite) createTestController(ctx context.Context, opts ...
 type testObjs struct {
 	*networkingv1.IngressClass
 	*networkingv1.Ingress
-	*corev1.Endpoints
+	*discoveryv1.EndpointSlice
 	*corev1.Service
 	*corev1.Secret
 }
@@ -296,13 +313,15 @@ func (s *ControllerTestSuite) initialTestObjects(namespace string) *testObjs {
 				}},
 			},
 		},
-		&corev1.Endpoints{
+		&discoveryv1.EndpointSlice{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      "service",
+				Name:      "service-abcde",
 				Namespace: namespace,
+				Labels:    map[string]string{discoveryv1.LabelServiceName: "service"},
 			},
-			Subsets: []corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
+			AddressType: discoveryv1.AddressTypeIPv4,
+			Endpoints: []discoveryv1.Endpoint{{
+				Addresses: []string{"1.2.3.4"},
 			}},
 		},
 		&corev1.Service{
@@ -339,14 +358,14 @@ func (s *ControllerTestSuite) TestIngressClass() {
 	s.createTestController(ctx)
 
 	to := s.initialTestObjects("default")
-	ingressClass, ingress, endpoints, service := to.IngressClass, to.Ingress, to.Endpoints, to.Service
+	ingressClass, ingress, endpointSlice, service := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service
 
 	s.Run("no ingress class", func() {
 		ingress.Spec.IngressClassName = nil
 		// ingress should not be picked up for reconciliation as there's no ingress class record
 		s.NoError(s.Client.Create(ctx, to.Secret))
 		s.NoError(s.Client.Create(ctx, ingress))
-		s.NoError(s.Client.Create(ctx, endpoints))
+		s.NoError(s.Client.Create(ctx, endpointSlice))
 		s.NoError(s.Client.Create(ctx, service))
 		s.NeverEqual(func(ic *model.IngressConfig) string {
 			return cmp.Diff(ingress, ic.Ingress, cmpOpts...)
@@ -405,11 +424,11 @@ func (s *ControllerTestSuite) TestDependencies() {
 	s.createTestController(ctx)
 
 	to := s.initialTestObjects("default")
-	ingressClass, ingress, endpoints, service, secret := to.IngressClass, to.Ingress, to.Endpoints, to.Service, to.Secret
+	ingressClass, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
 	svcName := types.NamespacedName{Name: "service", Namespace: "default"}
 	secretName := types.NamespacedName{Name: "secret", Namespace: "default"}
 
-	for _, obj := range []client.Object{ingress, endpoints, service, secret} {
+	for _, obj := range []client.Object{ingress, endpointSlice, service, secret} {
 		s.NoError(s.Client.Create(ctx, obj))
 	}
 	s.NeverEqual(func(ic *model.IngressConfig) string {
@@ -444,7 +463,7 @@ func (s *ControllerTestSuite) TestAnnotationDependencies() {
 	s.createTestController(ctx)
 
 	to := s.initialTestObjects("default")
-	ingressClass, ingress, endpoints, service, secret := to.IngressClass, to.Ingress, to.Endpoints, to.Service, to.Secret
+	ingressClass, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
 	ingress.Annotations = map[string]string{
 		fmt.Sprintf("%s/%s", ingress_controller.DefaultAnnotationPrefix, model.TLSCustomCASecret):           "custom-ca",
 		fmt.Sprintf("%s/%s", ingress_controller.DefaultAnnotationPrefix, model.TLSClientSecret):             "client",
@@ -453,7 +472,7 @@ func (s *ControllerTestSuite) TestAnnotationDependencies() {
 	svcName := types.NamespacedName{Name: "service", Namespace: "default"}
 	secretName := types.NamespacedName{Name: "secret", Namespace: "default"}
 
-	for _, obj := range []client.Object{ingress, endpoints, service, secret, ingressClass} {
+	for _, obj := range []client.Object{ingress, endpointSlice, service, secret, ingressClass} {
 		s.NoError(s.Client.Create(ctx, obj))
 	}
 	s.NeverEqual(func(ic *model.IngressConfig) string {
@@ -496,6 +515,42 @@ func (s *ControllerTestSuite) TestAnnotationDependencies() {
 	}, "secret, service, ingress up to date")
 }
 
+func (s *ControllerTestSuite) TestPolicyRefDependencies() {
+	ctx := context.Background()
+	s.createTestController(ctx)
+
+	to := s.initialTestObjects("default")
+	ingressClass, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
+	ingress.Annotations = map[string]string{
+		fmt.Sprintf("%s/%s", ingress_controller.DefaultAnnotationPrefix, model.PolicyRef): "shared",
+	}
+	pfName := types.NamespacedName{Name: "shared", Namespace: "default"}
+
+	for _, obj := range []client.Object{ingress, endpointSlice, service, secret, ingressClass} {
+		s.NoError(s.Client.Create(ctx, obj))
+	}
+	s.NeverEqual(func(ic *model.IngressConfig) string {
+		return cmp.Diff(ingress, ic.Ingress, cmpOpts...)
+	})
+
+	pf := &icgv1alpha1.PolicyFilter{
+		ObjectMeta: metav1.ObjectMeta{Name: pfName.Name, Namespace: pfName.Namespace},
+		Spec:       icgv1alpha1.PolicyFilterSpec{PPL: `{"allow":{"and":[{"email":{"is":"a@example.com"}}]}}`},
+	}
+	s.NoError(s.Client.Create(ctx, pf))
+	s.EventuallyUpsert(func(ic *model.IngressConfig) string {
+		return cmp.Diff(pf, ic.PolicyFilters[pfName], cmpOpts...) +
+			cmp.Diff(ingress, ic.Ingress, cmpOpts...)
+	}, "policy filter fetched")
+
+	// editing the shared policy re-reconciles the ingress
+	pf.Spec.PPL = `{"allow":{"and":[{"email":{"is":"b@example.com"}}]}}`
+	s.NoError(s.Client.Update(ctx, pf))
+	s.EventuallyUpsert(func(ic *model.IngressConfig) string {
+		return cmp.Diff(pf, ic.PolicyFilters[pfName], cmpOpts...)
+	}, "policy filter updated")
+}
+
 // TestNamespaces checks that controller would only
 func (s *ControllerTestSuite) TestNamespaces() {
 	namespaces := map[string]bool{"a": true, "b": false, "c": true, "d": false}
@@ -509,10 +564,10 @@ func (s *ControllerTestSuite) TestNamespaces() {
 
 	for ns, shouldCreate := range namespaces {
 		to := s.initialTestObjects(ns)
-		ingress, endpoints, service, secret := to.Ingress, to.Endpoints, to.Service, to.Secret
+		ingress, endpointSlice, service, secret := to.Ingress, to.EndpointSlice, to.Service, to.Secret
 		for _, obj := range []client.Object{
 			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}},
-			ingress, endpoints, service, secret,
+			ingress, endpointSlice, service, secret,
 		} {
 			s.NoError(s.Client.Create(ctx, obj), "%s/%s %s", obj.GetNamespace(), obj.GetName(), reflect.TypeOf(obj))
 			defer del(obj)
@@ -557,11 +612,11 @@ func (s *ControllerTestSuite) TestIngressStatus() {
 		},
 	}
 	to := s.initialTestObjects("default")
-	class, ingress, endpoints, service, secret := to.IngressClass, to.Ingress, to.Endpoints, to.Service, to.Secret
+	class, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
 	del := func(obj client.Object) { s.Client.Delete(ctx, obj) }
 	for _, obj := range []client.Object{
 		ns, proxySvc,
-		class, endpoints, service, secret, ingress,
+		class, endpointSlice, service, secret, ingress,
 	} {
 		s.NoError(s.Client.Create(ctx, obj))
 		defer del(obj)
@@ -631,11 +686,11 @@ func (s *ControllerTestSuite) TestIngressAddressWithNodeport() {
 		},
 	}
 	to := s.initialTestObjects("default")
-	class, ingress, endpoints, service, secret := to.IngressClass, to.Ingress, to.Endpoints, to.Service, to.Secret
+	class, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
 	del := func(obj client.Object) { s.Client.Delete(ctx, obj) }
 	for _, obj := range []client.Object{
 		ns, proxySvc,
-		class, endpoints, service, secret, ingress,
+		class, endpointSlice, service, secret, ingress,
 	} {
 		s.NoError(s.Client.Create(ctx, obj))
 		defer del(obj)
@@ -704,13 +759,15 @@ func (s *ControllerTestSuite) TestHttp01Solver() {
 		},
 	}
 
-	endpoints := &corev1.Endpoints{
+	endpointSlice := &discoveryv1.EndpointSlice{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "service",
+			Name:      "service-abcde",
 			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "service"},
 		},
-		Subsets: []corev1.EndpointSubset{{
-			Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{{
+			Addresses: []string{"1.2.3.4"},
 		}},
 	}
 	service := &corev1.Service{
@@ -731,7 +788,7 @@ func (s *ControllerTestSuite) TestHttp01Solver() {
 	// ingress should not be picked up unless there's a certificate
 	s.NoError(s.Client.Create(ctx, ingressClass))
 	s.NoError(s.Client.Create(ctx, ingress))
-	s.NoError(s.Client.Create(ctx, endpoints))
+	s.NoError(s.Client.Create(ctx, endpointSlice))
 	s.NoError(s.Client.Create(ctx, service))
 
 	s.EventuallyUpsert(func(ic *model.IngressConfig) string {
@@ -743,14 +800,14 @@ func (s *ControllerTestSuite) TestCustomSecrets() {
 	ctx := context.Background()
 	s.createTestController(ctx)
 	to := s.initialTestObjects("default")
-	ingressClass, ingress, endpoints, service, secret := to.IngressClass, to.Ingress, to.Endpoints, to.Service, to.Secret
+	ingressClass, ingress, endpointSlice, service, secret := to.IngressClass, to.Ingress, to.EndpointSlice, to.Service, to.Secret
 	ingress.Annotations = map[string]string{
 		fmt.Sprintf("%s/%s", ingress_controller.DefaultAnnotationPrefix, model.SetRequestHeadersSecret):  "request-headers",
 		fmt.Sprintf("%s/%s", ingress_controller.DefaultAnnotationPrefix, model.SetResponseHeadersSecret): "response-headers",
 	}
 
 	for _, obj := range []client.Object{
-		endpoints, service, secret, ingressClass,
+		endpointSlice, service, secret, ingressClass,
 		&corev1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      "k8s-token",
@@ -793,8 +850,8 @@ func (s *ControllerTestSuite) TestCustomSecrets() {
 func (s *ControllerTestSuite) TestSettingsStatusUpdate() {
 	ctx := context.Background()
 
-	name := types.NamespacedName{Namespace: "default", Name: "config"}
-	reporter := reporter.IngressSettingsReporter{
+	name := types.NamespacedName{Name: "config"}
+	rep := reporter.IngressRouteStatusReporter{
 		SettingsReporter: reporter.SettingsReporter{
 			NamespacedName: name,
 			Client:         s.Client,
@@ -810,7 +867,7 @@ func (s *ControllerTestSuite) TestSettingsStatusUpdate() {
 	s.NoError(s.Client.Create(ctx, &other))
 
 	gs := icsv1.Pomerium{
-		ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace},
+		ObjectMeta: metav1.ObjectMeta{Name: name.Name},
 		Spec: icsv1.PomeriumSpec{
 			IdentityProvider: &icsv1.IdentityProvider{
 				Provider: "oidc",
@@ -824,53 +881,59 @@ func (s *ControllerTestSuite) TestSettingsStatusUpdate() {
 	}
 	s.NoError(s.Client.Create(ctx, &gs))
 
-	s.NoError(reporter.IngressReconciled(ctx, &other))
-
-	s.NoError(reporter.IngressNotReconciled(ctx, to.Ingress, errors.New("some error")))
-	s.NoError(s.Client.Get(ctx, name, &gs))
+	routeStatus := func(name types.NamespacedName) *icsv1.ResourceStatus {
+		var rs icsv1.RouteStatus
+		if err := s.Client.Get(ctx, name, &rs); err != nil {
+			s.True(apierrors.IsNotFound(err), err)
+			return nil
+		}
+		s.Equal("config", rs.Labels[reporter.RouteStatusSettingsLabel])
+		if s.Len(rs.OwnerReferences, 1) {
+			s.Equal(name.Name, rs.OwnerReferences[0].Name)
+		}
+		return &rs.Status
+	}
+	counts := func() *icsv1.IngressStatusCounts {
+		s.NoError(rep.UpdateCounts(ctx, s.Client))
+		s.NoError(s.Client.Get(ctx, name, &gs))
+		return gs.Status.Ingresses
+	}
 	cOpts := cmpopts.IgnoreFields(icsv1.ResourceStatus{}, "ObservedAt")
-	assert.Empty(s.T(), cmp.Diff(
-		gs.Status.Routes,
-		map[string]icsv1.ResourceStatus{
-			ingressName.String(): {
-				ObservedGeneration: to.Ingress.Generation,
-				Reconciled:         false,
-				Error:              proto.String("some error"),
-			},
-			otherName.String(): {
-				ObservedGeneration: other.Generation,
-				Reconciled:         true,
-			},
-		},
-		cOpts))
-
-	s.NoError(reporter.IngressReconciled(ctx, to.Ingress))
-	s.NoError(s.Client.Get(ctx, name, &gs))
-	assert.Empty(s.T(), cmp.Diff(
-		gs.Status.Routes,
-		map[string]icsv1.ResourceStatus{
-			ingressName.String(): {
-				ObservedGeneration: to.Ingress.Generation,
-				Reconciled:         true,
-			},
-			otherName.String(): {
-				ObservedGeneration: other.Generation,
-				Reconciled:         true,
-			},
-		},
-		cOpts))
 
-	s.NoError(reporter.IngressDeleted(ctx, ingressName, "test"))
+	s.NoError(rep.IngressReconciled(ctx, &other))
+	s.NoError(rep.IngressNotReconciled(ctx, to.Ingress, errors.New("some error")))
+	assert.Empty(s.T(), cmp.Diff(&icsv1.ResourceStatus{
+		ObservedGeneration: to.Ingress.Generation,
+		Reconciled:         false,
+		Error:              proto.String("some error"),
+	}, routeStatus(ingressName), cOpts))
+	assert.Empty(s.T(), cmp.Diff(&icsv1.ResourceStatus{
+		ObservedGeneration: other.Generation,
+		Reconciled:         true,
+	}, routeStatus(otherName), cOpts))
+	s.Equal(&icsv1.IngressStatusCounts{Reconciled: 1, Failed: 1}, counts())
+
+	s.NoError(rep.IngressReconciled(ctx, to.Ingress))
+	assert.Empty(s.T(), cmp.Diff(&icsv1.ResourceStatus{
+		ObservedGeneration: to.Ingress.Generation,
+		Reconciled:         true,
+	}, routeStatus(ingressName), cOpts))
+	s.Equal(&icsv1.IngressStatusCounts{Reconciled: 2, Failed: 0}, counts())
+
+	s.NoError(rep.IngressDeleted(ctx, ingressName, "test"))
+	s.Nil(routeStatus(ingressName))
+	s.NotNil(routeStatus(otherName))
+	s.Equal(&icsv1.IngressStatusCounts{Reconciled: 1, Failed: 0}, counts())
+
+	// the per-Ingress status kept by earlier versions is cleared
+	gs.Status.Routes = map[string]icsv1.ResourceStatus{
+		otherName.String(): {ObservedGeneration: other.Generation, Reconciled: true},
+	}
+	s.NoError(s.Client.Status().Update(ctx, &gs))
+	s.NoError(reporter.ClearLegacyIngressStatus(ctx, s.Client, name))
 	s.NoError(s.Client.Get(ctx, name, &gs))
-	assert.Empty(s.T(), cmp.Diff(
-		gs.Status.Routes,
-		map[string]icsv1.ResourceStatus{
-			otherName.String(): {
-				ObservedGeneration: other.Generation,
-				Reconciled:         true,
-			},
-		},
-		cOpts))
+	s.Empty(gs.Status.Routes)
+	s.Equal(&icsv1.IngressStatusCounts{Reconciled: 1, Failed: 0}, gs.Status.Ingresses)
 }
 
 func TestIngressController(t *testing.T) {
@@ -25,7 +25,6 @@ func TestManagingIngressClass(t *testing.T) {
 		controllerName:   pomeriumControllerName,
 		annotationPrefix: DefaultAnnotationPrefix,
 		Client:           mc,
-		endpointsKind:    "Endpoints",
 		ingressKind:      "Ingress",
 		ingressClassKind: "IngressClass",
 		secretKind:       "Secret",
@@ -127,3 +126,22 @@ func TestManagingIngressClass(t *testing.T) {
 		}
 	}
 }
+
+func TestGetHostsWithoutSecret(t *testing.T) {
+	ingress := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{
+				{Hosts: []string{"a.example.com"}, SecretName: "a-tls"},
+				{Hosts: []string{"b.example.com"}},
+			},
+			Rules: []networkingv1.IngressRule{
+				{Host: "a.example.com"},
+				{Host: "b.example.com"},
+				{Host: "c.example.com"},
+				{Host: "c.example.com"},
+				{},
+			},
+		},
+	}
+	assert.Equal(t, []string{"b.example.com", "c.example.com"}, getHostsWithoutSecret(ingress))
+}
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"reflect"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	"github.com/pomerium/ingress-controller/model"
 )
 
@@ -36,6 +40,65 @@ func (r *ingressController) getDependantIngressFn(kind string) handler.MapFunc {
 	}
 }
 
+// getEndpointSliceDependantIngressFn returns a function that maps an EndpointSlice
+// to the ingresses that depend on the service it belongs to.
+// slices are named by the endpoint slice controller and come and go as the service scales,
+// so dependencies are tracked against the owning service rather than individual slices
+func (r *ingressController) getEndpointSliceDependantIngressFn() handler.MapFunc {
+	serviceDeps := r.getDependantIngressFn(r.serviceKind)
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		svcName, ok := a.GetLabels()[discoveryv1.LabelServiceName]
+		if !ok || svcName == "" {
+			return nil
+		}
+
+		svc := new(metav1.PartialObjectMetadata)
+		svc.SetName(svcName)
+		svc.SetNamespace(a.GetNamespace())
+		return serviceDeps(ctx, svc)
+	}
+}
+
+// getNamespaceDependantIngressFn returns a function that maps a Namespace
+// to the ingresses within it that depend on it
+func (r *ingressController) getNamespaceDependantIngressFn() handler.MapFunc {
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		if len(r.namespaces) > 0 && !r.namespaces[a.GetName()] {
+			return nil
+		}
+
+		deps := r.DepsOfKind(model.Key{Kind: r.namespaceKind, NamespacedName: types.NamespacedName{Name: a.GetName()}}, r.ingressKind)
+		reqs := make([]reconcile.Request, 0, len(deps))
+		for _, k := range deps {
+			reqs = append(reqs, reconcile.Request{NamespacedName: k.NamespacedName})
+		}
+		log.FromContext(ctx).V(5).Info("watch", "namespace", a.GetName(), "deps", reqs)
+		return reqs
+	}
+}
+
+// getResyncIngressFn returns a function that maps an object whose synced Pomerium configuration
+// needs to be recreated to the ingresses to reconcile: the object itself if it is an ingress,
+// or else the ingresses that depend on it
+func (r *ingressController) getResyncIngressFn() handler.MapFunc {
+	secretDeps := r.getDependantIngressFn(r.secretKind)
+	policyFilterDeps := r.getDependantIngressFn(r.policyFilterKind)
+	return func(ctx context.Context, a client.Object) []reconcile.Request {
+		switch a.(type) {
+		case *networkingv1.Ingress:
+			if !r.isWatching(a) {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: a.GetName(), Namespace: a.GetNamespace()}}}
+		case *corev1.Secret:
+			return secretDeps(ctx, a)
+		case *icgv1alpha1.PolicyFilter:
+			return policyFilterDeps(ctx, a)
+		}
+		return nil
+	}
+}
+
 func (r *ingressController) watchIngressClass() handler.MapFunc {
 	return func(ctx context.Context, a client.Object) []reconcile.Request {
 		logger := log.FromContext(ctx)
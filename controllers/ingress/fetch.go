@@ -6,16 +6,25 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/controllers/deps"
 	"github.com/pomerium/ingress-controller/model"
 )
 
-func (r *ingressController) fetchIngress(ctx context.Context, ingress *networkingv1.Ingress) (*model.IngressConfig, error) {
+func (r *ingressController) fetchIngress(
+	ctx context.Context,
+	ingress *networkingv1.Ingress,
+	class *networkingv1.IngressClass,
+) (*model.IngressConfig, error) {
 	key := model.ObjectKey(ingress, r.Scheme)
 	r.DeleteCascade(key)
 	defer func() {
@@ -28,7 +37,89 @@ func (r *ingressController) fetchIngress(ctx context.Context, ingress *networkin
 		_ = client.Get(ctx, *r.updateStatusFromService, new(corev1.Service))
 	}
 
-	return FetchIngress(ctx, client, ingress, r.annotationPrefix)
+	ic, err := FetchIngress(ctx, client, ingress, r.annotationPrefix)
+	if err != nil {
+		return nil, err
+	}
+	ic.IngressClass = class
+
+	if r.globalSettings != nil {
+		if err := r.provisionCertificate(ctx, client, ic); err != nil {
+			return nil, fmt.Errorf("certificate: %w", err)
+		}
+	}
+
+	if r.fetchNamespace {
+		ns := new(corev1.Namespace)
+		if err := client.Get(ctx, types.NamespacedName{Name: ingress.Namespace}, ns); err != nil {
+			return nil, fmt.Errorf("get namespace %s: %w", ingress.Namespace, err)
+		}
+		ic.IngressNamespace = ns
+	}
+	return ic, nil
+}
+
+// provisionCertificate provisions a certificate for any hosts of the ingress not covered by a TLS
+// secret, if the global settings enable it, and adds the resulting secret to the ingress config
+// once the certificate is issued.
+func (r *ingressController) provisionCertificate(
+	ctx context.Context,
+	client client.Client,
+	ic *model.IngressConfig,
+) error {
+	settings := new(icsv1.Pomerium)
+	if err := client.Get(ctx, *r.globalSettings, settings); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get settings %s: %w", r.globalSettings.Name, err)
+	}
+	issuer, ok := certificate.RouteIssuer(settings, ic.Namespace)
+	if !ok {
+		return nil
+	}
+
+	name := certificate.RouteCertificateName(ic.Name)
+	hosts := getHostsWithoutSecret(ic.Ingress)
+	if len(hosts) == 0 {
+		return certificate.RemoveRouteCertificate(ctx, r.Client, ic.Ingress, name)
+	}
+	if err := certificate.EnsureRouteCertificate(ctx, r.Client, ic.Ingress, name, issuer, hosts); err != nil {
+		return err
+	}
+
+	secretName := types.NamespacedName{Namespace: ic.Namespace, Name: name}
+	secret := new(corev1.Secret)
+	if err := client.Get(ctx, secretName, secret); apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get secret %s: %w", secretName.String(), err)
+	}
+	if certificate.IsIssued(secret) {
+		ic.Secrets[secretName] = secret
+	}
+	return nil
+}
+
+// getHostsWithoutSecret returns the rule hosts of an ingress that are not listed in any TLS
+// entry with a secret.
+func getHostsWithoutSecret(ingress *networkingv1.Ingress) []string {
+	covered := make(map[string]bool)
+	for _, tls := range ingress.Spec.TLS {
+		if tls.SecretName == "" {
+			continue
+		}
+		for _, host := range tls.Hosts {
+			covered[host] = true
+		}
+	}
+	var hosts []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" && !covered[rule.Host] {
+			covered[rule.Host] = true
+			hosts = append(hosts, rule.Host)
+		}
+	}
+	return hosts
 }
 
 // FetchIngress populates a model.IngressConfig for ingress.
@@ -43,28 +134,56 @@ func FetchIngress(
 		return nil, fmt.Errorf("tls: %w", err)
 	}
 
-	services, endpoints, err := fetchIngressServices(ctx, client, ingress)
+	services, endpointSlices, err := fetchIngressServices(ctx, client, ingress)
 	if err != nil {
 		return nil, fmt.Errorf("services: %w", err)
 	}
 
-	return &model.IngressConfig{
+	ic := &model.IngressConfig{
 		AnnotationPrefix: annotationPrefix,
 		Ingress:          ingress,
-		Endpoints:        endpoints,
+		EndpointSlices:   endpointSlices,
 		Secrets:          secrets,
 		Services:         services,
-	}, nil
+	}
+	if ic.PolicyFilters, err = fetchIngressPolicyFilters(ctx, client, ic); err != nil {
+		return nil, fmt.Errorf("%s: %w", model.PolicyRef, err)
+	}
+	return ic, nil
+}
+
+// fetchIngressPolicyFilters returns the PolicyFilters referenced by the policy_ref annotation, if any
+func fetchIngressPolicyFilters(ctx context.Context, client client.Client, ic *model.IngressConfig) (
+	map[types.NamespacedName]*icgv1alpha1.PolicyFilter,
+	error,
+) {
+	refs, err := ic.GetPolicyRefs()
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	filters := make(map[types.NamespacedName]*icgv1alpha1.PolicyFilter, len(refs))
+	for _, name := range refs {
+		pf := new(icgv1alpha1.PolicyFilter)
+		if err := client.Get(ctx, name, pf); err != nil {
+			return nil, fmt.Errorf("get policy filter %s: %w", name.String(), err)
+		}
+		filters[name] = pf
+	}
+	return filters, nil
 }
 
 // fetchIngressServices returns list of services referred from named port in the ingress path backend spec
 func fetchIngressServices(ctx context.Context, client client.Client, ingress *networkingv1.Ingress) (
 	map[types.NamespacedName]*corev1.Service,
-	map[types.NamespacedName]*corev1.Endpoints,
+	map[types.NamespacedName][]*discoveryv1.EndpointSlice,
 	error,
 ) {
 	sm := make(map[types.NamespacedName]*corev1.Service)
-	em := make(map[types.NamespacedName]*corev1.Endpoints)
+	em := make(map[types.NamespacedName][]*discoveryv1.EndpointSlice)
 
 	for _, rule := range ingress.Spec.Rules {
 		if rule.HTTP == nil {
@@ -100,13 +219,13 @@ func fetchIngressServices(ctx context.Context, client client.Client, ingress *ne
 
 func fetchIngressService(
 	ctx context.Context,
-	client client.Client,
+	k8sClient client.Client,
 	servicesDst map[types.NamespacedName]*corev1.Service,
-	endpointsDst map[types.NamespacedName]*corev1.Endpoints,
+	endpointSlicesDst map[types.NamespacedName][]*discoveryv1.EndpointSlice,
 	name types.NamespacedName,
 ) error {
 	service := new(corev1.Service)
-	if err := client.Get(ctx, name, service); err != nil {
+	if err := k8sClient.Get(ctx, name, service); err != nil {
 		return err
 	}
 	servicesDst[name] = service
@@ -115,11 +234,20 @@ func fetchIngressService(
 		return nil
 	}
 
-	endpoint := new(corev1.Endpoints)
-	if err := client.Get(ctx, name, endpoint); err != nil {
-		return err
+	// a service may have multiple endpoint slices, i.e. when it has more than 100 endpoints
+	// or is dual-stack; they are all linked to the service via a well-known label
+	sl := new(discoveryv1.EndpointSliceList)
+	if err := k8sClient.List(ctx, sl,
+		client.InNamespace(name.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: name.Name},
+	); err != nil {
+		return fmt.Errorf("list endpoint slices: %w", err)
+	}
+	slices := make([]*discoveryv1.EndpointSlice, 0, len(sl.Items))
+	for i := range sl.Items {
+		slices = append(slices, &sl.Items[i])
 	}
-	endpointsDst[name] = endpoint
+	endpointSlicesDst[name] = slices
 
 	return nil
 }
@@ -22,16 +22,14 @@ const (
 type ingressManageResult struct {
 	reasonIfNot string
 	managed     bool
+	// class is the IngressClass of a managed ingress
+	class *networkingv1.IngressClass
 }
 
-var (
-	ingressIsManaged = &ingressManageResult{managed: true}
-)
-
 func (r *ingressController) isManaging(ctx context.Context, ing *networkingv1.Ingress) (*ingressManageResult, error) {
-	_, err := r.getManagingClass(ctx, ing)
+	class, err := r.getManagingClass(ctx, ing)
 	if err == nil {
-		return ingressIsManaged, nil
+		return &ingressManageResult{managed: true, class: class}, nil
 	}
 
 	if status := apierrors.APIStatus(nil); errors.As(err, &status) {
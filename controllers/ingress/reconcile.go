@@ -3,6 +3,8 @@ package ingress
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -43,7 +45,7 @@ func (r *ingressController) reconcileInitial(ctx context.Context) (err error) {
 			logger.V(1).Info("skipping ingress", "ingress", ingress.Name, "reason", res.reasonIfNot)
 			continue
 		}
-		ic, err := r.fetchIngress(ctx, ingress)
+		ic, err := r.fetchIngress(ctx, ingress, res.class)
 		if err != nil {
 			return fmt.Errorf("fetch ingress %s/%s: %w", ingress.Namespace, ingress.Name, err)
 		}
@@ -61,6 +63,9 @@ func (r *ingressController) reconcileInitial(ctx context.Context) (err error) {
 		} else {
 			r.IngressReconciled(ctx, ingress)
 		}
+		if err == nil {
+			r.observeCertificates(ctx, ics[i])
+		}
 	}
 
 	return err
@@ -94,7 +99,7 @@ func (r *ingressController) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return r.deleteIngress(ctx, req.NamespacedName, managing.reasonIfNot)
 	}
 
-	ic, err := r.fetchIngress(ctx, ingress)
+	ic, err := r.fetchIngress(ctx, ingress, managing.class)
 	if err != nil {
 		r.IngressNotReconciled(ctx, ingress, err)
 		return ctrl.Result{Requeue: true}, fmt.Errorf("fetch ingress related resources: %w", err)
@@ -115,6 +120,7 @@ func (r *ingressController) deleteIngress(ctx context.Context, name types.Namesp
 	if changed {
 		r.IngressDeleted(ctx, name, reason)
 	}
+	r.certificateMonitor.Forget(ctx, certificateSource(name))
 	r.DeleteCascade(model.Key{Kind: r.ingressKind, NamespacedName: name})
 	return ctrl.Result{}, nil
 }
@@ -127,6 +133,7 @@ func (r *ingressController) upsertIngress(ctx context.Context, ic *model.Ingress
 	}
 
 	r.IngressReconciled(ctx, ic.Ingress)
+	r.observeCertificates(ctx, ic)
 
 	if err = r.updateIngressStatus(ctx, ic.Ingress); err != nil {
 		return ctrl.Result{Requeue: true}, fmt.Errorf("update ingress status: %w", err)
@@ -135,6 +142,17 @@ func (r *ingressController) upsertIngress(ctx context.Context, ic *model.Ingress
 	return ctrl.Result{}, nil
 }
 
+// observeCertificates reports the TLS certificates synced for an ingress to the certificate monitor.
+func (r *ingressController) observeCertificates(ctx context.Context, ic *model.IngressConfig) {
+	r.certificateMonitor.Observe(ctx,
+		certificateSource(types.NamespacedName{Namespace: ic.Namespace, Name: ic.Name}),
+		slices.Collect(maps.Values(ic.Secrets)))
+}
+
+func certificateSource(name types.NamespacedName) string {
+	return "Ingress " + name.String()
+}
+
 func (r *ingressController) updateIngressStatus(ctx context.Context, ingress *networkingv1.Ingress) error {
 	if r.updateStatusFromService == nil {
 		return nil
@@ -0,0 +1,62 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// FetchManagedIngresses returns the configuration of all the Ingresses that a controller with
+// the same options would manage, as fetched during its initial sync. Certificates are not
+// provisioned, so any hosts without a TLS secret are left without a certificate. The Ingresses
+// that could not be fetched are left out, and returned as errors along with the others.
+func FetchManagedIngresses(ctx context.Context, c client.Client, opts ...Option) ([]*model.IngressConfig, error) {
+	r := &ingressController{
+		annotationPrefix: DefaultAnnotationPrefix,
+		controllerName:   DefaultClassControllerName,
+		Client:           c,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	ingressList := new(networkingv1.IngressList)
+	if err := c.List(ctx, ingressList); err != nil {
+		return nil, fmt.Errorf("list ingresses: %w", err)
+	}
+
+	var ics []*model.IngressConfig
+	var errs []error
+	for i := range ingressList.Items {
+		ingress := &ingressList.Items[i]
+		res, err := r.isManaging(ctx, ingress)
+		if err != nil {
+			return nil, fmt.Errorf("get ingressClass info: %w", err)
+		}
+		if !res.managed {
+			continue
+		}
+		ic, err := FetchIngress(ctx, c, ingress, r.annotationPrefix)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fetch ingress %s/%s: %w", ingress.Namespace, ingress.Name, err))
+			continue
+		}
+		ic.IngressClass = res.class
+		if r.fetchNamespace {
+			ns := new(corev1.Namespace)
+			if err := c.Get(ctx, types.NamespacedName{Name: ingress.Namespace}, ns); err != nil {
+				return nil, fmt.Errorf("get namespace %s: %w", ingress.Namespace, err)
+			}
+			ic.IngressNamespace = ns
+		}
+		ics = append(ics, ic)
+	}
+	return ics, errors.Join(errs...)
+}
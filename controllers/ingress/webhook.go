@@ -0,0 +1,84 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+// NewIngressWebhook registers a validating admission webhook for the Ingresses that a controller
+// with the same options would manage. Each Ingress is translated to Pomerium routes as it would be
+// on reconcile, and rejected with the translation error, or admitted with a warning if warnOnly.
+func NewIngressWebhook(mgr ctrl.Manager, warnOnly bool, opts ...Option) error {
+	ic := &ingressController{
+		annotationPrefix: DefaultAnnotationPrefix,
+		controllerName:   DefaultClassControllerName,
+		Client:           mgr.GetClient(),
+	}
+	for _, opt := range opts {
+		opt(ic)
+	}
+
+	err := ctrl.NewWebhookManagedBy(mgr, new(networkingv1.Ingress)).
+		WithValidator(&ingressValidator{ingressController: ic, warnOnly: warnOnly}).
+		Complete()
+	if err != nil {
+		return fmt.Errorf("build ingress webhook: %w", err)
+	}
+	return nil
+}
+
+type ingressValidator struct {
+	*ingressController
+	warnOnly bool
+}
+
+var _ admission.Validator[*networkingv1.Ingress] = (*ingressValidator)(nil)
+
+// ValidateCreate implements admission.Validator
+func (v *ingressValidator) ValidateCreate(ctx context.Context, ingress *networkingv1.Ingress) (admission.Warnings, error) {
+	return v.validate(ctx, ingress)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *ingressValidator) ValidateUpdate(ctx context.Context, _, ingress *networkingv1.Ingress) (admission.Warnings, error) {
+	return v.validate(ctx, ingress)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *ingressValidator) ValidateDelete(context.Context, *networkingv1.Ingress) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ingressValidator) validate(ctx context.Context, ingress *networkingv1.Ingress) (admission.Warnings, error) {
+	// do not block removing finalizers, or cert-manager solving HTTP-01 challenges
+	if ingress.DeletionTimestamp != nil || model.IsHTTP01Solver(ingress) {
+		return nil, nil
+	}
+
+	if _, err := v.getManagingClass(ctx, ingress); err != nil {
+		if status := apierrors.APIStatus(nil); errors.As(err, &status) {
+			return admission.Warnings{fmt.Sprintf("could not determine the IngressClass: %v", err)}, nil
+		}
+		// not managed by this controller
+		return nil, nil
+	}
+
+	// the objects an Ingress references may be created after it, as by a single apply,
+	// so only the annotations are validated until they are available
+	ic, err := FetchIngress(ctx, v.Client, ingress, v.annotationPrefix)
+	if err != nil {
+		return util.AdmissionResult(pomerium.ValidateIngressAnnotations(ingress.Annotations, v.annotationPrefix), v.warnOnly,
+			fmt.Sprintf("only annotations were validated, as referenced objects could not be fetched: %v", err))
+	}
+	return util.AdmissionResult(pomerium.ValidateIngress(ctx, ic), v.warnOnly)
+}
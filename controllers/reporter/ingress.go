@@ -3,8 +3,8 @@ package reporter
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
@@ -31,76 +32,140 @@ type IngressStatusReporter interface {
 	IngressDeleted(ctx context.Context, name types.NamespacedName, reason string) error
 }
 
-// IngressSettingsReporter reflects ingress updates in a Pomerium Settings CRD /status section
-type IngressSettingsReporter struct {
+// RouteStatusSettingsLabel is set on RouteStatus objects to the name of the Pomerium CRD that
+// counts them.
+const RouteStatusSettingsLabel = "ingress.pomerium.io/settings"
+
+// IngressRouteStatusReporter records ingress updates in a RouteStatus object of the same name and
+// namespace as the Ingress, and counts them in the Pomerium Settings CRD /status section. The
+// counts are not updated along with each RouteStatus, but by UpdateCounts.
+type IngressRouteStatusReporter struct {
 	SettingsReporter
+
+	// countsUpToDate is cleared whenever a RouteStatus is updated or deleted.
+	countsUpToDate atomic.Bool
 }
 
 // IngressReconciled an ingress was successfully reconciled with Pomerium
-func (r *IngressSettingsReporter) IngressReconciled(ctx context.Context, ingress *networkingv1.Ingress) error {
-	obj := icsv1.Pomerium{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: r.Name,
-		},
-		Status: icsv1.PomeriumStatus{
-			Routes: map[string]icsv1.ResourceStatus{
-				types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}.String(): {
-					ObservedGeneration: ingress.Generation,
-					ObservedAt:         metav1.Time{Time: time.Now()},
-					Reconciled:         true,
-					Error:              nil,
-				}},
-		},
-	}
-	return r.Status().Patch(ctx, &obj, client.MergeFrom(&icsv1.Pomerium{ObjectMeta: obj.ObjectMeta}))
+func (r *IngressRouteStatusReporter) IngressReconciled(ctx context.Context, ingress *networkingv1.Ingress) error {
+	return r.setStatus(ctx, ingress, icsv1.ResourceStatus{
+		ObservedGeneration: ingress.Generation,
+		ObservedAt:         metav1.Time{Time: time.Now()},
+		Reconciled:         true,
+		Error:              nil,
+	})
 }
 
 // IngressNotReconciled an updated ingress resource was received,
 // however it could not be reconciled with Pomerium due to errors
-func (r *IngressSettingsReporter) IngressNotReconciled(ctx context.Context, ingress *networkingv1.Ingress, reason error) error {
-	obj := icsv1.Pomerium{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: r.Name,
-		},
-		Status: icsv1.PomeriumStatus{
-			Routes: map[string]icsv1.ResourceStatus{
-				types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}.String(): {
-					ObservedGeneration: ingress.Generation,
-					ObservedAt:         metav1.Time{Time: time.Now()},
-					Reconciled:         false,
-					Error:              proto.String(reason.Error()),
-				}},
-		},
-	}
-	return r.Status().Patch(ctx, &obj, client.MergeFrom(&icsv1.Pomerium{ObjectMeta: obj.ObjectMeta}))
+func (r *IngressRouteStatusReporter) IngressNotReconciled(ctx context.Context, ingress *networkingv1.Ingress, reason error) error {
+	return r.setStatus(ctx, ingress, icsv1.ResourceStatus{
+		ObservedGeneration: ingress.Generation,
+		ObservedAt:         metav1.Time{Time: time.Now()},
+		Reconciled:         false,
+		Error:              proto.String(reason.Error()),
+	})
 }
 
 // IngressDeleted an ingress resource was deleted and Pomerium no longer serves it
-func (r *IngressSettingsReporter) IngressDeleted(ctx context.Context, name types.NamespacedName, _ string) error {
-	patch, err := json.Marshal([]struct {
-		Op   string `json:"op"`
-		Path string `json:"path"`
-	}{{
-		Op: "remove",
-		// https://datatracker.ietf.org/doc/html/rfc6901#section-3
-		// "/"(forward slash) is encoded as "~1"
-		// ¯\_(ツ)_/¯
-		Path: fmt.Sprintf("/status/ingress/%s~1%s", name.Namespace, name.Name),
-	}})
+func (r *IngressRouteStatusReporter) IngressDeleted(ctx context.Context, name types.NamespacedName, _ string) error {
+	err := r.Delete(ctx, &icsv1.RouteStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace},
+	})
+	if err = client.IgnoreNotFound(err); err != nil {
+		return fmt.Errorf("delete route status %v: %w", name, err)
+	}
+	r.countsUpToDate.Store(false)
+	return nil
+}
+
+func (r *IngressRouteStatusReporter) setStatus(ctx context.Context, ingress *networkingv1.Ingress, status icsv1.ResourceStatus) error {
+	name := types.NamespacedName{Namespace: ingress.Namespace, Name: ingress.Name}
+	obj := &icsv1.RouteStatus{
+		ObjectMeta: metav1.ObjectMeta{Name: ingress.Name, Namespace: ingress.Namespace},
+	}
+	// RouteStatus has no status subresource, so the status is written along with the object
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string)
+		}
+		obj.Labels[RouteStatusSettingsLabel] = r.Name
+		obj.Status = status
+		return controllerutil.SetControllerReference(ingress, obj, r.Scheme())
+	})
 	if err != nil {
-		return err
+		return fmt.Errorf("update route status %v: %w", name, err)
 	}
+	r.countsUpToDate.Store(false)
+	return nil
+}
 
-	err = r.Status().Patch(ctx, &icsv1.Pomerium{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: r.Name,
-		},
-		Status: icsv1.PomeriumStatus{
-			Routes: map[string]icsv1.ResourceStatus{},
-		},
-	}, client.RawPatch(types.JSONPatchType, patch))
+// UpdateCounts updates the Ingress counts in the Pomerium CRD /status section, if any RouteStatus
+// was updated since the counts were last updated. It is meant to be called periodically, so that
+// the RouteStatus objects are listed at most once per period rather than on every update. The
+// objects are listed using reader, which should not be a cache, as it may lag behind the updates.
+func (r *IngressRouteStatusReporter) UpdateCounts(ctx context.Context, reader client.Reader) (err error) {
+	if r.countsUpToDate.Swap(true) {
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			r.countsUpToDate.Store(false)
+		}
+	}()
+
+	counts := new(icsv1.IngressStatusCounts)
+	var continueToken string
+	for {
+		var list icsv1.RouteStatusList
+		err := reader.List(ctx, &list, client.MatchingLabels{RouteStatusSettingsLabel: r.Name},
+			client.Limit(routeStatusListPageSize), client.Continue(continueToken))
+		if err != nil {
+			return fmt.Errorf("list route statuses: %w", err)
+		}
+		for i := range list.Items {
+			if list.Items[i].Status.Reconciled {
+				counts.Reconciled++
+			} else {
+				counts.Failed++
+			}
+		}
+		if continueToken = list.Continue; continueToken == "" {
+			break
+		}
+	}
+
+	var obj icsv1.Pomerium
+	if err := r.Get(ctx, r.NamespacedName, &obj); err != nil {
+		return fmt.Errorf("get %s: %w", r.NamespacedName, err)
+	}
+	if obj.Status.Ingresses != nil && *obj.Status.Ingresses == *counts {
+		return nil
+	}
+	patch := client.MergeFrom(obj.DeepCopy())
+	obj.Status.Ingresses = counts
+	if err := r.Status().Patch(ctx, &obj, patch); err != nil {
+		return fmt.Errorf("patch %s status: %w", r.NamespacedName, err)
+	}
+	return nil
+}
+
+// routeStatusListPageSize is the number of RouteStatus objects listed per request by UpdateCounts.
+const routeStatusListPageSize = 500
+
+// ClearLegacyIngressStatus removes the per-Ingress status map that was kept in the Pomerium
+// Settings CRD /status section, before it was recorded in RouteStatus objects.
+func ClearLegacyIngressStatus(ctx context.Context, c client.Client, name types.NamespacedName) error {
+	var obj icsv1.Pomerium
+	if err := c.Get(ctx, name, &obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if len(obj.Status.Routes) == 0 {
+		return nil
+	}
+	err := c.Status().Patch(ctx, &obj, client.RawPatch(types.MergePatchType, []byte(`{"status":{"ingress":null}}`)))
 	if err != nil {
-		return fmt.Errorf("failed to patch /status for ingress %v: %w", name, err)
+		return fmt.Errorf("clear %s /status/ingress: %w", name, err)
 	}
 	return nil
 }
@@ -48,7 +48,7 @@ func (s *SettingsStatusReporter) SettingsUpdated(ctx context.Context, obj *icsv1
 				ObservedAt:         metav1.Time{Time: time.Now()},
 				Reconciled:         true,
 				Error:              nil,
-				Warnings:           getConfigWarnings(ctx),
+				Warnings:           ConfigWarnings(ctx),
 			},
 		},
 	}, client.MergeFrom(&icsv1.Pomerium{ObjectMeta: obj.ObjectMeta}))
@@ -64,13 +64,14 @@ func (s *SettingsStatusReporter) SettingsRejected(ctx context.Context, obj *icsv
 				ObservedAt:         metav1.Time{Time: time.Now()},
 				Reconciled:         false,
 				Error:              proto.String(err.Error()),
-				Warnings:           getConfigWarnings(ctx),
+				Warnings:           ConfigWarnings(ctx),
 			},
 		},
 	}, client.MergeFrom(&icsv1.Pomerium{ObjectMeta: obj.ObjectMeta}))
 }
 
-func getConfigWarnings(ctx context.Context) []string {
+// ConfigWarnings returns the configuration warnings collected in the context.
+func ConfigWarnings(ctx context.Context) []string {
 	var out []string
 	for _, msg := range util.Get[pom_cfg.FieldMsg](ctx) {
 		out = append(out, fmt.Sprintf("%s: %s, please see %s", msg.Key, msg.FieldCheckMsg, msg.DocsURL))
@@ -104,7 +105,7 @@ type SettingsEventReporter struct {
 
 // SettingsUpdated marks configuration was reconciled with pomerium
 func (s *SettingsEventReporter) SettingsUpdated(ctx context.Context, obj *icsv1.Pomerium) error {
-	for _, msg := range getConfigWarnings(ctx) {
+	for _, msg := range ConfigWarnings(ctx) {
 		s.Event(obj, corev1.EventTypeWarning, reasonPomeriumConfigValidation, msg)
 	}
 	s.Event(obj, corev1.EventTypeNormal, reasonPomeriumConfigUpdated, msgPomeriumConfigUpdated)
@@ -113,7 +114,7 @@ func (s *SettingsEventReporter) SettingsUpdated(ctx context.Context, obj *icsv1.
 
 // SettingsRejected marks configuration was rejected
 func (s *SettingsEventReporter) SettingsRejected(ctx context.Context, obj *icsv1.Pomerium, err error) error {
-	for _, msg := range getConfigWarnings(ctx) {
+	for _, msg := range ConfigWarnings(ctx) {
 		s.Event(obj, corev1.EventTypeWarning, reasonPomeriumConfigValidation, msg)
 	}
 	s.Event(obj, corev1.EventTypeNormal, reasonPomeriumConfigUpdateError, err.Error())
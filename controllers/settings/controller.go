@@ -3,6 +3,8 @@ package settings
 import (
 	context "context"
 	"fmt"
+	"maps"
+	"slices"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -18,6 +20,7 @@ import (
 	"github.com/pomerium/pomerium/pkg/health"
 
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/certificate"
 	"github.com/pomerium/ingress-controller/controllers/deps"
 	"github.com/pomerium/ingress-controller/controllers/reporter"
 	"github.com/pomerium/ingress-controller/model"
@@ -45,6 +48,8 @@ type settingsController struct {
 	reporter.MultiPomeriumStatusReporter
 	// emitWarnings related to configuration. as there are multiple controllers running, not all should report
 	emitWarnings bool
+	// certificateMonitor, if set, tracks the expiry of the certificates from settings
+	certificateMonitor *certificate.Monitor
 
 	ctrlCheck health.Check
 }
@@ -58,6 +63,7 @@ func NewSettingsController(
 	controllerName string,
 	emitWarnings bool,
 	check health.Check,
+	monitor *certificate.Monitor,
 ) error {
 	if name.Namespace != "" {
 		return fmt.Errorf("pomerium CRD is cluster-scoped")
@@ -89,8 +95,9 @@ func NewSettingsController(
 			},
 			&reporter.SettingsLogReporter{},
 		},
-		emitWarnings: emitWarnings,
-		ctrlCheck:    check,
+		emitWarnings:       emitWarnings,
+		certificateMonitor: monitor,
+		ctrlCheck:          check,
 	}
 	secretKind := generic.GVKForType[*corev1.Secret](mgr.GetScheme()).Kind
 	err := ctrl.NewControllerManagedBy(mgr).
@@ -154,6 +161,7 @@ func (c *settingsController) Reconcile(ctx context.Context, req ctrl.Request) (c
 	if changed || !statusUpToDate(&cfg.Pomerium, true) {
 		c.SettingsUpdated(ctx, &cfg.Pomerium)
 	}
+	c.certificateMonitor.Observe(ctx, "Pomerium "+cfg.Name, slices.Collect(maps.Values(cfg.Certs)))
 
 	return ctrl.Result{}, nil
 }
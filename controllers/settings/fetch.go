@@ -10,6 +10,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
 	"github.com/pomerium/ingress-controller/model"
 	"github.com/pomerium/ingress-controller/util"
 	"github.com/pomerium/pomerium/pkg/identity/oidc/hosted"
@@ -17,11 +18,17 @@ import (
 
 // FetchConfig returns
 func FetchConfig(ctx context.Context, client client.Client, name types.NamespacedName) (*model.Config, error) {
-	var cfg model.Config
-	if err := client.Get(ctx, name, &cfg.Pomerium); err != nil {
+	var pom icsv1.Pomerium
+	if err := client.Get(ctx, name, &pom); err != nil {
 		return nil, fmt.Errorf("get %s: %w", name, err)
 	}
+	return FetchConfigFor(ctx, client, &pom)
+}
 
+// FetchConfigFor returns the config of a Pomerium CRD object with the Secrets it references.
+// On error, the config is returned with the object and any Secrets that could be fetched.
+func FetchConfigFor(ctx context.Context, client client.Client, pom *icsv1.Pomerium) (*model.Config, error) {
+	cfg := model.Config{Pomerium: *pom}
 	if err := fetchConfigSecrets(ctx, client, &cfg); err != nil {
 		return &cfg, fmt.Errorf("secrets: %w", err)
 	}
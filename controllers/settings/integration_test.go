@@ -98,7 +98,7 @@ func (s *ControllerTestSuite) createTestController(ctx context.Context, reconcil
 		Scheme: s.Environment.Scheme,
 	})
 	s.NoError(err)
-	s.NoError(settings.NewSettingsController(mgr, reconciler, name, "test", false, health_ctrl.SettingsReconciler))
+	s.NoError(settings.NewSettingsController(mgr, reconciler, name, "test", false, health_ctrl.SettingsReconciler, nil))
 
 	go func() {
 		if err = mgr.Start(ctx); err != nil && ctx.Err() == nil {
@@ -0,0 +1,72 @@
+package settings
+
+import (
+	context "context"
+	"fmt"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	pom_cfg "github.com/pomerium/pomerium/config"
+
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+	"github.com/pomerium/ingress-controller/controllers/reporter"
+	"github.com/pomerium/ingress-controller/pomerium"
+	"github.com/pomerium/ingress-controller/util"
+)
+
+// NewSettingsWebhook registers a validating admission webhook for the Pomerium CRD. The settings
+// are applied as they would be on reconcile, and rejected with the resulting error, or admitted
+// with a warning if warnOnly.
+func NewSettingsWebhook(mgr ctrl.Manager, warnOnly bool) error {
+	err := ctrl.NewWebhookManagedBy(mgr, new(icsv1.Pomerium)).
+		WithValidator(&settingsValidator{Client: mgr.GetClient(), warnOnly: warnOnly}).
+		Complete()
+	if err != nil {
+		return fmt.Errorf("build settings webhook: %w", err)
+	}
+	return nil
+}
+
+type settingsValidator struct {
+	client.Client
+	warnOnly bool
+}
+
+var _ admission.Validator[*icsv1.Pomerium] = (*settingsValidator)(nil)
+
+// ValidateCreate implements admission.Validator
+func (v *settingsValidator) ValidateCreate(ctx context.Context, obj *icsv1.Pomerium) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+// ValidateUpdate implements admission.Validator
+func (v *settingsValidator) ValidateUpdate(ctx context.Context, _, obj *icsv1.Pomerium) (admission.Warnings, error) {
+	return v.validate(ctx, obj)
+}
+
+// ValidateDelete implements admission.Validator
+func (v *settingsValidator) ValidateDelete(context.Context, *icsv1.Pomerium) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *settingsValidator) validate(ctx context.Context, obj *icsv1.Pomerium) (admission.Warnings, error) {
+	if obj.DeletionTimestamp != nil {
+		return nil, nil
+	}
+
+	ctx = util.WithBin[pom_cfg.FieldMsg](ctx)
+	if deprecations, err := icsv1.GetDeprecations(&obj.Spec); err == nil {
+		util.Add(ctx, deprecations...)
+	}
+
+	// the Secrets may be created after the Pomerium CRD, as by a single apply
+	cfg, err := FetchConfigFor(ctx, v.Client, obj)
+	if err != nil {
+		return append(reporter.ConfigWarnings(ctx),
+			fmt.Sprintf("settings were not validated, as referenced Secrets could not be fetched: %v", err)), nil
+	}
+	err = pomerium.ValidateSettings(ctx, cfg)
+	return util.AdmissionResult(err, v.warnOnly, reporter.ConfigWarnings(ctx)...)
+}
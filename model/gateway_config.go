@@ -2,7 +2,10 @@
 package model
 
 import (
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
@@ -10,11 +13,22 @@ import (
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
 )
 
+// GatewayEndpointUpstreamAnnotation may be set to "true" on a Gateway API route to use the
+// individual endpoints of each backend Service as upstreams, instead of the Service DNS name.
+// This corresponds to the default Ingress behavior (see UseServiceProxy).
+const GatewayEndpointUpstreamAnnotation = "gateway.pomerium.io/endpoint_upstream"
+
 // GatewayConfig represents the entirety of the Gateway-defined configuration.
 type GatewayConfig struct {
 	Routes           []GatewayHTTPRouteConfig
+	GRPCRoutes       []GatewayGRPCRouteConfig
+	TCPRoutes        []GatewayTCPRouteConfig
+	UDPRoutes        []GatewayUDPRouteConfig
 	Certificates     []*corev1.Secret
 	ExtensionFilters map[ExtensionFilterKey]ExtensionFilter
+
+	// BackendTLS holds the upstream TLS settings from all accepted BackendTLSPolicies.
+	BackendTLS map[BackendTLSKey]*BackendTLSConfig
 }
 
 // GatewayHTTPRouteConfig represents a single Gateway-defined route together
@@ -22,15 +36,139 @@ type GatewayConfig struct {
 type GatewayHTTPRouteConfig struct {
 	*gateway_v1.HTTPRoute
 
-	// Hostnames this route should match. This may differ from the list of Hostnames in the
-	// HTTPRoute Spec depending on the Gateway configuration. "All" is represented as "*".
-	Hostnames []gateway_v1.Hostname
+	// Addresses of the listeners this route is attached to. The hostnames may differ from the
+	// list of Hostnames in the HTTPRoute Spec depending on the Gateway configuration. "All" is
+	// represented as "*".
+	Addresses []GatewayListenerAddress
+
+	// ValidBackendRefs determines which BackendRefs are allowed to be used for route "To" URLs.
+	ValidBackendRefs BackendRefChecker
+
+	// Services is a map of all known services in the cluster.
+	Services map[types.NamespacedName]*corev1.Service
+
+	// EndpointSlices are all known endpoint slices in the cluster, keyed by service name.
+	EndpointSlices map[types.NamespacedName][]*discoveryv1.EndpointSlice
+
+	// Defaults are the route defaults from the parameters of the Gateway's GatewayClass, if any.
+	Defaults *GatewayRouteDefaults
+
+	// Fingerprint is a digest of all inputs used to translate this route. Routes with the same
+	// Fingerprint translate to the same Pomerium routes, so reconcilers may reuse the result of
+	// a previous translation.
+	Fingerprint string
+}
+
+// GatewayGRPCRouteConfig represents a single Gateway-defined gRPC route together
+// with all objects needed to translate it into Pomerium routes.
+type GatewayGRPCRouteConfig struct {
+	*gateway_v1.GRPCRoute
+
+	// Addresses of the listeners this route is attached to. The hostnames may differ from the
+	// list of Hostnames in the GRPCRoute Spec depending on the Gateway configuration. "All" is
+	// represented as "*".
+	Addresses []GatewayListenerAddress
+
+	// ValidBackendRefs determines which BackendRefs are allowed to be used for route "To" URLs.
+	ValidBackendRefs BackendRefChecker
+
+	// Services is a map of all known services in the cluster.
+	Services map[types.NamespacedName]*corev1.Service
+
+	// EndpointSlices are all known endpoint slices in the cluster, keyed by service name.
+	EndpointSlices map[types.NamespacedName][]*discoveryv1.EndpointSlice
+
+	// Defaults are the route defaults from the parameters of the Gateway's GatewayClass, if any.
+	Defaults *GatewayRouteDefaults
+
+	// Fingerprint is a digest of all inputs used to translate this route. Routes with the same
+	// Fingerprint translate to the same Pomerium routes, so reconcilers may reuse the result of
+	// a previous translation.
+	Fingerprint string
+}
+
+// GatewayTCPRouteConfig represents a single Gateway-defined TCP route together
+// with all objects needed to translate it into Pomerium TCP tunnel routes.
+type GatewayTCPRouteConfig struct {
+	*gateway_v1.TCPRoute
+
+	// Addresses of the listeners this route is attached to.
+	Addresses []GatewayListenerAddress
 
 	// ValidBackendRefs determines which BackendRefs are allowed to be used for route "To" URLs.
 	ValidBackendRefs BackendRefChecker
 
 	// Services is a map of all known services in the cluster.
 	Services map[types.NamespacedName]*corev1.Service
+
+	// EndpointSlices are all known endpoint slices in the cluster, keyed by service name.
+	EndpointSlices map[types.NamespacedName][]*discoveryv1.EndpointSlice
+
+	// Fingerprint is a digest of all inputs used to translate this route. Routes with the same
+	// Fingerprint translate to the same Pomerium routes, so reconcilers may reuse the result of
+	// a previous translation.
+	Fingerprint string
+}
+
+// GatewayUDPRouteConfig represents a single Gateway-defined UDP route together
+// with all objects needed to translate it into Pomerium UDP tunnel routes.
+type GatewayUDPRouteConfig struct {
+	*gateway_v1.UDPRoute
+
+	// Addresses of the listeners this route is attached to.
+	Addresses []GatewayListenerAddress
+
+	// ValidBackendRefs determines which BackendRefs are allowed to be used for route "To" URLs.
+	ValidBackendRefs BackendRefChecker
+
+	// Services is a map of all known services in the cluster.
+	Services map[types.NamespacedName]*corev1.Service
+
+	// EndpointSlices are all known endpoint slices in the cluster, keyed by service name.
+	EndpointSlices map[types.NamespacedName][]*discoveryv1.EndpointSlice
+
+	// Fingerprint is a digest of all inputs used to translate this route. Routes with the same
+	// Fingerprint translate to the same Pomerium routes, so reconcilers may reuse the result of
+	// a previous translation.
+	Fingerprint string
+}
+
+// GatewayListenerAddress is the protocol, hostname and port a route is exposed on through a single
+// Gateway listener. The port is the port clients connect to (for TCP and UDP listeners, the port
+// clients request when opening a tunnel over the Pomerium HTTPS port), rather than a port Pomerium
+// listens on.
+type GatewayListenerAddress struct {
+	Protocol gateway_v1.ProtocolType
+	Hostname gateway_v1.Hostname
+	Port     gateway_v1.PortNumber
+}
+
+// GatewayRouteDefaults holds the defaults from a GatewayClassConfig, applied to all HTTP and gRPC
+// routes of Gateways of the corresponding GatewayClass.
+type GatewayRouteDefaults struct {
+	// PolicyFilter is applied to any route rule without a PolicyFilter of its own.
+	PolicyFilter *ExtensionFilterKey
+	// Timeout is the upstream timeout.
+	Timeout *time.Duration
+	// IdleTimeout is the upstream idle timeout.
+	IdleTimeout *time.Duration
+}
+
+// BackendTLSKey identifies the Service port a BackendTLSPolicy applies to. An empty SectionName
+// refers to all ports of the Service.
+type BackendTLSKey struct {
+	types.NamespacedName
+	SectionName string
+}
+
+// BackendTLSConfig holds the upstream TLS settings from a single BackendTLSPolicy.
+type BackendTLSConfig struct {
+	// Hostname is used for SNI and to validate the upstream certificate.
+	Hostname string
+
+	// CACertificates holds PEM-encoded CA certificates used to validate the upstream
+	// certificate. If empty, the system CA certificates are used.
+	CACertificates []byte
 }
 
 // BackendRefChecker is used to determine which BackendRefs are valid.
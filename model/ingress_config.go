@@ -3,12 +3,15 @@ package model
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
 	"github.com/pomerium/ingress-controller/util"
 )
@@ -95,6 +98,9 @@ const (
 	UpstreamTunnel = "upstream_tunnel"
 	// UpstreamTunnelSSHPolicy sets the upstream tunnel ssh policy property.
 	UpstreamTunnelSSHPolicy = "upstream_tunnel_ssh_policy"
+	// PolicyRef attaches the policies of one or more PolicyFilters, given as a comma-separated
+	// list of names in the namespace of the Ingress, in addition to any policy annotations
+	PolicyRef = "policy_ref"
 )
 
 // SSHSecrets is a grouping of ssh-related secrets.
@@ -176,9 +182,16 @@ type Config struct {
 type IngressConfig struct {
 	AnnotationPrefix string
 	*networkingv1.Ingress
-	Endpoints map[types.NamespacedName]*corev1.Endpoints
-	Secrets   map[types.NamespacedName]*corev1.Secret
-	Services  map[types.NamespacedName]*corev1.Service
+	// EndpointSlices are all slices that belong to a service, keyed by the service name
+	EndpointSlices map[types.NamespacedName][]*discoveryv1.EndpointSlice
+	Secrets        map[types.NamespacedName]*corev1.Secret
+	Services       map[types.NamespacedName]*corev1.Service
+	// IngressClass is the class of the Ingress, if known
+	IngressClass *networkingv1.IngressClass
+	// IngressNamespace is the namespace of the Ingress, if fetched
+	IngressNamespace *corev1.Namespace
+	// PolicyFilters are the PolicyFilters referenced by the PolicyRef annotation
+	PolicyFilters map[types.NamespacedName]*icgv1alpha1.PolicyFilter
 }
 
 // IsAnnotationSet checks if a boolean annotation is set to true
@@ -216,6 +229,40 @@ func (ic *IngressConfig) UseServiceProxy() bool {
 	return ic.IsAnnotationSet(UseServiceProxy)
 }
 
+// GetPolicyRefs returns the names of the PolicyFilters referenced by the PolicyRef annotation
+func (ic *IngressConfig) GetPolicyRefs() ([]types.NamespacedName, error) {
+	value, ok := ic.Ingress.Annotations[fmt.Sprintf("%s/%s", ic.AnnotationPrefix, PolicyRef)]
+	if !ok {
+		return nil, nil
+	}
+	names, err := ParsePolicyRefs(value)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]types.NamespacedName, 0, len(names))
+	for _, name := range names {
+		refs = append(refs, ic.GetNamespacedName(name))
+	}
+	return refs, nil
+}
+
+// ParsePolicyRefs parses the value of the PolicyRef annotation, a comma-separated list of
+// PolicyFilter names; duplicate names are dropped
+func ParsePolicyRefs(value string) ([]string, error) {
+	var names []string
+	for name := range strings.SplitSeq(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || slices.Contains(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: expected a comma-separated list of PolicyFilter names", PolicyRef)
+	}
+	return names, nil
+}
+
 // GetNamespacedName returns namespaced name of a resource
 func (ic *IngressConfig) GetNamespacedName(name string) types.NamespacedName {
 	return types.NamespacedName{Namespace: ic.Ingress.Namespace, Name: name}
@@ -258,9 +305,26 @@ func (ic *IngressConfig) Clone() *IngressConfig {
 	dst := &IngressConfig{
 		AnnotationPrefix: ic.AnnotationPrefix,
 		Ingress:          ic.Ingress.DeepCopy(),
-		Endpoints:        make(map[types.NamespacedName]*corev1.Endpoints, len(ic.Endpoints)),
+		EndpointSlices:   make(map[types.NamespacedName][]*discoveryv1.EndpointSlice, len(ic.EndpointSlices)),
 		Secrets:          make(map[types.NamespacedName]*corev1.Secret, len(ic.Secrets)),
 		Services:         make(map[types.NamespacedName]*corev1.Service, len(ic.Services)),
+		IngressClass:     ic.IngressClass.DeepCopy(),
+		IngressNamespace: ic.IngressNamespace.DeepCopy(),
+	}
+
+	if ic.PolicyFilters != nil {
+		dst.PolicyFilters = make(map[types.NamespacedName]*icgv1alpha1.PolicyFilter, len(ic.PolicyFilters))
+		for k, v := range ic.PolicyFilters {
+			dst.PolicyFilters[k] = v.DeepCopy()
+		}
+	}
+
+	for k, v := range ic.EndpointSlices {
+		slices := make([]*discoveryv1.EndpointSlice, 0, len(v))
+		for _, slice := range v {
+			slices = append(slices, slice.DeepCopy())
+		}
+		dst.EndpointSlices[k] = slices
 	}
 
 	for k, v := range ic.Secrets {
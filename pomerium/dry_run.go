@@ -0,0 +1,247 @@
+package pomerium
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+	"github.com/pomerium/pomerium/pkg/grpc/databroker"
+)
+
+// ConfigChangeAction is the kind of a ConfigChange.
+type ConfigChangeAction string
+
+const (
+	// ConfigChangeCreate is the creation of an object.
+	ConfigChangeCreate ConfigChangeAction = "create"
+	// ConfigChangeUpdate is an update to an existing object.
+	ConfigChangeUpdate ConfigChangeAction = "update"
+	// ConfigChangeDelete is the deletion of an existing object.
+	ConfigChangeDelete ConfigChangeAction = "delete"
+)
+
+// ConfigChange is a change to the Pomerium configuration that a reconciler in dry-run mode
+// would have made.
+type ConfigChange struct {
+	Action ConfigChangeAction `json:"action"`
+	// Kind is the kind of the changed object: route, policy, keypair or settings.
+	Kind string `json:"kind"`
+	// Record is the ID of the changed databroker config record, in databroker mode.
+	Record string `json:"record,omitempty"`
+	// ID is the ID of the changed object, if it has one.
+	ID string `json:"id,omitempty"`
+	// Name is the name of the changed object, if it has one.
+	Name string `json:"name,omitempty"`
+	// Diff is a human-readable diff of the object, with private keys and credentials redacted.
+	Diff string `json:"diff,omitempty"`
+}
+
+// DryRunFunc is called with each change that a reconciler in dry-run mode would have made.
+// It may be called concurrently.
+type DryRunFunc func(ctx context.Context, change ConfigChange)
+
+// LogConfigChange is a DryRunFunc that logs each change.
+func LogConfigChange(ctx context.Context, change ConfigChange) {
+	log.FromContext(ctx).Info("dry run: configuration change not applied",
+		"action", change.Action,
+		"kind", change.Kind,
+		"record", change.Record,
+		"id", change.ID,
+		"name", change.Name,
+		"diff", change.Diff)
+}
+
+// NewDryRunDataBrokerClient wraps client so that Put requests are not made, and instead each
+// route and settings change that they would have made to the Pomerium config records is passed
+// to fn. All other requests, including reads and leases, are made as usual.
+func NewDryRunDataBrokerClient(
+	client databroker.DataBrokerServiceClient,
+	fn DryRunFunc,
+) databroker.DataBrokerServiceClient {
+	return &dryRunDataBrokerClient{DataBrokerServiceClient: client, fn: fn}
+}
+
+type dryRunDataBrokerClient struct {
+	databroker.DataBrokerServiceClient
+	fn DryRunFunc
+}
+
+func (c *dryRunDataBrokerClient) Put(
+	ctx context.Context, req *databroker.PutRequest, _ ...grpc.CallOption,
+) (*databroker.PutResponse, error) {
+	for _, record := range req.GetRecords() {
+		next := new(pb.Config)
+		if record.GetDeletedAt() == nil {
+			if err := record.GetData().UnmarshalTo(next); err != nil {
+				return nil, fmt.Errorf("dry run: unmarshal record %s: %w", record.GetId(), err)
+			}
+		}
+		prev, err := (&DataBrokerReconciler{
+			ConfigID:                record.GetId(),
+			DataBrokerServiceClient: c.DataBrokerServiceClient,
+		}).getConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range diffConfig(prev, next) {
+			change.Record = record.GetId()
+			c.fn(ctx, change)
+		}
+	}
+	return new(databroker.PutResponse), nil
+}
+
+// diffConfig returns the route and settings changes from prev to next.
+func diffConfig(prev, next *pb.Config) []ConfigChange {
+	var changes []ConfigChange
+
+	prevRoutes, nextRoutes := routesByKey(prev.GetRoutes()), routesByKey(next.GetRoutes())
+	keys := slices.Collect(maps.Keys(prevRoutes))
+	for key := range nextRoutes {
+		if _, ok := prevRoutes[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		p, n := prevRoutes[key], nextRoutes[key]
+		var action ConfigChangeAction
+		route := n
+		switch {
+		case p == nil:
+			action = ConfigChangeCreate
+		case n == nil:
+			action, route = ConfigChangeDelete, p
+		case !proto.Equal(p, n):
+			action = ConfigChangeUpdate
+		default:
+			continue
+		}
+		changes = append(changes, ConfigChange{
+			Action: action,
+			Kind:   string(apiKindRoute),
+			ID:     route.GetId(),
+			Name:   routeDisplayName(route),
+			Diff:   redactedDiff(p, n),
+		})
+	}
+
+	if p, n := prev.GetSettings(), next.GetSettings(); !proto.Equal(p, n) {
+		action := ConfigChangeUpdate
+		if p == nil {
+			action = ConfigChangeCreate
+		} else if n == nil {
+			action = ConfigChangeDelete
+		}
+		changes = append(changes, ConfigChange{
+			Action: action,
+			Kind:   string(apiKindSettings),
+			Diff:   redactedDiff(p, n),
+		})
+	}
+	return changes
+}
+
+// routesByKey indexes routes by their ID, name or else their from URL, which are not
+// necessarily unique.
+func routesByKey(routes []*pb.Route) map[string]*pb.Route {
+	m := make(map[string]*pb.Route, len(routes))
+	for _, route := range routes {
+		key := route.GetId()
+		if key == "" {
+			key = routeDisplayName(route)
+		}
+		for i, k := 2, key; ; i++ {
+			if _, ok := m[k]; !ok {
+				m[k] = route
+				break
+			}
+			k = fmt.Sprintf("%s#%d", key, i)
+		}
+	}
+	return m
+}
+
+func routeDisplayName(route *pb.Route) string {
+	if name := route.GetName(); name != "" {
+		return name
+	}
+	return route.GetFrom()
+}
+
+// redactedDiff returns a human-readable diff from prev to next, either of which may be nil,
+// with private keys and credentials redacted.
+func redactedDiff[T proto.Message](prev, next T) string {
+	return cmp.Diff(redacted(prev), redacted(next), protocmp.Transform())
+}
+
+// redacted returns a redacted copy of m, or an empty message of the same type if m is nil.
+func redacted[T proto.Message](m T) proto.Message {
+	if !m.ProtoReflect().IsValid() {
+		return m.ProtoReflect().Type().New().Interface()
+	}
+	c := proto.Clone(m)
+	redactMessage(c.ProtoReflect())
+	return c
+}
+
+// redactMessage replaces the values of the string and bytes fields of m and its nested messages
+// that hold private keys or credentials, as told by their names.
+func redactMessage(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			if fd.Message() != nil {
+				for i, l := 0, v.List(); i < l.Len(); i++ {
+					redactMessage(l.Get(i).Message())
+				}
+			}
+		case fd.IsMap():
+			if fd.MapValue().Message() != nil {
+				v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+					redactMessage(v.Message())
+					return true
+				})
+			}
+		case fd.Message() != nil:
+			redactMessage(v.Message())
+		case !isSensitiveField(fd):
+			// keep the value
+		case fd.Kind() == protoreflect.StringKind && v.String() != "":
+			m.Set(fd, protoreflect.ValueOfString(RedactedValue))
+		case fd.Kind() == protoreflect.BytesKind && len(v.Bytes()) > 0:
+			m.Set(fd, protoreflect.ValueOfBytes([]byte(RedactedValue)))
+		}
+		return true
+	})
+}
+
+// sensitiveFieldSuffixes are the suffixes of the names of fields holding private keys or
+// credentials, such as tls_client_key, idp_client_secret or shared_secret.
+var sensitiveFieldSuffixes = []string{
+	"_secret", "_key", "_token", "key_bytes", "password", "basic_auth", "connection_string",
+	"service_account",
+}
+
+func isSensitiveField(fd protoreflect.FieldDescriptor) bool {
+	name := string(fd.Name())
+	if name == "key" {
+		return true
+	}
+	for _, suffix := range sensitiveFieldSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package pomerium
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+	"github.com/pomerium/pomerium/pkg/grpc/databroker"
+	"github.com/pomerium/pomerium/pkg/protoutil"
+
+	controllers_mock "github.com/pomerium/ingress-controller/controllers/mock"
+)
+
+type fakeDataBrokerClient struct {
+	databroker.DataBrokerServiceClient
+	configs map[string]*pb.Config
+}
+
+func (c *fakeDataBrokerClient) Get(
+	_ context.Context, req *databroker.GetRequest, _ ...grpc.CallOption,
+) (*databroker.GetResponse, error) {
+	cfg, ok := c.configs[req.GetId()]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "not found")
+	}
+	return &databroker.GetResponse{
+		Record: &databroker.Record{Id: req.GetId(), Data: protoutil.NewAny(cfg)},
+	}, nil
+}
+
+func TestDryRunDataBrokerClient(t *testing.T) {
+	t.Parallel()
+
+	prev := &pb.Config{Routes: []*pb.Route{
+		{Id: new("a"), From: "https://a.localhost.pomerium.io", To: []string{"http://a"}},
+		{Id: new("b"), From: "https://b.localhost.pomerium.io", To: []string{"http://b"}},
+	}}
+	next := &pb.Config{
+		Routes: []*pb.Route{
+			{Id: new("a"), From: "https://a.localhost.pomerium.io", To: []string{"http://a"}, TlsClientKey: "private-key"},
+			{Name: new("c"), From: "https://c.localhost.pomerium.io", To: []string{"http://c"}},
+		},
+		Settings: &pb.Settings{IdpClientSecret: new("client-secret")},
+	}
+
+	var changes []ConfigChange
+	client := NewDryRunDataBrokerClient(&fakeDataBrokerClient{
+		configs: map[string]*pb.Config{IngressControllerConfigID: prev},
+	}, func(_ context.Context, change ConfigChange) {
+		changes = append(changes, change)
+	})
+
+	_, err := client.Put(t.Context(), &databroker.PutRequest{
+		Records: []*databroker.Record{{
+			Id:   IngressControllerConfigID,
+			Data: protoutil.NewAny(next),
+		}, {
+			Id:        GatewayControllerConfigID,
+			Data:      protoutil.NewAny(new(pb.Config)),
+			DeletedAt: timestamppb.Now(),
+		}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, changes, 4)
+	for _, change := range changes {
+		assert.Equal(t, IngressControllerConfigID, change.Record)
+		assert.NotContains(t, change.Diff, "private-key")
+		assert.NotContains(t, change.Diff, "client-secret")
+	}
+	assert.Equal(t, []ConfigChangeAction{
+		ConfigChangeUpdate, ConfigChangeDelete, ConfigChangeCreate, ConfigChangeCreate,
+	}, []ConfigChangeAction{changes[0].Action, changes[1].Action, changes[2].Action, changes[3].Action})
+	assert.Equal(t, "a", changes[0].ID)
+	assert.Contains(t, changes[0].Diff, RedactedValue)
+	assert.Equal(t, "b", changes[1].ID)
+	assert.Equal(t, "c", changes[2].Name)
+	assert.Equal(t, "settings", changes[3].Kind)
+}
+
+func TestDryRunAPIClient(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	apiClient := controllers_mock.NewMockSDKClient(ctrl)
+
+	var changes []ConfigChange
+	client := newDryRunAPIClient(apiClient, func(_ context.Context, change ConfigChange) {
+		changes = append(changes, change)
+	})
+	ctx := t.Context()
+
+	// Creates are reported, and the created objects are then not found.
+	created, err := client.CreateRoute(ctx, connect.NewRequest(&pb.CreateRouteRequest{
+		Route: &pb.Route{Name: new("new-route"), From: "https://new.localhost.pomerium.io"},
+	}))
+	require.NoError(t, err)
+	_, err = client.GetRoute(ctx, connect.NewRequest(&pb.GetRouteRequest{Id: created.Msg.Route.GetId()}))
+	assert.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+
+	// Updates are diffed against the existing object, masked as on sync.
+	apiClient.EXPECT().GetRoute(ctx, RequestEq(&pb.GetRouteRequest{Id: "route-1"})).Return(
+		connect.NewResponse(&pb.GetRouteResponse{Route: &pb.Route{
+			Id:         new("route-1"),
+			Name:       new("route"),
+			From:       "https://route.localhost.pomerium.io",
+			To:         []string{"http://old"},
+			ModifiedAt: timestamppb.Now(),
+		}}), nil)
+	_, err = client.UpdateRoute(ctx, connect.NewRequest(&pb.UpdateRouteRequest{Route: &pb.Route{
+		Id:   new("route-1"),
+		Name: new("route"),
+		From: "https://route.localhost.pomerium.io",
+		To:   []string{"http://new"},
+	}}))
+	require.NoError(t, err)
+
+	// Deletes are diffed against the existing object, with private keys redacted.
+	apiClient.EXPECT().GetKeyPair(ctx, RequestEq(&pb.GetKeyPairRequest{Id: "keypair-1"})).Return(
+		connect.NewResponse(&pb.GetKeyPairResponse{KeyPair: &pb.KeyPair{
+			Id:   new("keypair-1"),
+			Name: new("keypair"),
+			Key:  []byte("private-key"),
+		}}), nil)
+	_, err = client.DeleteKeyPair(ctx, connect.NewRequest(&pb.DeleteKeyPairRequest{Id: "keypair-1"}))
+	require.NoError(t, err)
+
+	require.Len(t, changes, 3)
+	assert.Equal(t, ConfigChangeCreate, changes[0].Action)
+	assert.Equal(t, "new-route", changes[0].Name)
+
+	assert.Equal(t, ConfigChangeUpdate, changes[1].Action)
+	assert.Equal(t, "route-1", changes[1].ID)
+	assert.Contains(t, changes[1].Diff, "http://new")
+	assert.NotContains(t, changes[1].Diff, "modified_at")
+
+	assert.Equal(t, ConfigChangeDelete, changes[2].Action)
+	assert.Equal(t, "keypair", changes[2].Name)
+	assert.NotContains(t, changes[2].Diff, "private-key")
+}
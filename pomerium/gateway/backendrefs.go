@@ -1,23 +1,113 @@
 package gateway
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/util"
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
 )
 
+// Well-known Service port appProtocol values, from
+// https://kubernetes.io/docs/concepts/services-networking/service/#application-protocol
+const (
+	appProtocolHTTPS = "https"
+	appProtocolH2C   = "kubernetes.io/h2c"
+	appProtocolWS    = "kubernetes.io/ws"
+	appProtocolWSS   = "kubernetes.io/wss"
+)
+
+// routeBackends holds everything needed to translate the backendRefs of a single route,
+// independent of the route kind.
+type routeBackends struct {
+	route            client.Object
+	validBackendRefs model.BackendRefChecker
+	services         map[types.NamespacedName]*corev1.Service
+	endpointSlices   map[types.NamespacedName][]*discoveryv1.EndpointSlice
+
+	// upstreamScheme is the default scheme of the resulting "To" URLs.
+	upstreamScheme string
+
+	// backendTLS holds BackendTLSPolicy settings. If nil, Service port appProtocol values are
+	// also ignored and upstreamScheme is always used (as for TCP and UDP tunnels).
+	backendTLS map[model.BackendTLSKey]*model.BackendTLSConfig
+}
+
+func httpRouteBackends(
+	config *model.GatewayConfig,
+	gc *model.GatewayHTTPRouteConfig,
+) *routeBackends {
+	return &routeBackends{
+		route:            gc.HTTPRoute,
+		validBackendRefs: gc.ValidBackendRefs,
+		services:         gc.Services,
+		endpointSlices:   gc.EndpointSlices,
+		upstreamScheme:   "http",
+		backendTLS:       nonNilBackendTLS(config.BackendTLS),
+	}
+}
+
+func grpcRouteBackends(
+	config *model.GatewayConfig,
+	gc *model.GatewayGRPCRouteConfig,
+) *routeBackends {
+	// gRPC requires HTTP/2 all the way to the upstream, so use cleartext HTTP/2 by default.
+	return &routeBackends{
+		route:            gc.GRPCRoute,
+		validBackendRefs: gc.ValidBackendRefs,
+		services:         gc.Services,
+		endpointSlices:   gc.EndpointSlices,
+		upstreamScheme:   "h2c",
+		backendTLS:       nonNilBackendTLS(config.BackendTLS),
+	}
+}
+
+func tcpRouteBackends(gc *model.GatewayTCPRouteConfig) *routeBackends {
+	return &routeBackends{
+		route:            gc.TCPRoute,
+		validBackendRefs: gc.ValidBackendRefs,
+		services:         gc.Services,
+		endpointSlices:   gc.EndpointSlices,
+		upstreamScheme:   "tcp",
+	}
+}
+
+func udpRouteBackends(gc *model.GatewayUDPRouteConfig) *routeBackends {
+	return &routeBackends{
+		route:            gc.UDPRoute,
+		validBackendRefs: gc.ValidBackendRefs,
+		services:         gc.Services,
+		endpointSlices:   gc.EndpointSlices,
+		upstreamScheme:   "udp",
+	}
+}
+
+func nonNilBackendTLS(
+	m map[model.BackendTLSKey]*model.BackendTLSConfig,
+) map[model.BackendTLSKey]*model.BackendTLSConfig {
+	if m == nil {
+		return map[model.BackendTLSKey]*model.BackendTLSConfig{}
+	}
+	return m
+}
+
 // applyBackendRefs translates backendRefs to a weighted set of Pomerium "To" URLs.
 // [applyFilters] must be called prior to this method.
 func applyBackendRefs(
 	route *pb.Route,
-	gc *model.GatewayHTTPRouteConfig,
-	backendRefs []gateway_v1.HTTPBackendRef,
+	b *routeBackends,
+	backendRefs []*gateway_v1.BackendRef,
 ) {
 	// From the spec: "BackendRefs defines API objects where matching requests should be sent. If
 	// unspecified, the rule performs no forwarding. If unspecified and no filters are specified
@@ -30,14 +120,14 @@ func applyBackendRefs(
 		return
 	}
 
-	for i := range backendRefs {
-		if !gc.ValidBackendRefs.Valid(gc.HTTPRoute, &backendRefs[i].BackendRef) {
-			continue
-		}
-		if u, w := backendRefToToURLAndWeight(gc, &backendRefs[i]); w > 0 {
-			route.To = append(route.To, u)
-			route.LoadBalancingWeights = append(route.LoadBalancingWeights, w)
+	if err := appendBackendRefs(route, b, backendRefs); err != nil {
+		route.To = nil
+		route.LoadBalancingWeights = nil
+		route.Response = &pb.RouteDirectResponse{
+			Status: http.StatusInternalServerError,
+			Body:   err.Error(),
 		}
+		return
 	}
 
 	// From the spec: "If all entries in BackendRefs are invalid, and there are also no filters
@@ -51,38 +141,242 @@ func applyBackendRefs(
 	}
 }
 
-func backendRefToToURLAndWeight(
-	gc *model.GatewayHTTPRouteConfig,
-	br *gateway_v1.HTTPBackendRef,
-) (string, uint32) {
+// appendBackendRefs appends the "To" URLs and weights of all valid backendRefs, and applies the
+// corresponding upstream protocol settings. All backends of a single Pomerium route must share
+// the same protocol settings, so an error is returned if these differ.
+func appendBackendRefs(
+	route *pb.Route,
+	b *routeBackends,
+	backendRefs []*gateway_v1.BackendRef,
+) error {
+	var upstreams []upstream
+	for _, br := range backendRefs {
+		if !b.validBackendRefs.Valid(b.route, br) {
+			continue
+		}
+		u := backendRefToUpstream(b, br)
+		if u.weight == 0 {
+			continue
+		}
+		if len(upstreams) > 0 && !upstreams[0].sameProtocol(&u) {
+			return fmt.Errorf("backends use conflicting protocols")
+		}
+		upstreams = append(upstreams, u)
+	}
+	if len(upstreams) == 0 {
+		return nil
+	}
+
+	// A backendRef weight applies to the backend as a whole, so when a backend is represented by
+	// multiple endpoints, its weight is divided between them.
+	scale := endpointWeightScale(upstreams)
+	for _, u := range upstreams {
+		w := u.weight * scale / uint32(len(u.hosts)) //nolint:gosec
+		for _, h := range u.hosts {
+			route.To = append(route.To, u.scheme+"://"+h)
+			route.LoadBalancingWeights = append(route.LoadBalancingWeights, max(w, 1))
+		}
+	}
+
+	upstreams[0].applyToRoute(route)
+	return nil
+}
+
+// maxEndpointWeightScale limits the scaling of backendRef weights, to keep the resulting
+// weights well within range. Above this, weights are divided between endpoints approximately.
+const maxEndpointWeightScale = 1000
+
+// endpointWeightScale returns a factor by which to multiply all backendRef weights so that each
+// can be divided evenly between the endpoints of the backend: the least common multiple of all
+// endpoint counts, or maxEndpointWeightScale if that is lower.
+func endpointWeightScale(upstreams []upstream) uint32 {
+	scale := 1
+	for _, u := range upstreams {
+		n := len(u.hosts)
+		scale = scale / gcd(scale, n) * n
+		if scale > maxEndpointWeightScale {
+			return maxEndpointWeightScale
+		}
+	}
+	return uint32(scale) //nolint:gosec
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// upstream describes how to connect to a single backend.
+type upstream struct {
+	// hosts holds one "host:port" for the Service DNS name, or one per endpoint.
+	hosts      []string
+	weight     uint32
+	scheme     string
+	websockets bool
+	tls        *model.BackendTLSConfig
+
+	// serverName is the TLS server name to use in the absence of a BackendTLSPolicy.
+	serverName string
+}
+
+func (u *upstream) sameProtocol(other *upstream) bool {
+	return u.scheme == other.scheme && u.websockets == other.websockets && u.tls == other.tls &&
+		u.serverName == other.serverName
+}
+
+func (u *upstream) applyToRoute(route *pb.Route) {
+	if u.websockets {
+		route.AllowWebsockets = true
+	}
+	if u.tls != nil {
+		route.TlsServerName = u.tls.Hostname
+		if len(u.tls.CACertificates) > 0 {
+			route.TlsCustomCa = base64.StdEncoding.EncodeToString(u.tls.CACertificates)
+		}
+	} else if u.serverName != "" {
+		route.TlsServerName = u.serverName
+	}
+}
+
+func backendRefToUpstream(
+	b *routeBackends,
+	br *gateway_v1.BackendRef,
+) upstream {
 	// Note: currently the only supported backendRef kind is "Service".
-	namespace := gc.Namespace
+	namespace := b.route.GetNamespace()
 	if br.Namespace != nil {
 		namespace = string(*br.Namespace)
 	}
+	serviceName := types.NamespacedName{Namespace: namespace, Name: string(br.Name)}
+	svc := b.services[serviceName]
+	slices := b.endpointSlices[serviceName]
 
-	port := *br.Port
+	// The controller reports backendRefs with a port that cannot be resolved as invalid, so any
+	// error here can only be due to incomplete configuration; use the port as specified.
+	servicePort, port, err := ResolveServicePort(svc, slices, *br.Port)
+	if err != nil {
+		port = int32(*br.Port)
+	}
 
-	// For a headless service we need the targetPort instead.
-	// For now this supports only port numbers, not named ports, but this is enough to pass the
-	// HTTPRouteServiceTypes conformance test cases.
-	svc := gc.Services[types.NamespacedName{Namespace: namespace, Name: string(br.Name)}]
-	if svc != nil && svc.Spec.ClusterIP == "None" {
-		for i := range svc.Spec.Ports {
-			p := &svc.Spec.Ports[i]
-			if p.Port == port && p.TargetPort.Type == intstr.Int {
-				port = p.TargetPort.IntVal
-				break
-			}
+	u := upstream{
+		weight: 1,
+		scheme: b.upstreamScheme,
+	}
+	if br.Weight != nil {
+		u.weight = uint32(*br.Weight) //nolint:gosec
+	}
+	if b.backendTLS != nil {
+		applyUpstreamProtocol(&u, b.backendTLS, serviceName, servicePort)
+	}
+
+	dnsName := fmt.Sprintf("%s.%s.svc.cluster.local", br.Name, namespace)
+	if servicePort != nil && useEndpointUpstream(b.route) {
+		u.hosts = util.EndpointSliceHosts(slices, util.EndpointPortNameMatcher(servicePort.Name))
+	}
+	// If no endpoints are ready, fall back to the Kubernetes DNS name.
+	if len(u.hosts) == 0 {
+		u.hosts = []string{net.JoinHostPort(dnsName, strconv.Itoa(int(port)))}
+	} else if u.tls == nil && u.scheme == "https" {
+		// Endpoint addresses are not valid TLS server names.
+		u.serverName = dnsName
+	}
+
+	return u
+}
+
+// useEndpointUpstream reports whether a route should use the individual endpoints of a Service
+// as upstreams, instead of the Service DNS name.
+func useEndpointUpstream(route client.Object) bool {
+	return route.GetAnnotations()[model.GatewayEndpointUpstreamAnnotation] == "true"
+}
+
+// ResolveServicePort finds the Service port with the given port number, and returns it together
+// with the port number to use with the Service DNS name. For a headless Service this is the
+// targetPort, which is resolved using the Service EndpointSlices if it refers to a named
+// container port. An ExternalName Service need not declare any ports, so the port number is used
+// as is, with a nil ServicePort.
+func ResolveServicePort(
+	svc *corev1.Service,
+	slices []*discoveryv1.EndpointSlice,
+	port gateway_v1.PortNumber,
+) (*corev1.ServicePort, int32, error) {
+	if svc == nil {
+		return nil, 0, fmt.Errorf("service not found")
+	}
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return nil, int32(port), nil
+	}
+
+	var servicePort *corev1.ServicePort
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Port == int32(port) {
+			servicePort = &svc.Spec.Ports[i]
+			break
 		}
 	}
+	if servicePort == nil {
+		return nil, 0, fmt.Errorf("service has no port %d", port)
+	}
 
-	u := fmt.Sprintf("http://%s.%s.svc.cluster.local:%d", br.Name, namespace, port)
+	// A headless Service DNS name resolves to the endpoint addresses, so we need the targetPort.
+	if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		return servicePort, servicePort.Port, nil
+	}
+	switch tp := servicePort.TargetPort; {
+	case tp.Type == intstr.Int && tp.IntVal == 0:
+		return servicePort, servicePort.Port, nil
+	case tp.Type == intstr.Int:
+		return servicePort, tp.IntVal, nil
+	}
+	// EndpointSlice ports carry the resolved number of a named targetPort.
+	match := util.EndpointPortNameMatcher(servicePort.Name)
+	for _, slice := range slices {
+		for _, p := range slice.Ports {
+			if p.Port != nil && match(p) {
+				return servicePort, *p.Port, nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("couldn't resolve named target port %q of headless service",
+		servicePort.TargetPort.StrVal)
+}
 
-	weight := uint32(1)
-	if br.Weight != nil {
-		weight = uint32(*br.Weight) //nolint:gosec
+// applyUpstreamProtocol updates the upstream according to the Service port appProtocol and any
+// BackendTLSPolicy targeting the Service port.
+func applyUpstreamProtocol(
+	u *upstream,
+	backendTLS map[model.BackendTLSKey]*model.BackendTLSConfig,
+	serviceName types.NamespacedName,
+	servicePort *corev1.ServicePort,
+) {
+	var portName string
+	if servicePort != nil {
+		portName = servicePort.Name
+		if servicePort.AppProtocol != nil {
+			switch *servicePort.AppProtocol {
+			case appProtocolHTTPS:
+				u.scheme = "https"
+			case appProtocolH2C:
+				u.scheme = "h2c"
+			case appProtocolWS:
+				u.websockets = true
+			case appProtocolWSS:
+				u.scheme = "https"
+				u.websockets = true
+			}
+		}
 	}
 
-	return u, weight
+	// A policy targeting a specific port takes precedence over one targeting the whole Service.
+	tls := backendTLS[model.BackendTLSKey{NamespacedName: serviceName, SectionName: portName}]
+	if tls == nil {
+		tls = backendTLS[model.BackendTLSKey{NamespacedName: serviceName}]
+	}
+	if tls != nil {
+		// HTTP/2 will be negotiated using ALPN.
+		u.scheme = "https"
+		u.tls = tls
+	}
 }
@@ -2,7 +2,11 @@ package gateway
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
@@ -14,42 +18,165 @@ import (
 func applyFilters(
 	route *pb.Route,
 	config *model.GatewayConfig,
-	routeConfig *model.GatewayHTTPRouteConfig,
+	routeNamespace string,
 	filters []gateway_v1.HTTPRouteFilter,
 ) error {
 	for i := range filters {
-		if err := applyFilter(route, config, routeConfig, &filters[i]); err != nil {
+		if err := applyFilter(route, config, routeNamespace, &filters[i]); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ValidateFilter checks whether an HTTPRouteFilter can be represented using Pomerium route
+// configuration. Filters that pass validation are guaranteed to be applied in full.
+func ValidateFilter(filter *gateway_v1.HTTPRouteFilter) error {
+	switch filter.Type {
+	case gateway_v1.HTTPRouteFilterRequestHeaderModifier:
+		// Pomerium always overwrites an existing header value, so appending is not supported.
+		if f := filter.RequestHeaderModifier; f != nil && len(f.Add) > 0 {
+			return fmt.Errorf("%s: add is not supported, use set instead", filter.Type)
+		}
+	case gateway_v1.HTTPRouteFilterResponseHeaderModifier:
+		// Pomerium routes can set response headers, overwriting any existing value, but neither
+		// append to nor remove them.
+		if f := filter.ResponseHeaderModifier; f != nil && len(f.Add) > 0 {
+			return fmt.Errorf("%s: add is not supported, use set instead", filter.Type)
+		} else if f != nil && len(f.Remove) > 0 {
+			return fmt.Errorf("%s: remove is not supported", filter.Type)
+		}
+	case gateway_v1.HTTPRouteFilterURLRewrite:
+		if f := filter.URLRewrite; f != nil && f.Path != nil {
+			switch f.Path.Type {
+			case gateway_v1.FullPathHTTPPathModifier, gateway_v1.PrefixMatchHTTPPathModifier:
+			default:
+				return fmt.Errorf("%s: path modifier type %q not supported", filter.Type, f.Path.Type)
+			}
+		}
+	case gateway_v1.HTTPRouteFilterRequestRedirect,
+		gateway_v1.HTTPRouteFilterExtensionRef:
+	case gateway_v1.HTTPRouteFilterRequestMirror:
+		// Pomerium routes have no equivalent of Envoy's request mirror policies.
+		return fmt.Errorf("%s: request mirroring is not supported", filter.Type)
+	default:
+		return fmt.Errorf("filter type %q not supported", filter.Type)
+	}
+	return nil
+}
+
+// ValidateRule checks whether all matches and filters of an HTTPRouteRule can be represented
+// using Pomerium route configuration.
+func ValidateRule(rule *gateway_v1.HTTPRouteRule) error {
+	if unsupported := ValidateRules([]gateway_v1.HTTPRouteRule{*rule}); len(unsupported) > 0 {
+		return unsupported[0].Err
+	}
+	return nil
+}
+
+// UnsupportedRule describes an HTTPRouteRule that cannot be represented in full using Pomerium
+// route configuration.
+type UnsupportedRule struct {
+	// Index is the index of the rule within the HTTPRoute.
+	Index int
+	// Err lists the unsupported matches and filters of the rule.
+	Err error
+	// Dropped is set if none of the rule is routed: either all of its matches are unsupported,
+	// or one of its filters is, in which case matching requests get an error response.
+	Dropped bool
+	// UnsupportedMatch is set if any of the matches of the rule are unsupported. Leaving these
+	// out would route requests meant for other rules or routes, so the route is not accepted.
+	UnsupportedMatch bool
+}
+
+// ValidateRules checks whether all matches and filters of the rules of an HTTPRoute can be
+// represented using Pomerium route configuration. Unsupported matches are left out of the
+// translated routes without affecting the other matches of the rule, or the other rules.
+func ValidateRules(rules []gateway_v1.HTTPRouteRule) []UnsupportedRule {
+	conflicts := conflictingMethodMatches(rules)
+
+	var unsupported []UnsupportedRule
+	for i := range rules {
+		rule := &rules[i]
+		var messages []string
+		var unsupportedMatches int
+		for j := range rule.Matches {
+			err := ValidateMatch(&rule.Matches[j])
+			if _, ok := conflicts[matchIndex{i, j}]; ok && err == nil {
+				err = fmt.Errorf("method match has the same path match as another match")
+			}
+			if err != nil {
+				messages = append(messages, fmt.Sprintf("match %d: %v", j, err))
+				unsupportedMatches++
+			}
+		}
+		var unsupportedFilter bool
+		for j := range rule.Filters {
+			f := &rule.Filters[j]
+			if err := ValidateFilter(f); err != nil {
+				messages = append(messages, fmt.Sprintf("filter %d: %v", j, err))
+				unsupportedFilter = true
+				continue
+			}
+			// From the spec: "ReplacePrefixMatch is only compatible with a `PathPrefix`
+			// HTTPRouteMatch."
+			if isPrefixRewrite(f) {
+				for k := range rule.Matches {
+					if !isPathPrefixMatch(&rule.Matches[k]) {
+						messages = append(messages, fmt.Sprintf("filter %d: %s is only compatible with %s matches",
+							j, gateway_v1.PrefixMatchHTTPPathModifier, gateway_v1.PathMatchPathPrefix))
+						unsupportedFilter = true
+						break
+					}
+				}
+			}
+		}
+		if len(messages) > 0 {
+			unsupported = append(unsupported, UnsupportedRule{
+				Index:            i,
+				Err:              fmt.Errorf("%s", strings.Join(messages, "; ")),
+				Dropped:          unsupportedFilter || unsupportedMatches == len(rule.Matches),
+				UnsupportedMatch: unsupportedMatches > 0,
+			})
+		}
+	}
+	return unsupported
+}
+
 func applyFilter(
 	route *pb.Route,
 	config *model.GatewayConfig,
-	routeConfig *model.GatewayHTTPRouteConfig,
+	routeNamespace string,
 	filter *gateway_v1.HTTPRouteFilter,
 ) error {
+	if err := ValidateFilter(filter); err != nil {
+		return err
+	}
+
 	switch filter.Type {
 	case gateway_v1.HTTPRouteFilterRequestHeaderModifier:
 		applyRequestHeaderFilter(route, filter.RequestHeaderModifier)
+	case gateway_v1.HTTPRouteFilterResponseHeaderModifier:
+		applyResponseHeaderFilter(route, filter.ResponseHeaderModifier)
 	case gateway_v1.HTTPRouteFilterRequestRedirect:
 		applyRedirectFilter(route, filter.RequestRedirect)
+	case gateway_v1.HTTPRouteFilterURLRewrite:
+		applyURLRewriteFilter(route, filter.URLRewrite)
 	case gateway_v1.HTTPRouteFilterExtensionRef:
-		return applyExtensionFilter(route, config, routeConfig, filter.ExtensionRef)
-	default:
-		return fmt.Errorf("filter type %q not supported", filter.Type)
+		return applyExtensionFilter(route, config, routeNamespace, filter.ExtensionRef)
 	}
 	return nil
 }
 
 func applyRequestHeaderFilter(route *pb.Route, filter *gateway_v1.HTTPHeaderFilter) {
-	// Note: "append" is not supported yet.
 	route.SetRequestHeaders = makeHeadersMap(filter.Set)
 	route.RemoveRequestHeaders = filter.Remove
 }
 
+func applyResponseHeaderFilter(route *pb.Route, filter *gateway_v1.HTTPHeaderFilter) {
+	route.SetResponseHeaders = makeHeadersMap(filter.Set)
+}
+
 func makeHeadersMap(headers []gateway_v1.HTTPHeader) map[string]string {
 	if len(headers) == 0 {
 		return nil
@@ -78,10 +205,63 @@ func applyRedirectFilter(route *pb.Route, filter *gateway_v1.HTTPRequestRedirect
 	route.Redirect = &rr
 }
 
+func applyURLRewriteFilter(route *pb.Route, filter *gateway_v1.HTTPURLRewriteFilter) {
+	if filter.Hostname != nil {
+		route.HostRewrite = proto.String(string(*filter.Hostname))
+		route.PreserveHostHeader = false
+	}
+
+	// A prefix rewrite depends on the path match, so it is applied by [applyPrefixRewrite]
+	// once the match is known.
+	if filter.Path != nil && filter.Path.Type == gateway_v1.FullPathHTTPPathModifier &&
+		filter.Path.ReplaceFullPath != nil {
+		route.RegexRewritePattern = "^.*$"
+		route.RegexRewriteSubstitution = *filter.Path.ReplaceFullPath
+	}
+}
+
+// applyPrefixRewrite replaces the matched path prefix of a route if the rule has a URLRewrite
+// filter with a ReplacePrefixMatch path modifier. [applyMatch] must be called prior to this method.
+func applyPrefixRewrite(route *pb.Route, filters []gateway_v1.HTTPRouteFilter) {
+	for i := range filters {
+		if !isPrefixRewrite(&filters[i]) {
+			continue
+		}
+
+		// Prefix matches operate on full path elements and ignore a trailing "/", in both the
+		// match and the replacement. A route without a path match is an implicit "/" prefix match.
+		prefix := strings.TrimSuffix(route.Prefix, "/")
+		replacement := strings.TrimSuffix(*filters[i].URLRewrite.Path.ReplacePrefixMatch, "/")
+		if replacement == "" {
+			// Collapse any slashes following the prefix, so that the result is never empty
+			// and never starts with "//".
+			route.RegexRewritePattern = "^" + regexp.QuoteMeta(prefix) + "/*"
+			route.RegexRewriteSubstitution = "/"
+		} else {
+			route.RegexRewritePattern = "^" + regexp.QuoteMeta(prefix)
+			route.RegexRewriteSubstitution = replacement
+		}
+		return
+	}
+}
+
+func isPrefixRewrite(filter *gateway_v1.HTTPRouteFilter) bool {
+	return filter.Type == gateway_v1.HTTPRouteFilterURLRewrite &&
+		filter.URLRewrite != nil &&
+		filter.URLRewrite.Path != nil &&
+		filter.URLRewrite.Path.Type == gateway_v1.PrefixMatchHTTPPathModifier &&
+		filter.URLRewrite.Path.ReplacePrefixMatch != nil
+}
+
+func isPathPrefixMatch(match *gateway_v1.HTTPRouteMatch) bool {
+	return match.Path == nil || match.Path.Type == nil ||
+		*match.Path.Type == gateway_v1.PathMatchPathPrefix
+}
+
 func applyExtensionFilter(
 	route *pb.Route,
 	config *model.GatewayConfig,
-	routeConfig *model.GatewayHTTPRouteConfig,
+	routeNamespace string,
 	filter *gateway_v1.LocalObjectReference,
 ) error {
 	// Make sure the API group is the one we expect.
@@ -91,7 +271,7 @@ func applyExtensionFilter(
 
 	k := model.ExtensionFilterKey{
 		Kind:      string(filter.Kind),
-		Namespace: routeConfig.Namespace,
+		Namespace: routeNamespace,
 		Name:      string(filter.Name),
 	}
 	f := config.ExtensionFilters[k]
@@ -102,6 +282,32 @@ func applyExtensionFilter(
 	return f.ApplyToRoute(route)
 }
 
+// applyRouteDefaults applies any GatewayClass route defaults: the upstream timeouts, and the default
+// PolicyFilter if no PolicyFilter was applied to the route.
+func applyRouteDefaults(
+	route *pb.Route,
+	config *model.GatewayConfig,
+	defaults *model.GatewayRouteDefaults,
+) error {
+	if defaults == nil {
+		return nil
+	}
+	if defaults.Timeout != nil {
+		route.Timeout = durationpb.New(*defaults.Timeout)
+	}
+	if defaults.IdleTimeout != nil {
+		route.IdleTimeout = durationpb.New(*defaults.IdleTimeout)
+	}
+	if defaults.PolicyFilter != nil && len(route.Policies) == 0 {
+		f := config.ExtensionFilters[*defaults.PolicyFilter]
+		if f == nil {
+			return fmt.Errorf("default filter not found (%v)", *defaults.PolicyFilter)
+		}
+		return f.ApplyToRoute(route)
+	}
+	return nil
+}
+
 // PolicyFilter applies a Pomerium policy defined by the PolicyFilter CRD.
 type PolicyFilter struct {
 	ppl  *string
@@ -0,0 +1,248 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// TranslateGRPCRoutes converts from a Gateway-defined gRPC route to Pomerium route configuration
+// protos. The resulting routes use HTTP/2 upstreams.
+func TranslateGRPCRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayGRPCRouteConfig,
+) []*pb.Route {
+	prs, err := translateGRPCRoutes(ctx, gatewayConfig, routeConfig)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("skipping invalid routes",
+			"route", routeConfig.Namespace+"/"+routeConfig.Name, "error", err.Error())
+	}
+	return prs
+}
+
+// ValidateGRPCRoutes translates a GRPCRoute as TranslateGRPCRoutes does, and returns an
+// *InvalidRoutesError if any of the resulting Pomerium routes fail validation.
+func ValidateGRPCRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayGRPCRouteConfig,
+) error {
+	_, err := translateGRPCRoutes(ctx, gatewayConfig, routeConfig)
+	return err
+}
+
+func translateGRPCRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayGRPCRouteConfig,
+) ([]*pb.Route, error) {
+	trs := templateGRPCRoutes(ctx, gatewayConfig, routeConfig)
+
+	// Include the kind in the name, so that these routes cannot collide with the routes of an
+	// HTTPRoute of the same name.
+	namespaceAndName := slug.Make(fmt.Sprintf("%s %s grpc", routeConfig.Namespace, routeConfig.Name))
+	return expandAddresses(namespaceAndName, routeConfig.Addresses, trs)
+}
+
+// templateGRPCRoutes converts a GRPCRoute into zero or more Pomerium routes, ignoring hostname.
+func templateGRPCRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayGRPCRouteConfig,
+) []*pb.Route {
+	logger := log.FromContext(ctx)
+
+	var prs []*pb.Route
+
+	backends := grpcRouteBackends(gatewayConfig, routeConfig)
+	rules := routeConfig.Spec.Rules
+	for i := range rules {
+		rule := &rules[i]
+		pr := &pb.Route{}
+
+		// The same Host header requirements apply to gRPC routes as to HTTP routes.
+		pr.PreserveHostHeader = true
+
+		err := applyFilters(pr, gatewayConfig, routeConfig.Namespace, grpcFilters(rule.Filters))
+		if err == nil {
+			err = applyRouteDefaults(pr, gatewayConfig, routeConfig.Defaults)
+		}
+		if err != nil {
+			logger.Error(err, "couldn't apply filter")
+			pr.Response = &pb.RouteDirectResponse{
+				Status: http.StatusInternalServerError,
+				Body:   "invalid filter",
+			}
+		} else {
+			applyBackendRefs(pr, backends, grpcBackendRefs(rule.BackendRefs))
+		}
+
+		if len(rule.Matches) == 0 {
+			prs = append(prs, pr)
+			continue
+		}
+
+		for j := range rule.Matches {
+			cloned := proto.Clone(pr).(*pb.Route)
+			if applyGRPCMatch(cloned, &rule.Matches[j]) {
+				prs = append(prs, cloned)
+			}
+		}
+	}
+
+	return prs
+}
+
+// ValidateGRPCRule checks whether all matches and filters of a GRPCRouteRule can be represented
+// using Pomerium route configuration.
+func ValidateGRPCRule(rule *gateway_v1.GRPCRouteRule) error {
+	if unsupported := ValidateGRPCRules([]gateway_v1.GRPCRouteRule{*rule}); len(unsupported) > 0 {
+		return unsupported[0].Err
+	}
+	return nil
+}
+
+// ValidateGRPCRules checks whether all matches and filters of the rules of a GRPCRoute can be
+// represented using Pomerium route configuration. As with ValidateRules, unsupported matches are
+// left out of the translated routes without affecting the other matches and rules.
+func ValidateGRPCRules(rules []gateway_v1.GRPCRouteRule) []UnsupportedRule {
+	var unsupported []UnsupportedRule
+	for i := range rules {
+		rule := &rules[i]
+		var messages []string
+		var unsupportedMatches int
+		for j := range rule.Matches {
+			if err := ValidateGRPCMatch(&rule.Matches[j]); err != nil {
+				messages = append(messages, fmt.Sprintf("match %d: %v", j, err))
+				unsupportedMatches++
+			}
+		}
+		var unsupportedFilter bool
+		for j, f := range grpcFilters(rule.Filters) {
+			if err := ValidateFilter(&f); err != nil {
+				messages = append(messages, fmt.Sprintf("filter %d: %v", j, err))
+				unsupportedFilter = true
+			}
+		}
+		if len(messages) > 0 {
+			unsupported = append(unsupported, UnsupportedRule{
+				Index:            i,
+				Err:              fmt.Errorf("%s", strings.Join(messages, "; ")),
+				Dropped:          unsupportedFilter || unsupportedMatches == len(rule.Matches),
+				UnsupportedMatch: unsupportedMatches > 0,
+			})
+		}
+	}
+	return unsupported
+}
+
+// ValidateGRPCMatch checks whether a GRPCRouteMatch can be represented using Pomerium route
+// matching. As with HTTPRouteMatch, header matches cannot be expressed, so are reported as
+// unsupported and left out of the translated routes.
+func ValidateGRPCMatch(match *gateway_v1.GRPCRouteMatch) error {
+	if len(match.Headers) > 0 {
+		return fmt.Errorf("header matches are not supported")
+	}
+	if m := match.Method; m != nil && grpcMethodMatchType(m) == gateway_v1.GRPCMethodMatchRegularExpression {
+		if _, err := regexp.Compile(grpcMethodRegex(m)); err != nil {
+			return fmt.Errorf("invalid method regular expression: %w", err)
+		}
+	}
+	return nil
+}
+
+func applyGRPCMatch(route *pb.Route, match *gateway_v1.GRPCRouteMatch) (ok bool) {
+	if ValidateGRPCMatch(match) != nil {
+		return false
+	}
+	applyGRPCMethodMatch(route, match.Method)
+	return true
+}
+
+// applyGRPCMethodMatch translates a method match into a match on the gRPC request path, which
+// has the form "/<service>/<method>".
+func applyGRPCMethodMatch(route *pb.Route, match *gateway_v1.GRPCMethodMatch) {
+	if match == nil {
+		return
+	}
+
+	service, method := ptrValue(match.Service), ptrValue(match.Method)
+	switch {
+	case grpcMethodMatchType(match) == gateway_v1.GRPCMethodMatchRegularExpression:
+		route.Regex = grpcMethodRegex(match)
+	case service != "" && method != "":
+		route.Path = "/" + service + "/" + method
+	case service != "":
+		route.Prefix = "/" + service + "/"
+	case method != "":
+		route.Regex = "^/[^/]+/" + regexp.QuoteMeta(method) + "$"
+	}
+}
+
+func grpcMethodMatchType(match *gateway_v1.GRPCMethodMatch) gateway_v1.GRPCMethodMatchType {
+	if match.Type == nil {
+		return gateway_v1.GRPCMethodMatchExact
+	}
+	return *match.Type
+}
+
+// grpcMethodRegex returns a regular expression for the full gRPC request path corresponding to a
+// RegularExpression method match. An empty service or method matches any value.
+func grpcMethodRegex(match *gateway_v1.GRPCMethodMatch) string {
+	service, method := ptrValue(match.Service), ptrValue(match.Method)
+	if service == "" {
+		service = "[^/]+"
+	}
+	if method == "" {
+		method = "[^/]+"
+	}
+	return "^/(?:" + service + ")/(?:" + method + ")$"
+}
+
+// grpcFilters converts GRPCRouteFilters to the equivalent HTTPRouteFilters. Every GRPCRouteFilter
+// type has an HTTPRouteFilter counterpart with the same name and configuration.
+func grpcFilters(filters []gateway_v1.GRPCRouteFilter) []gateway_v1.HTTPRouteFilter {
+	if len(filters) == 0 {
+		return nil
+	}
+	hfs := make([]gateway_v1.HTTPRouteFilter, len(filters))
+	for i := range filters {
+		f := &filters[i]
+		hfs[i] = gateway_v1.HTTPRouteFilter{
+			Type:                   gateway_v1.HTTPRouteFilterType(f.Type),
+			RequestHeaderModifier:  f.RequestHeaderModifier,
+			ResponseHeaderModifier: f.ResponseHeaderModifier,
+			RequestMirror:          f.RequestMirror,
+			ExtensionRef:           f.ExtensionRef,
+		}
+	}
+	return hfs
+}
+
+func grpcBackendRefs(backendRefs []gateway_v1.GRPCBackendRef) []*gateway_v1.BackendRef {
+	refs := make([]*gateway_v1.BackendRef, len(backendRefs))
+	for i := range backendRefs {
+		refs[i] = &backendRefs[i].BackendRef
+	}
+	return refs
+}
+
+func ptrValue[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
@@ -1,16 +1,166 @@
 package gateway
 
 import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
 	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
 
+	"github.com/pomerium/ingress-controller/internal/policy"
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
 )
 
-func applyMatch(route *pb.Route, match *gateway_v1.HTTPRouteMatch) (ok bool) {
-	if len(match.Headers) > 0 || len(match.QueryParams) > 0 || match.Method != nil {
-		return false // these features are not supported yet
+// ValidateMatch checks whether an HTTPRouteMatch can be represented using Pomerium route matching.
+//
+// Pomerium routes match on hostname and path only. A method match is translated into a policy
+// that denies requests with any other method, which is only possible if no other match for the
+// same hostname has the same path match (see ValidateRules). Header and query parameter matches
+// cannot be expressed at all, as Pomerium policies have no criteria for these. These narrow the
+// set of requests a rule applies to, so translating the rule without them would capture requests
+// meant for other rules. Routes with such matches are not accepted.
+func ValidateMatch(match *gateway_v1.HTTPRouteMatch) error {
+	var unsupported []string
+	if len(match.Headers) > 0 {
+		unsupported = append(unsupported, "header")
+	}
+	if len(match.QueryParams) > 0 {
+		unsupported = append(unsupported, "query parameter")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s matches are not supported", strings.Join(unsupported, ", "))
+	}
+
+	if p := match.Path; p != nil && p.Type != nil && p.Value != nil &&
+		*p.Type == gateway_v1.PathMatchRegularExpression {
+		if _, err := regexp.Compile(*p.Value); err != nil {
+			return fmt.Errorf("invalid path regular expression %q: %w", *p.Value, err)
+		}
+	}
+
+	if match.Method != nil {
+		if _, err := methodPolicy(*match.Method); err != nil {
+			return fmt.Errorf("method %q: %w", *match.Method, err)
+		}
+	}
+
+	return nil
+}
+
+// matchIndex identifies a match within the rules of a route. A rule without matches has a single
+// implicit match, with index -1.
+type matchIndex struct {
+	rule, match int
+}
+
+// PathMatchKey identifies the path match of an HTTPRouteMatch.
+type PathMatchKey struct {
+	Type  gateway_v1.PathMatchType
+	Value string
+}
+
+// NewPathMatchKey returns the key for a path match, which may be nil for the default "/" prefix
+// match.
+func NewPathMatchKey(p *gateway_v1.HTTPPathMatch) PathMatchKey {
+	k := PathMatchKey{gateway_v1.PathMatchPathPrefix, "/"}
+	if p != nil && p.Type != nil {
+		k.Type = *p.Type
+	}
+	if p != nil && p.Value != nil {
+		k.Value = *p.Value
+	}
+	return k
+}
+
+// conflictingMethodMatches returns the method matches of a route that share their path match with
+// another match of the route. The route translated from such a match would deny the requests
+// meant for the other match, rather than leaving these to the other route. Conflicts with the
+// matches of other routes are found by the caller, which knows the listeners and hostnames the
+// routes are attached to.
+func conflictingMethodMatches(rules []gateway_v1.HTTPRouteRule) map[matchIndex]struct{} {
+	byPath := make(map[PathMatchKey][]matchIndex)
+	for i := range rules {
+		if len(rules[i].Matches) == 0 {
+			k := NewPathMatchKey(nil)
+			byPath[k] = append(byPath[k], matchIndex{i, -1})
+		}
+		for j := range rules[i].Matches {
+			k := NewPathMatchKey(rules[i].Matches[j].Path)
+			byPath[k] = append(byPath[k], matchIndex{i, j})
+		}
+	}
+
+	conflicts := make(map[matchIndex]struct{})
+	for _, indexes := range byPath {
+		if len(indexes) < 2 {
+			continue
+		}
+		for _, idx := range indexes {
+			if idx.match >= 0 && rules[idx.rule].Matches[idx.match].Method != nil {
+				conflicts[idx] = struct{}{}
+			}
+		}
+	}
+	return conflicts
+}
+
+// MethodMatchPolicies returns the policies that the method matches of an HTTPRoute are translated
+// into, without duplicates.
+func MethodMatchPolicies(route *gateway_v1.HTTPRoute) []*pb.Policy {
+	var policies []*pb.Policy
+	seen := make(map[gateway_v1.HTTPMethod]bool)
+	rules := route.Spec.Rules
+	conflicts := conflictingMethodMatches(rules)
+	for i := range rules {
+		for j := range rules[i].Matches {
+			match := &rules[i].Matches[j]
+			if _, ok := conflicts[matchIndex{i, j}]; ok || match.Method == nil || seen[*match.Method] ||
+				ValidateMatch(match) != nil {
+				continue
+			}
+			seen[*match.Method] = true
+			p, _ := methodPolicy(*match.Method)
+			policies = append(policies, p)
+		}
+	}
+	return policies
+}
+
+// methodPolicy returns a policy denying requests with any method other than the given one.
+func methodPolicy(method gateway_v1.HTTPMethod) (*pb.Policy, error) {
+	src, err := json.Marshal(map[string]any{
+		"deny": map[string]any{
+			"not": []any{
+				map[string]any{"http_method": map[string]any{"is": string(method)}},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ppl, rego, err := policy.Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Policy{
+		Rego:      rego,
+		SourcePpl: ppl,
+	}, nil
+}
+
+func applyMatch(route *pb.Route, match *gateway_v1.HTTPRouteMatch, conflicting bool) (ok bool) {
+	if conflicting || ValidateMatch(match) != nil {
+		return false
 	}
 	applyPathMatch(route, match.Path)
+	if match.Method != nil {
+		p, err := methodPolicy(*match.Method)
+		if err != nil {
+			return false
+		}
+		route.Policies = append(route.Policies, p)
+	}
 	return true
 }
 
@@ -4,13 +4,19 @@ package gateway
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/gosimple/slug"
 	"google.golang.org/protobuf/proto"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	"github.com/pomerium/pomerium/config"
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
@@ -28,30 +34,103 @@ func TranslateRoutes(
 	//  - An HTTPRoute may have multiple hostnames.
 	//  - An HTTPRoute may have multiple HTTPRouteRules.
 	//  - An HTTPRouteRule may have multiple HTTPRouteMatches.
+	//  - An HTTPRoute may be attached to multiple listeners.
 	// First we'll expand all HTTPRouteRules into "template" Pomerium routes, and then we'll
-	// repeat each "template" route once per hostname.
+	// repeat each "template" route once per listener address.
+	prs, err := translateRoutes(ctx, gatewayConfig, routeConfig)
+	if err != nil {
+		log.FromContext(ctx).V(1).Info("skipping invalid routes",
+			"route", routeConfig.Namespace+"/"+routeConfig.Name, "error", err.Error())
+	}
+	return prs
+}
+
+// ValidateRoutes translates an HTTPRoute as TranslateRoutes does, and returns an
+// *InvalidRoutesError if any of the resulting Pomerium routes fail validation.
+func ValidateRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayHTTPRouteConfig,
+) error {
+	_, err := translateRoutes(ctx, gatewayConfig, routeConfig)
+	return err
+}
+
+func translateRoutes(
+	ctx context.Context,
+	gatewayConfig *model.GatewayConfig,
+	routeConfig *model.GatewayHTTPRouteConfig,
+) ([]*pb.Route, error) {
 	trs := templateRoutes(ctx, gatewayConfig, routeConfig)
 
-	prs := make([]*pb.Route, 0, len(routeConfig.Hostnames)*len(trs))
 	namespaceAndName := slug.Make(fmt.Sprintf("%s %s", routeConfig.Namespace, routeConfig.Name))
-	for _, h := range routeConfig.Hostnames {
-		from := (&url.URL{
-			Scheme: "https",
-			Host:   string(h),
-		}).String()
-		namePrefix := namespaceAndName + "-" + slug.Make(string(h))
-		for i, tr := range trs {
+	return expandAddresses(namespaceAndName, routeConfig.Addresses, trs)
+}
+
+// InvalidRoutesError describes the Pomerium routes, translated from a Gateway API route, that fail
+// validation. These are left out of the translated routes.
+type InvalidRoutesError struct {
+	// Valid is the number of translated routes that pass validation.
+	Valid int
+	// Errs holds the validation error of each invalid route.
+	Errs []error
+}
+
+// maxInvalidRouteErrors is the number of validation errors included in an InvalidRoutesError
+// message, which is reported in the route status.
+const maxInvalidRouteErrors = 3
+
+func (e *InvalidRoutesError) Error() string {
+	var messages []string
+	for _, err := range e.Errs[:min(len(e.Errs), maxInvalidRouteErrors)] {
+		messages = append(messages, err.Error())
+	}
+	if n := len(e.Errs) - maxInvalidRouteErrors; n > 0 {
+		messages = append(messages, fmt.Sprintf("and %d more", n))
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validateRoute checks a translated route as Pomerium does when loading its configuration.
+func validateRoute(r *pb.Route) error {
+	coreRoute, err := config.NewPolicyFromProto(r)
+	if err != nil {
+		return err
+	}
+	return coreRoute.Validate()
+}
+
+// expandAddresses repeats each "template" route once per listener address, leaving out any routes
+// that fail to validate. These are described by the returned *InvalidRoutesError.
+func expandAddresses(
+	namespaceAndName string,
+	addresses []model.GatewayListenerAddress,
+	trs []*pb.Route,
+) ([]*pb.Route, error) {
+	var invalid []error
+	prs := make([]*pb.Route, 0, len(addresses)*len(trs))
+	for _, a := range addresses {
+		from, nameSuffix := listenerFrom(a)
+		namePrefix := namespaceAndName + "-" + slug.Make(string(a.Hostname)) + nameSuffix
+		names := make(map[string]int, len(trs))
+		for _, tr := range trs {
 			r := proto.Clone(tr).(*pb.Route)
 			r.From = from
 			if len(trs) == 1 {
 				r.Name = new(namePrefix)
 			} else {
-				r.Name = new(fmt.Sprintf("%s-%d", namePrefix, i))
+				// Derive the name from the route matches rather than the rule index, so that
+				// inserting or reordering rules does not rename the other routes.
+				name := namePrefix + "-" + RouteMatchKey(r)[:8]
+				names[name]++
+				if n := names[name]; n > 1 {
+					name = fmt.Sprintf("%s-%d", name, n)
+				}
+				r.Name = new(name)
 			}
 
-			// Skip any routes that fail to validate.
-			coreRoute, err := config.NewPolicyFromProto(r)
-			if err != nil || coreRoute.Validate() != nil {
+			if err := validateRoute(r); err != nil {
+				invalid = append(invalid, fmt.Errorf("route %s: %w", r.GetName(), err))
 				continue
 			}
 
@@ -59,7 +138,42 @@ func TranslateRoutes(
 		}
 	}
 
-	return prs
+	if len(invalid) > 0 {
+		return prs, &InvalidRoutesError{Valid: len(prs), Errs: invalid}
+	}
+	return prs, nil
+}
+
+// RouteMatchKey returns a hex-encoded digest of the fields of a Pomerium route that determine which
+// requests it matches. This identifies a route independently of its position among the routes
+// translated from the same object.
+func RouteMatchKey(r *pb.Route) string {
+	h := sha256.New()
+	for _, s := range []string{r.GetFrom(), r.GetPrefix(), r.GetPath(), r.GetRegex()} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// listenerFrom returns the route "From" URL for an HTTP or HTTPS listener address, along with a
+// route name suffix to distinguish it from the default HTTPS listener on port 443. The port is
+// omitted from the URL if it is the default port for the scheme.
+func listenerFrom(a model.GatewayListenerAddress) (from, nameSuffix string) {
+	scheme, defaultPort := "https", gateway_v1.PortNumber(443)
+	if a.Protocol == gateway_v1.HTTPProtocolType {
+		scheme, defaultPort = "http", 80
+		nameSuffix = "-http"
+	}
+
+	host := string(a.Hostname)
+	if a.Port != 0 && a.Port != defaultPort {
+		port := strconv.Itoa(int(a.Port))
+		host = net.JoinHostPort(host, port)
+		nameSuffix += "-" + port
+	}
+
+	return (&url.URL{Scheme: scheme, Host: host}).String(), nameSuffix
 }
 
 // templateRoutes converts an HTTPRoute into zero or more Pomerium routes, ignoring hostname.
@@ -72,7 +186,9 @@ func templateRoutes(
 
 	var prs []*pb.Route
 
+	backends := httpRouteBackends(gatewayConfig, routeConfig)
 	rules := routeConfig.Spec.Rules
+	conflicts := conflictingMethodMatches(rules)
 	for i := range rules {
 		rule := &rules[i]
 		pr := &pb.Route{}
@@ -83,24 +199,31 @@ func templateRoutes(
 		// forward this header unmodified to the backend."
 		pr.PreserveHostHeader = true
 
-		if err := applyFilters(pr, gatewayConfig, routeConfig, rule.Filters); err != nil {
+		err := applyFilters(pr, gatewayConfig, routeConfig.Namespace, rule.Filters)
+		if err == nil {
+			err = applyRouteDefaults(pr, gatewayConfig, routeConfig.Defaults)
+		}
+		if err != nil {
 			logger.Error(err, "couldn't apply filter")
 			pr.Response = &pb.RouteDirectResponse{
 				Status: http.StatusInternalServerError,
 				Body:   "invalid filter",
 			}
 		} else {
-			applyBackendRefs(pr, routeConfig, rule.BackendRefs)
+			applyBackendRefs(pr, backends, httpBackendRefs(rule.BackendRefs))
 		}
 
 		if len(rule.Matches) == 0 {
+			applyPrefixRewrite(pr, rule.Filters)
 			prs = append(prs, pr)
 			continue
 		}
 
 		for j := range rule.Matches {
 			cloned := proto.Clone(pr).(*pb.Route)
-			if applyMatch(cloned, &rule.Matches[j]) {
+			_, conflicting := conflicts[matchIndex{i, j}]
+			if applyMatch(cloned, &rule.Matches[j], conflicting) {
+				applyPrefixRewrite(cloned, rule.Filters)
 				prs = append(prs, cloned)
 			}
 		}
@@ -108,3 +231,11 @@ func templateRoutes(
 
 	return prs
 }
+
+func httpBackendRefs(backendRefs []gateway_v1.HTTPBackendRef) []*gateway_v1.BackendRef {
+	refs := make([]*gateway_v1.BackendRef, len(backendRefs))
+	for i := range backendRefs {
+		refs[i] = &backendRefs[i].BackendRef
+	}
+	return refs
+}
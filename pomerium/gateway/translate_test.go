@@ -2,12 +2,22 @@ package gateway_test
 
 import (
 	"encoding/json"
+	"errors"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	v1 "sigs.k8s.io/gateway-api/apis/v1"
 
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+
 	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	"github.com/pomerium/ingress-controller/model"
 	"github.com/pomerium/ingress-controller/pomerium/gateway"
@@ -61,7 +71,7 @@ func TestTranslate(t *testing.T) {
 			},
 			&model.GatewayHTTPRouteConfig{
 				HTTPRoute: &route,
-				Hostnames: []v1.Hostname{"example.com"},
+				Addresses: httpsAddresses("example.com"),
 			})
 		if assert.Len(t, result, 1) {
 			if assert.Len(t, result[0].Policies, 1) {
@@ -70,4 +80,983 @@ func TestTranslate(t *testing.T) {
 			}
 		}
 	})
+	t.Run("skips unsupported matches", func(t *testing.T) {
+		t.Parallel()
+
+		var route v1.HTTPRoute
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"metadata": {
+				"name": "example",
+				"namespace": "default"
+			},
+			"spec": {
+				"hostnames": ["example.com"],
+				"rules": [{
+					"matches": [{
+						"path": {
+							"type": "PathPrefix",
+							"value": "/v2"
+						},
+						"headers": [{
+							"name": "X-Version",
+							"value": "2"
+						}]
+					}, {
+						"path": {
+							"type": "PathPrefix",
+							"value": "/v1"
+						}
+					}],
+					"backendRefs": [{
+						"name": "service",
+						"port": 80
+					}]
+				}]
+			}
+		}`), &route))
+
+		result := gateway.TranslateRoutes(t.Context(),
+			&model.GatewayConfig{},
+			&model.GatewayHTTPRouteConfig{
+				HTTPRoute:        &route,
+				Addresses:        httpsAddresses("example.com"),
+				ValidBackendRefs: allBackendRefsValid{},
+			})
+		if assert.Len(t, result, 1) {
+			assert.Equal(t, "/v1", result[0].GetPrefix())
+		}
+	})
+}
+
+func TestTranslateListenerAddresses(t *testing.T) {
+	t.Parallel()
+
+	var route v1.HTTPRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default"
+		},
+		"spec": {
+			"rules": [{
+				"backendRefs": [{
+					"name": "service",
+					"port": 80
+				}]
+			}]
+		}
+	}`), &route))
+
+	result := gateway.TranslateRoutes(t.Context(),
+		&model.GatewayConfig{},
+		&model.GatewayHTTPRouteConfig{
+			HTTPRoute: &route,
+			Addresses: []model.GatewayListenerAddress{
+				{Protocol: v1.HTTPSProtocolType, Hostname: "example.com", Port: 443},
+				{Protocol: v1.HTTPSProtocolType, Hostname: "example.com", Port: 8443},
+				{Protocol: v1.HTTPProtocolType, Hostname: "example.com", Port: 80},
+				{Protocol: v1.HTTPProtocolType, Hostname: "example.com", Port: 8080},
+			},
+			ValidBackendRefs: allBackendRefsValid{},
+		})
+	require.Len(t, result, 4)
+
+	for i, expected := range []struct{ from, name string }{
+		{"https://example.com", "default-example-example-com"},
+		{"https://example.com:8443", "default-example-example-com-8443"},
+		{"http://example.com", "default-example-example-com-http"},
+		{"http://example.com:8080", "default-example-example-com-http-8080"},
+	} {
+		assert.Equal(t, expected.from, result[i].GetFrom())
+		assert.Equal(t, expected.name, result[i].GetName())
+	}
+}
+
+func TestTranslateRouteNamesStable(t *testing.T) {
+	t.Parallel()
+
+	var route v1.HTTPRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default"
+		},
+		"spec": {
+			"rules": [{
+				"matches": [{"path": {"type": "PathPrefix", "value": "/a"}}],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}, {
+				"matches": [{"path": {"type": "PathPrefix", "value": "/b"}}],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}]
+		}
+	}`), &route))
+
+	namesByPrefix := func() map[string]string {
+		result := gateway.TranslateRoutes(t.Context(),
+			&model.GatewayConfig{},
+			&model.GatewayHTTPRouteConfig{
+				HTTPRoute:        &route,
+				Addresses:        httpsAddresses("example.com"),
+				ValidBackendRefs: allBackendRefsValid{},
+			})
+		require.Len(t, result, 2)
+		m := make(map[string]string)
+		for _, r := range result {
+			m[r.GetPrefix()] = r.GetName()
+		}
+		return m
+	}
+
+	before := namesByPrefix()
+	assert.NotEqual(t, before["/a"], before["/b"])
+
+	// Reordering the rules should not change the route names.
+	rules := route.Spec.Rules
+	rules[0], rules[1] = rules[1], rules[0]
+	assert.Equal(t, before, namesByPrefix())
+}
+
+func TestInvalidRoutesError(t *testing.T) {
+	t.Parallel()
+
+	err := &gateway.InvalidRoutesError{Valid: 1, Errs: []error{
+		errors.New("route a: invalid"),
+		errors.New("route b: invalid"),
+		errors.New("route c: invalid"),
+		errors.New("route d: invalid"),
+		errors.New("route e: invalid"),
+	}}
+	assert.Equal(t, "route a: invalid; route b: invalid; route c: invalid; and 2 more", err.Error())
+
+	err.Errs = err.Errs[:1]
+	assert.Equal(t, "route a: invalid", err.Error())
+}
+
+func TestTranslateRouteDefaults(t *testing.T) {
+	t.Parallel()
+
+	newPolicyFilter := func(ppl string) model.ExtensionFilter {
+		f, err := gateway.NewPolicyFilter(&icgv1alpha1.PolicyFilter{
+			Spec: icgv1alpha1.PolicyFilterSpec{PPL: ppl},
+		})
+		require.NoError(t, err)
+		return f
+	}
+	defaultPPL := "allow:\n  and:\n    - domain:\n        is: example.com"
+	ownPPL := "allow:\n  and:\n    - email:\n        is: user@example.com"
+
+	var route v1.HTTPRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default"
+		},
+		"spec": {
+			"rules": [{
+				"matches": [{"path": {"type": "PathPrefix", "value": "/a"}}],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}, {
+				"matches": [{"path": {"type": "PathPrefix", "value": "/b"}}],
+				"filters": [{
+					"type": "ExtensionRef",
+					"extensionRef": {
+						"group": "gateway.pomerium.io",
+						"kind": "PolicyFilter",
+						"name": "own"
+					}
+				}],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}]
+		}
+	}`), &route))
+
+	result := gateway.TranslateRoutes(t.Context(),
+		&model.GatewayConfig{
+			ExtensionFilters: map[model.ExtensionFilterKey]model.ExtensionFilter{
+				{Kind: "PolicyFilter", Namespace: "shared", Name: "default-policy"}: newPolicyFilter(defaultPPL),
+				{Kind: "PolicyFilter", Namespace: "default", Name: "own"}:           newPolicyFilter(ownPPL),
+			},
+		},
+		&model.GatewayHTTPRouteConfig{
+			HTTPRoute:        &route,
+			Addresses:        httpsAddresses("example.com"),
+			ValidBackendRefs: allBackendRefsValid{},
+			Defaults: &model.GatewayRouteDefaults{
+				PolicyFilter: &model.ExtensionFilterKey{
+					Kind: "PolicyFilter", Namespace: "shared", Name: "default-policy",
+				},
+				Timeout:     new(30 * time.Second),
+				IdleTimeout: new(5 * time.Minute),
+			},
+		})
+	require.Len(t, result, 2)
+
+	for i, expectedPPL := range []string{defaultPPL, ownPPL} {
+		assert.Equal(t, 30*time.Second, result[i].GetTimeout().AsDuration())
+		assert.Equal(t, 5*time.Minute, result[i].GetIdleTimeout().AsDuration())
+		if assert.Len(t, result[i].Policies, 1) {
+			assert.Equal(t, expectedPPL, result[i].Policies[0].GetSourcePpl())
+		}
+	}
+}
+
+func TestTranslateFilters(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		matches string
+		filter  string
+		check   func(t *testing.T, r *pb.Route)
+	}{
+		{
+			"request headers",
+			`[]`,
+			`{"type": "RequestHeaderModifier", "requestHeaderModifier": {"set": [{"name": "X-A", "value": "a"}], "remove": ["X-B"]}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, map[string]string{"X-A": "a"}, r.GetSetRequestHeaders())
+				assert.Equal(t, []string{"X-B"}, r.GetRemoveRequestHeaders())
+			},
+		},
+		{
+			"response headers",
+			`[]`,
+			`{"type": "ResponseHeaderModifier", "responseHeaderModifier": {"set": [{"name": "X-A", "value": "a"}]}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, map[string]string{"X-A": "a"}, r.GetSetResponseHeaders())
+			},
+		},
+		{
+			"response headers add",
+			`[]`,
+			`{"type": "ResponseHeaderModifier", "responseHeaderModifier": {"add": [{"name": "X-A", "value": "a"}]}}`,
+			func(t *testing.T, r *pb.Route) {
+				// Adding would overwrite any existing value, so the filter is rejected.
+				assert.EqualValues(t, http.StatusInternalServerError, r.GetResponse().GetStatus())
+				assert.Empty(t, r.GetSetResponseHeaders())
+			},
+		},
+		{
+			"rewrite hostname",
+			`[]`,
+			`{"type": "URLRewrite", "urlRewrite": {"hostname": "internal.example.com"}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, "internal.example.com", r.GetHostRewrite())
+				assert.False(t, r.GetPreserveHostHeader())
+			},
+		},
+		{
+			"rewrite full path",
+			`[{"path": {"type": "Exact", "value": "/a"}}]`,
+			`{"type": "URLRewrite", "urlRewrite": {"path": {"type": "ReplaceFullPath", "replaceFullPath": "/b"}}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, "^.*$", r.GetRegexRewritePattern())
+				assert.Equal(t, "/b", r.GetRegexRewriteSubstitution())
+				assert.True(t, r.GetPreserveHostHeader())
+			},
+		},
+		{
+			"rewrite prefix",
+			`[{"path": {"type": "PathPrefix", "value": "/foo/"}}]`,
+			`{"type": "URLRewrite", "urlRewrite": {"path": {"type": "ReplacePrefixMatch", "replacePrefixMatch": "/xyz/"}}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, "/foo/", r.GetPrefix())
+				assert.Equal(t, "^/foo", r.GetRegexRewritePattern())
+				assert.Equal(t, "/xyz", r.GetRegexRewriteSubstitution())
+			},
+		},
+		{
+			"rewrite prefix to root",
+			`[{"path": {"type": "PathPrefix", "value": "/foo.bar"}}]`,
+			`{"type": "URLRewrite", "urlRewrite": {"path": {"type": "ReplacePrefixMatch", "replacePrefixMatch": "/"}}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, `^/foo\.bar/*`, r.GetRegexRewritePattern())
+				assert.Equal(t, "/", r.GetRegexRewriteSubstitution())
+			},
+		},
+		{
+			"rewrite implicit prefix",
+			`[]`,
+			`{"type": "URLRewrite", "urlRewrite": {"path": {"type": "ReplacePrefixMatch", "replacePrefixMatch": "/xyz"}}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.Equal(t, "^", r.GetRegexRewritePattern())
+				assert.Equal(t, "/xyz", r.GetRegexRewriteSubstitution())
+			},
+		},
+		{
+			"unsupported filter",
+			`[]`,
+			`{"type": "RequestMirror", "requestMirror": {"backendRef": {"name": "mirror", "port": 80}}}`,
+			func(t *testing.T, r *pb.Route) {
+				assert.EqualValues(t, http.StatusInternalServerError, r.GetResponse().GetStatus())
+				assert.Empty(t, r.GetTo())
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var route v1.HTTPRoute
+			require.NoError(t, json.Unmarshal([]byte(`{
+				"metadata": {
+					"name": "example",
+					"namespace": "default"
+				},
+				"spec": {
+					"hostnames": ["example.com"],
+					"rules": [{
+						"matches": `+tc.matches+`,
+						"filters": [`+tc.filter+`],
+						"backendRefs": [{
+							"name": "service",
+							"port": 80
+						}]
+					}]
+				}
+			}`), &route))
+
+			result := gateway.TranslateRoutes(t.Context(),
+				&model.GatewayConfig{},
+				&model.GatewayHTTPRouteConfig{
+					HTTPRoute:        &route,
+					Addresses:        httpsAddresses("example.com"),
+					ValidBackendRefs: allBackendRefsValid{},
+				})
+			if assert.Len(t, result, 1) {
+				tc.check(t, result[0])
+			}
+		})
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		rule   string
+		expect string
+	}{
+		{
+			"supported",
+			`{"filters": [{"type": "RequestHeaderModifier", "requestHeaderModifier": {"set": [{"name": "a", "value": "b"}]}}]}`,
+			"",
+		},
+		{
+			"header add",
+			`{"filters": [{"type": "RequestHeaderModifier", "requestHeaderModifier": {"add": [{"name": "a", "value": "b"}]}}]}`,
+			"filter 0: RequestHeaderModifier: add is not supported, use set instead",
+		},
+		{
+			"response header add and remove",
+			`{"filters": [{"type": "ResponseHeaderModifier", "responseHeaderModifier": {"add": [{"name": "a", "value": "b"}], "remove": ["c"]}}]}`,
+			"filter 0: ResponseHeaderModifier: add is not supported, use set instead",
+		},
+		{
+			"response header remove",
+			`{"filters": [{"type": "ResponseHeaderModifier", "responseHeaderModifier": {"remove": ["a"]}}]}`,
+			"filter 0: ResponseHeaderModifier: remove is not supported",
+		},
+		{
+			"request mirror",
+			`{"filters": [{"type": "RequestMirror", "requestMirror": {"backendRef": {"name": "mirror"}}}]}`,
+			"filter 0: RequestMirror: request mirroring is not supported",
+		},
+		{
+			"prefix rewrite with exact match",
+			`{
+				"matches": [{"path": {"type": "Exact", "value": "/a"}}],
+				"filters": [{"type": "URLRewrite", "urlRewrite": {"path": {"type": "ReplacePrefixMatch", "replacePrefixMatch": "/b"}}}]
+			}`,
+			"filter 0: ReplacePrefixMatch is only compatible with PathPrefix matches",
+		},
+		{
+			"method match",
+			`{"matches": [{"method": "GET"}]}`,
+			"",
+		},
+		{
+			"method matches with the same path",
+			`{"matches": [{"method": "GET"}, {"method": "POST"}]}`,
+			"match 0: method match has the same path match as another match; match 1: method match",
+		},
+		{
+			"header match",
+			`{"matches": [{"headers": [{"name": "X-Version", "value": "2"}]}]}`,
+			"match 0: header matches are not supported",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var rule v1.HTTPRouteRule
+			require.NoError(t, json.Unmarshal([]byte(tc.rule), &rule))
+			err := gateway.ValidateRule(&rule)
+			if tc.expect == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	t.Parallel()
+
+	var rules []v1.HTTPRouteRule
+	require.NoError(t, json.Unmarshal([]byte(`[{
+		"matches": [{"path": {"type": "PathPrefix", "value": "/a"}}]
+	}, {
+		"matches": [
+			{"path": {"type": "PathPrefix", "value": "/b"}, "headers": [{"name": "X-Version", "value": "2"}]},
+			{"path": {"type": "PathPrefix", "value": "/c"}}
+		]
+	}, {
+		"matches": [{"path": {"type": "PathPrefix", "value": "/d"}, "queryParams": [{"name": "canary", "value": "true"}]}]
+	}, {
+		"filters": [{"type": "RequestMirror", "requestMirror": {"backendRef": {"name": "mirror"}}}]
+	}, {
+		"matches": [{"path": {"type": "PathPrefix", "value": "/a"}, "method": "POST"}]
+	}]`), &rules))
+
+	unsupported := gateway.ValidateRules(rules)
+	require.Len(t, unsupported, 4)
+
+	assert.Equal(t, 1, unsupported[0].Index)
+	assert.False(t, unsupported[0].Dropped, "only one match of the rule is unsupported")
+	assert.True(t, unsupported[0].UnsupportedMatch)
+	assert.ErrorContains(t, unsupported[0].Err, "match 0: header matches are not supported")
+
+	assert.Equal(t, 2, unsupported[1].Index)
+	assert.True(t, unsupported[1].Dropped)
+	assert.ErrorContains(t, unsupported[1].Err, "match 0: query parameter matches are not supported")
+
+	assert.Equal(t, 3, unsupported[2].Index)
+	assert.True(t, unsupported[2].Dropped)
+	assert.False(t, unsupported[2].UnsupportedMatch, "only a filter of the rule is unsupported")
+	assert.ErrorContains(t, unsupported[2].Err, "filter 0: RequestMirror")
+
+	assert.Equal(t, 4, unsupported[3].Index)
+	assert.True(t, unsupported[3].Dropped)
+	assert.True(t, unsupported[3].UnsupportedMatch)
+	assert.ErrorContains(t, unsupported[3].Err, "match 0: method match has the same path match as another match")
+}
+
+func TestTranslateMatches(t *testing.T) {
+	t.Parallel()
+
+	var route v1.HTTPRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default"
+		},
+		"spec": {
+			"hostnames": ["example.com"],
+			"rules": [{
+				"matches": [
+					{"path": {"type": "PathPrefix", "value": "/a"}, "method": "GET"},
+					{"path": {"type": "PathPrefix", "value": "/b"}, "headers": [{"name": "X-Version", "value": "2"}]}
+				],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}, {
+				"matches": [{"path": {"type": "PathPrefix", "value": "/c"}}],
+				"backendRefs": [{"name": "service", "port": 80}]
+			}]
+		}
+	}`), &route))
+
+	result := gateway.TranslateRoutes(t.Context(),
+		&model.GatewayConfig{},
+		&model.GatewayHTTPRouteConfig{
+			HTTPRoute:        &route,
+			Addresses:        httpsAddresses("example.com"),
+			ValidBackendRefs: allBackendRefsValid{},
+		})
+	require.Len(t, result, 2, "the header match should be left out, without affecting the other rule")
+	byPrefix := make(map[string]*pb.Route)
+	for _, r := range result {
+		byPrefix[r.GetPrefix()] = r
+	}
+
+	if assert.Len(t, byPrefix["/a"].GetPolicies(), 1) {
+		ppl := byPrefix["/a"].GetPolicies()[0].GetSourcePpl()
+		assert.Contains(t, ppl, "http_method")
+		assert.Contains(t, ppl, "GET")
+
+		policies := gateway.MethodMatchPolicies(&route)
+		if assert.Len(t, policies, 1) {
+			assert.Equal(t, ppl, policies[0].GetSourcePpl())
+		}
+	}
+	assert.Empty(t, byPrefix["/c"].GetPolicies())
+}
+
+func TestValidateMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		match  string
+		expect string
+	}{
+		{"path", `{"path": {"type": "Exact", "value": "/"}}`, ""},
+		{"headers", `{"headers": [{"name": "X-Version", "value": "2"}]}`, "header matches are not supported"},
+		{"query params", `{"queryParams": [{"name": "canary", "value": "true"}]}`, "query parameter matches are not supported"},
+		{"method", `{"method": "GET"}`, ""},
+		{
+			"all",
+			`{"method": "GET", "headers": [{"name": "a", "value": "b"}], "queryParams": [{"name": "c", "value": "d"}]}`,
+			"header, query parameter matches are not supported",
+		},
+		{"invalid regex", `{"path": {"type": "RegularExpression", "value": "/("}}`, `invalid path regular expression "/("`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var match v1.HTTPRouteMatch
+			require.NoError(t, json.Unmarshal([]byte(tc.match), &match))
+			err := gateway.ValidateMatch(&match)
+			if tc.expect == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestTranslateBackendProtocol(t *testing.T) {
+	t.Parallel()
+
+	services := map[types.NamespacedName]*corev1.Service{
+		{Namespace: "default", Name: "plain"}: {Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "http", Port: 80},
+		}}},
+		{Namespace: "default", Name: "app-protocol"}: {Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "https", Port: 443, AppProtocol: new("https")},
+			{Name: "h2c", Port: 81, AppProtocol: new("kubernetes.io/h2c")},
+			{Name: "ws", Port: 82, AppProtocol: new("kubernetes.io/ws")},
+			{Name: "wss", Port: 83, AppProtocol: new("kubernetes.io/wss")},
+		}}},
+		{Namespace: "default", Name: "tls"}: {Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "a", Port: 8443},
+			{Name: "b", Port: 9443},
+		}}},
+	}
+	serviceTLS := &model.BackendTLSConfig{Hostname: "service.example.com", CACertificates: []byte("CA")}
+	portTLS := &model.BackendTLSConfig{Hostname: "port.example.com"}
+	config := &model.GatewayConfig{
+		BackendTLS: map[model.BackendTLSKey]*model.BackendTLSConfig{
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "tls"}}:                   serviceTLS,
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "tls"}, SectionName: "b"}: portTLS,
+		},
+	}
+
+	translate := func(t *testing.T, backendRefs string) *pb.Route {
+		t.Helper()
+
+		var route v1.HTTPRoute
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"metadata": {
+				"name": "example",
+				"namespace": "default"
+			},
+			"spec": {
+				"hostnames": ["example.com"],
+				"rules": [{
+					"backendRefs": `+backendRefs+`
+				}]
+			}
+		}`), &route))
+
+		result := gateway.TranslateRoutes(t.Context(), config, &model.GatewayHTTPRouteConfig{
+			HTTPRoute:        &route,
+			Addresses:        httpsAddresses("example.com"),
+			ValidBackendRefs: allBackendRefsValid{},
+			Services:         services,
+		})
+		require.Len(t, result, 1)
+		return result[0]
+	}
+
+	for _, tc := range []struct {
+		name        string
+		backendRefs string
+		to          string
+		websockets  bool
+		serverName  string
+		customCA    string
+	}{
+		{"plain", `[{"name": "plain", "port": 80}]`, "http://plain.default.svc.cluster.local:80", false, "", ""},
+		{"https", `[{"name": "app-protocol", "port": 443}]`, "https://app-protocol.default.svc.cluster.local:443", false, "", ""},
+		{"h2c", `[{"name": "app-protocol", "port": 81}]`, "h2c://app-protocol.default.svc.cluster.local:81", false, "", ""},
+		{"ws", `[{"name": "app-protocol", "port": 82}]`, "http://app-protocol.default.svc.cluster.local:82", true, "", ""},
+		{"wss", `[{"name": "app-protocol", "port": 83}]`, "https://app-protocol.default.svc.cluster.local:83", true, "", ""},
+		{"service policy", `[{"name": "tls", "port": 8443}]`, "https://tls.default.svc.cluster.local:8443", false, "service.example.com", "Q0E="},
+		{"port policy", `[{"name": "tls", "port": 9443}]`, "https://tls.default.svc.cluster.local:9443", false, "port.example.com", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := translate(t, tc.backendRefs)
+			assert.Equal(t, []string{tc.to}, r.GetTo())
+			assert.Equal(t, tc.websockets, r.GetAllowWebsockets())
+			assert.Equal(t, tc.serverName, r.GetTlsServerName())
+			assert.Equal(t, tc.customCA, r.GetTlsCustomCa())
+		})
+	}
+
+	t.Run("conflicting protocols", func(t *testing.T) {
+		t.Parallel()
+
+		r := translate(t, `[{"name": "plain", "port": 80}, {"name": "tls", "port": 8443}]`)
+		assert.Empty(t, r.GetTo())
+		assert.EqualValues(t, http.StatusInternalServerError, r.GetResponse().GetStatus())
+	})
+}
+
+func TestResolveServicePort(t *testing.T) {
+	t.Parallel()
+
+	headless := func(targetPort intstr.IntOrString) *corev1.Service {
+		return &corev1.Service{Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Ports:     []corev1.ServicePort{{Name: "web", Port: 80, TargetPort: targetPort}},
+		}}
+	}
+	slices := []*discoveryv1.EndpointSlice{{
+		Ports: []discoveryv1.EndpointPort{{Name: new("web"), Port: new(int32(8080))}},
+	}}
+
+	for _, tc := range []struct {
+		name   string
+		svc    *corev1.Service
+		slices []*discoveryv1.EndpointSlice
+		port   v1.PortNumber
+		expect int32
+		err    string
+	}{
+		{"cluster ip", &corev1.Service{Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromString("http")}},
+		}}, nil, 80, 80, ""},
+		{"headless numeric target port", headless(intstr.FromInt32(8000)), nil, 80, 8000, ""},
+		{"headless named target port", headless(intstr.FromString("http")), slices, 80, 8080, ""},
+		{"headless named target port without endpoints", headless(intstr.FromString("http")), nil, 80, 0,
+			`couldn't resolve named target port "http" of headless service`},
+		{"unknown port", headless(intstr.FromInt32(8000)), nil, 81, 0, "service has no port 81"},
+		{"external name", &corev1.Service{Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeExternalName,
+		}}, nil, 443, 443, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, port, err := gateway.ResolveServicePort(tc.svc, tc.slices, tc.port)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, port)
+		})
+	}
+}
+
+func TestTranslateEndpointUpstream(t *testing.T) {
+	t.Parallel()
+
+	endpoint := func(address string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{
+			Addresses:  []string{address},
+			Conditions: discoveryv1.EndpointConditions{Ready: new(true)},
+		}
+	}
+
+	var route v1.HTTPRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default",
+			"annotations": {
+				"gateway.pomerium.io/endpoint_upstream": "true"
+			}
+		},
+		"spec": {
+			"rules": [{
+				"backendRefs": [{
+					"name": "a",
+					"port": 80,
+					"weight": 3
+				}, {
+					"name": "b",
+					"port": 80
+				}]
+			}]
+		}
+	}`), &route))
+
+	result := gateway.TranslateRoutes(t.Context(), &model.GatewayConfig{}, &model.GatewayHTTPRouteConfig{
+		HTTPRoute:        &route,
+		Addresses:        httpsAddresses("example.com"),
+		ValidBackendRefs: allBackendRefsValid{},
+		Services: map[types.NamespacedName]*corev1.Service{
+			{Namespace: "default", Name: "a"}: {Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80},
+			}}},
+			{Namespace: "default", Name: "b"}: {Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80},
+			}}},
+		},
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
+			{Namespace: "default", Name: "a"}: {{
+				Ports:     []discoveryv1.EndpointPort{{Name: new("http"), Port: new(int32(8080))}},
+				Endpoints: []discoveryv1.Endpoint{endpoint("10.0.0.1"), endpoint("10.0.0.2")},
+			}},
+		},
+	})
+	require.Len(t, result, 1)
+
+	// Service "b" has no endpoints, so it falls back to the Service DNS name. The weight of
+	// service "a" is divided between its two endpoints.
+	assert.Equal(t, []string{
+		"http://10.0.0.1:8080",
+		"http://10.0.0.2:8080",
+		"http://b.default.svc.cluster.local:80",
+	}, result[0].GetTo())
+	assert.Equal(t, []uint32{3, 3, 2}, result[0].GetLoadBalancingWeights())
+}
+
+func TestTranslateGRPCRoutes(t *testing.T) {
+	t.Parallel()
+
+	var route v1.GRPCRoute
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"metadata": {
+			"name": "example",
+			"namespace": "default"
+		},
+		"spec": {
+			"hostnames": ["example.com"],
+			"rules": [{
+				"matches": [
+					{"method": {"service": "foo.Foo", "method": "Get"}},
+					{"method": {"service": "foo.Foo"}},
+					{"method": {"method": "Get"}},
+					{"method": {"type": "RegularExpression", "service": "foo\\..*", "method": "Get|List"}},
+					{"headers": [{"name": "x-version", "value": "2"}]}
+				],
+				"backendRefs": [{
+					"name": "a",
+					"port": 9000,
+					"weight": 3
+				}, {
+					"name": "b",
+					"namespace": "other",
+					"port": 9001
+				}]
+			}]
+		}
+	}`), &route))
+
+	result := gateway.TranslateGRPCRoutes(t.Context(),
+		&model.GatewayConfig{},
+		&model.GatewayGRPCRouteConfig{
+			GRPCRoute:        &route,
+			Addresses:        httpsAddresses("example.com"),
+			ValidBackendRefs: allBackendRefsValid{},
+		})
+	require.Len(t, result, 4, "header match should be skipped")
+
+	assert.Equal(t, "/foo.Foo/Get", result[0].GetPath())
+	assert.Equal(t, "/foo.Foo/", result[1].GetPrefix())
+	assert.Equal(t, `^/[^/]+/Get$`, result[2].GetRegex())
+	assert.Equal(t, `^/(?:foo\..*)/(?:Get|List)$`, result[3].GetRegex())
+
+	for _, r := range result {
+		assert.Equal(t, "https://example.com", r.GetFrom())
+		assert.Equal(t, "default-example-grpc-example-com-"+gateway.RouteMatchKey(r)[:8], r.GetName())
+		assert.Equal(t, []string{
+			"h2c://a.default.svc.cluster.local:9000",
+			"h2c://b.other.svc.cluster.local:9001",
+		}, r.GetTo())
+		assert.Equal(t, []uint32{3, 1}, r.GetLoadBalancingWeights())
+	}
+}
+
+func TestValidateGRPCRule(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		rule   string
+		expect string
+	}{
+		{"method match", `{"matches": [{"method": {"service": "foo.Foo"}}]}`, ""},
+		{"header match", `{"matches": [{"headers": [{"name": "x-a", "value": "a"}]}]}`,
+			"match 0: header matches are not supported"},
+		{"invalid regex", `{"matches": [{"method": {"type": "RegularExpression", "method": "("}}]}`,
+			"match 0: invalid method regular expression: error parsing regexp: missing closing ): `^/(?:[^/]+)/(?:()$`"},
+		{"request header set", `{"filters": [{"type": "RequestHeaderModifier", "requestHeaderModifier": {"set": [{"name": "x-a", "value": "a"}]}}]}`, ""},
+		{"request mirror", `{"filters": [{"type": "RequestMirror", "requestMirror": {"backendRef": {"name": "a", "port": 80}}}]}`,
+			`filter 0: RequestMirror: request mirroring is not supported`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var rule v1.GRPCRouteRule
+			require.NoError(t, json.Unmarshal([]byte(tc.rule), &rule))
+			err := gateway.ValidateGRPCRule(&rule)
+			if tc.expect == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestValidateGRPCRules(t *testing.T) {
+	t.Parallel()
+
+	var rules []v1.GRPCRouteRule
+	require.NoError(t, json.Unmarshal([]byte(`[{
+		"matches": [{"method": {"service": "foo.Foo"}}, {"headers": [{"name": "x-version", "value": "2"}]}]
+	}, {
+		"matches": [{"headers": [{"name": "x-version", "value": "2"}]}]
+	}, {
+		"matches": [{"method": {"service": "bar.Bar"}}]
+	}]`), &rules))
+
+	unsupported := gateway.ValidateGRPCRules(rules)
+	require.Len(t, unsupported, 2)
+
+	assert.Equal(t, 0, unsupported[0].Index)
+	assert.False(t, unsupported[0].Dropped, "only one match of the rule is unsupported")
+	assert.True(t, unsupported[0].UnsupportedMatch)
+	assert.EqualError(t, unsupported[0].Err, "match 1: header matches are not supported")
+
+	assert.Equal(t, 1, unsupported[1].Index)
+	assert.True(t, unsupported[1].Dropped)
+	assert.EqualError(t, unsupported[1].Err, "match 0: header matches are not supported")
+}
+
+func TestTranslateTunnelRoutes(t *testing.T) {
+	t.Parallel()
+
+	addresses := []model.GatewayListenerAddress{
+		{Protocol: v1.TCPProtocolType, Hostname: "db.example.com", Port: 5432},
+		{Protocol: v1.TCPProtocolType, Hostname: "db.example.net", Port: 5433},
+	}
+
+	t.Run("tcp", func(t *testing.T) {
+		t.Parallel()
+
+		var route v1.TCPRoute
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"metadata": {
+				"name": "postgres",
+				"namespace": "default"
+			},
+			"spec": {
+				"rules": [{
+					"backendRefs": [{
+						"name": "postgres",
+						"port": 5432
+					}]
+				}]
+			}
+		}`), &route))
+
+		result := gateway.TranslateTCPRoutes(&model.GatewayTCPRouteConfig{
+			TCPRoute:         &route,
+			Addresses:        addresses,
+			ValidBackendRefs: allBackendRefsValid{},
+		})
+		require.Len(t, result, 2)
+		assert.Equal(t, "tcp+https://db.example.com:5432", result[0].GetFrom())
+		assert.Equal(t, "default-postgres-tcp-db-example-com-5432", result[0].GetName())
+		assert.Equal(t, "tcp+https://db.example.net:5433", result[1].GetFrom())
+		for _, r := range result {
+			assert.Equal(t, []string{"tcp://postgres.default.svc.cluster.local:5432"}, r.GetTo())
+		}
+	})
+	t.Run("udp", func(t *testing.T) {
+		t.Parallel()
+
+		var route v1.UDPRoute
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"metadata": {
+				"name": "dns",
+				"namespace": "default"
+			},
+			"spec": {
+				"rules": [{
+					"backendRefs": [{
+						"name": "dns-a",
+						"port": 53
+					}]
+				}, {
+					"backendRefs": [{
+						"name": "dns-b",
+						"port": 53,
+						"weight": 2
+					}]
+				}]
+			}
+		}`), &route))
+
+		result := gateway.TranslateUDPRoutes(&model.GatewayUDPRouteConfig{
+			UDPRoute:         &route,
+			Addresses:        addresses[:1],
+			ValidBackendRefs: allBackendRefsValid{},
+		})
+		require.Len(t, result, 1)
+		assert.Equal(t, "udp+https://db.example.com:5432", result[0].GetFrom())
+		assert.Equal(t, []string{
+			"udp://dns-a.default.svc.cluster.local:53",
+			"udp://dns-b.default.svc.cluster.local:53",
+		}, result[0].GetTo())
+		assert.Equal(t, []uint32{1, 2}, result[0].GetLoadBalancingWeights())
+	})
+	t.Run("no valid backends", func(t *testing.T) {
+		t.Parallel()
+
+		var route v1.TCPRoute
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"spec": {
+				"rules": [{
+					"backendRefs": [{
+						"name": "postgres",
+						"port": 5432
+					}]
+				}]
+			}
+		}`), &route))
+
+		result := gateway.TranslateTCPRoutes(&model.GatewayTCPRouteConfig{
+			TCPRoute:         &route,
+			Addresses:        addresses,
+			ValidBackendRefs: noBackendRefsValid{},
+		})
+		assert.Empty(t, result)
+	})
+}
+
+type allBackendRefsValid struct{}
+
+func (allBackendRefsValid) Valid(client.Object, *v1.BackendRef) bool { return true }
+
+type noBackendRefsValid struct{}
+
+func (noBackendRefsValid) Valid(client.Object, *v1.BackendRef) bool { return false }
+
+func httpsAddresses(hostnames ...v1.Hostname) []model.GatewayListenerAddress {
+	addresses := make([]model.GatewayListenerAddress, len(hostnames))
+	for i, h := range hostnames {
+		addresses[i] = model.GatewayListenerAddress{Protocol: v1.HTTPSProtocolType, Hostname: h, Port: 443}
+	}
+	return addresses
 }
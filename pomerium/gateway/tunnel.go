@@ -0,0 +1,113 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/gosimple/slug"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// TranslateTCPRoutes converts from a Gateway-defined TCP route to Pomerium TCP tunnel routes, one
+// per listener address. Any routes that fail to validate are left out (see ValidateTCPRoutes).
+func TranslateTCPRoutes(routeConfig *model.GatewayTCPRouteConfig) []*pb.Route {
+	prs, _ := translateTCPRoutes(routeConfig)
+	return prs
+}
+
+// ValidateTCPRoutes translates a TCPRoute as TranslateTCPRoutes does, and returns an
+// *InvalidRoutesError if any of the resulting Pomerium routes fail validation.
+func ValidateTCPRoutes(routeConfig *model.GatewayTCPRouteConfig) error {
+	_, err := translateTCPRoutes(routeConfig)
+	return err
+}
+
+func translateTCPRoutes(routeConfig *model.GatewayTCPRouteConfig) ([]*pb.Route, error) {
+	var backendRefs []*gateway_v1.BackendRef
+	for i := range routeConfig.Spec.Rules {
+		backendRefs = append(backendRefs, tunnelBackendRefs(routeConfig.Spec.Rules[i].BackendRefs)...)
+	}
+	return translateTunnelRoutes("tcp", routeConfig.Addresses, tcpRouteBackends(routeConfig), backendRefs)
+}
+
+// TranslateUDPRoutes converts from a Gateway-defined UDP route to Pomerium UDP tunnel routes, one
+// per listener address. Any routes that fail to validate are left out (see ValidateUDPRoutes).
+func TranslateUDPRoutes(routeConfig *model.GatewayUDPRouteConfig) []*pb.Route {
+	prs, _ := translateUDPRoutes(routeConfig)
+	return prs
+}
+
+// ValidateUDPRoutes translates a UDPRoute as TranslateUDPRoutes does, and returns an
+// *InvalidRoutesError if any of the resulting Pomerium routes fail validation.
+func ValidateUDPRoutes(routeConfig *model.GatewayUDPRouteConfig) error {
+	_, err := translateUDPRoutes(routeConfig)
+	return err
+}
+
+func translateUDPRoutes(routeConfig *model.GatewayUDPRouteConfig) ([]*pb.Route, error) {
+	var backendRefs []*gateway_v1.BackendRef
+	for i := range routeConfig.Spec.Rules {
+		backendRefs = append(backendRefs, tunnelBackendRefs(routeConfig.Spec.Rules[i].BackendRefs)...)
+	}
+	return translateTunnelRoutes("udp", routeConfig.Addresses, udpRouteBackends(routeConfig), backendRefs)
+}
+
+func translateTunnelRoutes(
+	protocol string,
+	addresses []model.GatewayListenerAddress,
+	b *routeBackends,
+	backendRefs []*gateway_v1.BackendRef,
+) ([]*pb.Route, error) {
+	// TCPRoute and UDPRoute rules have no matches to distinguish between them, so the backendRefs
+	// of all rules are combined into a single set of upstreams.
+	//
+	// A tunnel route has no way to respond with an error, so if there are no valid backends
+	// there is nothing to configure.
+	template := &pb.Route{}
+	if err := appendBackendRefs(template, b, backendRefs); err != nil || len(template.To) == 0 {
+		return nil, nil
+	}
+
+	namespaceAndName := slug.Make(fmt.Sprintf("%s %s %s", b.route.GetNamespace(), b.route.GetName(), protocol))
+
+	var invalid []error
+	prs := make([]*pb.Route, 0, len(addresses))
+	for _, a := range addresses {
+		port := strconv.Itoa(int(a.Port))
+		r := &pb.Route{
+			From: (&url.URL{
+				Scheme: protocol + "+https",
+				Host:   net.JoinHostPort(string(a.Hostname), port),
+			}).String(),
+			To:                   template.To,
+			LoadBalancingWeights: template.LoadBalancingWeights,
+			Name:                 new(namespaceAndName + "-" + slug.Make(string(a.Hostname)) + "-" + port),
+		}
+
+		if err := validateRoute(r); err != nil {
+			invalid = append(invalid, fmt.Errorf("route %s: %w", r.GetName(), err))
+			continue
+		}
+
+		prs = append(prs, r)
+	}
+
+	if len(invalid) > 0 {
+		return prs, &InvalidRoutesError{Valid: len(prs), Errs: invalid}
+	}
+	return prs, nil
+}
+
+func tunnelBackendRefs(backendRefs []gateway_v1.BackendRef) []*gateway_v1.BackendRef {
+	refs := make([]*gateway_v1.BackendRef, len(backendRefs))
+	for i := range backendRefs {
+		refs[i] = &backendRefs[i]
+	}
+	return refs
+}
@@ -0,0 +1,64 @@
+package pomerium
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+)
+
+// gatewayRouteCache holds the Pomerium routes translated from each Gateway API route object,
+// keyed by the route fingerprint computed by the Gateway controller, so that only routes whose
+// inputs have changed need to be translated again.
+type gatewayRouteCache struct {
+	current map[string][]*pb.Route
+	pending map[string][]*pb.Route
+}
+
+// translate returns the cached routes for fingerprint, or calls fn and records its result.
+// Routes without a fingerprint are always translated.
+func (c *gatewayRouteCache) translate(fingerprint string, fn func() []*pb.Route) []*pb.Route {
+	if fingerprint == "" {
+		return fn()
+	}
+	if c.pending == nil {
+		c.pending = make(map[string][]*pb.Route)
+	}
+	routes, ok := c.current[fingerprint]
+	if !ok {
+		routes = fn()
+	}
+	c.pending[fingerprint] = routes
+	return routes
+}
+
+// commit replaces the cache contents with the routes used since the last commit or discard,
+// dropping the entries for routes that no longer exist or have changed.
+func (c *gatewayRouteCache) commit() {
+	c.current, c.pending = c.pending, nil
+}
+
+// discard drops the routes translated since the last commit, for when they could not be applied.
+func (c *gatewayRouteCache) discard() {
+	c.pending = nil
+}
+
+// gatewayRouteSyncKey returns the key under which a synced Gateway API route is recorded. The
+// route is synced again if either its fingerprint or any of the policy or CA keypair IDs it may
+// reference has changed.
+func gatewayRouteSyncKey(fingerprint string, policyIDs, caKeyPairIDs map[string]string) string {
+	h := sha256.New()
+	h.Write([]byte(fingerprint))
+	for _, m := range []map[string]string{policyIDs, caKeyPairIDs} {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			h.Write([]byte{0})
+			h.Write([]byte(k))
+			h.Write([]byte{0})
+			h.Write([]byte(m[k]))
+		}
+		h.Write([]byte{1})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
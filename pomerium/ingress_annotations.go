@@ -15,6 +15,7 @@ import (
 
 	"github.com/pomerium/ingress-controller/internal/policy"
 	"github.com/pomerium/ingress-controller/model"
+	"github.com/pomerium/ingress-controller/pomerium/gateway"
 	"github.com/pomerium/ingress-controller/util"
 )
 
@@ -100,6 +101,7 @@ var (
 		model.UDPUpstream,
 		model.UseServiceProxy,
 		model.SubtleAllowEmptyHost,
+		model.PolicyRef,
 	})
 	unsupported = map[string]string{
 		"allowed_groups": "https://docs.pomerium.com/docs/overview/upgrading#idp-directory-sync",
@@ -198,6 +200,32 @@ func applyAnnotations(
 	if err := unmarshalPolicyAnnotations(p, kv.Policy); err != nil {
 		return fmt.Errorf("applying policy annotations: %w", err)
 	}
+	if err := applyPolicyRefs(r, ic); err != nil {
+		return fmt.Errorf("applying %s annotation: %w", model.PolicyRef, err)
+	}
+	return nil
+}
+
+// applyPolicyRefs appends the policies of the PolicyFilters referenced by the policy_ref
+// annotation, after the policy defined by the policy annotations.
+func applyPolicyRefs(r *configpb.Route, ic *model.IngressConfig) error {
+	refs, err := ic.GetPolicyRefs()
+	if err != nil {
+		return err
+	}
+	for _, name := range refs {
+		obj := ic.PolicyFilters[name]
+		if obj == nil {
+			return fmt.Errorf("policy filter %s wasn't fetched. this is a bug", name)
+		}
+		pf, err := gateway.NewPolicyFilter(obj)
+		if err != nil {
+			return fmt.Errorf("policy filter %s: %w", name, err)
+		}
+		if err := pf.ApplyToRoute(r); err != nil {
+			return fmt.Errorf("policy filter %s: %w", name, err)
+		}
+	}
 	return nil
 }
 
@@ -23,6 +23,7 @@ import (
 
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
 
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
 	"github.com/pomerium/ingress-controller/model"
 )
 
@@ -785,3 +786,51 @@ func TestNameAnnotation(t *testing.T) {
 		assert.Equal(t, "", r.GetName())
 	})
 }
+
+func TestPolicyRefAnnotation(t *testing.T) {
+	sharedPPL := `{"allow":{"or":[{"email":{"is":"shared@example.com"}}]}}`
+	newIngressConfig := func(ref string) *model.IngressConfig {
+		return &model.IngressConfig{
+			AnnotationPrefix: "a",
+			Ingress: &networkingv1.Ingress{
+				ObjectMeta: v1.ObjectMeta{
+					Name:      "test-ingress",
+					Namespace: "test",
+					Annotations: map[string]string{
+						"a/policy":     testPPL1,
+						"a/policy_ref": ref,
+					},
+				},
+			},
+			PolicyFilters: map[types.NamespacedName]*icgv1alpha1.PolicyFilter{
+				{Name: "one", Namespace: "test"}: {Spec: icgv1alpha1.PolicyFilterSpec{PPL: testPPL2}},
+				{Name: "two", Namespace: "test"}: {Spec: icgv1alpha1.PolicyFilterSpec{PPL: sharedPPL}},
+			},
+		}
+	}
+
+	t.Run("shared policies follow the ingress policy", func(t *testing.T) {
+		r := &pb.Route{}
+		require.NoError(t, applyAnnotations(r, newIngressConfig("two, one,two")))
+		require.Len(t, r.Policies, 3)
+		assert.NotEmpty(t, r.Policies[0].Rego)
+		assert.Equal(t, sharedPPL, r.Policies[1].GetSourcePpl())
+		assert.Equal(t, testPPL2, r.Policies[2].GetSourcePpl())
+	})
+
+	t.Run("empty list", func(t *testing.T) {
+		assert.Error(t, applyAnnotations(&pb.Route{}, newIngressConfig(" , ")))
+		assert.Error(t, ValidateIngressAnnotations(map[string]string{"a/policy_ref": ""}, "a"))
+		assert.NoError(t, ValidateIngressAnnotations(map[string]string{"a/policy_ref": "one,two"}, "a"))
+	})
+
+	t.Run("not fetched", func(t *testing.T) {
+		assert.ErrorContains(t, applyAnnotations(&pb.Route{}, newIngressConfig("three")), "wasn't fetched")
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		ic := newIngressConfig("one")
+		ic.PolicyFilters[types.NamespacedName{Name: "one", Namespace: "test"}].DeletionTimestamp = new(v1.Now())
+		assert.Error(t, applyAnnotations(&pb.Route{}, ic))
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/pomerium/ingress-controller/model"
 )
@@ -31,6 +33,60 @@ type ConfigReconciler interface {
 	SetConfig(ctx context.Context, cfg *model.Config) (changes bool, err error)
 }
 
+// GarbageCollector removes Pomerium configuration left behind by Kubernetes objects that no longer
+// exist. It must be safe to call concurrently with the other reconciler methods.
+type GarbageCollector interface {
+	// CollectGarbage deletes any configuration originated by the controller that is no longer
+	// referenced by a Kubernetes object.
+	CollectGarbage(ctx context.Context, k8s client.Reader, opts GarbageCollectionOptions) (changes bool, err error)
+}
+
+// GarbageCollectionOptions configures a garbage collection pass.
+type GarbageCollectionOptions struct {
+	// GatewayAPI should be set if Gateway API objects are synced, so that these are checked for
+	// references. Otherwise any configuration created for Gateway API objects is unreferenced.
+	GatewayAPI bool
+	// DryRun only logs the unreferenced configuration rather than deleting it.
+	DryRun bool
+}
+
+// DriftDetector detects changes made to the synced Pomerium configuration by other means, such as
+// the Pomerium Zero or Enterprise console. It must be safe to call concurrently with the other
+// reconciler methods.
+type DriftDetector interface {
+	// CheckDrift compares the current Pomerium configuration with the configuration last synced,
+	// and reverts or reports any differences.
+	CheckDrift(ctx context.Context, opts DriftCheckOptions) (changes bool, err error)
+}
+
+// DriftCheckOptions configures a drift check.
+type DriftCheckOptions struct {
+	// DefaultMode applies to objects without a drift mode annotation.
+	DefaultMode DriftMode
+	// EventRecorder, if set, records an Event on the Kubernetes object that any drifted
+	// configuration was synced from.
+	EventRecorder record.EventRecorder
+	// Resync, if set, is called with the Kubernetes object that any deleted configuration was
+	// synced from, in revert mode, so that it is synced again and the configuration recreated.
+	Resync func(owner client.Object)
+}
+
+// APITokenUpdater is implemented by reconcilers using a unified API token that may be replaced
+// while running.
+type APITokenUpdater interface {
+	// SetAPIToken replaces the unified API token used for subsequent requests.
+	SetAPIToken(token string)
+	// APIAuthError returns an error if the most recent request was rejected as unauthenticated.
+	APIAuthError() error
+}
+
+// APIHealthChecker is implemented by reconcilers that can report whether the unified API is
+// reachable.
+type APIHealthChecker interface {
+	// APIHealthError returns an error while unified API requests are failing repeatedly.
+	APIHealthError() error
+}
+
 // Reconciler is the combination of all the individual reconcilers.
 type Reconciler interface {
 	IngressReconciler
@@ -0,0 +1,69 @@
+package pomerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// RenderOptions are the inputs of the Pomerium configuration produced by Render.
+type RenderOptions struct {
+	// Settings, if set, are the global settings from the Pomerium CRD.
+	Settings *model.Config
+	// Ingresses are all the Ingresses managed by the controller.
+	Ingresses []*model.IngressConfig
+	// Gateway, if set, is the Gateway-defined configuration.
+	Gateway *model.GatewayConfig
+	// Redact replaces private keys and credentials with a placeholder. Header values set from
+	// Secrets can't be told apart from others, and are kept.
+	Redact bool
+}
+
+// RedactedValue replaces sensitive values in the configuration produced by Render.
+const RedactedValue = "REDACTED"
+
+// Render returns the Pomerium configuration that the databroker reconcilers would apply for opts,
+// merged into a single Config. Unlike the reconcilers, which skip an Ingress that can't be
+// translated, Render returns an error naming each such Ingress, so that it may be used to check
+// manifests ahead of deployment.
+func Render(ctx context.Context, opts RenderOptions) (*pb.Config, error) {
+	dst := new(pb.Config)
+	var errs []error
+
+	if opts.Settings != nil {
+		settings := new(pb.Config)
+		if err := ApplyConfig(ctx, settings, opts.Settings); err != nil {
+			errs = append(errs, fmt.Errorf("settings: %w", err))
+		}
+		proto.Merge(dst, settings)
+	}
+
+	ingresses := new(pb.Config)
+	for _, ic := range opts.Ingresses {
+		if err := upsertRoutes(ctx, ingresses, ic); err != nil {
+			errs = append(errs, fmt.Errorf("ingress %s/%s: %w", ic.Namespace, ic.Name, err))
+			continue
+		}
+		addCerts(ingresses, ic.Secrets)
+	}
+	if err := removeUnusedCerts(ingresses); err != nil {
+		errs = append(errs, fmt.Errorf("removing unused certs: %w", err))
+	}
+	proto.Merge(dst, ingresses)
+
+	if opts.Gateway != nil {
+		proto.Merge(dst, translateGatewayConfig(ctx, opts.Gateway, new(gatewayRouteCache)))
+	}
+
+	ensureDeterministicConfigOrder(dst)
+	if opts.Redact {
+		redactMessage(dst.ProtoReflect())
+	}
+	return dst, errors.Join(errs...)
+}
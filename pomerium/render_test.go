@@ -0,0 +1,90 @@
+package pomerium
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	newIngress := func(name, service string) *model.IngressConfig {
+		pathType := networkingv1.PathTypePrefix
+		return &model.IngressConfig{
+			AnnotationPrefix: "ingress.pomerium.io",
+			Ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Annotations: map[string]string{
+						"ingress.pomerium.io/allow_any_authenticated_user": "true",
+						"ingress.pomerium.io/service_proxy_upstream":       "true",
+						"ingress.pomerium.io/tls_client_secret":            "client",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{
+						Host: name + ".localhost.pomerium.io",
+						IngressRuleValue: networkingv1.IngressRuleValue{
+							HTTP: &networkingv1.HTTPIngressRuleValue{
+								Paths: []networkingv1.HTTPIngressPath{{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: service,
+											Port: networkingv1.ServiceBackendPort{Number: 80},
+										},
+									},
+								}},
+							},
+						},
+					}},
+				},
+			},
+			Secrets: map[types.NamespacedName]*corev1.Secret{
+				{Namespace: "default", Name: "client"}: {
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "client"},
+					Type:       corev1.SecretTypeOpaque,
+					Data: map[string][]byte{
+						corev1.TLSCertKey:       []byte("cert"),
+						corev1.TLSPrivateKeyKey: []byte("key"),
+					},
+				},
+			},
+			Services: map[types.NamespacedName]*corev1.Service{
+				{Namespace: "default", Name: "service"}: {
+					ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "service"},
+					Spec: corev1.ServiceSpec{
+						Ports: []corev1.ServicePort{{Port: 80, TargetPort: intstr.FromInt(8080)}},
+					},
+				},
+			},
+		}
+	}
+
+	cfg, err := Render(context.Background(), RenderOptions{
+		Ingresses: []*model.IngressConfig{
+			newIngress("valid", "service"),
+			newIngress("invalid", "missing"),
+		},
+		Redact: true,
+	})
+	require.ErrorContains(t, err, "ingress default/invalid")
+	require.Len(t, cfg.Routes, 1, "the routes of the valid ingress should be rendered")
+	route := cfg.Routes[0]
+	assert.Equal(t, "https://valid.localhost.pomerium.io", route.From)
+	assert.Equal(t, []string{"http://service.default.svc.cluster.local:80"}, route.To)
+	assert.Equal(t, RedactedValue, route.TlsClientKey)
+	assert.NotEqual(t, RedactedValue, route.TlsClientCert)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/url"
 	"sort"
 	"testing"
@@ -16,6 +17,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -278,16 +280,9 @@ func TestSecureUpstream(t *testing.T) {
 				}},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{{
-					Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-					Ports:     []corev1.EndpointPort{{Name: "https", Port: 443}},
-				}},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("https", 443)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -364,16 +359,9 @@ func TestCustomSecrets(t *testing.T) {
 				}},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{{
-					Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-					Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-				}},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -470,16 +458,9 @@ func TestKubernetesToken(t *testing.T) {
 				}},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{{
-					Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-					Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-				}},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -608,16 +589,9 @@ func TestTCPUpstream(t *testing.T) {
 				}},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{{
-					Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-					Ports:     []corev1.EndpointPort{{Name: "app", Port: 12345}},
-				}},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("app", 12345)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -980,16 +954,9 @@ func TestUseServiceProxy(t *testing.T) {
 				}},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{{
-					Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-					Ports:     []corev1.EndpointPort{{Port: 80}},
-				}},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -1081,18 +1048,18 @@ func shuffleRoutes(random *rand.Rand, routes []*pb.Route) {
 	}
 }
 
-// TestServicePortsAndEndpoints checks that only correct Endpoints would be selected for a Service
+// TestServicePortsAndEndpoints checks that only correct EndpointSlices would be selected for a Service
 // https://github.com/pomerium/ingress-controller/issues/157
 // - if there's just one port defined for the service, it may be defined in numerical form
-// - if there are multiple, then name is required, that would be repeated in the endpoints
+// - if there are multiple, then name is required, that would be repeated in the endpoint slices
 func TestServicePortsAndEndpoints(t *testing.T) {
 	for _, tc := range []struct {
-		name            string
-		ingressPort     networkingv1.ServiceBackendPort
-		svcPorts        []corev1.ServicePort
-		endpointSubsets []corev1.EndpointSubset
-		expectTO        []string
-		expectError     bool
+		name           string
+		ingressPort    networkingv1.ServiceBackendPort
+		svcPorts       []corev1.ServicePort
+		endpointSlices []*discoveryv1.EndpointSlice
+		expectTO       []string
+		expectError    bool
 	}{
 		{
 			"unnamed port",
@@ -1101,10 +1068,9 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       8080,
 				TargetPort: intstr.IntOrString{IntVal: 80},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-				Ports:     []corev1.EndpointPort{{Port: 80}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("", 80)),
+			},
 			[]string{
 				"http://1.2.3.4:80",
 			},
@@ -1118,10 +1084,9 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       8000,
 				TargetPort: intstr.IntOrString{IntVal: 80},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-				Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
+			},
 			[]string{
 				"http://1.2.3.4:80",
 			},
@@ -1135,10 +1100,9 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       8000,
 				TargetPort: intstr.IntOrString{StrVal: "grafana-http", Type: intstr.String},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-				Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
+			},
 			[]string{
 				"http://1.2.3.4:80",
 			},
@@ -1151,15 +1115,13 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       80,
 				TargetPort: intstr.IntOrString{StrVal: "backend-http", Type: intstr.String},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "192.0.2.1"}},
-				Ports:     []corev1.EndpointPort{{Port: 8080}},
-			}},
-			// In that case we can't easily determine which Endpoints port
-			// corresponds to the Service port, so we need to fallback to using the
-			// Service instead.
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"192.0.2.1"}, endpointPort("", 8080)),
+			},
+			// EndpointSlice ports are named after the Service port,
+			// so the resolved target port is known even if it was named.
 			[]string{
-				"http://service.default.svc.cluster.local:80",
+				"http://192.0.2.1:8080",
 			},
 			false,
 		},
@@ -1171,10 +1133,9 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       8000,
 				TargetPort: intstr.IntOrString{IntVal: 80},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "1.2.3.5"}},
-				Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("http", 80)),
+			},
 			[]string{
 				"http://1.2.3.4:80",
 				"http://1.2.3.5:80",
@@ -1193,16 +1154,94 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 				Port:       9090,
 				TargetPort: intstr.IntOrString{IntVal: 8090},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "1.2.3.5"}},
-				Ports: []corev1.EndpointPort{
-					{Name: "metrics", Port: 8090},
-					{Name: "http", Port: 80},
-				},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("metrics", 8090), endpointPort("http", 80)),
+			},
+			[]string{
+				"http://1.2.3.4:80",
+				"http://1.2.3.5:80",
+			},
+			false,
+		},
+		{
+			"multiple slices",
+			networkingv1.ServiceBackendPort{Name: "http"},
+			[]corev1.ServicePort{{
+				Name:       "http",
+				Port:       8000,
+				TargetPort: intstr.IntOrString{IntVal: 80},
 			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("http", 80)),
+				testEndpointSlice("service", []string{"1.2.3.5", "1.2.3.6"}, endpointPort("http", 80)),
+				testEndpointSlice("service", []string{"2001:db8::1"}, endpointPort("http", 80)),
+			},
 			[]string{
 				"http://1.2.3.4:80",
 				"http://1.2.3.5:80",
+				"http://1.2.3.6:80",
+				"http://[2001:db8::1]:80",
+			},
+			false,
+		},
+		{
+			"not serving and terminating endpoints",
+			networkingv1.ServiceBackendPort{Name: "http"},
+			[]corev1.ServicePort{{
+				Name:       "http",
+				Port:       8000,
+				TargetPort: intstr.IntOrString{IntVal: 80},
+			}},
+			[]*discoveryv1.EndpointSlice{
+				withEndpointConditions(
+					testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
+					discoveryv1.EndpointConditions{Ready: proto.Bool(false), Serving: proto.Bool(false)},
+				),
+				withEndpointConditions(
+					testEndpointSlice("service", []string{"1.2.3.5"}, endpointPort("http", 80)),
+					discoveryv1.EndpointConditions{Ready: proto.Bool(false), Serving: proto.Bool(true), Terminating: proto.Bool(true)},
+				),
+				testEndpointSlice("service", []string{"1.2.3.6"}, endpointPort("http", 80)),
+			},
+			[]string{
+				"http://1.2.3.6:80",
+			},
+			false,
+		},
+		{
+			"only terminating endpoints",
+			networkingv1.ServiceBackendPort{Name: "http"},
+			[]corev1.ServicePort{{
+				Name:       "http",
+				Port:       8000,
+				TargetPort: intstr.IntOrString{IntVal: 80},
+			}},
+			[]*discoveryv1.EndpointSlice{
+				withEndpointConditions(
+					testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
+					discoveryv1.EndpointConditions{Ready: proto.Bool(false), Serving: proto.Bool(true), Terminating: proto.Bool(true)},
+				),
+				withEndpointConditions(
+					testEndpointSlice("service", []string{"1.2.3.5"}, endpointPort("http", 80)),
+					discoveryv1.EndpointConditions{Ready: proto.Bool(false), Serving: proto.Bool(false), Terminating: proto.Bool(true)},
+				),
+			},
+			[]string{
+				"http://1.2.3.4:80",
+			},
+			false,
+		},
+		{
+			"no endpoints",
+			networkingv1.ServiceBackendPort{Name: "http"},
+			[]corev1.ServicePort{{
+				Name:       "http",
+				Port:       8000,
+				TargetPort: intstr.IntOrString{IntVal: 80},
+			}},
+			nil,
+			[]string{
+				"http://service.default.svc.cluster.local:8000",
 			},
 			false,
 		},
@@ -1240,14 +1279,8 @@ func TestServicePortsAndEndpoints(t *testing.T) {
 						}},
 					},
 				},
-				Endpoints: map[types.NamespacedName]*corev1.Endpoints{
-					{Name: "service", Namespace: "default"}: {
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "service",
-							Namespace: "default",
-						},
-						Subsets: tc.endpointSubsets,
-					},
+				EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
+					{Name: "service", Namespace: "default"}: tc.endpointSlices,
 				},
 				Services: map[types.NamespacedName]*corev1.Service{
 					{Name: "service", Namespace: "default"}: {
@@ -1288,7 +1321,7 @@ func TestEndpointsHTTPS(t *testing.T) {
 		ingressAnnotations  map[string]string
 		ingressPort         networkingv1.ServiceBackendPort
 		svcPorts            []corev1.ServicePort
-		endpointSubsets     []corev1.EndpointSubset
+		endpointSlices      []*discoveryv1.EndpointSlice
 		expectTO            []string
 		expectTLSServerName string
 	}{
@@ -1303,10 +1336,9 @@ func TestEndpointsHTTPS(t *testing.T) {
 				Port:       443,
 				TargetPort: intstr.IntOrString{IntVal: 443},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "1.2.3.5"}},
-				Ports:     []corev1.EndpointPort{{Name: "https", Port: 443}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("https", 443)),
+			},
 			[]string{
 				"https://1.2.3.4:443",
 				"https://1.2.3.5:443",
@@ -1325,10 +1357,9 @@ func TestEndpointsHTTPS(t *testing.T) {
 				Port:       443,
 				TargetPort: intstr.IntOrString{IntVal: 443},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "1.2.3.5"}},
-				Ports:     []corev1.EndpointPort{{Name: "https", Port: 443}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("https", 443)),
+			},
 			[]string{
 				"https://1.2.3.4:443",
 				"https://1.2.3.5:443",
@@ -1347,10 +1378,9 @@ func TestEndpointsHTTPS(t *testing.T) {
 				Port:       443,
 				TargetPort: intstr.IntOrString{IntVal: 443},
 			}},
-			[]corev1.EndpointSubset{{
-				Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}, {IP: "1.2.3.5"}},
-				Ports:     []corev1.EndpointPort{{Name: "https", Port: 443}},
-			}},
+			[]*discoveryv1.EndpointSlice{
+				testEndpointSlice("service", []string{"1.2.3.4", "1.2.3.5"}, endpointPort("https", 443)),
+			},
 			[]string{
 				"https://service.default.svc.cluster.local:443",
 			},
@@ -1391,14 +1421,8 @@ func TestEndpointsHTTPS(t *testing.T) {
 						}},
 					},
 				},
-				Endpoints: map[types.NamespacedName]*corev1.Endpoints{
-					{Name: "service", Namespace: "default"}: {
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "service",
-							Namespace: "default",
-						},
-						Subsets: tc.endpointSubsets,
-					},
+				EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
+					{Name: "service", Namespace: "default"}: tc.endpointSlices,
 				},
 				Services: map[types.NamespacedName]*corev1.Service{
 					{Name: "service", Namespace: "default"}: {
@@ -1469,18 +1493,9 @@ func TestEmptyHostRoute(t *testing.T) {
 				},
 			},
 		},
-		Endpoints: map[types.NamespacedName]*corev1.Endpoints{
+		EndpointSlices: map[types.NamespacedName][]*discoveryv1.EndpointSlice{
 			{Name: "service", Namespace: "default"}: {
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "service",
-					Namespace: "default",
-				},
-				Subsets: []corev1.EndpointSubset{
-					{
-						Addresses: []corev1.EndpointAddress{{IP: "1.2.3.4"}},
-						Ports:     []corev1.EndpointPort{{Name: "http", Port: 80}},
-					},
-				},
+				testEndpointSlice("service", []string{"1.2.3.4"}, endpointPort("http", 80)),
 			},
 		},
 		Services: map[types.NamespacedName]*corev1.Service{
@@ -1608,3 +1623,38 @@ func TestCustomRouteName(t *testing.T) {
 	// The envoy opts should have the unique slug for stats
 	assert.Equal(t, proto.String("default-test-ingress-service-localhost-pomerium-io"), route.StatName)
 }
+
+func testEndpointSlice(service string, addresses []string, ports ...discoveryv1.EndpointPort) *discoveryv1.EndpointSlice {
+	addressType := discoveryv1.AddressTypeIPv4
+	endpoints := make([]discoveryv1.Endpoint, 0, len(addresses))
+	for _, addr := range addresses {
+		if net.ParseIP(addr).To4() == nil {
+			addressType = discoveryv1.AddressTypeIPv6
+		}
+		endpoints = append(endpoints, discoveryv1.Endpoint{
+			Addresses:  []string{addr},
+			Conditions: discoveryv1.EndpointConditions{Ready: proto.Bool(true), Serving: proto.Bool(true)},
+		})
+	}
+	return &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", service, addressType),
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: service},
+		},
+		AddressType: addressType,
+		Endpoints:   endpoints,
+		Ports:       ports,
+	}
+}
+
+func withEndpointConditions(slice *discoveryv1.EndpointSlice, cond discoveryv1.EndpointConditions) *discoveryv1.EndpointSlice {
+	for i := range slice.Endpoints {
+		slice.Endpoints[i].Conditions = cond
+	}
+	return slice
+}
+
+func endpointPort(name string, port int32) discoveryv1.EndpointPort {
+	return discoveryv1.EndpointPort{Name: proto.String(name), Port: proto.Int32(port)}
+}
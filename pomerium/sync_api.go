@@ -2,7 +2,10 @@ package pomerium
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"maps"
@@ -12,10 +15,13 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"connectrpc.com/connect"
 	"github.com/google/go-cmp/cmp"
 	"github.com/gosimple/slug"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -37,15 +43,19 @@ import (
 )
 
 // NewAPIReconciler initializes a reconciler that syncs using the unified API,
-// for the given API url and API token.
+// for the given API url and API token. The token may be replaced later using SetAPIToken.
+// Objects are synced to the API namespace with the given ID, unless mapped to another
+// (see apiNamespaceFor).
 func NewAPIReconciler(
 	apiURL, namespaceID, apiToken string, baseOptions *config.Options, dialAddressOverride string,
+	options ...APIReconcilerOption,
 ) (Reconciler, error) {
 	opts := []sdk.ClientOption{
 		sdk.WithURL(apiURL),
 		sdk.WithAPIToken(apiToken),
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
 	if dialAddressOverride != "" {
 		u, err := url.Parse(apiURL)
 		if err != nil {
@@ -56,22 +66,37 @@ func NewAPIReconciler(
 				ServerName: u.Hostname(),
 			},
 		}
-		transport := http.DefaultTransport.(*http.Transport).Clone()
 		transport.DialTLSContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
 			return dialer.DialContext(ctx, network, dialAddressOverride)
 		}
-		opts = append(opts, sdk.WithHTTPClient(&http.Client{
-			Transport: transport,
-		}))
 	}
 	ar := &APIReconciler{
-		apiClient:   sdk.NewClient(opts...),
-		baseOptions: baseOptions,
-		secretsMap:  model.NewTLSSecretsMap(),
+		baseOptions:   baseOptions,
+		secretsMap:    model.NewTLSSecretsMap(),
+		clientOptions: DefaultAPIClientOptions(),
 	}
 	if namespaceID != "" {
 		ar.namespaceID = &namespaceID
 	}
+	for _, opt := range options {
+		opt(ar)
+	}
+	// Without a token, requests would only be rejected, so these wait until one is set (see
+	// SetAPIToken).
+	ar.creds.loaded = make(chan struct{})
+	if apiToken != "" {
+		ar.creds.setLoaded()
+	}
+	opts = append(opts, sdk.WithHTTPClient(&http.Client{
+		Transport: &apiTokenTransport{base: transport, creds: &ar.creds},
+	}))
+	var apiClient sdk.Client = sdk.NewClient(opts...)
+	if ar.dryRun != nil {
+		apiClient = newDryRunAPIClient(apiClient, ar.dryRun)
+	}
+	rc := newResilientClient(apiClient, ar.clientOptions)
+	rc.tokenLoaded = ar.creds.loaded
+	ar.apiClient = rc
 	return ar, nil
 }
 
@@ -88,17 +113,75 @@ type APIReconciler struct {
 
 	baseOptions *config.Options
 	namespaceID *string
+	namespaces  apiNamespaces
 	secretsMap  *model.TLSSecretsMap
+
+	// clientOptions configures the rate limiting, retries and circuit breaking of apiClient.
+	clientOptions APIClientOptions
+	// dryRun, if set, is passed the writes that apiClient would make instead (see WithDryRun).
+	dryRun DryRunFunc
+
+	// syncedGatewayRoutes holds the sync keys (see gatewayRouteSyncKey) of the Gateway API routes
+	// successfully synced by the previous SetGatewayConfig call, which need not be synced again.
+	syncedGatewayRoutes map[string]struct{}
+	// resyncGatewayRoutes is set when a synced route is found to have been deleted, so that the
+	// next SetGatewayConfig call syncs all routes again.
+	resyncGatewayRoutes atomic.Bool
+
+	// policyFilterMu serializes the sync of PolicyFilters, which may be shared by Gateway API
+	// routes and by any number of Ingresses synced concurrently.
+	policyFilterMu sync.Mutex
+	// policyFilterIDs holds the policy ID synced for each PolicyFilter and API namespace, for
+	// when the object being synced does not yet have the policy ID annotation.
+	policyFilterIDs map[policyFilterKey]string
+	// gatewayMatchPolicyIDs holds the policy ID synced for each HTTPRoute match policy (see
+	// syncGatewayMatchPolicies), keyed by source PPL.
+	gatewayMatchPolicyIDs map[string]string
+
+	// installID, if set, distinguishes the API objects created by this controller install from
+	// those created by any other install syncing to the same API namespaces (see WithInstallID).
+	installID string
+
+	drift driftTracker
+	creds apiCredentials
 }
 
 const (
+	apiRouteKeyAnnotationPrefix = "api.pomerium.io/route-key-"
+	// Deprecated: route IDs were formerly recorded by route index, now by route key.
 	apiRouteIDAnnotationPrefix = "api.pomerium.io/route-id-"
 	apiPolicyIDAnnotation      = "api.pomerium.io/policy-id"
 	apiKeyPairIDAnnotation     = "api.pomerium.io/keypair-id" //nolint:gosec
 	apiFinalizer               = "api.pomerium.io/finalizer"
 )
 
-var originatorID = "ingress-controller"
+// defaultOriginatorID is the originator ID of the API objects created by the controller, when no
+// install ID is set.
+const defaultOriginatorID = "ingress-controller"
+
+// WithInstallID sets an ID unique to this controller install, which is recorded in the originator
+// ID of the API objects it creates. Garbage collection only deletes objects carrying this ID, so
+// that several installs (or clusters) may safely share an API namespace.
+func WithInstallID(id string) APIReconcilerOption {
+	return func(r *APIReconciler) {
+		r.installID = id
+	}
+}
+
+// originatorID returns the originator ID of the API objects created by this controller install.
+func (r *APIReconciler) originatorID() string {
+	if r.installID == "" {
+		return defaultOriginatorID
+	}
+	return defaultOriginatorID + "/" + r.installID
+}
+
+// gatewayOriginatorID returns the originator ID of the API routes created for Gateway API routes,
+// which are kept apart from the routes created for Ingresses so that garbage collection can skip
+// them when Gateway API support is disabled.
+func (r *APIReconciler) gatewayOriginatorID() string {
+	return r.originatorID() + "/gateway"
+}
 
 // SetK8sClient sets the Kubernetes API client (used for metadata updates).
 func (r *APIReconciler) SetK8sClient(client client.Client) {
@@ -107,6 +190,13 @@ func (r *APIReconciler) SetK8sClient(client client.Client) {
 
 // Upsert should update or create the pomerium routes corresponding to this ingress
 func (r *APIReconciler) Upsert(ctx context.Context, ic *model.IngressConfig) (bool, error) {
+	// Roll back any keypair, policy and route writes if the Ingress cannot be synced in full.
+	return r.inChangeset(ctx, func(cs *apiChangeset) (bool, error) {
+		return r.upsert(ctx, cs, ic)
+	})
+}
+
+func (r *APIReconciler) upsert(ctx context.Context, cs *apiChangeset, ic *model.IngressConfig) (bool, error) {
 	var anyChanges bool
 
 	// Sync any referenced TLS secrets to API keypairs.
@@ -116,13 +206,13 @@ func (r *APIReconciler) Upsert(ctx context.Context, ic *model.IngressConfig) (bo
 			tlsSecrets = append(tlsSecrets, s)
 		}
 	}
-	changed, err := r.syncSecrets(ctx, tlsSecrets)
+	changed, err := r.syncSecrets(ctx, cs, tlsSecrets, r.apiNamespaceFor(ic))
 	if err != nil {
 		return anyChanges, err
 	}
 	anyChanges = anyChanges || changed
 
-	changed, err = r.upsertOneIngress(ctx, ic)
+	changed, err = r.upsertOneIngress(ctx, cs, ic)
 	if err != nil {
 		return anyChanges, err
 	}
@@ -142,39 +232,71 @@ func (r *APIReconciler) Upsert(ctx context.Context, ic *model.IngressConfig) (bo
 // Set configuration to match provided ingresses and shared config settings
 func (r *APIReconciler) Set(ctx context.Context, ics []*model.IngressConfig) (bool, error) {
 	tlsSecrets := make(map[types.NamespacedName]*corev1.Secret)
+	secretNamespaceIDs := make(map[types.NamespacedName]*string)
+	var errs []error
 	for _, ic := range ics {
 		// Collect all the referenced TLS secrets. These need to be synced
 		// before the routes, so that a route can reference a keypair ID.
+		// A keypair must be in the same API namespace as the routes using it.
+		namespaceID := r.apiNamespaceFor(ic)
 		for n, s := range ic.Secrets {
-			if s.Type == corev1.SecretTypeTLS {
-				r.secretsMap.Add(model.KeyForObject(ic), n)
-				tlsSecrets[n] = s
+			if s.Type != corev1.SecretTypeTLS {
+				continue
 			}
+			r.secretsMap.Add(model.KeyForObject(ic), n)
+			if id, ok := secretNamespaceIDs[n]; ok && nilToEmpty(id) != nilToEmpty(namespaceID) {
+				errs = append(errs, fmt.Errorf("secret %s is referenced by ingresses synced to API namespaces %q and %q",
+					n, nilToEmpty(id), nilToEmpty(namespaceID)))
+				continue
+			}
+			tlsSecrets[n] = s
+			secretNamespaceIDs[n] = namespaceID
 		}
 	}
 
-	anyChanges, err := r.syncSecrets(ctx, slices.Collect(maps.Values(tlsSecrets)))
+	anyChanges, err := r.inChangeset(ctx, func(cs *apiChangeset) (changes bool, err error) {
+		for n, s := range tlsSecrets {
+			changed, err := r.syncOneSecret(ctx, cs, s, secretNamespaceIDs[n])
+			if err != nil {
+				return changes, err
+			}
+			changes = changes || changed
+		}
+		return changes, nil
+	})
 	if err != nil {
-		return anyChanges, err
+		return anyChanges, errors.Join(append(errs, err)...)
 	}
 
-	// TODO: should we do an initial scan here for any Secrets that were deleted
-	// but still have our finalizer attached?
+	// Note: any Secrets that were deleted while the controller was not running
+	// will have their finalizer removed by CollectGarbage.
 
-	var errs []error
+	// The Ingresses are independent of one another, so can be synced concurrently, each with its
+	// own changeset.
+	var mu sync.Mutex
+	var eg errgroup.Group
+	eg.SetLimit(apiWriteConcurrency)
 	for _, ic := range ics {
-		changed, err := r.upsertOneIngress(ctx, ic)
-		if err != nil {
-			errs = append(errs, err)
-		}
-		anyChanges = anyChanges || changed
+		eg.Go(func() error {
+			changed, err := r.inChangeset(ctx, func(cs *apiChangeset) (bool, error) {
+				return r.upsertOneIngress(ctx, cs, ic)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+			}
+			anyChanges = anyChanges || changed
+			return nil
+		})
 	}
+	_ = eg.Wait()
 
 	return anyChanges, errors.Join(errs...)
 }
 
 func (r *APIReconciler) upsertOneIngress(
-	ctx context.Context, ic *model.IngressConfig,
+	ctx context.Context, cs *apiChangeset, ic *model.IngressConfig,
 ) (changed bool, err error) {
 	routes, err := ingressToRoutes(ctx, ic)
 	if err != nil {
@@ -183,7 +305,9 @@ func (r *APIReconciler) upsertOneIngress(
 
 	originalIngress := ic.Ingress.DeepCopy()
 	defer func() {
-		if !changed {
+		// If the sync failed, any objects it created are deleted when the changeset is rolled
+		// back, so their IDs must not be recorded.
+		if !changed || (err != nil && cs != nil) {
 			return
 		}
 		// Merge any error from Patch() with the named return parameter 'err' to
@@ -199,8 +323,9 @@ func (r *APIReconciler) upsertOneIngress(
 	}
 
 	existingPolicyID := ic.Annotations[apiPolicyIDAnnotation]
+	namespaceID := r.apiNamespaceFor(ic)
 
-	changedPolicy, updatedPolicyID, err := r.syncIngressPolicy(ctx, ic.Ingress, kv)
+	changedPolicy, updatedPolicyID, err := r.syncIngressPolicy(ctx, cs, ic.Ingress, kv, namespaceID)
 	if err != nil {
 		return changed, err
 	}
@@ -211,6 +336,14 @@ func (r *APIReconciler) upsertOneIngress(
 		policyIDs = []string{updatedPolicyID}
 	}
 
+	// Any shared policies apply in addition to the policy of the Ingress itself.
+	changedShared, sharedPolicyIDs, err := r.syncIngressPolicyRefs(ctx, cs, ic, namespaceID)
+	if err != nil {
+		return changed, err
+	}
+	changed = changed || changedShared
+	policyIDs = append(policyIDs, sharedPolicyIDs...)
+
 	var keypairErrs []error
 	keyPairIDForAnnotation := func(annotation string) *string {
 		secretName, hasAnnotation := kv.TLS[annotation]
@@ -240,12 +373,19 @@ func (r *APIReconciler) upsertOneIngress(
 		return changed, errors.Join(keypairErrs...)
 	}
 
+	routeIDKeys := routeIDAnnotations(routes)
+	migrated, err := r.migrateRouteIDAnnotations(ctx, ic.Ingress, routes, routeIDKeys)
+	if err != nil {
+		return changed, err
+	}
+	changed = changed || migrated
 	unusedRouteIDAnnotations := allRouteIDAnnotations(ic.Annotations)
 
 	for i, route := range routes {
-		k := routeIDAnnotationForIndex(i)
+		k := routeIDKeys[i]
 		delete(unusedRouteIDAnnotations, k)
 		route.Id = emptyToNil(ic.Annotations[k])
+		route.NamespaceId = namespaceID
 
 		// Swap out any inline policies for the policy ID reference, and swap
 		// out any TLS secrets for keypair ID references.
@@ -262,7 +402,7 @@ func (r *APIReconciler) upsertOneIngress(
 		// Clear the route StatName as it can't currently be set in Pomerium Zero.
 		route.StatName = nil
 
-		changedRoute, err := r.upsertOneRoute(ctx, route)
+		changedRoute, err := r.upsertOneRoute(ctx, cs, route, ic.Ingress)
 		if err != nil {
 			return changed, err
 		}
@@ -282,11 +422,10 @@ func (r *APIReconciler) upsertOneIngress(
 	changed = changed || anyDeletes
 
 	// If we had to recreate the linked policy e.g. due to a change in the
-	// namespace, now we can delete the old policy.
+	// namespace, now we can delete the old policy. Its ID is no longer recorded
+	// anywhere, so if this fails the policy is left unreferenced, and is deleted
+	// by CollectGarbage instead.
 	if existingPolicyID != "" && updatedPolicyID != "" && existingPolicyID != updatedPolicyID {
-		// I don't see an easy way to make this delete idempotent.
-		// TODO: figure out if it's worth additional complexity to avoid
-		// an orphaned policy in the old namespace.
 		_ = r.deletePolicy(ctx, existingPolicyID)
 	}
 
@@ -306,11 +445,13 @@ func (r *APIReconciler) upsertOneIngress(
 
 func (r *APIReconciler) syncSecrets(
 	ctx context.Context,
+	cs *apiChangeset,
 	secrets []*corev1.Secret,
+	namespaceID *string,
 ) (bool, error) {
 	var anyChanges bool
 	for _, secret := range secrets {
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, cs, secret, namespaceID)
 		if err != nil {
 			return anyChanges, err
 		}
@@ -325,7 +466,9 @@ func keyPairName(n types.NamespacedName) string {
 
 func (r *APIReconciler) syncOneSecret(
 	ctx context.Context,
+	cs *apiChangeset,
 	secret *corev1.Secret,
+	namespaceID *string,
 ) (bool, error) {
 	cert, hasTLSCert := secret.Data[corev1.TLSCertKey]
 	if !hasTLSCert {
@@ -335,17 +478,17 @@ func (r *APIReconciler) syncOneSecret(
 	name := keyPairName(util.GetNamespacedName(secret))
 	keyPair := &configpb.KeyPair{
 		Name:         &name,
-		NamespaceId:  r.namespaceID,
+		NamespaceId:  namespaceID,
 		Certificate:  cert,
 		Key:          secret.Data[corev1.TLSPrivateKeyKey],
-		OriginatorId: &originatorID,
+		OriginatorId: new(r.originatorID()),
 	}
 	if id := secret.Annotations[apiKeyPairIDAnnotation]; id != "" {
 		keyPair.Id = &id
 	}
 
 	originalSecret := secret.DeepCopy()
-	changed, err := r.upsertKeyPair(ctx, keyPair)
+	changed, err := r.upsertKeyPair(ctx, cs, keyPair, secret)
 	if err != nil {
 		return false, err
 	} else if changed {
@@ -370,7 +513,7 @@ func (r *APIReconciler) SetConfig(ctx context.Context, cfg *model.Config) (chang
 	allCertSecrets := make([]*corev1.Secret, 0, len(cfg.CASecrets)+len(cfg.Certs))
 	allCertSecrets = append(allCertSecrets, cfg.CASecrets...)
 	allCertSecrets = append(allCertSecrets, slices.Collect(maps.Values(cfg.Certs))...)
-	changedKeyPair, err := r.syncSecrets(ctx, allCertSecrets)
+	changedKeyPair, err := r.syncSecrets(ctx, nil, allCertSecrets, r.namespaceID)
 	if err != nil {
 		return changes, err
 	}
@@ -393,6 +536,36 @@ func (r *APIReconciler) SetConfig(ctx context.Context, cfg *model.Config) (chang
 		return false, err
 	}
 
+	r.drift.syncMu.RLock()
+	defer r.drift.syncMu.RUnlock()
+
+	existing, err := r.getSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	preserveSettings(settings, existing)
+
+	if proto.Equal(existing, settings) {
+		// No changes needed.
+		r.drift.record(apiKindSettings, settings.GetId(), settings, &cfg.Pomerium)
+		return changes, nil
+	}
+
+	logger := log.FromContext(ctx).WithName("APIReconciler.SetConfig")
+	logger.V(1).Info("updating settings", "diff", cmp.Diff(existing, settings, protocmp.Transform()))
+
+	_, err = r.apiClient.UpdateSettings(ctx, connect.NewRequest(&configpb.UpdateSettingsRequest{
+		Settings: settings,
+	}))
+	if err != nil {
+		return changes, err
+	}
+	r.drift.record(apiKindSettings, settings.GetId(), settings, &cfg.Pomerium)
+	return true, nil
+}
+
+// getSettings returns the current settings, with timestamp metadata masked.
+func (r *APIReconciler) getSettings(ctx context.Context) (*configpb.Settings, error) {
 	req := &configpb.GetSettingsRequest{}
 	if r.namespaceID != nil {
 		req.For = &configpb.GetSettingsRequest_NamespaceId{
@@ -401,34 +574,21 @@ func (r *APIReconciler) SetConfig(ctx context.Context, cfg *model.Config) (chang
 	}
 	resp, err := r.apiClient.GetSettings(ctx, connect.NewRequest(req))
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	existing := resp.Msg.Settings
+	existing.CreatedAt = nil
+	existing.ModifiedAt = nil
+	return existing, nil
+}
 
-	// Preserve any settings that cannot be set via the Pomerium CRD.
+// preserveSettings copies any settings that cannot be set via the Pomerium CRD from existing.
+func preserveSettings(settings, existing *configpb.Settings) {
 	settings.Id = existing.Id
 	settings.NamespaceId = existing.NamespaceId
 	settings.AutoApplyChangesets = existing.AutoApplyChangesets
 	settings.AutocertDir = existing.AutocertDir
 	settings.RuntimeFlags = existing.RuntimeFlags
-
-	// Mask timestamp metadata.
-	existing.CreatedAt = nil
-	existing.ModifiedAt = nil
-
-	if proto.Equal(existing, settings) {
-		// No changes needed.
-		return changes, nil
-	}
-
-	logger := log.FromContext(ctx).WithName("APIReconciler.SetConfig")
-	logger.V(1).Info("updating settings", "diff", cmp.Diff(existing, settings, protocmp.Transform()))
-
-	_, err = r.apiClient.UpdateSettings(ctx, connect.NewRequest(&configpb.UpdateSettingsRequest{
-		Settings: settings,
-	}))
-	changes = changes || (err == nil)
-	return changes, err
 }
 
 // Delete removes pomerium routes corresponding to this ingress.
@@ -484,6 +644,22 @@ func (r *APIReconciler) Delete(ctx context.Context, name types.NamespacedName) (
 func (r *APIReconciler) SetGatewayConfig(
 	ctx context.Context,
 	gatewayConfig *model.GatewayConfig,
+) (changes bool, err error) {
+	// Roll back any keypair, policy and route writes if the config cannot be synced in full.
+	changes, err = r.inChangeset(ctx, func(cs *apiChangeset) (bool, error) {
+		return r.setGatewayConfig(ctx, cs, gatewayConfig)
+	})
+	if err != nil {
+		// Any routes synced may have been rolled back.
+		r.syncedGatewayRoutes = nil
+	}
+	return changes, err
+}
+
+func (r *APIReconciler) setGatewayConfig(
+	ctx context.Context,
+	cs *apiChangeset,
+	gatewayConfig *model.GatewayConfig,
 ) (changes bool, err error) {
 	// Sync keypairs.
 	unreferencedSecrets := r.secretsMap.UpdateGatewayConfig(gatewayConfig)
@@ -493,70 +669,178 @@ func (r *APIReconciler) SetGatewayConfig(
 	}
 	changes = changes || anyDeletes
 
-	changedKeyPair, err := r.syncSecrets(ctx, gatewayConfig.Certificates)
+	changedKeyPair, err := r.syncSecrets(ctx, cs, gatewayConfig.Certificates, r.namespaceID)
 	if err != nil {
 		return changes, err
 	}
 	changes = changes || changedKeyPair
 
 	// Extract and sync any policies.
-	changedPolicy, policyIDs, err := r.syncGatewayPolicies(ctx, gatewayConfig)
+	changedPolicy, policyIDs, err := r.syncGatewayPolicies(ctx, cs, gatewayConfig)
 	if err != nil {
 		return changes, err
 	}
 	changes = changes || changedPolicy
 
+	// Sync the policies that HTTPRoute method matches are translated into.
+	changedMatchPolicies, err := r.syncGatewayMatchPolicies(ctx, cs, gatewayConfig, policyIDs)
+	if err != nil {
+		return changes, err
+	}
+	changes = changes || changedMatchPolicies
+
+	// Sync any BackendTLSPolicy CA certificates.
+	changedCAs, caKeyPairIDs, err := r.syncGatewayCAKeyPairs(ctx, cs, gatewayConfig)
+	if err != nil {
+		return changes, err
+	}
+	changes = changes || changedCAs
+
+	if r.resyncGatewayRoutes.Swap(false) {
+		r.syncedGatewayRoutes = nil
+	}
+	synced := make(map[string]struct{})
+	defer func() { r.syncedGatewayRoutes = synced }()
+
+	// Each route object is synced independently of the others, so these can be synced
+	// concurrently.
+	var mu sync.Mutex
+	var eg errgroup.Group
+	eg.SetLimit(apiWriteConcurrency)
+	syncRoute := func(fingerprint string, obj client.Object, translate func() []*configpb.Route) {
+		key := gatewayRouteSyncKey(fingerprint, policyIDs, caKeyPairIDs)
+		if _, ok := r.syncedGatewayRoutes[key]; ok && fingerprint != "" {
+			// Nothing this route depends on has changed since it was last synced.
+			mu.Lock()
+			synced[key] = struct{}{}
+			mu.Unlock()
+			return
+		}
+		eg.Go(func() error {
+			changed, err := r.syncGatewayRoute(ctx, cs, obj, policyIDs, caKeyPairIDs, translate)
+			mu.Lock()
+			defer mu.Unlock()
+			changes = changes || changed
+			if err != nil {
+				return err
+			}
+			if fingerprint != "" {
+				synced[key] = struct{}{}
+			}
+			return nil
+		})
+	}
+
 	for i := range gatewayConfig.Routes {
 		gr := &gatewayConfig.Routes[i]
-		originalRoute := gr.HTTPRoute.DeepCopy()
-
-		if gr.DeletionTimestamp == nil {
-			routes := gateway.TranslateRoutes(ctx, gatewayConfig, gr)
-			for i, route := range routes {
-				// Replace any inline policy with a policy ID reference.
-				if err := replaceInlinePolicies(route, policyIDs); err != nil {
-					return changes, err
-				}
+		syncRoute(gr.Fingerprint, gr.HTTPRoute, func() []*configpb.Route {
+			return gateway.TranslateRoutes(ctx, gatewayConfig, gr)
+		})
+	}
 
-				k := routeIDAnnotationForIndex(i)
-				route.Id = emptyToNil(gr.Annotations[k])
-				routeChanged, err := r.upsertOneRoute(ctx, route)
-				if err != nil {
-					return changes, err
-				}
-				changes = changes || routeChanged
-				if gr.Annotations[k] != *route.Id {
-					util.SetAnnotation(gr, k, *route.Id)
-				}
+	for i := range gatewayConfig.GRPCRoutes {
+		gr := &gatewayConfig.GRPCRoutes[i]
+		syncRoute(gr.Fingerprint, gr.GRPCRoute, func() []*configpb.Route {
+			return gateway.TranslateGRPCRoutes(ctx, gatewayConfig, gr)
+		})
+	}
+
+	for i := range gatewayConfig.TCPRoutes {
+		tr := &gatewayConfig.TCPRoutes[i]
+		syncRoute(tr.Fingerprint, tr.TCPRoute, func() []*configpb.Route {
+			return gateway.TranslateTCPRoutes(tr)
+		})
+	}
+
+	for i := range gatewayConfig.UDPRoutes {
+		ur := &gatewayConfig.UDPRoutes[i]
+		syncRoute(ur.Fingerprint, ur.UDPRoute, func() []*configpb.Route {
+			return gateway.TranslateUDPRoutes(ur)
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return changes, err
+	}
+
+	removed, err := r.removeDeletedGatewayPolicies(ctx, gatewayConfig)
+	if err != nil {
+		return changes, err
+	}
+	changes = changes || removed
+
+	return changes, nil
+}
+
+// syncGatewayRoute upserts the Pomerium routes translated from a single Gateway API route object,
+// or deletes them if the object is being deleted, and records the route IDs as annotations.
+func (r *APIReconciler) syncGatewayRoute(
+	ctx context.Context,
+	cs *apiChangeset,
+	obj client.Object,
+	policyIDs map[string]string,
+	caKeyPairIDs map[string]string,
+	translate func() []*configpb.Route,
+) (changes bool, err error) {
+	originalObj := obj.DeepCopyObject().(client.Object)
+
+	if obj.GetDeletionTimestamp() == nil {
+		routes := translate()
+		routeIDKeys := routeIDAnnotations(routes)
+		if _, err := r.migrateRouteIDAnnotations(ctx, obj, routes, routeIDKeys); err != nil {
+			return changes, err
+		}
+		unusedRouteIDAnnotations := allRouteIDAnnotations(obj.GetAnnotations())
+
+		for i, route := range routes {
+			// Replace any inline policy with a policy ID reference.
+			if err := replaceInlinePolicies(route, policyIDs); err != nil {
+				return changes, err
 			}
-			controllerutil.AddFinalizer(gr, apiFinalizer)
-		} else {
-			// This HTTPRoute was deleted, so delete any synced Pomerium routes.
-			anyDeletes, err := r.deleteRoutes(ctx, gr, allRouteIDAnnotations(gr.Annotations))
+			// Replace any inline CA certificate with a keypair ID reference.
+			if err := replaceInlineCA(route, caKeyPairIDs); err != nil {
+				return changes, err
+			}
+
+			k := routeIDKeys[i]
+			delete(unusedRouteIDAnnotations, k)
+			route.Id = emptyToNil(obj.GetAnnotations()[k])
+			route.NamespaceId = r.namespaceID
+			route.OriginatorId = new(r.gatewayOriginatorID())
+			routeChanged, err := r.upsertOneRoute(ctx, cs, route, obj)
 			if err != nil {
 				return changes, err
 			}
-			changes = changes || anyDeletes
+			changes = changes || routeChanged
+			if obj.GetAnnotations()[k] != *route.Id {
+				util.SetAnnotation(obj, k, *route.Id)
+			}
+		}
 
-			controllerutil.RemoveFinalizer(gr, apiFinalizer)
+		// Delete any API routes no longer produced by this object (e.g. for a removed rule).
+		anyDeletes, err := r.deleteRoutes(ctx, obj, unusedRouteIDAnnotations)
+		if err != nil {
+			return changes, err
 		}
+		changes = changes || anyDeletes
 
-		if err := r.k8sClient.Patch(ctx, gr.HTTPRoute, client.MergeFrom(originalRoute)); err != nil {
+		controllerutil.AddFinalizer(obj, apiFinalizer)
+	} else {
+		// This route was deleted, so delete any synced Pomerium routes.
+		anyDeletes, err := r.deleteRoutes(ctx, obj, allRouteIDAnnotations(obj.GetAnnotations()))
+		if err != nil {
 			return changes, err
 		}
-	}
+		changes = changes || anyDeletes
 
-	removed, err := r.removeDeletedGatewayPolicies(ctx, gatewayConfig)
-	if err != nil {
-		return changes, err
+		controllerutil.RemoveFinalizer(obj, apiFinalizer)
 	}
-	changes = changes || removed
 
-	return changes, nil
+	return changes, r.k8sClient.Patch(ctx, obj, client.MergeFrom(originalObj))
 }
 
 func (r *APIReconciler) syncGatewayPolicies(
-	ctx context.Context, gatewayConfig *model.GatewayConfig,
+	ctx context.Context, cs *apiChangeset, gatewayConfig *model.GatewayConfig,
 ) (changes bool, policyIDs map[string]string, err error) {
 	policyIDs = map[string]string{}
 	for _, ef := range gatewayConfig.ExtensionFilters {
@@ -573,31 +857,13 @@ func (r *APIReconciler) syncGatewayPolicies(
 		}
 		originalObj := obj.DeepCopy()
 
-		var route configpb.Route
-		if err := pf.ApplyToRoute(&route); err != nil {
-			return changes, nil, fmt.Errorf("internal error - couldn't extract policy: %w", err)
-		}
-		policy, err := convertProto[*configpb.Policy](route.Policies[0])
+		changedPolicy, policy, err := r.syncPolicyFilter(ctx, cs, pf, r.namespaceID)
 		if err != nil {
 			return changes, nil, err
 		}
-		policy.OriginatorId = &originatorID
-		policy.Rego = nil
-		policyName := slug.Make(fmt.Sprintf("%s %s", obj.Namespace, obj.Name))
-		policy.Name = &policyName
-		policy.NamespaceId = r.namespaceID
-		if id := obj.Annotations[apiPolicyIDAnnotation]; id != "" {
-			policy.Id = &id
-		}
-
-		changedPolicy, err := r.upsertPolicy(ctx, policy)
-		if err != nil {
-			return changes, nil, err
-		} else if changedPolicy {
-			changes = true
-			util.SetAnnotation(obj, apiPolicyIDAnnotation, policy.GetId())
-			controllerutil.AddFinalizer(obj, apiFinalizer)
-		}
+		changes = changes || changedPolicy
+		// The policy may have been created for an Ingress, which does not add the finalizer.
+		controllerutil.AddFinalizer(obj, apiFinalizer)
 
 		policyIDs[policy.GetSourcePpl()] = policy.GetId()
 
@@ -609,6 +875,164 @@ func (r *APIReconciler) syncGatewayPolicies(
 	return changes, policyIDs, nil
 }
 
+// syncGatewayMatchPolicies upserts a policy for each distinct HTTPRoute method match, and adds the
+// policy IDs to policyIDs (keyed by source PPL).
+func (r *APIReconciler) syncGatewayMatchPolicies(
+	ctx context.Context, cs *apiChangeset, gatewayConfig *model.GatewayConfig, policyIDs map[string]string,
+) (changes bool, err error) {
+	for i := range gatewayConfig.Routes {
+		for _, policy := range gateway.MethodMatchPolicies(gatewayConfig.Routes[i].HTTPRoute) {
+			ppl := policy.GetSourcePpl()
+			if _, ok := policyIDs[ppl]; ok {
+				continue
+			}
+			name := gatewayMatchPolicyName(ppl)
+			policy.Name = &name
+			policy.NamespaceId = r.namespaceID
+			policy.OriginatorId = new(r.originatorID())
+			policy.Rego = nil
+			if id := r.gatewayMatchPolicyIDs[ppl]; id != "" {
+				policy.Id = &id
+			} else if existing, err := r.findPolicyByName(ctx, name, r.namespaceID); err == nil {
+				// The policy IDs are not recorded on any Kubernetes object, so after a restart
+				// the policy is found by name rather than created again. Policies no longer
+				// referenced by any route are deleted by CollectGarbage.
+				policy.Id = existing.Id
+			} else if connect.CodeOf(err) != connect.CodeNotFound {
+				return changes, err
+			}
+			// These policies may be shared by multiple routes, so have no single owner.
+			changed, err := r.upsertPolicy(ctx, cs, policy, nil)
+			if err != nil {
+				return changes, err
+			}
+			changes = changes || changed
+			if r.gatewayMatchPolicyIDs == nil {
+				r.gatewayMatchPolicyIDs = make(map[string]string)
+			}
+			r.gatewayMatchPolicyIDs[ppl] = policy.GetId()
+			policyIDs[ppl] = policy.GetId()
+		}
+	}
+	return changes, nil
+}
+
+// gatewayMatchPolicyName returns a policy name derived from the policy source PPL, so that
+// HTTPRoute matches translated into the same policy share it.
+func gatewayMatchPolicyName(ppl string) string {
+	sum := sha256.Sum256([]byte(ppl))
+	return "gateway-match-" + hex.EncodeToString(sum[:8])
+}
+
+// syncIngressPolicyRefs syncs the PolicyFilters referenced by the policy_ref annotation of an
+// Ingress to the API namespace of the Ingress, returning their policy IDs. Each PolicyFilter is
+// synced to a single policy in each API namespace, shared by all Ingresses (and Gateway API
+// routes) in that namespace referencing it.
+func (r *APIReconciler) syncIngressPolicyRefs(
+	ctx context.Context, cs *apiChangeset, ic *model.IngressConfig, namespaceID *string,
+) (changed bool, policyIDs []string, err error) {
+	refs, err := ic.GetPolicyRefs()
+	if err != nil {
+		return false, nil, err
+	}
+
+	for _, name := range refs {
+		obj := ic.PolicyFilters[name]
+		if obj == nil {
+			return changed, nil, fmt.Errorf("internal error - policy filter %s referenced by ingress %s not fetched",
+				name, ic.GetIngressNamespacedName())
+		}
+		pf, err := gateway.NewPolicyFilter(obj)
+		if err != nil {
+			return changed, nil, fmt.Errorf("policy filter %s: %w", name, err)
+		}
+		originalObj := obj.DeepCopy()
+
+		changedPolicy, policy, err := r.syncPolicyFilter(ctx, cs, pf, namespaceID)
+		if err != nil {
+			return changed, nil, fmt.Errorf("policy filter %s: %w", name, err)
+		}
+		changed = changed || changedPolicy
+		policyIDs = append(policyIDs, policy.GetId())
+
+		k := r.policyFilterIDAnnotation(namespaceID)
+		if obj.Annotations[k] != originalObj.Annotations[k] {
+			if err := r.k8sClient.Patch(ctx, obj, client.MergeFrom(originalObj)); err != nil {
+				return changed, nil, err
+			}
+		}
+	}
+	return changed, policyIDs, nil
+}
+
+// policyFilterKey identifies the policy synced for a PolicyFilter in one API namespace.
+type policyFilterKey struct {
+	types.NamespacedName
+	namespaceID string
+}
+
+// syncPolicyFilter creates or updates the policy for a PolicyFilter in the given API namespace,
+// and records its ID as an annotation of the PolicyFilter object (see policyFilterIDAnnotation).
+func (r *APIReconciler) syncPolicyFilter(
+	ctx context.Context, cs *apiChangeset, pf *gateway.PolicyFilter, namespaceID *string,
+) (changed bool, policy *configpb.Policy, err error) {
+	obj := pf.GetObject()
+
+	var route configpb.Route
+	if err := pf.ApplyToRoute(&route); err != nil {
+		return false, nil, fmt.Errorf("internal error - couldn't extract policy: %w", err)
+	}
+	policy, err = convertProto[*configpb.Policy](route.Policies[0])
+	if err != nil {
+		return false, nil, err
+	}
+	policy.OriginatorId = new(r.originatorID())
+	policy.Rego = nil
+	policyName := slug.Make(fmt.Sprintf("%s %s", obj.Namespace, obj.Name))
+	policy.Name = &policyName
+	policy.NamespaceId = namespaceID
+
+	r.policyFilterMu.Lock()
+	defer r.policyFilterMu.Unlock()
+
+	key := policyFilterKey{
+		NamespacedName: types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name},
+		namespaceID:    nilToEmpty(namespaceID),
+	}
+	annotation := r.policyFilterIDAnnotation(namespaceID)
+	id := obj.Annotations[annotation]
+	if id == "" {
+		id = r.policyFilterIDs[key]
+	}
+	if id != "" {
+		policy.Id = &id
+	}
+
+	changed, err = r.upsertPolicy(ctx, cs, policy, obj)
+	if err != nil {
+		return false, nil, err
+	}
+	if r.policyFilterIDs == nil {
+		r.policyFilterIDs = make(map[policyFilterKey]string)
+	}
+	r.policyFilterIDs[key] = policy.GetId()
+	if obj.Annotations[annotation] != policy.GetId() {
+		util.SetAnnotation(obj, annotation, policy.GetId())
+	}
+	return changed, policy, nil
+}
+
+// policyFilterIDAnnotation returns the annotation recording the ID of the policy synced for a
+// PolicyFilter in the given API namespace. This is the policy ID annotation for the default API
+// namespace, and otherwise the policy ID annotation suffixed with a hash of the namespace ID.
+func (r *APIReconciler) policyFilterIDAnnotation(namespaceID *string) string {
+	if nilToEmpty(namespaceID) == nilToEmpty(r.namespaceID) {
+		return apiPolicyIDAnnotation
+	}
+	sum := sha256.Sum256([]byte(nilToEmpty(namespaceID)))
+	return apiPolicyIDAnnotation + "-" + hex.EncodeToString(sum[:8])
+}
+
 func (r *APIReconciler) removeDeletedGatewayPolicies(
 	ctx context.Context, gatewayConfig *model.GatewayConfig,
 ) (changes bool, err error) {
@@ -628,6 +1052,11 @@ func (r *APIReconciler) removeDeletedGatewayPolicies(
 			return changes, err
 		}
 		changes = changes || deleted
+		r.policyFilterMu.Lock()
+		maps.DeleteFunc(r.policyFilterIDs, func(k policyFilterKey, _ string) bool {
+			return k.NamespacedName == types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}
+		})
+		r.policyFilterMu.Unlock()
 
 		originalObj := obj.DeepCopy()
 		controllerutil.RemoveFinalizer(obj, apiFinalizer)
@@ -655,15 +1084,78 @@ func replaceInlinePolicies(route *configpb.Route, policyIDs map[string]string) e
 	return nil
 }
 
-func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Route) (bool, error) {
+// gatewayCAKeyPairName returns a keypair name derived from the CA certificate contents, so that
+// BackendTLSPolicies using the same CA certificates share a single keypair.
+func gatewayCAKeyPairName(ca []byte) string {
+	sum := sha256.Sum256(ca)
+	return "gateway-ca-" + hex.EncodeToString(sum[:8])
+}
+
+// syncGatewayCAKeyPairs upserts a keypair for each distinct BackendTLSPolicy CA certificate bundle,
+// and returns the keypair IDs keyed by the base64-encoded bundle (as set in a route's TlsCustomCa).
+func (r *APIReconciler) syncGatewayCAKeyPairs(
+	ctx context.Context, cs *apiChangeset, gatewayConfig *model.GatewayConfig,
+) (changes bool, caKeyPairIDs map[string]string, err error) {
+	caKeyPairIDs = map[string]string{}
+	for _, btc := range gatewayConfig.BackendTLS {
+		if len(btc.CACertificates) == 0 {
+			continue
+		}
+		k := base64.StdEncoding.EncodeToString(btc.CACertificates)
+		if _, ok := caKeyPairIDs[k]; ok {
+			continue
+		}
+		name := gatewayCAKeyPairName(btc.CACertificates)
+		keyPair := &configpb.KeyPair{
+			Name:         &name,
+			NamespaceId:  r.namespaceID,
+			Certificate:  btc.CACertificates,
+			OriginatorId: new(r.originatorID()),
+		}
+		// These keypairs may be shared by multiple BackendTLSPolicies, so have no single owner.
+		changed, err := r.upsertKeyPair(ctx, cs, keyPair, nil)
+		if err != nil {
+			return changes, nil, err
+		}
+		changes = changes || changed
+		caKeyPairIDs[k] = keyPair.GetId()
+	}
+	return changes, caKeyPairIDs, nil
+}
+
+func replaceInlineCA(route *configpb.Route, caKeyPairIDs map[string]string) error {
+	if route.TlsCustomCa == "" {
+		return nil
+	}
+	id, ok := caKeyPairIDs[route.TlsCustomCa]
+	if !ok {
+		return fmt.Errorf("internal error - CA keypair ID not found for route %q", route.GetName())
+	}
+	route.TlsCustomCaKeyPairId = &id
+	route.TlsCustomCa = ""
+	return nil
+}
+
+func (r *APIReconciler) upsertOneRoute(
+	ctx context.Context, cs *apiChangeset, route *configpb.Route, owner client.Object,
+) (changed bool, err error) {
 	logger := log.FromContext(ctx).WithName("APIReconciler.upsertOneRoute")
 
 	apiRoute, err := convertProto[*configpb.Route](route)
 	if err != nil {
 		return false, err
 	}
-	apiRoute.NamespaceId = r.namespaceID
-	apiRoute.OriginatorId = &originatorID
+	if apiRoute.OriginatorId == nil {
+		apiRoute.OriginatorId = new(r.originatorID())
+	}
+
+	r.drift.syncMu.RLock()
+	defer r.drift.syncMu.RUnlock()
+	defer func() {
+		if err == nil {
+			r.drift.record(apiKindRoute, apiRoute.GetId(), apiRoute, owner)
+		}
+	}()
 
 	var existing *configpb.Route
 	if id := route.GetId(); id != "" {
@@ -674,17 +1166,19 @@ func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Rout
 			return false, err
 		} else if err == nil {
 			existing = resp.Msg.Route
+		} else {
+			// Deleted outside of the controller, so will be recreated with a new ID.
+			r.drift.forget(apiKindRoute, id)
 		}
 	}
 
+	var moved *configpb.Route
 	if existing != nil && apiRoute.NamespaceId != nil &&
 		apiRoute.GetNamespaceId() != existing.GetNamespaceId() {
-		// The route exists already, but in a different namespace.
-		// Delete the existing route so we can recreate it in the new namespace.
-		if err := r.deleteRoute(ctx, existing.GetId()); err != nil {
-			return false, fmt.Errorf("couldn't delete existing route in different namespace: %w", err)
-		}
-		existing = nil
+		// The route exists already, but in a different namespace. Recreate it in the new
+		// namespace, and delete the existing route once the sync has succeeded, so that the
+		// move can be rolled back.
+		moved, existing = existing, nil
 	}
 
 	if existing == nil {
@@ -694,8 +1188,37 @@ func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Rout
 		resp, err := r.apiClient.CreateRoute(ctx, connect.NewRequest(&configpb.CreateRouteRequest{
 			Route: apiRoute,
 		}))
+		errCode := connect.CodeOf(err)
+		if moved != nil && (errCode == connect.CodeAlreadyExists || errCode == connect.CodeFailedPrecondition) {
+			// The API does not allow the route to exist in both namespaces at once, so the
+			// existing route must be deleted first. This cannot be rolled back.
+			logger.Info("deleting route before recreating it in a different namespace",
+				"id", moved.GetId(), "namespace", apiRoute.GetNamespaceId())
+			if err := r.deleteRoute(ctx, moved.GetId()); err != nil {
+				return false, fmt.Errorf("couldn't delete existing route in different namespace: %w", err)
+			}
+			moved = nil
+			resp, err = r.apiClient.CreateRoute(ctx, connect.NewRequest(&configpb.CreateRouteRequest{
+				Route: apiRoute,
+			}))
+			errCode = connect.CodeOf(err)
+		}
 		if err == nil {
 			route.Id = resp.Msg.Route.Id
+			apiRoute.Id = route.Id
+			cs.created(apiKindRoute, route.GetId())
+			if moved != nil {
+				movedID := moved.GetId()
+				err := cs.afterCommit(ctx, func(ctx context.Context) error {
+					if err := r.deleteRoute(ctx, movedID); err != nil {
+						return fmt.Errorf("couldn't delete existing route in different namespace: %w", err)
+					}
+					return nil
+				})
+				if err != nil {
+					return true, err
+				}
+			}
 			return true, nil
 		}
 
@@ -704,13 +1227,12 @@ func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Rout
 		// constraint in Pomerium Zero), or a failed_precondition error (there is
 		// a route 'From' overlap check in Pomerium Enterprise). Any other error
 		// should be returned as is.
-		errCode := connect.CodeOf(err)
 		if errCode != connect.CodeAlreadyExists && errCode != connect.CodeFailedPrecondition {
 			return false, err
 		}
 
 		// Attempt to look up the route by name.
-		existing, err = r.findRouteByName(ctx, route.GetName())
+		existing, err = r.findRouteByName(ctx, apiRoute.GetOriginatorId(), route.GetName())
 		if err != nil {
 			return false, err
 		}
@@ -718,15 +1240,7 @@ func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Rout
 		route.Id = existing.Id
 	}
 
-	// Clear the fields that should be ignored when looking for changes.
-	if r.namespaceID == nil {
-		existing.NamespaceId = nil
-	}
-	existing.CreatedAt = nil
-	existing.ModifiedAt = nil
-	existing.AssignedPolicies = nil
-	existing.EnforcedPolicies = nil
-	existing.StatName = nil
+	maskRoute(existing, apiRoute)
 
 	if proto.Equal(existing, apiRoute) {
 		// No changes needed.
@@ -740,11 +1254,15 @@ func (r *APIReconciler) upsertOneRoute(ctx context.Context, route *configpb.Rout
 	_, err = r.apiClient.UpdateRoute(ctx, connect.NewRequest(&configpb.UpdateRouteRequest{
 		Route: apiRoute,
 	}))
-	return err == nil, err
+	if err != nil {
+		return false, err
+	}
+	cs.updated(apiKindRoute, existing)
+	return true, nil
 }
 
 func (r *APIReconciler) findRouteByName(
-	ctx context.Context, name string,
+	ctx context.Context, originatorID, name string,
 ) (existing *configpb.Route, err error) {
 	filter, err := structpb.NewStruct(map[string]any{
 		"originator_id": originatorID,
@@ -783,6 +1301,7 @@ func (r *APIReconciler) deleteRoutes(
 }
 
 func (r *APIReconciler) deleteRoute(ctx context.Context, id string) error {
+	r.drift.forget(apiKindRoute, id)
 	_, err := r.apiClient.DeleteRoute(ctx, connect.NewRequest(&configpb.DeleteRouteRequest{
 		Id: id,
 	}))
@@ -793,7 +1312,7 @@ func (r *APIReconciler) deleteRoute(ctx context.Context, id string) error {
 }
 
 func (r *APIReconciler) syncIngressPolicy(
-	ctx context.Context, ingress *networkingv1.Ingress, kv *keys,
+	ctx context.Context, cs *apiChangeset, ingress *networkingv1.Ingress, kv *keys, namespaceID *string,
 ) (changed bool, updatedPolicyID string, err error) {
 	existingPolicyID := ingress.Annotations[apiPolicyIDAnnotation]
 	name := slug.Make(fmt.Sprintf("%s %s policy", ingress.Namespace, ingress.Name))
@@ -811,14 +1330,14 @@ func (r *APIReconciler) syncIngressPolicy(
 	if err != nil {
 		return false, "", fmt.Errorf("internal error: %w", err)
 	}
-	apiPolicy.NamespaceId = r.namespaceID
-	apiPolicy.OriginatorId = &originatorID
+	apiPolicy.NamespaceId = namespaceID
+	apiPolicy.OriginatorId = new(r.originatorID())
 	if existingPolicyID != "" {
 		apiPolicy.Id = &existingPolicyID
 	}
 
 	// Create or update the Pomerium policy as needed.
-	changed, err = r.upsertPolicy(ctx, apiPolicy)
+	changed, err = r.upsertPolicy(ctx, cs, apiPolicy, ingress)
 	if err != nil {
 		return false, "", fmt.Errorf("couldn't update ingress policy: %w", err)
 	}
@@ -829,7 +1348,17 @@ func (r *APIReconciler) syncIngressPolicy(
 
 // upsertPolicy will create or update a Pomerium policy. If a new ID is
 // assigned, policy.Id will be updated.
-func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Policy) (changed bool, err error) {
+func (r *APIReconciler) upsertPolicy(
+	ctx context.Context, cs *apiChangeset, policy *configpb.Policy, owner client.Object,
+) (changed bool, err error) {
+	r.drift.syncMu.RLock()
+	defer r.drift.syncMu.RUnlock()
+	defer func() {
+		if err == nil {
+			r.drift.record(apiKindPolicy, policy.GetId(), policy, owner)
+		}
+	}()
+
 	var existing *configpb.Policy
 	if id := policy.GetId(); id != "" {
 		resp, err := r.apiClient.GetPolicy(ctx, connect.NewRequest(&configpb.GetPolicyRequest{
@@ -839,6 +1368,9 @@ func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Polic
 			existing = resp.Msg.Policy
 		} else if connect.CodeOf(err) != connect.CodeNotFound {
 			return false, err
+		} else {
+			// Deleted outside of the controller, so will be recreated with a new ID.
+			r.drift.forget(apiKindPolicy, id)
 		}
 	}
 
@@ -859,6 +1391,7 @@ func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Polic
 		}))
 		if err == nil {
 			policy.Id = resp.Msg.Policy.Id
+			cs.created(apiKindPolicy, policy.GetId())
 			return true, nil
 		} else if connect.CodeOf(err) != connect.CodeAlreadyExists {
 			return false, err
@@ -866,7 +1399,7 @@ func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Polic
 
 		// If we already created a policy, but failed to save the ID annotation,
 		// attempt to look up the policy by name.
-		existing, err = r.findPolicyByName(ctx, policy.GetName())
+		existing, err = r.findPolicyByName(ctx, policy.GetName(), policy.NamespaceId)
 		if err != nil {
 			return false, err
 		}
@@ -874,14 +1407,7 @@ func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Polic
 		changed = true
 	}
 
-	// Zero out fields that should be ignored when looking for changes.
-	if r.namespaceID == nil {
-		existing.NamespaceId = nil
-	}
-	existing.CreatedAt = nil
-	existing.ModifiedAt = nil
-	existing.AssignedRoutes = nil
-	existing.Enforced = falseToNil(existing.Enforced)
+	maskPolicy(existing, policy)
 
 	if proto.Equal(existing, policy) {
 		// No changes needed.
@@ -897,14 +1423,16 @@ func (r *APIReconciler) upsertPolicy(ctx context.Context, policy *configpb.Polic
 	if err != nil {
 		return changed, err
 	}
+	cs.updated(apiKindPolicy, existing)
 	return true, nil
 }
 
+// findPolicyByName returns the policy with the given name, in the given API namespace if not nil.
 func (r *APIReconciler) findPolicyByName(
-	ctx context.Context, name string,
+	ctx context.Context, name string, namespaceID *string,
 ) (existing *configpb.Policy, err error) {
 	filter, err := structpb.NewStruct(map[string]any{
-		"originator_id": originatorID,
+		"originator_id": r.originatorID(),
 		"name":          name,
 	})
 	if err != nil {
@@ -915,31 +1443,37 @@ func (r *APIReconciler) findPolicyByName(
 	}))
 	if err != nil {
 		return nil, err
-	} else if len(resp.Msg.Policies) == 0 {
-		return nil, fmt.Errorf("could not find policy by name")
 	}
-	return resp.Msg.Policies[0], nil
+	for _, p := range resp.Msg.Policies {
+		if namespaceID == nil || p.GetNamespaceId() == *namespaceID {
+			return p, nil
+		}
+	}
+	return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("could not find policy by name"))
 }
 
-// deletePolicyForObject deletes the policy for obj and clears its policy ID
-// annotation. Returns true if any changes were made, or an error if the delete
-// operation failed.
+// deletePolicyForObject deletes the policy for obj (and for a PolicyFilter, the policies synced
+// to other API namespaces) and clears its policy ID annotations. Returns true if any changes were
+// made, or an error if the delete operation failed.
 func (r *APIReconciler) deletePolicyForObject(
 	ctx context.Context, obj client.Object,
 ) (deleted bool, err error) {
 	annotations := obj.GetAnnotations()
-	policyID := annotations[apiPolicyIDAnnotation]
-	if policyID == "" {
-		return false, nil
-	}
-	if err := r.deletePolicy(ctx, policyID); err != nil {
-		return false, err
+	for k, policyID := range annotations {
+		if k != apiPolicyIDAnnotation && !strings.HasPrefix(k, apiPolicyIDAnnotation+"-") {
+			continue
+		}
+		if err := r.deletePolicy(ctx, policyID); err != nil {
+			return deleted, err
+		}
+		delete(annotations, k)
+		deleted = true
 	}
-	delete(annotations, apiPolicyIDAnnotation)
-	return true, nil
+	return deleted, nil
 }
 
 func (r *APIReconciler) deletePolicy(ctx context.Context, id string) (err error) {
+	r.drift.forget(apiKindPolicy, id)
 	_, err = r.apiClient.DeletePolicy(ctx, connect.NewRequest(&configpb.DeletePolicyRequest{
 		Id: id,
 	}))
@@ -949,7 +1483,17 @@ func (r *APIReconciler) deletePolicy(ctx context.Context, id string) (err error)
 	return err
 }
 
-func (r *APIReconciler) upsertKeyPair(ctx context.Context, keyPair *configpb.KeyPair) (changed bool, err error) {
+func (r *APIReconciler) upsertKeyPair(
+	ctx context.Context, cs *apiChangeset, keyPair *configpb.KeyPair, owner client.Object,
+) (changed bool, err error) {
+	r.drift.syncMu.RLock()
+	defer r.drift.syncMu.RUnlock()
+	defer func() {
+		if err == nil {
+			r.drift.record(apiKindKeyPair, keyPair.GetId(), keyPair, owner)
+		}
+	}()
+
 	var existing *configpb.KeyPair
 	if id := keyPair.GetId(); id != "" {
 		resp, err := r.apiClient.GetKeyPair(ctx, connect.NewRequest(&configpb.GetKeyPairRequest{
@@ -959,6 +1503,9 @@ func (r *APIReconciler) upsertKeyPair(ctx context.Context, keyPair *configpb.Key
 			existing = resp.Msg.KeyPair
 		} else if connect.CodeOf(err) != connect.CodeNotFound {
 			return false, err
+		} else {
+			// Deleted outside of the controller, so will be recreated with a new ID.
+			r.drift.forget(apiKindKeyPair, id)
 		}
 	}
 
@@ -981,6 +1528,7 @@ func (r *APIReconciler) upsertKeyPair(ctx context.Context, keyPair *configpb.Key
 		}))
 		if err == nil {
 			keyPair.Id = resp.Msg.KeyPair.Id
+			cs.created(apiKindKeyPair, keyPair.GetId())
 			return true, nil
 		} else if connect.CodeOf(err) != connect.CodeAlreadyExists {
 			return false, err
@@ -996,15 +1544,7 @@ func (r *APIReconciler) upsertKeyPair(ctx context.Context, keyPair *configpb.Key
 		changed = true
 	}
 
-	// Zero out fields that should be ignored when looking for changes.
-	if r.namespaceID == nil {
-		existing.NamespaceId = nil
-	}
-	existing.CreatedAt = nil
-	existing.ModifiedAt = nil
-	existing.CertificateInfo = nil
-	existing.Origin = configpb.KeyPairOrigin_KEY_PAIR_ORIGIN_UNKNOWN
-	existing.Status = configpb.KeyPairStatus_KEY_PAIR_STATUS_UNKNOWN
+	maskKeyPair(existing, keyPair)
 
 	if proto.Equal(existing, keyPair) {
 		// No changes needed.
@@ -1022,6 +1562,7 @@ func (r *APIReconciler) upsertKeyPair(ctx context.Context, keyPair *configpb.Key
 	if err != nil {
 		return changed, err
 	}
+	cs.updated(apiKindKeyPair, existing)
 	return true, nil
 }
 
@@ -1029,7 +1570,7 @@ func (r *APIReconciler) findKeyPairByName(
 	ctx context.Context, name string,
 ) (existing *configpb.KeyPair, err error) {
 	filter, err := structpb.NewStruct(map[string]any{
-		"originator_id": originatorID,
+		"originator_id": r.originatorID(),
 		"name":          name,
 	})
 	if err != nil {
@@ -1098,6 +1639,7 @@ func (r *APIReconciler) deleteKeyPairsForSecrets(
 }
 
 func (r *APIReconciler) deleteKeyPair(ctx context.Context, id string) error {
+	r.drift.forget(apiKindKeyPair, id)
 	_, err := r.apiClient.DeleteKeyPair(ctx, connect.NewRequest(&configpb.DeleteKeyPairRequest{
 		Id: id,
 	}))
@@ -1107,14 +1649,94 @@ func (r *APIReconciler) deleteKeyPair(ctx context.Context, id string) error {
 	return err
 }
 
-func routeIDAnnotationForIndex(i int) string {
-	return apiRouteIDAnnotationPrefix + strconv.Itoa(i)
+// routeIDAnnotations returns the annotation key recording the API route ID for each route. The keys
+// are derived from the route matches rather than the route positions, so that inserting or
+// reordering rules does not cause an existing API route to be overwritten by a different route.
+func routeIDAnnotations(routes []*configpb.Route) []string {
+	keys := make([]string, len(routes))
+	seen := make(map[string]int, len(routes))
+	for i, route := range routes {
+		k := apiRouteKeyAnnotationPrefix + gateway.RouteMatchKey(route)[:16]
+		seen[k]++
+		if n := seen[k]; n > 1 {
+			// Routes with identical matches can be distinguished only by their order.
+			k += "-" + strconv.Itoa(n)
+		}
+		keys[i] = k
+	}
+	return keys
+}
+
+// migrateRouteIDAnnotations converts any legacy index-based route ID annotations to the
+// corresponding key-based annotations (see routeIDAnnotations). The routes may have been reordered
+// since the legacy annotations were recorded, so each API route is fetched and its ID adopted only
+// for a route with the same matches. The legacy annotations of any other API routes are left in
+// place, so that these routes will be deleted as unused.
+func (r *APIReconciler) migrateRouteIDAnnotations(
+	ctx context.Context, obj client.Object, routes []*configpb.Route, keys []string,
+) (modified bool, err error) {
+	annotations := obj.GetAnnotations()
+	var legacyKeys []string
+	for k := range annotations {
+		if strings.HasPrefix(k, apiRouteIDAnnotationPrefix) {
+			legacyKeys = append(legacyKeys, k)
+		}
+	}
+	if len(legacyKeys) == 0 {
+		return false, nil
+	}
+	// Routes with identical matches are adopted in their previous order.
+	slices.SortFunc(legacyKeys, func(a, b string) int {
+		return legacyRouteIndex(a) - legacyRouteIndex(b)
+	})
+
+	keysByMatch := make(map[string][]string, len(routes))
+	for i, route := range routes {
+		mk := gateway.RouteMatchKey(route)
+		keysByMatch[mk] = append(keysByMatch[mk], keys[i])
+	}
+
+	for _, legacyKey := range legacyKeys {
+		id := annotations[legacyKey]
+		resp, err := r.apiClient.GetRoute(ctx, connect.NewRequest(&configpb.GetRouteRequest{
+			Id: id,
+		}))
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			delete(annotations, legacyKey)
+			modified = true
+			continue
+		} else if err != nil {
+			return modified, fmt.Errorf("couldn't migrate route ID annotation %s: %w", legacyKey, err)
+		}
+
+		mk := gateway.RouteMatchKey(resp.Msg.Route)
+		for len(keysByMatch[mk]) > 0 {
+			k := keysByMatch[mk][0]
+			keysByMatch[mk] = keysByMatch[mk][1:]
+			if existing, ok := annotations[k]; ok && existing != id {
+				continue
+			}
+			annotations[k] = id
+			delete(annotations, legacyKey)
+			modified = true
+			break
+		}
+	}
+	return modified, nil
+}
+
+// legacyRouteIndex returns the route index of a legacy index-based route ID annotation.
+func legacyRouteIndex(k string) int {
+	i, _ := strconv.Atoi(strings.TrimPrefix(k, apiRouteIDAnnotationPrefix))
+	return i
 }
 
+// allRouteIDAnnotations returns the keys of all route ID annotations, either key-based or legacy
+// index-based.
 func allRouteIDAnnotations(annotations map[string]string) map[string]struct{} {
 	m := make(map[string]struct{})
 	for k := range annotations {
-		if strings.HasPrefix(k, apiRouteIDAnnotationPrefix) {
+		if strings.HasPrefix(k, apiRouteKeyAnnotationPrefix) || strings.HasPrefix(k, apiRouteIDAnnotationPrefix) {
 			m[k] = struct{}{}
 		}
 	}
@@ -1133,6 +1755,44 @@ func convertProto[Dst, Src proto.Message](msg Src) (Dst, error) {
 	return newMsg, err
 }
 
+// maskRoute clears the fields of an existing route that are not set by the controller, so that
+// it may be compared with a desired route.
+func maskRoute(existing, desired *configpb.Route) {
+	if desired.NamespaceId == nil {
+		existing.NamespaceId = nil
+	}
+	existing.CreatedAt = nil
+	existing.ModifiedAt = nil
+	existing.AssignedPolicies = nil
+	existing.EnforcedPolicies = nil
+	existing.StatName = nil
+}
+
+// maskPolicy clears the fields of an existing policy that are not set by the controller, so that
+// it may be compared with a desired policy.
+func maskPolicy(existing, desired *configpb.Policy) {
+	if desired.NamespaceId == nil {
+		existing.NamespaceId = nil
+	}
+	existing.CreatedAt = nil
+	existing.ModifiedAt = nil
+	existing.AssignedRoutes = nil
+	existing.Enforced = falseToNil(existing.Enforced)
+}
+
+// maskKeyPair clears the fields of an existing keypair that are not set by the controller, so
+// that it may be compared with a desired keypair.
+func maskKeyPair(existing, desired *configpb.KeyPair) {
+	if desired.NamespaceId == nil {
+		existing.NamespaceId = nil
+	}
+	existing.CreatedAt = nil
+	existing.ModifiedAt = nil
+	existing.CertificateInfo = nil
+	existing.Origin = configpb.KeyPairOrigin_KEY_PAIR_ORIGIN_UNKNOWN
+	existing.Status = configpb.KeyPairStatus_KEY_PAIR_STATUS_UNKNOWN
+}
+
 func falseToNil(x *bool) *bool {
 	if x != nil && !*x {
 		return nil
@@ -1140,6 +1800,13 @@ func falseToNil(x *bool) *bool {
 	return x
 }
 
+func nilToEmpty(x *string) string {
+	if x == nil {
+		return ""
+	}
+	return *x
+}
+
 func emptyToNil(x string) *string {
 	if x == "" {
 		return nil
@@ -0,0 +1,154 @@
+package pomerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+)
+
+// apiWriteConcurrency bounds the number of Kubernetes objects synced to the unified API at once.
+const apiWriteConcurrency = 8
+
+// apiChangeset records the unified API writes made while syncing one Ingress (or the whole
+// Gateway config), along with compensating writes to undo them if the sync fails part-way. It is
+// not atomic: the unified API has no batch or transactional writes (the AutoApplyChangesets
+// setting is not used), so each write is applied as it is made, and other writers may interleave
+// with both the writes and their undo.
+//
+// Undoing is best-effort: a created object is deleted, and an updated object is restored to its
+// previous state. Deletes cannot be undone, as a deleted object cannot be recreated with the same
+// ID, so deletes that replace an object (such as when a route moves to another API namespace)
+// are deferred until the sync has succeeded (see afterCommit). Other deletes are not recorded.
+//
+// A nil *apiChangeset records nothing. Methods may be called concurrently.
+type apiChangeset struct {
+	r *APIReconciler
+
+	mu     sync.Mutex
+	undo   []func(context.Context) error
+	commit []func(context.Context) error
+}
+
+func (cs *apiChangeset) onRollback(undo func(context.Context) error) {
+	if cs == nil {
+		return
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.undo = append(cs.undo, undo)
+}
+
+// created records the creation of an API object.
+func (cs *apiChangeset) created(kind apiKind, id string) {
+	cs.onRollback(func(ctx context.Context) error {
+		// The object is no longer expected to exist.
+		cs.r.drift.forget(kind, id)
+		return cs.r.deleteObject(ctx, kind, id)
+	})
+}
+
+// updated records an update to an API object from its previous state.
+func (cs *apiChangeset) updated(kind apiKind, previous proto.Message) {
+	if cs == nil {
+		return
+	}
+	previous = proto.Clone(previous)
+	cs.onRollback(func(ctx context.Context) error {
+		// The previous state is no longer the desired state.
+		cs.r.drift.forget(kind, previous.(interface{ GetId() string }).GetId())
+		return cs.r.updateObject(ctx, previous)
+	})
+}
+
+// afterCommit defers fn until the sync has succeeded, or calls it immediately for a nil
+// changeset.
+func (cs *apiChangeset) afterCommit(ctx context.Context, fn func(context.Context) error) error {
+	if cs == nil {
+		return fn(ctx)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.commit = append(cs.commit, fn)
+	return nil
+}
+
+// rollback undoes the recorded writes, most recent first. The rollback is not cancelled along
+// with ctx, as it usually follows a failed or cancelled sync.
+func (cs *apiChangeset) rollback(ctx context.Context) error {
+	cs.mu.Lock()
+	undo := slices.Clone(cs.undo)
+	cs.undo = nil
+	cs.mu.Unlock()
+
+	ctx = context.WithoutCancel(ctx)
+	var errs []error
+	for _, fn := range slices.Backward(undo) {
+		errs = append(errs, fn(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// inChangeset calls fn with a new changeset, and rolls back its writes if fn returns an error, or
+// otherwise makes the writes deferred until it succeeds.
+func (r *APIReconciler) inChangeset(
+	ctx context.Context, fn func(cs *apiChangeset) (bool, error),
+) (changes bool, err error) {
+	cs := &apiChangeset{r: r}
+	changes, err = fn(cs)
+	if err == nil {
+		var errs []error
+		for _, commit := range cs.commit {
+			errs = append(errs, commit(ctx))
+		}
+		return changes, errors.Join(errs...)
+	}
+	if len(cs.undo) == 0 {
+		return changes, err
+	}
+
+	log.FromContext(ctx).Info("rolling back failed sync", "writes", len(cs.undo))
+	r.drift.syncMu.RLock()
+	defer r.drift.syncMu.RUnlock()
+	if rbErr := cs.rollback(ctx); rbErr != nil {
+		err = errors.Join(err, fmt.Errorf("couldn't roll back: %w", rbErr))
+	}
+	return changes, err
+}
+
+// deleteObject deletes an API route, policy or keypair.
+func (r *APIReconciler) deleteObject(ctx context.Context, kind apiKind, id string) error {
+	switch kind {
+	case apiKindRoute:
+		return r.deleteRoute(ctx, id)
+	case apiKindPolicy:
+		return r.deletePolicy(ctx, id)
+	case apiKindKeyPair:
+		return r.deleteKeyPair(ctx, id)
+	}
+	return fmt.Errorf("internal error - cannot delete %s", kind)
+}
+
+// updateObject updates an API route, policy, keypair or settings.
+func (r *APIReconciler) updateObject(ctx context.Context, msg proto.Message) (err error) {
+	switch m := msg.(type) {
+	case *configpb.Route:
+		_, err = r.apiClient.UpdateRoute(ctx, connect.NewRequest(&configpb.UpdateRouteRequest{Route: m}))
+	case *configpb.Policy:
+		_, err = r.apiClient.UpdatePolicy(ctx, connect.NewRequest(&configpb.UpdatePolicyRequest{Policy: m}))
+	case *configpb.KeyPair:
+		_, err = r.apiClient.UpdateKeyPair(ctx, connect.NewRequest(&configpb.UpdateKeyPairRequest{KeyPair: m}))
+	case *configpb.Settings:
+		_, err = r.apiClient.UpdateSettings(ctx, connect.NewRequest(&configpb.UpdateSettingsRequest{Settings: m}))
+	default:
+		err = fmt.Errorf("internal error - unexpected type %T", msg)
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package pomerium
+
+import (
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+func TestAPIReconciler_changesetRollback(t *testing.T) {
+	newIngressConfig := func(name, host string) *model.IngressConfig {
+		return &model.IngressConfig{
+			AnnotationPrefix: "a",
+			Ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "test",
+				},
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: new("pomerium"),
+					Rules: []networkingv1.IngressRule{{
+						Host:             host,
+						IngressRuleValue: exampleIngressRuleValue,
+					}},
+				},
+			},
+			Services: map[types.NamespacedName]*corev1.Service{
+				{Name: "example-svc", Namespace: "test"}: {},
+			},
+		}
+	}
+	unavailable := connect.NewError(connect.CodeUnavailable, fmt.Errorf("unavailable"))
+
+	t.Run("upsert", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+
+		ic := newIngressConfig("my-ingress", "a.localhost.pomerium.io")
+		ic.Annotations = map[string]string{
+			"a/policy": `allow: {or: [{authenticated_user: true}]}`,
+		}
+		ic.Secrets = map[types.NamespacedName]*corev1.Secret{
+			{Name: "secret-1", Namespace: "test"}: {
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret-1",
+					Namespace:   "test",
+					Annotations: map[string]string{apiKeyPairIDAnnotation: "existing-keypair-id"},
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: map[string][]byte{
+					"tls.crt": []byte("cert-data"),
+					"tls.key": []byte("key-data"),
+				},
+			},
+		}
+		// The Ingress should not be patched, as the IDs of the objects created for it are rolled back.
+		k8sClient.EXPECT().Patch(ctx, gomock.AssignableToTypeOf(&corev1.Secret{}), gomock.Any()).
+			Return(nil).AnyTimes()
+
+		previousKeyPair := &configpb.KeyPair{
+			OriginatorId: new("ingress-controller"),
+			Id:           new("existing-keypair-id"),
+			Name:         new("test-secret-1"),
+			Certificate:  []byte("previous-cert-data"),
+			Key:          []byte("previous-key-data"),
+		}
+		apiClient.EXPECT().GetKeyPair(ctx, RequestEq(&configpb.GetKeyPairRequest{
+			Id: "existing-keypair-id",
+		})).Return(connect.NewResponse(&configpb.GetKeyPairResponse{KeyPair: previousKeyPair}), nil)
+		updateKeyPair := apiClient.EXPECT().UpdateKeyPair(ctx, RequestEq(&configpb.UpdateKeyPairRequest{
+			KeyPair: &configpb.KeyPair{
+				OriginatorId: new("ingress-controller"),
+				Id:           new("existing-keypair-id"),
+				Name:         new("test-secret-1"),
+				Certificate:  []byte("cert-data"),
+				Key:          []byte("key-data"),
+			},
+		})).Return(connect.NewResponse(&configpb.UpdateKeyPairResponse{}), nil)
+		createPolicy := apiClient.EXPECT().CreatePolicy(ctx, gomock.Any()).
+			Return(createPolicyResponseWithID("new-policy-id"), nil)
+		createRoute := apiClient.EXPECT().CreateRoute(ctx, gomock.Any()).Return(nil, unavailable)
+
+		// The failure to create the route should roll back the other writes, most recent first.
+		rollbackPolicy := apiClient.EXPECT().DeletePolicy(gomock.Any(), RequestEq(&configpb.DeletePolicyRequest{
+			Id: "new-policy-id",
+		})).Return(connect.NewResponse(&configpb.DeletePolicyResponse{}), nil)
+		rollbackKeyPair := apiClient.EXPECT().UpdateKeyPair(gomock.Any(), RequestEq(&configpb.UpdateKeyPairRequest{
+			KeyPair: previousKeyPair,
+		})).Return(connect.NewResponse(&configpb.UpdateKeyPairResponse{}), nil)
+		gomock.InOrder(updateKeyPair, createPolicy, createRoute, rollbackPolicy, rollbackKeyPair)
+
+		_, err := r.Upsert(ctx, ic)
+		assert.ErrorIs(t, err, unavailable)
+		assert.Empty(t, r.drift.snapshot(), "rolled back objects should not be checked for drift")
+	})
+
+	t.Run("namespace move", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		r.namespaceID = new("namespace-bravo")
+		ctx := t.Context()
+
+		ic := newIngressConfig("my-ingress", "a.localhost.pomerium.io")
+		ic.Spec.Rules = append(ic.Spec.Rules, networkingv1.IngressRule{
+			Host:             "b.localhost.pomerium.io",
+			IngressRuleValue: exampleIngressRuleValue,
+		})
+		ic.Annotations = map[string]string{
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
+		}
+		k8sClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "existing-route-id",
+		})).Return(connect.NewResponse(&configpb.GetRouteResponse{
+			Route: &configpb.Route{
+				Id:           new("existing-route-id"),
+				OriginatorId: new("ingress-controller"),
+				Name:         new("test-my-ingress-a-localhost-pomerium-io"),
+				NamespaceId:  new("namespace-alpha"),
+				From:         "https://a.localhost.pomerium.io",
+				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+				Prefix:       "/",
+			},
+		}), nil)
+		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+			Route: &configpb.Route{
+				OriginatorId: new("ingress-controller"),
+				Name:         new("test-my-ingress-a-localhost-pomerium-io"),
+				NamespaceId:  new("namespace-bravo"),
+				From:         "https://a.localhost.pomerium.io",
+				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+				Prefix:       "/",
+			},
+		})).Return(createRouteResponseWithID("moved-route-id"), nil)
+		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+			Route: &configpb.Route{
+				OriginatorId: new("ingress-controller"),
+				Name:         new("test-my-ingress-b-localhost-pomerium-io"),
+				NamespaceId:  new("namespace-bravo"),
+				From:         "https://b.localhost.pomerium.io",
+				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+				Prefix:       "/",
+			},
+		})).Return(nil, unavailable)
+
+		// The moved route should be deleted from the new namespace, and the existing route in the
+		// previous namespace should be kept.
+		apiClient.EXPECT().DeleteRoute(gomock.Any(), RequestEq(&configpb.DeleteRouteRequest{
+			Id: "moved-route-id",
+		})).Return(connect.NewResponse(&configpb.DeleteRouteResponse{}), nil)
+
+		_, err := r.Upsert(ctx, ic)
+		assert.ErrorIs(t, err, unavailable)
+	})
+
+	t.Run("set", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+
+		k8sClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+		// Each Ingress is synced in its own changeset, so a failure to sync one Ingress should not
+		// roll back another.
+		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+			Route: &configpb.Route{
+				OriginatorId: new("ingress-controller"),
+				Name:         new("test-ingress-1-a-localhost-pomerium-io"),
+				From:         "https://a.localhost.pomerium.io",
+				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+				Prefix:       "/",
+			},
+		})).Return(createRouteResponseWithID("new-route-id"), nil)
+		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+			Route: &configpb.Route{
+				OriginatorId: new("ingress-controller"),
+				Name:         new("test-ingress-2-b-localhost-pomerium-io"),
+				From:         "https://b.localhost.pomerium.io",
+				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+				Prefix:       "/",
+			},
+		})).Return(nil, unavailable)
+
+		changed, err := r.Set(ctx, []*model.IngressConfig{
+			newIngressConfig("ingress-1", "a.localhost.pomerium.io"),
+			newIngressConfig("ingress-2", "b.localhost.pomerium.io"),
+		})
+		assert.True(t, changed)
+		assert.ErrorIs(t, err, unavailable)
+	})
+}
@@ -0,0 +1,441 @@
+package pomerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+	"github.com/pomerium/pomerium/pkg/health"
+	sdk "github.com/pomerium/sdk-go"
+
+	health_ctrl "github.com/pomerium/ingress-controller/util/health"
+)
+
+var (
+	_ = APIHealthChecker((*APIReconciler)(nil))
+	_ = sdk.Client((*resilientClient)(nil))
+)
+
+// APIClientOptions configures the rate limiting, retries and circuit breaking of unified API
+// requests.
+type APIClientOptions struct {
+	// RateLimit is the maximum sustained rate of requests per second, or 0 for no limit.
+	RateLimit float64
+	// Burst is the maximum number of requests that may be made at once, within the rate limit.
+	Burst int
+	// MaxRetries is the maximum number of times a request failing with a retryable error (such as
+	// a 429 or 503 response) is retried, with exponential backoff. Create requests are only
+	// retried if these cannot have been committed (see shouldRetry).
+	MaxRetries uint64
+	// MaxRetryInterval is the maximum interval between retries.
+	MaxRetryInterval time.Duration
+	// BreakerThreshold is the number of consecutive requests that must fail with a retryable error
+	// (after retries) to open the circuit breaker, or 0 to disable the circuit breaker. Requests
+	// rejected as unauthenticated are not counted. While the circuit breaker is open, requests
+	// fail immediately.
+	BreakerThreshold int
+	// BreakerTimeout is how long the circuit breaker stays open before a trial request is allowed.
+	BreakerTimeout time.Duration
+}
+
+// DefaultAPIClientOptions returns the default unified API client options.
+func DefaultAPIClientOptions() APIClientOptions {
+	return APIClientOptions{
+		RateLimit:        20,
+		Burst:            40,
+		MaxRetries:       5,
+		MaxRetryInterval: 10 * time.Second,
+		BreakerThreshold: 10,
+		BreakerTimeout:   30 * time.Second,
+	}
+}
+
+// WithAPIClientOptions configures the rate limiting, retries and circuit breaking of unified API
+// requests, instead of DefaultAPIClientOptions.
+func WithAPIClientOptions(opts APIClientOptions) APIReconcilerOption {
+	return func(r *APIReconciler) {
+		r.clientOptions = opts
+	}
+}
+
+var (
+	apiRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pomerium_ingress_controller",
+		Subsystem: "sync_api",
+		Name:      "requests_total",
+		Help:      "Number of unified API request attempts, by method and result code.",
+	}, []string{"method", "code"})
+	apiRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "pomerium_ingress_controller",
+		Subsystem: "sync_api",
+		Name:      "retries_total",
+		Help:      "Number of unified API requests retried after a retryable error, by method.",
+	}, []string{"method"})
+	apiRateLimitWaitSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "pomerium_ingress_controller",
+		Subsystem: "sync_api",
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time unified API requests spent waiting for the client-side rate limit.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+	})
+	apiCircuitBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "pomerium_ingress_controller",
+		Subsystem: "sync_api",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the unified API circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		apiRequestsTotal,
+		apiRetriesTotal,
+		apiRateLimitWaitSeconds,
+		apiCircuitBreakerState,
+	)
+}
+
+// errCircuitOpen is returned for requests made while the circuit breaker is open.
+var errCircuitOpen = errors.New("unified API circuit breaker is open after repeated failures")
+
+// isRetryable reports whether a request that failed with err may succeed if retried.
+func isRetryable(err error) bool {
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeResourceExhausted, connect.CodeAborted:
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a request to the given method that failed with err is retried.
+// A create request that failed with an Unavailable or Aborted error may have been committed
+// nonetheless, and names are not unique for all kinds of objects, so retrying it could create a
+// duplicate object. Create requests are therefore only retried if rate limited, or if the request
+// could not be sent at all.
+func shouldRetry(method string, err error) bool {
+	if !isRetryable(err) {
+		return false
+	}
+	if !strings.HasPrefix(method, "Create") {
+		return true
+	}
+	var opErr *net.OpError
+	return connect.CodeOf(err) == connect.CodeResourceExhausted ||
+		(errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// circuitState is the state of a circuitBreaker.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+// circuitBreaker fails requests immediately after repeated failures, until a trial request
+// succeeds.
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	trial    bool
+	lastErr  error
+}
+
+// allow returns an error if a request may not be made.
+func (b *circuitBreaker) allow(now time.Time) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if now.Sub(b.openedAt) < b.timeout {
+			return connect.NewError(connect.CodeUnavailable, errCircuitOpen)
+		}
+		b.setState(circuitHalfOpen)
+		fallthrough
+	case circuitHalfOpen:
+		// Only one trial request at a time.
+		if b.trial {
+			return connect.NewError(connect.CodeUnavailable, errCircuitOpen)
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// record records the outcome of an allowed request.
+func (b *circuitBreaker) record(now time.Time, err error) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	switch connect.CodeOf(err) {
+	case connect.CodeCanceled, connect.CodeDeadlineExceeded:
+		// The request was abandoned, so says nothing about the API.
+		return
+	case connect.CodeUnauthenticated, connect.CodePermissionDenied:
+		// The token was rejected, which is reported separately (see APIAuthError), and is
+		// resolved by replacing the token rather than by waiting.
+		return
+	}
+	if !isRetryable(err) {
+		b.failures = 0
+		b.lastErr = nil
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	b.lastErr = err
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = now
+		b.setState(circuitOpen)
+	}
+}
+
+// err returns an error if the circuit breaker is not closed.
+func (b *circuitBreaker) err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == circuitClosed {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", errCircuitOpen, b.lastErr)
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	apiCircuitBreakerState.Set(float64(state))
+	if state == circuitClosed {
+		health.ReportRunning(health_ctrl.SyncAPIClient)
+	} else {
+		health.ReportError(health_ctrl.SyncAPIClient, fmt.Errorf("%w: %w", errCircuitOpen, b.lastErr))
+	}
+}
+
+// resilientClient wraps a unified API client with client-side rate limiting, retries, and a
+// circuit breaker.
+type resilientClient struct {
+	sdk.Client
+
+	opts    APIClientOptions
+	limiter *rate.Limiter
+	breaker *circuitBreaker
+	// tokenLoaded, if set, is closed once an API token is available. Until then requests wait.
+	tokenLoaded <-chan struct{}
+}
+
+func newResilientClient(client sdk.Client, opts APIClientOptions) *resilientClient {
+	limit := rate.Inf
+	if opts.RateLimit > 0 {
+		limit = rate.Limit(opts.RateLimit)
+	}
+	health.ReportRunning(health_ctrl.SyncAPIClient)
+	return &resilientClient{
+		Client:  client,
+		opts:    opts,
+		limiter: rate.NewLimiter(limit, max(opts.Burst, 1)),
+		breaker: &circuitBreaker{threshold: opts.BreakerThreshold, timeout: opts.BreakerTimeout},
+	}
+}
+
+// waitForToken waits until an API token is available, so that requests made before the token is
+// first loaded are not rejected.
+func (c *resilientClient) waitForToken(ctx context.Context, method string) error {
+	if c.tokenLoaded == nil {
+		return nil
+	}
+	select {
+	case <-c.tokenLoaded:
+		return nil
+	default:
+	}
+	log.FromContext(ctx).V(1).Info("waiting for the unified API token", "method", method)
+	select {
+	case <-c.tokenLoaded:
+		return nil
+	case <-ctx.Done():
+		return connect.NewError(connect.CodeCanceled, ctx.Err())
+	}
+}
+
+// call makes a unified API request via c.
+func call[Req, Resp any](
+	ctx context.Context,
+	c *resilientClient,
+	method string,
+	fn func(context.Context, *connect.Request[Req]) (*connect.Response[Resp], error),
+	req *connect.Request[Req],
+) (*connect.Response[Resp], error) {
+	if err := c.waitForToken(ctx, method); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(time.Now()); err != nil {
+		apiRequestsTotal.WithLabelValues(method, "circuit_open").Inc()
+		return nil, err
+	}
+
+	bo := backoff.NewExponentialBackOff()
+	bo.MaxInterval = c.opts.MaxRetryInterval
+	bo.InitialInterval = min(bo.InitialInterval, bo.MaxInterval)
+	bo.MaxElapsedTime = 0
+	resp, err := backoff.RetryNotifyWithData(func() (*connect.Response[Resp], error) {
+		start := time.Now()
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, backoff.Permanent(err)
+		}
+		apiRateLimitWaitSeconds.Observe(time.Since(start).Seconds())
+
+		resp, err := fn(ctx, req)
+		code := "ok"
+		if err != nil {
+			code = connect.CodeOf(err).String()
+		}
+		apiRequestsTotal.WithLabelValues(method, code).Inc()
+		if err != nil && !shouldRetry(method, err) {
+			return nil, backoff.Permanent(err)
+		}
+		return resp, err
+	}, backoff.WithContext(backoff.WithMaxRetries(bo, c.opts.MaxRetries), ctx),
+		func(err error, next time.Duration) {
+			apiRetriesTotal.WithLabelValues(method).Inc()
+			log.FromContext(ctx).V(1).Info("retrying unified API request",
+				"method", method, "error", err.Error(), "after", next)
+		})
+
+	c.breaker.record(time.Now(), err)
+	return resp, err
+}
+
+func (c *resilientClient) CreateKeyPair(ctx context.Context, req *connect.Request[configpb.CreateKeyPairRequest]) (*connect.Response[configpb.CreateKeyPairResponse], error) {
+	return call(ctx, c, "CreateKeyPair", c.Client.CreateKeyPair, req)
+}
+
+func (c *resilientClient) CreatePolicy(ctx context.Context, req *connect.Request[configpb.CreatePolicyRequest]) (*connect.Response[configpb.CreatePolicyResponse], error) {
+	return call(ctx, c, "CreatePolicy", c.Client.CreatePolicy, req)
+}
+
+func (c *resilientClient) CreateRoute(ctx context.Context, req *connect.Request[configpb.CreateRouteRequest]) (*connect.Response[configpb.CreateRouteResponse], error) {
+	return call(ctx, c, "CreateRoute", c.Client.CreateRoute, req)
+}
+
+func (c *resilientClient) CreateServiceAccount(ctx context.Context, req *connect.Request[configpb.CreateServiceAccountRequest]) (*connect.Response[configpb.CreateServiceAccountResponse], error) {
+	return call(ctx, c, "CreateServiceAccount", c.Client.CreateServiceAccount, req)
+}
+
+func (c *resilientClient) DeleteKeyPair(ctx context.Context, req *connect.Request[configpb.DeleteKeyPairRequest]) (*connect.Response[configpb.DeleteKeyPairResponse], error) {
+	return call(ctx, c, "DeleteKeyPair", c.Client.DeleteKeyPair, req)
+}
+
+func (c *resilientClient) DeletePolicy(ctx context.Context, req *connect.Request[configpb.DeletePolicyRequest]) (*connect.Response[configpb.DeletePolicyResponse], error) {
+	return call(ctx, c, "DeletePolicy", c.Client.DeletePolicy, req)
+}
+
+func (c *resilientClient) DeleteRoute(ctx context.Context, req *connect.Request[configpb.DeleteRouteRequest]) (*connect.Response[configpb.DeleteRouteResponse], error) {
+	return call(ctx, c, "DeleteRoute", c.Client.DeleteRoute, req)
+}
+
+func (c *resilientClient) DeleteServiceAccount(ctx context.Context, req *connect.Request[configpb.DeleteServiceAccountRequest]) (*connect.Response[configpb.DeleteServiceAccountResponse], error) {
+	return call(ctx, c, "DeleteServiceAccount", c.Client.DeleteServiceAccount, req)
+}
+
+func (c *resilientClient) GetKeyPair(ctx context.Context, req *connect.Request[configpb.GetKeyPairRequest]) (*connect.Response[configpb.GetKeyPairResponse], error) {
+	return call(ctx, c, "GetKeyPair", c.Client.GetKeyPair, req)
+}
+
+func (c *resilientClient) GetPolicy(ctx context.Context, req *connect.Request[configpb.GetPolicyRequest]) (*connect.Response[configpb.GetPolicyResponse], error) {
+	return call(ctx, c, "GetPolicy", c.Client.GetPolicy, req)
+}
+
+func (c *resilientClient) GetRoute(ctx context.Context, req *connect.Request[configpb.GetRouteRequest]) (*connect.Response[configpb.GetRouteResponse], error) {
+	return call(ctx, c, "GetRoute", c.Client.GetRoute, req)
+}
+
+func (c *resilientClient) GetServerInfo(ctx context.Context, req *connect.Request[configpb.GetServerInfoRequest]) (*connect.Response[configpb.GetServerInfoResponse], error) {
+	return call(ctx, c, "GetServerInfo", c.Client.GetServerInfo, req)
+}
+
+func (c *resilientClient) GetServiceAccount(ctx context.Context, req *connect.Request[configpb.GetServiceAccountRequest]) (*connect.Response[configpb.GetServiceAccountResponse], error) {
+	return call(ctx, c, "GetServiceAccount", c.Client.GetServiceAccount, req)
+}
+
+func (c *resilientClient) GetSettings(ctx context.Context, req *connect.Request[configpb.GetSettingsRequest]) (*connect.Response[configpb.GetSettingsResponse], error) {
+	return call(ctx, c, "GetSettings", c.Client.GetSettings, req)
+}
+
+func (c *resilientClient) ListAvailableLogFields(ctx context.Context, req *connect.Request[configpb.ListAvailableLogFieldsRequest]) (*connect.Response[configpb.ListAvailableLogFieldsResponse], error) {
+	return call(ctx, c, "ListAvailableLogFields", c.Client.ListAvailableLogFields, req)
+}
+
+func (c *resilientClient) ListKeyPairs(ctx context.Context, req *connect.Request[configpb.ListKeyPairsRequest]) (*connect.Response[configpb.ListKeyPairsResponse], error) {
+	return call(ctx, c, "ListKeyPairs", c.Client.ListKeyPairs, req)
+}
+
+func (c *resilientClient) ListPolicies(ctx context.Context, req *connect.Request[configpb.ListPoliciesRequest]) (*connect.Response[configpb.ListPoliciesResponse], error) {
+	return call(ctx, c, "ListPolicies", c.Client.ListPolicies, req)
+}
+
+func (c *resilientClient) ListRoutes(ctx context.Context, req *connect.Request[configpb.ListRoutesRequest]) (*connect.Response[configpb.ListRoutesResponse], error) {
+	return call(ctx, c, "ListRoutes", c.Client.ListRoutes, req)
+}
+
+func (c *resilientClient) ListServiceAccounts(ctx context.Context, req *connect.Request[configpb.ListServiceAccountsRequest]) (*connect.Response[configpb.ListServiceAccountsResponse], error) {
+	return call(ctx, c, "ListServiceAccounts", c.Client.ListServiceAccounts, req)
+}
+
+func (c *resilientClient) ListSettings(ctx context.Context, req *connect.Request[configpb.ListSettingsRequest]) (*connect.Response[configpb.ListSettingsResponse], error) {
+	return call(ctx, c, "ListSettings", c.Client.ListSettings, req)
+}
+
+func (c *resilientClient) UpdateKeyPair(ctx context.Context, req *connect.Request[configpb.UpdateKeyPairRequest]) (*connect.Response[configpb.UpdateKeyPairResponse], error) {
+	return call(ctx, c, "UpdateKeyPair", c.Client.UpdateKeyPair, req)
+}
+
+func (c *resilientClient) UpdatePolicy(ctx context.Context, req *connect.Request[configpb.UpdatePolicyRequest]) (*connect.Response[configpb.UpdatePolicyResponse], error) {
+	return call(ctx, c, "UpdatePolicy", c.Client.UpdatePolicy, req)
+}
+
+func (c *resilientClient) UpdateRoute(ctx context.Context, req *connect.Request[configpb.UpdateRouteRequest]) (*connect.Response[configpb.UpdateRouteResponse], error) {
+	return call(ctx, c, "UpdateRoute", c.Client.UpdateRoute, req)
+}
+
+func (c *resilientClient) UpdateServiceAccount(ctx context.Context, req *connect.Request[configpb.UpdateServiceAccountRequest]) (*connect.Response[configpb.UpdateServiceAccountResponse], error) {
+	return call(ctx, c, "UpdateServiceAccount", c.Client.UpdateServiceAccount, req)
+}
+
+func (c *resilientClient) UpdateSettings(ctx context.Context, req *connect.Request[configpb.UpdateSettingsRequest]) (*connect.Response[configpb.UpdateSettingsResponse], error) {
+	return call(ctx, c, "UpdateSettings", c.Client.UpdateSettings, req)
+}
+
+// APIHealthError returns an error while unified API requests are failing immediately, as the
+// circuit breaker is open after repeated failures.
+func (r *APIReconciler) APIHealthError() error {
+	if c, ok := r.apiClient.(*resilientClient); ok {
+		return c.breaker.err()
+	}
+	return nil
+}
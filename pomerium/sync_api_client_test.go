@@ -0,0 +1,138 @@
+package pomerium
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	controllers_mock "github.com/pomerium/ingress-controller/controllers/mock"
+)
+
+func TestResilientClient(t *testing.T) {
+	unavailable := connect.NewError(connect.CodeUnavailable, fmt.Errorf("unavailable"))
+	notFound := connect.NewError(connect.CodeNotFound, fmt.Errorf("not found"))
+	opts := APIClientOptions{
+		MaxRetries:       2,
+		MaxRetryInterval: time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerTimeout:   time.Hour,
+	}
+	req := connect.NewRequest(&configpb.GetRouteRequest{Id: "route-id"})
+	resp := connect.NewResponse(&configpb.GetRouteResponse{})
+
+	t.Run("retry", func(t *testing.T) {
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		c := newResilientClient(apiClient, opts)
+		ctx := t.Context()
+
+		gomock.InOrder(
+			apiClient.EXPECT().GetRoute(ctx, req).Return(nil, unavailable).Times(2),
+			apiClient.EXPECT().GetRoute(ctx, req).Return(resp, nil),
+		)
+		got, err := c.GetRoute(ctx, req)
+		require.NoError(t, err)
+		assert.Same(t, resp, got)
+
+		// Errors that are not retryable are returned as is.
+		apiClient.EXPECT().GetRoute(ctx, req).Return(nil, notFound)
+		_, err = c.GetRoute(ctx, req)
+		assert.ErrorIs(t, err, notFound)
+		assert.NoError(t, c.breaker.err())
+	})
+
+	t.Run("retry create", func(t *testing.T) {
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		c := newResilientClient(apiClient, opts)
+		ctx := t.Context()
+		createReq := connect.NewRequest(&configpb.CreatePolicyRequest{Policy: &configpb.Policy{}})
+		createResp := connect.NewResponse(&configpb.CreatePolicyResponse{})
+
+		// A create request that may have been committed is not retried, as that could create a
+		// duplicate object.
+		apiClient.EXPECT().CreatePolicy(ctx, createReq).Return(nil, unavailable)
+		_, err := c.CreatePolicy(ctx, createReq)
+		assert.ErrorIs(t, err, unavailable)
+
+		// A create request that was rate limited, or never sent, is retried.
+		resourceExhausted := connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("rate limited"))
+		dialError := connect.NewError(connect.CodeUnavailable, &net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")})
+		gomock.InOrder(
+			apiClient.EXPECT().CreatePolicy(ctx, createReq).Return(nil, resourceExhausted),
+			apiClient.EXPECT().CreatePolicy(ctx, createReq).Return(nil, dialError),
+			apiClient.EXPECT().CreatePolicy(ctx, createReq).Return(createResp, nil),
+		)
+		got, err := c.CreatePolicy(ctx, createReq)
+		require.NoError(t, err)
+		assert.Same(t, createResp, got)
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		c := newResilientClient(apiClient, opts)
+		ctx := t.Context()
+
+		// Each request is attempted 3 times.
+		apiClient.EXPECT().GetRoute(ctx, req).Return(nil, unavailable).Times(6)
+		for range 2 {
+			_, err := c.GetRoute(ctx, req)
+			assert.ErrorIs(t, err, unavailable)
+		}
+
+		// The circuit breaker should now be open, so requests fail without being attempted.
+		_, err := c.GetRoute(ctx, req)
+		assert.ErrorIs(t, err, errCircuitOpen)
+		assert.ErrorIs(t, c.breaker.err(), errCircuitOpen)
+
+		// After the timeout a trial request is allowed, which closes the circuit breaker if it
+		// succeeds.
+		c.breaker.openedAt = time.Now().Add(-opts.BreakerTimeout)
+		apiClient.EXPECT().GetRoute(ctx, req).Return(resp, nil)
+		_, err = c.GetRoute(ctx, req)
+		require.NoError(t, err)
+		assert.NoError(t, c.breaker.err())
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		c := newResilientClient(apiClient, opts)
+		ctx := t.Context()
+
+		// Requests rejected as unauthenticated are not retried, and do not open the circuit
+		// breaker.
+		unauthenticated := connect.NewError(connect.CodeUnauthenticated, fmt.Errorf("unauthenticated"))
+		apiClient.EXPECT().GetRoute(ctx, req).Return(nil, unauthenticated).Times(3)
+		for range 3 {
+			_, err := c.GetRoute(ctx, req)
+			assert.ErrorIs(t, err, unauthenticated)
+		}
+		assert.NoError(t, c.breaker.err())
+	})
+
+	t.Run("wait for token", func(t *testing.T) {
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		c := newResilientClient(apiClient, opts)
+		r := &APIReconciler{apiClient: c}
+		r.creds.loaded = make(chan struct{})
+		c.tokenLoaded = r.creds.loaded
+
+		// Requests made before a token is set wait for it, rather than being rejected.
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		_, err := c.GetRoute(ctx, req)
+		assert.Equal(t, connect.CodeCanceled, connect.CodeOf(err))
+
+		r.SetAPIToken("token")
+		apiClient.EXPECT().GetRoute(t.Context(), req).Return(resp, nil)
+		_, err = c.GetRoute(t.Context(), req)
+		require.NoError(t, err)
+	})
+}
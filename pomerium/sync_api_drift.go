@@ -0,0 +1,340 @@
+package pomerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	icsv1 "github.com/pomerium/ingress-controller/apis/ingress/v1"
+)
+
+var _ = DriftDetector((*APIReconciler)(nil))
+
+// DriftMode determines how out-of-band changes to the synced API objects are handled.
+type DriftMode string
+
+const (
+	// DriftModeRevert reverts any changes to the synced configuration.
+	DriftModeRevert DriftMode = "revert"
+	// DriftModeReport reports any changes as an Event and a status condition, but leaves them in place.
+	DriftModeReport DriftMode = "report"
+	// DriftModeIgnore ignores any changes.
+	DriftModeIgnore DriftMode = "ignore"
+)
+
+// ParseDriftMode parses a DriftMode.
+func ParseDriftMode(s string) (DriftMode, error) {
+	switch mode := DriftMode(s); mode {
+	case DriftModeRevert, DriftModeReport, DriftModeIgnore:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown drift mode %q, expected one of %q, %q or %q",
+		s, DriftModeRevert, DriftModeReport, DriftModeIgnore)
+}
+
+// apiDriftAnnotation may be set on a Kubernetes object to select the DriftMode for the API objects
+// synced from it.
+const apiDriftAnnotation = "api.pomerium.io/drift"
+
+// DriftDetectedCondition is the Pomerium CRD status condition reporting whether any synced API
+// objects were found to have been changed out-of-band and left in place.
+const DriftDetectedCondition = "DriftDetected"
+
+type apiKind string
+
+const (
+	apiKindRoute    apiKind = "route"
+	apiKindPolicy   apiKind = "policy"
+	apiKindKeyPair  apiKind = "keypair"
+	apiKindSettings apiKind = "settings"
+)
+
+type driftKey struct {
+	kind apiKind
+	id   string
+}
+
+// driftEntry holds the desired state of a synced API object.
+type driftEntry struct {
+	desired proto.Message
+	// owner is a copy of the Kubernetes object the API object was synced from, if any.
+	owner client.Object
+}
+
+// driftTracker records the desired state of each API object synced by the APIReconciler, so that
+// any out-of-band changes can be detected.
+type driftTracker struct {
+	// syncMu is held for reading while syncing an API object, and for writing while reverting
+	// drift, so that drift is never reverted to a desired state that is being replaced.
+	syncMu sync.RWMutex
+
+	mu      sync.Mutex
+	entries map[driftKey]*driftEntry
+}
+
+func (t *driftTracker) record(kind apiKind, id string, desired proto.Message, owner client.Object) {
+	if id == "" && kind != apiKindSettings {
+		return
+	}
+	e := &driftEntry{desired: proto.Clone(desired)}
+	if owner != nil {
+		e.owner = owner.DeepCopyObject().(client.Object)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.entries == nil {
+		t.entries = make(map[driftKey]*driftEntry)
+	}
+	t.entries[driftKey{kind, id}] = e
+}
+
+func (t *driftTracker) forget(kind apiKind, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, driftKey{kind, id})
+}
+
+func (t *driftTracker) get(key driftKey) *driftEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.entries[key]
+}
+
+func (t *driftTracker) snapshot() map[driftKey]*driftEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return maps.Clone(t.entries)
+}
+
+// CheckDrift fetches each API object synced by the reconciler and compares it with the desired
+// state as last synced. Any changes are reverted or reported according to the current drift
+// annotation of the Kubernetes object the API object was synced from, or else opts.DefaultMode.
+func (r *APIReconciler) CheckDrift(ctx context.Context, opts DriftCheckOptions) (changes bool, err error) {
+	logger := log.FromContext(ctx).WithName("APIReconciler.CheckDrift")
+
+	var errs []error
+	var reported []string
+	var settingsOwner client.Object
+	for key, entry := range r.drift.snapshot() {
+		if key.kind == apiKindSettings {
+			settingsOwner = entry.owner
+		}
+		mode := driftModeFor(r.liveOwner(ctx, entry.owner), opts.DefaultMode)
+		if mode == DriftModeIgnore {
+			continue
+		}
+
+		current, desired, err := r.fetchForDrift(ctx, key, entry.desired)
+		if connect.CodeOf(err) == connect.CodeNotFound {
+			// This cannot be reverted here, as the API object would be recreated with a new ID.
+			// Instead the object it was synced from is synced again, which recreates it and
+			// replaces this entry. Until then it is reported on every check.
+			logger.Info("synced object was deleted", "kind", key.kind, "id", key.id)
+			reported = append(reported, describeDrift(key, entry)+" (deleted)")
+			recordDriftEvent(opts.EventRecorder, entry.owner, corev1.EventTypeWarning, "DriftDetected",
+				"%s was deleted outside of the controller, and will be recreated when next synced",
+				describeDrift(key, entry))
+			if mode == DriftModeRevert {
+				r.resyncGatewayRoutes.Store(true)
+				if opts.Resync != nil && entry.owner != nil {
+					opts.Resync(entry.owner)
+				}
+			}
+			continue
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't fetch %s %q: %w", key.kind, key.id, err))
+			continue
+		}
+		if proto.Equal(current, desired) {
+			continue
+		}
+
+		diff := cmp.Diff(current, desired, protocmp.Transform())
+		if mode == DriftModeReport {
+			logger.Info("detected drift", "kind", key.kind, "id", key.id, "diff", diff)
+			reported = append(reported, describeDrift(key, entry))
+			recordDriftEvent(opts.EventRecorder, entry.owner, corev1.EventTypeWarning, "DriftDetected",
+				"%s was modified outside of the controller", describeDrift(key, entry))
+			continue
+		}
+
+		reverted, err := r.revertDrift(ctx, key, entry, desired)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't revert %s %q: %w", key.kind, key.id, err))
+			continue
+		} else if reverted {
+			logger.Info("reverted drift", "kind", key.kind, "id", key.id, "diff", diff)
+			recordDriftEvent(opts.EventRecorder, entry.owner, corev1.EventTypeNormal, "DriftReverted",
+				"%s was modified outside of the controller, and has been reverted", describeDrift(key, entry))
+			changes = true
+		}
+	}
+
+	if pom, ok := settingsOwner.(*icsv1.Pomerium); ok {
+		if err := r.updateDriftCondition(ctx, pom, reported); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't update drift condition: %w", err))
+		}
+	}
+
+	return changes, errors.Join(errs...)
+}
+
+// fetchForDrift returns the current state of an API object, masked for comparison, along with the
+// desired state to compare it against.
+func (r *APIReconciler) fetchForDrift(
+	ctx context.Context, key driftKey, desired proto.Message,
+) (current, want proto.Message, err error) {
+	switch key.kind {
+	case apiKindRoute:
+		resp, err := r.apiClient.GetRoute(ctx, connect.NewRequest(&configpb.GetRouteRequest{Id: key.id}))
+		if err != nil {
+			return nil, nil, err
+		}
+		maskRoute(resp.Msg.Route, desired.(*configpb.Route))
+		return resp.Msg.Route, desired, nil
+	case apiKindPolicy:
+		resp, err := r.apiClient.GetPolicy(ctx, connect.NewRequest(&configpb.GetPolicyRequest{Id: key.id}))
+		if err != nil {
+			return nil, nil, err
+		}
+		maskPolicy(resp.Msg.Policy, desired.(*configpb.Policy))
+		return resp.Msg.Policy, desired, nil
+	case apiKindKeyPair:
+		resp, err := r.apiClient.GetKeyPair(ctx, connect.NewRequest(&configpb.GetKeyPairRequest{Id: key.id}))
+		if err != nil {
+			return nil, nil, err
+		}
+		maskKeyPair(resp.Msg.KeyPair, desired.(*configpb.KeyPair))
+		return resp.Msg.KeyPair, desired, nil
+	case apiKindSettings:
+		existing, err := r.getSettings(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		settings := proto.Clone(desired).(*configpb.Settings)
+		preserveSettings(settings, existing)
+		return existing, settings, nil
+	}
+	return nil, nil, fmt.Errorf("internal error - unknown kind %q", key.kind)
+}
+
+// revertDrift updates an API object to its desired state, unless it has been synced again or
+// deleted since the drift was detected.
+func (r *APIReconciler) revertDrift(
+	ctx context.Context, key driftKey, entry *driftEntry, desired proto.Message,
+) (bool, error) {
+	r.drift.syncMu.Lock()
+	defer r.drift.syncMu.Unlock()
+
+	if r.drift.get(key) != entry {
+		return false, nil
+	}
+
+	err := r.updateObject(ctx, desired)
+	return err == nil, err
+}
+
+// updateDriftCondition sets the DriftDetected condition on the Pomerium CRD. Other controllers
+// set other conditions on the same object, so the patch is made with an optimistic lock, and
+// retried on conflict.
+func (r *APIReconciler) updateDriftCondition(ctx context.Context, owner *icsv1.Pomerium, reported []string) error {
+	condition := metav1.Condition{
+		Type:    DriftDetectedCondition,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoDrift",
+		Message: "synced configuration matches the Kubernetes objects",
+	}
+	if len(reported) > 0 {
+		const maxListed = 10
+		slices.Sort(reported)
+		listed := reported[:min(len(reported), maxListed)]
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "DriftDetected"
+		condition.Message = fmt.Sprintf("%d synced objects were changed outside of the controller: %s",
+			len(reported), strings.Join(listed, ", "))
+		if len(reported) > maxListed {
+			condition.Message += ", ..."
+		}
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var pom icsv1.Pomerium
+		if err := r.k8sClient.Get(ctx, client.ObjectKeyFromObject(owner), &pom); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		original := pom.DeepCopy()
+		condition.ObservedGeneration = pom.Generation
+		if !meta.SetStatusCondition(&pom.Status.Conditions, condition) {
+			return nil
+		}
+		return r.k8sClient.Status().Patch(ctx, &pom,
+			client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// liveOwner returns the current state of owner, read from the (cached) Kubernetes client, as a
+// change to its drift annotation alone does not cause it to be synced again. If owner cannot be
+// read, the copy recorded when it was last synced is returned.
+func (r *APIReconciler) liveOwner(ctx context.Context, owner client.Object) client.Object {
+	if owner == nil {
+		return nil
+	}
+	live := owner.DeepCopyObject().(client.Object)
+	if err := r.k8sClient.Get(ctx, client.ObjectKeyFromObject(owner), live); err != nil {
+		log.FromContext(ctx).V(1).Info("couldn't read drift annotation, using the last synced value",
+			"name", owner.GetName(), "namespace", owner.GetNamespace(), "error", err)
+		return owner
+	}
+	return live
+}
+
+// driftModeFor returns the DriftMode selected by the annotation on owner, or else defaultMode.
+func driftModeFor(owner client.Object, defaultMode DriftMode) DriftMode {
+	if owner != nil {
+		if mode, err := ParseDriftMode(owner.GetAnnotations()[apiDriftAnnotation]); err == nil {
+			return mode
+		}
+	}
+	if defaultMode == "" {
+		return DriftModeReport
+	}
+	return defaultMode
+}
+
+func describeDrift(key driftKey, entry *driftEntry) string {
+	if m, ok := entry.desired.(interface{ GetName() string }); ok && m.GetName() != "" {
+		return fmt.Sprintf("%s %q", key.kind, m.GetName())
+	}
+	if key.id == "" {
+		return string(key.kind)
+	}
+	return fmt.Sprintf("%s %q", key.kind, key.id)
+}
+
+func recordDriftEvent(
+	recorder record.EventRecorder, owner client.Object, eventType, reason, messageFmt string, args ...any,
+) {
+	if recorder == nil || owner == nil {
+		return
+	}
+	recorder.Eventf(owner, eventType, reason, messageFmt, args...)
+}
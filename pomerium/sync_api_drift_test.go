@@ -0,0 +1,186 @@
+package pomerium
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	controllers_mock "github.com/pomerium/ingress-controller/controllers/mock"
+)
+
+func TestAPIReconciler_CheckDrift(t *testing.T) {
+	desired := &configpb.Route{
+		Id:           new("route-id"),
+		Name:         new("my-route"),
+		From:         "https://example.com",
+		To:           []string{"http://example.svc.cluster.local"},
+		OriginatorId: new(defaultOriginatorID),
+	}
+	modified := &configpb.Route{
+		Id:           new("route-id"),
+		Name:         new("my-route"),
+		From:         "https://example.com",
+		To:           []string{"http://somewhere-else.example.com"},
+		OriginatorId: new(defaultOriginatorID),
+	}
+	owner := func(mode string) *networkingv1.Ingress {
+		ingress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-ingress", Namespace: "default"},
+		}
+		if mode != "" {
+			ingress.Annotations = map[string]string{apiDriftAnnotation: mode}
+		}
+		return ingress
+	}
+	// The drift annotation is read from the current state of the owner.
+	expectOwner := func(k8sClient *controllers_mock.MockClient, live *networkingv1.Ingress) *gomock.Call {
+		return k8sClient.EXPECT().Get(gomock.Any(), client.ObjectKeyFromObject(live),
+			gomock.AssignableToTypeOf((*networkingv1.Ingress)(nil))).DoAndReturn(
+			func(_ context.Context, _ client.ObjectKey, dst *networkingv1.Ingress, _ ...client.GetOption) error {
+				*dst = *live
+				return nil
+			})
+	}
+
+	t.Run("revert", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+		recorder := record.NewFakeRecorder(10)
+		r.drift.record(apiKindRoute, "route-id", desired, owner(string(DriftModeRevert)))
+		expectOwner(k8sClient, owner(string(DriftModeRevert)))
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "route-id",
+		})).Return(connect.NewResponse(&configpb.GetRouteResponse{Route: modified}), nil)
+		apiClient.EXPECT().UpdateRoute(ctx, RequestEq(&configpb.UpdateRouteRequest{
+			Route: desired,
+		})).Return(connect.NewResponse(&configpb.UpdateRouteResponse{Route: desired}), nil)
+
+		changes, err := r.CheckDrift(ctx, DriftCheckOptions{EventRecorder: recorder})
+		require.NoError(t, err)
+		assert.True(t, changes)
+		assert.Equal(t,
+			`Normal DriftReverted route "my-route" was modified outside of the controller, and has been reverted`,
+			<-recorder.Events)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		// Drift is only reported by default.
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+		recorder := record.NewFakeRecorder(10)
+		r.drift.record(apiKindRoute, "route-id", desired, owner(""))
+		expectOwner(k8sClient, owner(""))
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "route-id",
+		})).Return(connect.NewResponse(&configpb.GetRouteResponse{Route: modified}), nil)
+
+		changes, err := r.CheckDrift(ctx, DriftCheckOptions{EventRecorder: recorder})
+		require.NoError(t, err)
+		assert.False(t, changes)
+		assert.Equal(t,
+			`Warning DriftDetected route "my-route" was modified outside of the controller`,
+			<-recorder.Events)
+	})
+
+	t.Run("annotation changed", func(t *testing.T) {
+		// The owner was annotated after it was last synced.
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+		r.drift.record(apiKindRoute, "route-id", desired, owner(""))
+		expectOwner(k8sClient, owner(string(DriftModeRevert)))
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "route-id",
+		})).Return(connect.NewResponse(&configpb.GetRouteResponse{Route: modified}), nil)
+		apiClient.EXPECT().UpdateRoute(ctx, RequestEq(&configpb.UpdateRouteRequest{
+			Route: desired,
+		})).Return(connect.NewResponse(&configpb.UpdateRouteResponse{Route: desired}), nil)
+
+		changes, err := r.CheckDrift(ctx, DriftCheckOptions{})
+		require.NoError(t, err)
+		assert.True(t, changes)
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		_, k8sClient, r := setupReconciler(t)
+		r.drift.record(apiKindRoute, "route-id", desired, owner(""))
+		expectOwner(k8sClient, owner(""))
+
+		changes, err := r.CheckDrift(t.Context(), DriftCheckOptions{DefaultMode: DriftModeIgnore})
+		require.NoError(t, err)
+		assert.False(t, changes)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+		r.drift.record(apiKindRoute, "route-id", desired, owner(""))
+		expectOwner(k8sClient, owner("")).Times(2)
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "route-id",
+		})).Return(nil, connect.NewError(connect.CodeNotFound, nil)).Times(3)
+
+		// The owner is synced again, and the deletion reported until the route is recreated.
+		var resynced []client.Object
+		opts := DriftCheckOptions{
+			DefaultMode: DriftModeRevert,
+			Resync:      func(owner client.Object) { resynced = append(resynced, owner) },
+		}
+		for range 2 {
+			changes, err := r.CheckDrift(ctx, opts)
+			require.NoError(t, err)
+			assert.False(t, changes)
+		}
+		assert.Len(t, r.drift.snapshot(), 1)
+		assert.True(t, r.resyncGatewayRoutes.Load())
+		if assert.Len(t, resynced, 2) {
+			assert.Equal(t, "my-ingress", resynced[0].GetName())
+		}
+
+		// Recreating the route replaces the entry for the deleted route.
+		recreated := proto.Clone(desired).(*configpb.Route)
+		recreated.Id = new("new-route-id")
+		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+			Route: &configpb.Route{
+				Name:         desired.Name,
+				From:         desired.From,
+				To:           desired.To,
+				OriginatorId: desired.OriginatorId,
+			},
+		})).Return(connect.NewResponse(&configpb.CreateRouteResponse{Route: recreated}), nil)
+		_, err := r.upsertOneRoute(ctx, nil, proto.Clone(desired).(*configpb.Route), owner(""))
+		require.NoError(t, err)
+		snapshot := r.drift.snapshot()
+		assert.Len(t, snapshot, 1)
+		assert.Contains(t, snapshot, driftKey{apiKindRoute, "new-route-id"})
+	})
+
+	t.Run("synced", func(t *testing.T) {
+		apiClient, k8sClient, r := setupReconciler(t)
+		ctx := t.Context()
+		r.drift.record(apiKindRoute, "route-id", desired, owner(""))
+		expectOwner(k8sClient, owner(""))
+
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+			Id: "route-id",
+		})).Return(connect.NewResponse(&configpb.GetRouteResponse{Route: desired}), nil)
+
+		changes, err := r.CheckDrift(ctx, DriftCheckOptions{})
+		require.NoError(t, err)
+		assert.False(t, changes)
+	})
+}
@@ -0,0 +1,228 @@
+package pomerium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+	sdk "github.com/pomerium/sdk-go"
+)
+
+// WithDryRun makes the reconciler pass each route, policy, keypair and settings change to fn,
+// instead of writing it to the unified API. Reads are made as usual.
+func WithDryRun(fn DryRunFunc) APIReconcilerOption {
+	return func(r *APIReconciler) {
+		r.dryRun = fn
+	}
+}
+
+var _ = sdk.Client((*dryRunAPIClient)(nil))
+
+// dryRunIDPrefix prefixes the IDs returned for the objects created by a dryRunAPIClient.
+const dryRunIDPrefix = "dry-run-"
+
+var errDryRunUnsupported = errors.New("not supported in dry-run mode")
+
+// dryRunAPIClient reports the writes made through it to fn instead of making them, and responds
+// as if they had succeeded. The objects reported as created are not found by later reads.
+type dryRunAPIClient struct {
+	sdk.Client
+	fn DryRunFunc
+
+	lastID atomic.Int64
+}
+
+func newDryRunAPIClient(client sdk.Client, fn DryRunFunc) *dryRunAPIClient {
+	return &dryRunAPIClient{Client: client, fn: fn}
+}
+
+// apiObject is a unified API route, policy or keypair.
+type apiObject interface {
+	proto.Message
+	GetId() string
+	GetName() string
+}
+
+// reportChange reports a change to an API object of the given kind. The existing object, if any,
+// is fetched with get and masked with mask, so that the diff is limited to the fields set by the
+// controller. desired is nil for deletes.
+func reportChange[T apiObject](
+	ctx context.Context, c *dryRunAPIClient, action ConfigChangeAction, kind apiKind, id string,
+	desired T, get func(context.Context, string) (T, error), mask func(existing, desired T),
+) {
+	var existing T
+	if action != ConfigChangeCreate {
+		if obj, err := get(ctx, id); err == nil {
+			existing = obj
+			if desired.ProtoReflect().IsValid() {
+				mask(existing, desired)
+			} else {
+				mask(existing, existing)
+			}
+		}
+	}
+	name := desired.GetName()
+	if name == "" {
+		name = existing.GetName()
+	}
+	c.fn(ctx, ConfigChange{
+		Action: action,
+		Kind:   string(kind),
+		ID:     id,
+		Name:   name,
+		Diff:   redactedDiff(existing, desired),
+	})
+}
+
+func (c *dryRunAPIClient) newID() *string {
+	return new(fmt.Sprintf("%s%d", dryRunIDPrefix, c.lastID.Add(1)))
+}
+
+// errCreatedInDryRun returns a not found error for the IDs of objects reported as created.
+func errCreatedInDryRun(id string) error {
+	if strings.HasPrefix(id, dryRunIDPrefix) {
+		return connect.NewError(connect.CodeNotFound, fmt.Errorf("%s was only created in dry-run mode", id))
+	}
+	return nil
+}
+
+func (c *dryRunAPIClient) getRoute(ctx context.Context, id string) (*configpb.Route, error) {
+	resp, err := c.GetRoute(ctx, connect.NewRequest(&configpb.GetRouteRequest{Id: id}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetRoute(), nil
+}
+
+func (c *dryRunAPIClient) getPolicy(ctx context.Context, id string) (*configpb.Policy, error) {
+	resp, err := c.GetPolicy(ctx, connect.NewRequest(&configpb.GetPolicyRequest{Id: id}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetPolicy(), nil
+}
+
+func (c *dryRunAPIClient) getKeyPair(ctx context.Context, id string) (*configpb.KeyPair, error) {
+	resp, err := c.GetKeyPair(ctx, connect.NewRequest(&configpb.GetKeyPairRequest{Id: id}))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Msg.GetKeyPair(), nil
+}
+
+func (c *dryRunAPIClient) GetRoute(ctx context.Context, req *connect.Request[configpb.GetRouteRequest]) (*connect.Response[configpb.GetRouteResponse], error) {
+	if err := errCreatedInDryRun(req.Msg.GetId()); err != nil {
+		return nil, err
+	}
+	return c.Client.GetRoute(ctx, req)
+}
+
+func (c *dryRunAPIClient) GetPolicy(ctx context.Context, req *connect.Request[configpb.GetPolicyRequest]) (*connect.Response[configpb.GetPolicyResponse], error) {
+	if err := errCreatedInDryRun(req.Msg.GetId()); err != nil {
+		return nil, err
+	}
+	return c.Client.GetPolicy(ctx, req)
+}
+
+func (c *dryRunAPIClient) GetKeyPair(ctx context.Context, req *connect.Request[configpb.GetKeyPairRequest]) (*connect.Response[configpb.GetKeyPairResponse], error) {
+	if err := errCreatedInDryRun(req.Msg.GetId()); err != nil {
+		return nil, err
+	}
+	return c.Client.GetKeyPair(ctx, req)
+}
+
+func (c *dryRunAPIClient) CreateRoute(ctx context.Context, req *connect.Request[configpb.CreateRouteRequest]) (*connect.Response[configpb.CreateRouteResponse], error) {
+	reportChange(ctx, c, ConfigChangeCreate, apiKindRoute, "", req.Msg.GetRoute(), c.getRoute, maskRoute)
+	route := proto.Clone(req.Msg.GetRoute()).(*configpb.Route)
+	route.Id = c.newID()
+	return connect.NewResponse(&configpb.CreateRouteResponse{Route: route}), nil
+}
+
+func (c *dryRunAPIClient) UpdateRoute(ctx context.Context, req *connect.Request[configpb.UpdateRouteRequest]) (*connect.Response[configpb.UpdateRouteResponse], error) {
+	route := req.Msg.GetRoute()
+	reportChange(ctx, c, ConfigChangeUpdate, apiKindRoute, route.GetId(), route, c.getRoute, maskRoute)
+	return connect.NewResponse(&configpb.UpdateRouteResponse{}), nil
+}
+
+func (c *dryRunAPIClient) DeleteRoute(ctx context.Context, req *connect.Request[configpb.DeleteRouteRequest]) (*connect.Response[configpb.DeleteRouteResponse], error) {
+	reportChange(ctx, c, ConfigChangeDelete, apiKindRoute, req.Msg.GetId(), nil, c.getRoute, maskRoute)
+	return connect.NewResponse(&configpb.DeleteRouteResponse{}), nil
+}
+
+func (c *dryRunAPIClient) CreatePolicy(ctx context.Context, req *connect.Request[configpb.CreatePolicyRequest]) (*connect.Response[configpb.CreatePolicyResponse], error) {
+	reportChange(ctx, c, ConfigChangeCreate, apiKindPolicy, "", req.Msg.GetPolicy(), c.getPolicy, maskPolicy)
+	policy := proto.Clone(req.Msg.GetPolicy()).(*configpb.Policy)
+	policy.Id = c.newID()
+	return connect.NewResponse(&configpb.CreatePolicyResponse{Policy: policy}), nil
+}
+
+func (c *dryRunAPIClient) UpdatePolicy(ctx context.Context, req *connect.Request[configpb.UpdatePolicyRequest]) (*connect.Response[configpb.UpdatePolicyResponse], error) {
+	policy := req.Msg.GetPolicy()
+	reportChange(ctx, c, ConfigChangeUpdate, apiKindPolicy, policy.GetId(), policy, c.getPolicy, maskPolicy)
+	return connect.NewResponse(&configpb.UpdatePolicyResponse{}), nil
+}
+
+func (c *dryRunAPIClient) DeletePolicy(ctx context.Context, req *connect.Request[configpb.DeletePolicyRequest]) (*connect.Response[configpb.DeletePolicyResponse], error) {
+	reportChange(ctx, c, ConfigChangeDelete, apiKindPolicy, req.Msg.GetId(), nil, c.getPolicy, maskPolicy)
+	return connect.NewResponse(&configpb.DeletePolicyResponse{}), nil
+}
+
+func (c *dryRunAPIClient) CreateKeyPair(ctx context.Context, req *connect.Request[configpb.CreateKeyPairRequest]) (*connect.Response[configpb.CreateKeyPairResponse], error) {
+	reportChange(ctx, c, ConfigChangeCreate, apiKindKeyPair, "", req.Msg.GetKeyPair(), c.getKeyPair, maskKeyPair)
+	keyPair := proto.Clone(req.Msg.GetKeyPair()).(*configpb.KeyPair)
+	keyPair.Id = c.newID()
+	return connect.NewResponse(&configpb.CreateKeyPairResponse{KeyPair: keyPair}), nil
+}
+
+func (c *dryRunAPIClient) UpdateKeyPair(ctx context.Context, req *connect.Request[configpb.UpdateKeyPairRequest]) (*connect.Response[configpb.UpdateKeyPairResponse], error) {
+	keyPair := req.Msg.GetKeyPair()
+	reportChange(ctx, c, ConfigChangeUpdate, apiKindKeyPair, keyPair.GetId(), keyPair, c.getKeyPair, maskKeyPair)
+	return connect.NewResponse(&configpb.UpdateKeyPairResponse{}), nil
+}
+
+func (c *dryRunAPIClient) DeleteKeyPair(ctx context.Context, req *connect.Request[configpb.DeleteKeyPairRequest]) (*connect.Response[configpb.DeleteKeyPairResponse], error) {
+	reportChange(ctx, c, ConfigChangeDelete, apiKindKeyPair, req.Msg.GetId(), nil, c.getKeyPair, maskKeyPair)
+	return connect.NewResponse(&configpb.DeleteKeyPairResponse{}), nil
+}
+
+func (c *dryRunAPIClient) UpdateSettings(ctx context.Context, req *connect.Request[configpb.UpdateSettingsRequest]) (*connect.Response[configpb.UpdateSettingsResponse], error) {
+	settings := req.Msg.GetSettings()
+	get := &configpb.GetSettingsRequest{}
+	if ns := settings.GetNamespaceId(); ns != "" {
+		get.For = &configpb.GetSettingsRequest_NamespaceId{NamespaceId: ns}
+	}
+	var existing *configpb.Settings
+	if resp, err := c.GetSettings(ctx, connect.NewRequest(get)); err == nil {
+		existing = resp.Msg.GetSettings()
+		existing.CreatedAt = nil
+		existing.ModifiedAt = nil
+	}
+	c.fn(ctx, ConfigChange{
+		Action: ConfigChangeUpdate,
+		Kind:   string(apiKindSettings),
+		ID:     settings.GetId(),
+		Diff:   redactedDiff(existing, settings),
+	})
+	return connect.NewResponse(&configpb.UpdateSettingsResponse{}), nil
+}
+
+// The controller does not manage service accounts, so their writes are rejected rather than
+// reported.
+
+func (c *dryRunAPIClient) CreateServiceAccount(context.Context, *connect.Request[configpb.CreateServiceAccountRequest]) (*connect.Response[configpb.CreateServiceAccountResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errDryRunUnsupported)
+}
+
+func (c *dryRunAPIClient) UpdateServiceAccount(context.Context, *connect.Request[configpb.UpdateServiceAccountRequest]) (*connect.Response[configpb.UpdateServiceAccountResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errDryRunUnsupported)
+}
+
+func (c *dryRunAPIClient) DeleteServiceAccount(context.Context, *connect.Request[configpb.DeleteServiceAccountRequest]) (*connect.Response[configpb.DeleteServiceAccountResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errDryRunUnsupported)
+}
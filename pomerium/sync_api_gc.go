@@ -0,0 +1,383 @@
+package pomerium
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	gateway_v1 "sigs.k8s.io/gateway-api/apis/v1"
+
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	icgv1alpha1 "github.com/pomerium/ingress-controller/apis/gateway/v1alpha1"
+)
+
+var _ = GarbageCollector((*APIReconciler)(nil))
+
+// gcMinAge is how long after its last modification an unreferenced API object may be deleted.
+// This avoids racing with a concurrent sync that has created an object but not yet recorded its
+// ID as an annotation.
+const gcMinAge = 10 * time.Minute
+
+// gcListPageSize is the page size used to list Kubernetes objects.
+const gcListPageSize = 500
+
+// gcAPIPageSize is the page size used to list API objects.
+const gcAPIPageSize uint64 = 100
+
+// gcObjectKinds lists the kinds of Kubernetes objects that may hold API ID annotations.
+// PolicyFilters may be referenced by Ingresses as well as by Gateway API routes.
+var gcObjectKinds = []schema.GroupVersionKind{
+	networkingv1.SchemeGroupVersion.WithKind("IngressList"),
+	corev1.SchemeGroupVersion.WithKind("SecretList"),
+	icgv1alpha1.GroupVersion.WithKind("PolicyFilterList"),
+}
+
+// gcGatewayObjectKinds lists the kinds of Gateway API related objects that may hold API ID
+// annotations.
+var gcGatewayObjectKinds = []schema.GroupVersionKind{
+	gateway_v1.SchemeGroupVersion.WithKind("HTTPRouteList"),
+	gateway_v1.SchemeGroupVersion.WithKind("GRPCRouteList"),
+	gateway_v1.SchemeGroupVersion.WithKind("TCPRouteList"),
+	gateway_v1.SchemeGroupVersion.WithKind("UDPRouteList"),
+}
+
+// apiReferences holds the IDs of API objects referenced from Kubernetes objects.
+type apiReferences struct {
+	routes   map[string]struct{}
+	policies map[string]struct{}
+	keyPairs map[string]struct{}
+
+	// terminatingSecrets holds the Secrets being deleted that still have the API finalizer,
+	// keyed by keypair ID.
+	terminatingSecrets map[string]*metav1.PartialObjectMetadata
+}
+
+// CollectGarbage deletes any routes, policies and keypairs originated by this controller install
+// (within the API namespaces it syncs to) that are no longer referenced by any Kubernetes object.
+// These may be left behind if objects are deleted while the controller is not running, or if the
+// ID annotations are edited by hand.
+//
+// Objects are only deleted if an install ID is set (see WithInstallID): without one, the objects
+// created by this install cannot be told apart from those created by any other install syncing to
+// the same API namespaces, so unreferenced objects are only logged. Routes created for Gateway API
+// routes are only deleted if opts.GatewayAPI is set.
+func (r *APIReconciler) CollectGarbage(
+	ctx context.Context, k8s client.Reader, opts GarbageCollectionOptions,
+) (changes bool, err error) {
+	logger := log.FromContext(ctx).WithName("APIReconciler.CollectGarbage")
+	dryRun := opts.DryRun
+	if !dryRun && r.installID == "" {
+		logger.Info("no install ID is set, so unreferenced configuration will not be deleted")
+		dryRun = true
+	}
+
+	// List the API objects before the Kubernetes objects, so that any object created in between
+	// will not be considered.
+	objs, err := r.listOriginatedObjects(ctx)
+	if err != nil {
+		return false, err
+	}
+	kinds := gcObjectKinds
+	if opts.GatewayAPI {
+		kinds = append(slices.Clip(kinds), gcGatewayObjectKinds...)
+	}
+	refs, err := findAPIReferences(ctx, k8s, kinds)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+
+	deleteOrphan := func(kind, id, name string, del func(context.Context, string) error) error {
+		logger.Info("found unreferenced "+kind, "id", id, "name", name, "dryRun", dryRun)
+		if dryRun {
+			return nil
+		}
+		if err := del(ctx, id); err != nil {
+			return fmt.Errorf("couldn't delete unreferenced %s %q: %w", kind, id, err)
+		}
+		changes = true
+		return nil
+	}
+
+	// Delete routes first, as these may reference policies and keypairs. Any policies and keypairs
+	// referenced by remaining routes are kept. The routes of Gateway API routes are kept if these
+	// are not synced, as their references cannot be checked.
+	for _, route := range objs.routes {
+		isGatewayRoute := route.GetOriginatorId() == r.gatewayOriginatorID()
+		if _, ok := refs.routes[route.GetId()]; ok || (isGatewayRoute && !opts.GatewayAPI) ||
+			recentlyModified(now, route.ModifiedAt, route.CreatedAt) {
+			for _, id := range route.PolicyIds {
+				refs.policies[id] = struct{}{}
+			}
+			for _, id := range []string{
+				route.GetTlsCustomCaKeyPairId(),
+				route.GetTlsClientKeyPairId(),
+				route.GetTlsDownstreamClientCaKeyPairId(),
+			} {
+				if id != "" {
+					refs.keyPairs[id] = struct{}{}
+				}
+			}
+			continue
+		}
+		if err := deleteOrphan("route", route.GetId(), route.GetName(), r.deleteRoute); err != nil {
+			return changes, err
+		}
+	}
+
+	for _, policy := range objs.policies {
+		if _, ok := refs.policies[policy.GetId()]; ok || recentlyModified(now, policy.ModifiedAt, policy.CreatedAt) {
+			continue
+		}
+		if err := deleteOrphan("policy", policy.GetId(), policy.GetName(), r.deletePolicy); err != nil {
+			return changes, err
+		}
+	}
+
+	for _, keyPair := range objs.keyPairs {
+		if _, ok := refs.keyPairs[keyPair.GetId()]; ok || recentlyModified(now, keyPair.ModifiedAt, keyPair.CreatedAt) {
+			continue
+		}
+		if _, ok := refs.terminatingSecrets[keyPair.GetId()]; ok {
+			// Handled below, along with the Secret finalizer.
+			continue
+		}
+		if err := deleteOrphan("keypair", keyPair.GetId(), keyPair.GetName(), r.deleteKeyPair); err != nil {
+			return changes, err
+		}
+	}
+
+	// A Secret deleted while the controller was not running will be stuck terminating until its
+	// finalizer is removed. This can be done once the keypair is no longer in use.
+	for id, secret := range refs.terminatingSecrets {
+		if _, ok := refs.keyPairs[id]; ok {
+			continue
+		}
+		logger.Info("found terminating Secret with unreferenced keypair",
+			"secret", client.ObjectKeyFromObject(secret), "id", id, "dryRun", dryRun)
+		if dryRun {
+			continue
+		}
+		if err := r.deleteKeyPair(ctx, id); err != nil {
+			return changes, fmt.Errorf("couldn't delete keypair %q: %w", id, err)
+		}
+		original := secret.DeepCopy()
+		controllerutil.RemoveFinalizer(secret, apiFinalizer)
+		if err := r.k8sClient.Patch(ctx, secret, client.MergeFrom(original)); err != nil {
+			return changes, err
+		}
+		changes = true
+	}
+
+	return changes, nil
+}
+
+// originatedObjects holds the API objects originated by this controller install.
+type originatedObjects struct {
+	routes   []*configpb.Route
+	policies []*configpb.Policy
+	keyPairs []*configpb.KeyPair
+}
+
+// listOriginatedObjects lists all routes, policies and keypairs originated by this controller
+// install, within any of the API namespaces it syncs to.
+func (r *APIReconciler) listOriginatedObjects(ctx context.Context) (*originatedObjects, error) {
+	namespaceIDs := r.knownAPINamespaces()
+	if namespaceIDs == nil {
+		namespaceIDs = []string{""}
+	}
+	var objs originatedObjects
+	for _, id := range namespaceIDs {
+		if err := r.listOriginatedObjectsIn(ctx, id, &objs); err != nil {
+			return nil, err
+		}
+	}
+	return &objs, nil
+}
+
+// listOriginatedObjectsIn appends the routes, policies and keypairs originated by this controller
+// install within one API namespace, or within all API namespaces if namespaceID is empty, to objs.
+func (r *APIReconciler) listOriginatedObjectsIn(
+	ctx context.Context, namespaceID string, objs *originatedObjects,
+) error {
+	filterFor := func(originatorID string) (*structpb.Struct, error) {
+		fields := map[string]any{
+			"originator_id": originatorID,
+		}
+		if namespaceID != "" {
+			fields["namespace_id"] = namespaceID
+		}
+		filter, err := structpb.NewStruct(fields)
+		if err != nil {
+			return nil, fmt.Errorf("internal error - couldn't create list filter: %w", err)
+		}
+		return filter, nil
+	}
+	filter, err := filterFor(r.originatorID())
+	if err != nil {
+		return err
+	}
+	gatewayFilter, err := filterFor(r.gatewayOriginatorID())
+	if err != nil {
+		return err
+	}
+
+	for _, routeFilter := range []*structpb.Struct{filter, gatewayFilter} {
+		routes, err := listAllPages(func(offset, limit uint64) ([]*configpb.Route, uint64, error) {
+			resp, err := r.apiClient.ListRoutes(ctx, connect.NewRequest(&configpb.ListRoutesRequest{
+				Offset: &offset,
+				Limit:  &limit,
+				Filter: routeFilter,
+			}))
+			if err != nil {
+				return nil, 0, err
+			}
+			return resp.Msg.Routes, resp.Msg.TotalCount, nil
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't list routes: %w", err)
+		}
+		objs.routes = append(objs.routes, routes...)
+	}
+	policies, err := listAllPages(func(offset, limit uint64) ([]*configpb.Policy, uint64, error) {
+		resp, err := r.apiClient.ListPolicies(ctx, connect.NewRequest(&configpb.ListPoliciesRequest{
+			Offset: &offset,
+			Limit:  &limit,
+			Filter: filter,
+		}))
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Msg.Policies, resp.Msg.TotalCount, nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't list policies: %w", err)
+	}
+	objs.policies = append(objs.policies, policies...)
+	keyPairs, err := listAllPages(func(offset, limit uint64) ([]*configpb.KeyPair, uint64, error) {
+		resp, err := r.apiClient.ListKeyPairs(ctx, connect.NewRequest(&configpb.ListKeyPairsRequest{
+			Offset: &offset,
+			Limit:  &limit,
+			Filter: filter,
+		}))
+		if err != nil {
+			return nil, 0, err
+		}
+		return resp.Msg.KeyPairs, resp.Msg.TotalCount, nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't list keypairs: %w", err)
+	}
+	objs.keyPairs = append(objs.keyPairs, keyPairs...)
+	return nil
+}
+
+// listAllPages calls list for successive pages of gcAPIPageSize items, until all items are listed.
+// list returns the items in a page, and the total number of items if known (or else zero).
+func listAllPages[T any](list func(offset, limit uint64) (items []T, total uint64, err error)) ([]T, error) {
+	var all []T
+	for offset := uint64(0); ; {
+		items, total, err := list(offset, gcAPIPageSize)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		offset += uint64(len(items))
+		if uint64(len(items)) < gcAPIPageSize || (total != 0 && offset >= total) {
+			return all, nil
+		}
+	}
+}
+
+// findAPIReferences collects the API object IDs recorded in the annotations of all Kubernetes
+// objects of the given list kinds. Kinds that are not installed are skipped.
+func findAPIReferences(
+	ctx context.Context, k8s client.Reader, kinds []schema.GroupVersionKind,
+) (*apiReferences, error) {
+	refs := &apiReferences{
+		routes:             make(map[string]struct{}),
+		policies:           make(map[string]struct{}),
+		keyPairs:           make(map[string]struct{}),
+		terminatingSecrets: make(map[string]*metav1.PartialObjectMetadata),
+	}
+	for _, gvk := range kinds {
+		err := listObjectMetadata(ctx, k8s, gvk, func(obj *metav1.PartialObjectMetadata) {
+			refs.add(obj)
+		})
+		if meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("couldn't list %s: %w", gvk.Kind, err)
+		}
+	}
+	return refs, nil
+}
+
+func (refs *apiReferences) add(obj *metav1.PartialObjectMetadata) {
+	for k, id := range obj.GetAnnotations() {
+		if id == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(k, apiRouteKeyAnnotationPrefix), strings.HasPrefix(k, apiRouteIDAnnotationPrefix):
+			refs.routes[id] = struct{}{}
+		case k == apiPolicyIDAnnotation, strings.HasPrefix(k, apiPolicyIDAnnotation+"-"):
+			refs.policies[id] = struct{}{}
+		case k == apiKeyPairIDAnnotation:
+			if obj.Kind == "Secret" && obj.GetDeletionTimestamp() != nil &&
+				controllerutil.ContainsFinalizer(obj, apiFinalizer) {
+				// Referenced only if the keypair is in use by some route (see CollectGarbage).
+				refs.terminatingSecrets[id] = obj
+				continue
+			}
+			refs.keyPairs[id] = struct{}{}
+		}
+	}
+}
+
+// listObjectMetadata calls fn with the metadata of each object of the given list kind.
+func listObjectMetadata(
+	ctx context.Context, k8s client.Reader, gvk schema.GroupVersionKind, fn func(*metav1.PartialObjectMetadata),
+) error {
+	var continueToken string
+	for {
+		var list metav1.PartialObjectMetadataList
+		list.SetGroupVersionKind(gvk)
+		err := k8s.List(ctx, &list, client.Limit(gcListPageSize), client.Continue(continueToken))
+		if err != nil {
+			return err
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			obj.SetGroupVersionKind(gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List")))
+			fn(obj)
+		}
+		continueToken = list.GetContinue()
+		if continueToken == "" {
+			return nil
+		}
+	}
+}
+
+// recentlyModified reports whether an API object was created or modified within gcMinAge.
+func recentlyModified(now time.Time, modifiedAt, createdAt *timestamppb.Timestamp) bool {
+	t := modifiedAt
+	if t == nil {
+		t = createdAt
+	}
+	return t != nil && now.Sub(t.AsTime()) < gcMinAge
+}
@@ -0,0 +1,307 @@
+package pomerium
+
+import (
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/pomerium/pomerium/config"
+	configpb "github.com/pomerium/pomerium/pkg/grpc/config"
+
+	controllers_mock "github.com/pomerium/ingress-controller/controllers/mock"
+	"github.com/pomerium/ingress-controller/model"
+)
+
+func TestAPIReconciler_CollectGarbage(t *testing.T) {
+	old := timestamppb.New(time.Now().Add(-time.Hour))
+	recent := timestamppb.Now()
+
+	setup := func(t *testing.T, installID string) (*controllers_mock.MockSDKClient, client.Client, *APIReconciler) {
+		scheme := runtime.NewScheme()
+		require.NoError(t, clientgoscheme.AddToScheme(scheme))
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingress",
+					Namespace: "test",
+					Annotations: map[string]string{
+						apiRouteKeyAnnotationPrefix + "0123456789abcdef": "route-1",
+						apiPolicyIDAnnotation:                            "policy-1",
+					},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "secret",
+					Namespace:   "test",
+					Annotations: map[string]string{apiKeyPairIDAnnotation: "keypair-1"},
+				},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "deleted-secret",
+					Namespace:         "test",
+					Annotations:       map[string]string{apiKeyPairIDAnnotation: "keypair-3"},
+					Finalizers:        []string{apiFinalizer},
+					DeletionTimestamp: new(metav1.Now()),
+				},
+			},
+		).Build()
+
+		apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+		r := &APIReconciler{
+			apiClient:   apiClient,
+			baseOptions: config.NewDefaultOptions(),
+			secretsMap:  model.NewTLSSecretsMap(),
+			installID:   installID,
+		}
+		r.SetK8sClient(k8sClient)
+
+		filter, err := structpb.NewStruct(map[string]any{"originator_id": r.originatorID()})
+		require.NoError(t, err)
+		gatewayFilter, err := structpb.NewStruct(map[string]any{"originator_id": r.gatewayOriginatorID()})
+		require.NoError(t, err)
+		apiClient.EXPECT().ListRoutes(gomock.Any(), RequestEq(&configpb.ListRoutesRequest{
+			Offset: new(uint64(0)),
+			Limit:  new(gcAPIPageSize),
+			Filter: filter,
+		})).Return(connect.NewResponse(&configpb.ListRoutesResponse{
+			Routes: []*configpb.Route{
+				{Id: new("route-1"), Name: new("route-1"), ModifiedAt: old, PolicyIds: []string{"policy-1"}},
+				{Id: new("route-2"), Name: new("route-2"), ModifiedAt: old, PolicyIds: []string{"policy-2"}},
+				// Unreferenced, but too recently modified to be deleted.
+				{Id: new("route-3"), Name: new("route-3"), ModifiedAt: recent, TlsClientKeyPairId: new("keypair-2")},
+			},
+			TotalCount: 3,
+		}), nil)
+		apiClient.EXPECT().ListRoutes(gomock.Any(), RequestEq(&configpb.ListRoutesRequest{
+			Offset: new(uint64(0)),
+			Limit:  new(gcAPIPageSize),
+			Filter: gatewayFilter,
+		})).Return(connect.NewResponse(&configpb.ListRoutesResponse{
+			Routes: []*configpb.Route{
+				// Unreferenced, but Gateway API routes are not synced, so kept along with its policy.
+				{
+					Id: new("route-4"), Name: new("route-4"), ModifiedAt: old, PolicyIds: []string{"policy-3"},
+					OriginatorId: new(r.gatewayOriginatorID()),
+				},
+			},
+			TotalCount: 1,
+		}), nil)
+		apiClient.EXPECT().ListPolicies(gomock.Any(), RequestEq(&configpb.ListPoliciesRequest{
+			Offset: new(uint64(0)),
+			Limit:  new(gcAPIPageSize),
+			Filter: filter,
+		})).Return(connect.NewResponse(&configpb.ListPoliciesResponse{
+			Policies: []*configpb.Policy{
+				{Id: new("policy-1"), Name: new("policy-1"), ModifiedAt: old},
+				{Id: new("policy-2"), Name: new("policy-2"), ModifiedAt: old},
+				{Id: new("policy-3"), Name: new("policy-3"), ModifiedAt: old},
+			},
+			TotalCount: 3,
+		}), nil)
+		apiClient.EXPECT().ListKeyPairs(gomock.Any(), RequestEq(&configpb.ListKeyPairsRequest{
+			Offset: new(uint64(0)),
+			Limit:  new(gcAPIPageSize),
+			Filter: filter,
+		})).Return(connect.NewResponse(&configpb.ListKeyPairsResponse{
+			KeyPairs: []*configpb.KeyPair{
+				{Id: new("keypair-1"), Name: new("keypair-1"), ModifiedAt: old},
+				{Id: new("keypair-2"), Name: new("keypair-2"), ModifiedAt: old},
+				{Id: new("keypair-3"), Name: new("keypair-3"), ModifiedAt: old},
+				{Id: new("keypair-4"), Name: new("keypair-4"), ModifiedAt: old},
+			},
+			TotalCount: 4,
+		}), nil)
+
+		return apiClient, k8sClient, r
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		apiClient, k8sClient, r := setup(t, "test")
+		ctx := t.Context()
+
+		apiClient.EXPECT().DeleteRoute(ctx, RequestEq(&configpb.DeleteRouteRequest{
+			Id: "route-2",
+		})).Return(connect.NewResponse(&configpb.DeleteRouteResponse{}), nil)
+		apiClient.EXPECT().DeletePolicy(ctx, RequestEq(&configpb.DeletePolicyRequest{
+			Id: "policy-2",
+		})).Return(connect.NewResponse(&configpb.DeletePolicyResponse{}), nil)
+		apiClient.EXPECT().DeleteKeyPair(ctx, RequestEq(&configpb.DeleteKeyPairRequest{
+			Id: "keypair-4",
+		})).Return(connect.NewResponse(&configpb.DeleteKeyPairResponse{}), nil)
+		apiClient.EXPECT().DeleteKeyPair(ctx, RequestEq(&configpb.DeleteKeyPairRequest{
+			Id: "keypair-3",
+		})).Return(connect.NewResponse(&configpb.DeleteKeyPairResponse{}), nil)
+
+		changes, err := r.CollectGarbage(ctx, k8sClient, GarbageCollectionOptions{})
+		require.NoError(t, err)
+		assert.True(t, changes)
+
+		// Removing the finalizer should allow the deleted Secret to go away.
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "deleted-secret"}, &corev1.Secret{})
+		assert.True(t, apierrors.IsNotFound(err), "unexpected error: %v", err)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		_, k8sClient, r := setup(t, "test")
+		ctx := t.Context()
+
+		changes, err := r.CollectGarbage(ctx, k8sClient, GarbageCollectionOptions{DryRun: true})
+		require.NoError(t, err)
+		assert.False(t, changes)
+
+		var secret corev1.Secret
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "deleted-secret"}, &secret)
+		require.NoError(t, err)
+		assert.Contains(t, secret.Finalizers, apiFinalizer)
+	})
+
+	t.Run("no install ID", func(t *testing.T) {
+		// Without an install ID, objects owned by another install cannot be told apart, so none
+		// are deleted.
+		_, k8sClient, r := setup(t, "")
+		ctx := t.Context()
+
+		changes, err := r.CollectGarbage(ctx, k8sClient, GarbageCollectionOptions{})
+		require.NoError(t, err)
+		assert.False(t, changes)
+
+		var secret corev1.Secret
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "deleted-secret"}, &secret)
+		require.NoError(t, err)
+		assert.Contains(t, secret.Finalizers, apiFinalizer)
+	})
+}
+
+func TestAPIReconciler_CollectGarbage_movedIngress(t *testing.T) {
+	// An Ingress moved to another API namespace is synced to a new policy. If the old policy
+	// cannot be deleted then, it is deleted as unreferenced by CollectGarbage.
+	old := timestamppb.New(time.Now().Add(-time.Hour))
+	ctx := t.Context()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-ingress",
+				Namespace: "test",
+				Annotations: map[string]string{
+					"a/policy": `allow: {or: [{authenticated_user: true}]}`,
+					routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "old-route-id",
+					apiPolicyIDAnnotation: "old-policy-id",
+				},
+			},
+			Spec: networkingv1.IngressSpec{
+				IngressClassName: new("pomerium"),
+				Rules: []networkingv1.IngressRule{{
+					Host:             "a.localhost.pomerium.io",
+					IngressRuleValue: exampleIngressRuleValue,
+				}},
+			},
+		},
+	).Build()
+
+	apiClient := controllers_mock.NewMockSDKClient(gomock.NewController(t))
+	r := &APIReconciler{
+		apiClient:   apiClient,
+		baseOptions: config.NewDefaultOptions(),
+		secretsMap:  model.NewTLSSecretsMap(),
+		installID:   "test",
+	}
+	r.SetK8sClient(k8sClient)
+	r.namespaces.byKubernetesNamespace = map[string]string{"test": "namespace-bravo"}
+
+	var ingress networkingv1.Ingress
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "my-ingress"}, &ingress))
+	ic := &model.IngressConfig{
+		AnnotationPrefix: "a",
+		Ingress:          &ingress,
+		Services: map[types.NamespacedName]*corev1.Service{
+			{Name: "example-svc", Namespace: "test"}: {},
+		},
+	}
+
+	// The policy and route were synced to another API namespace, so are recreated.
+	apiClient.EXPECT().GetPolicy(ctx, RequestEq(&configpb.GetPolicyRequest{
+		Id: "old-policy-id",
+	})).Return(connect.NewResponse(&configpb.GetPolicyResponse{
+		Policy: &configpb.Policy{Id: new("old-policy-id"), NamespaceId: new("namespace-alpha")},
+	}), nil)
+	apiClient.EXPECT().CreatePolicy(ctx, gomock.Any()).Return(createPolicyResponseWithID("new-policy-id"), nil)
+	apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+		Id: "old-route-id",
+	})).Return(connect.NewResponse(&configpb.GetRouteResponse{
+		Route: &configpb.Route{Id: new("old-route-id"), NamespaceId: new("namespace-alpha")},
+	}), nil)
+	apiClient.EXPECT().CreateRoute(ctx, gomock.Any()).Return(createRouteResponseWithID("new-route-id"), nil)
+	apiClient.EXPECT().DeleteRoute(ctx, RequestEq(&configpb.DeleteRouteRequest{
+		Id: "old-route-id",
+	})).Return(connect.NewResponse(&configpb.DeleteRouteResponse{}), nil)
+	apiClient.EXPECT().DeletePolicy(ctx, RequestEq(&configpb.DeletePolicyRequest{
+		Id: "old-policy-id",
+	})).Return(nil, connect.NewError(connect.CodeUnavailable, nil))
+
+	changed, err := r.upsertOneIngress(ctx, nil, ic)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	filter, err := structpb.NewStruct(map[string]any{"originator_id": r.originatorID()})
+	require.NoError(t, err)
+	gatewayFilter, err := structpb.NewStruct(map[string]any{"originator_id": r.gatewayOriginatorID()})
+	require.NoError(t, err)
+	apiClient.EXPECT().ListRoutes(gomock.Any(), RequestEq(&configpb.ListRoutesRequest{
+		Offset: new(uint64(0)),
+		Limit:  new(gcAPIPageSize),
+		Filter: filter,
+	})).Return(connect.NewResponse(&configpb.ListRoutesResponse{
+		Routes: []*configpb.Route{
+			{Id: new("new-route-id"), ModifiedAt: old, PolicyIds: []string{"new-policy-id"}},
+		},
+		TotalCount: 1,
+	}), nil)
+	apiClient.EXPECT().ListRoutes(gomock.Any(), RequestEq(&configpb.ListRoutesRequest{
+		Offset: new(uint64(0)),
+		Limit:  new(gcAPIPageSize),
+		Filter: gatewayFilter,
+	})).Return(connect.NewResponse(&configpb.ListRoutesResponse{}), nil)
+	apiClient.EXPECT().ListPolicies(gomock.Any(), RequestEq(&configpb.ListPoliciesRequest{
+		Offset: new(uint64(0)),
+		Limit:  new(gcAPIPageSize),
+		Filter: filter,
+	})).Return(connect.NewResponse(&configpb.ListPoliciesResponse{
+		Policies: []*configpb.Policy{
+			{Id: new("old-policy-id"), NamespaceId: new("namespace-alpha"), ModifiedAt: old},
+			{Id: new("new-policy-id"), NamespaceId: new("namespace-bravo"), ModifiedAt: old},
+		},
+		TotalCount: 2,
+	}), nil)
+	apiClient.EXPECT().ListKeyPairs(gomock.Any(), RequestEq(&configpb.ListKeyPairsRequest{
+		Offset: new(uint64(0)),
+		Limit:  new(gcAPIPageSize),
+		Filter: filter,
+	})).Return(connect.NewResponse(&configpb.ListKeyPairsResponse{}), nil)
+	apiClient.EXPECT().DeletePolicy(ctx, RequestEq(&configpb.DeletePolicyRequest{
+		Id: "old-policy-id",
+	})).Return(connect.NewResponse(&configpb.DeletePolicyResponse{}), nil)
+
+	changes, err := r.CollectGarbage(ctx, k8sClient, GarbageCollectionOptions{})
+	require.NoError(t, err)
+	assert.True(t, changes)
+}
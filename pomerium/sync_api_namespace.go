@@ -0,0 +1,90 @@
+package pomerium
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+// APINamespaceLabel may be set on a Kubernetes Namespace or IngressClass to sync the Ingresses in
+// that namespace, or of that class, to the unified API namespace with the given ID.
+const APINamespaceLabel = "api.pomerium.io/namespace-id"
+
+// APIReconcilerOption customizes an APIReconciler.
+type APIReconcilerOption func(r *APIReconciler)
+
+// WithAPINamespaceMap syncs the Ingresses in each Kubernetes namespace (the map keys) to the unified
+// API namespace with the given ID (the map values), rather than to the default API namespace.
+func WithAPINamespaceMap(m map[string]string) APIReconcilerOption {
+	return func(r *APIReconciler) {
+		r.namespaces.byKubernetesNamespace = maps.Clone(m)
+	}
+}
+
+// apiNamespaces maps Ingresses to unified API namespaces. (Gateway API routes, and the
+// certificates referenced by the Pomerium CRD, are always synced to the default API namespace.)
+type apiNamespaces struct {
+	// byKubernetesNamespace maps Kubernetes namespace names to API namespace IDs.
+	byKubernetesNamespace map[string]string
+
+	mu sync.Mutex
+	// used holds the IDs of the API namespaces that Ingresses have been mapped to, other than the
+	// default.
+	used map[string]struct{}
+}
+
+// apiNamespaceFor returns the ID of the unified API namespace to sync the routes, policy and
+// keypairs for an Ingress to. This is, in order of precedence:
+//   - the APINamespaceLabel of the Ingress's Namespace, if fetched
+//   - the API namespace mapped to the Ingress's Kubernetes namespace
+//   - the APINamespaceLabel of the Ingress's IngressClass, if known
+//   - the default API namespace, if any
+//
+// If an Ingress is mapped to a different API namespace than before, its routes, policy and
+// keypairs are recreated in the new API namespace, and deleted from the old one, when next synced.
+// (If there is no default API namespace, objects no longer mapped to any are left where they are.)
+func (r *APIReconciler) apiNamespaceFor(ic *model.IngressConfig) *string {
+	id, ok := r.mappedAPINamespace(ic)
+	if !ok {
+		return r.namespaceID
+	}
+	r.namespaces.mu.Lock()
+	defer r.namespaces.mu.Unlock()
+	if r.namespaces.used == nil {
+		r.namespaces.used = make(map[string]struct{})
+	}
+	r.namespaces.used[id] = struct{}{}
+	return &id
+}
+
+func (r *APIReconciler) mappedAPINamespace(ic *model.IngressConfig) (string, bool) {
+	if ns := ic.IngressNamespace; ns != nil && ns.Labels[APINamespaceLabel] != "" {
+		return ns.Labels[APINamespaceLabel], true
+	}
+	if id := r.namespaces.byKubernetesNamespace[ic.Namespace]; id != "" {
+		return id, true
+	}
+	if class := ic.IngressClass; class != nil && class.Labels[APINamespaceLabel] != "" {
+		return class.Labels[APINamespaceLabel], true
+	}
+	return "", false
+}
+
+// knownAPINamespaces returns the IDs of the default API namespace, and all API namespaces that
+// Ingresses are mapped to or have been mapped to since the controller started. It returns nil if
+// there is no default API namespace, as the controller then syncs to all namespaces.
+func (r *APIReconciler) knownAPINamespaces() []string {
+	if r.namespaceID == nil {
+		return nil
+	}
+	ids := map[string]struct{}{*r.namespaceID: {}}
+	for _, id := range r.namespaces.byKubernetesNamespace {
+		ids[id] = struct{}{}
+	}
+	r.namespaces.mu.Lock()
+	maps.Copy(ids, r.namespaces.used)
+	r.namespaces.mu.Unlock()
+	return slices.Sorted(maps.Keys(ids))
+}
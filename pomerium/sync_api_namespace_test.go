@@ -0,0 +1,61 @@
+package pomerium
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pomerium/ingress-controller/model"
+)
+
+func TestAPIReconciler_apiNamespaceFor(t *testing.T) {
+	labeled := func(id string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Labels: map[string]string{APINamespaceLabel: id}}
+	}
+	newIngressConfig := func(namespace *corev1.Namespace, class *networkingv1.IngressClass) *model.IngressConfig {
+		return &model.IngressConfig{
+			Ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-ingress", Namespace: "team-a"},
+			},
+			IngressNamespace: namespace,
+			IngressClass:     class,
+		}
+	}
+
+	_, _, r := setupReconciler(t)
+	r.namespaceID = new("default-id")
+	WithAPINamespaceMap(map[string]string{"team-a": "mapped-id"})(r)
+
+	for _, tc := range []struct {
+		name   string
+		ic     *model.IngressConfig
+		expect string
+	}{
+		{"namespace label", newIngressConfig(
+			&corev1.Namespace{ObjectMeta: labeled("namespace-label-id")},
+			&networkingv1.IngressClass{ObjectMeta: labeled("class-label-id")},
+		), "namespace-label-id"},
+		{"namespace map", newIngressConfig(
+			&corev1.Namespace{},
+			&networkingv1.IngressClass{ObjectMeta: labeled("class-label-id")},
+		), "mapped-id"},
+	} {
+		assert.Equal(t, tc.expect, nilToEmpty(r.apiNamespaceFor(tc.ic)), tc.name)
+	}
+
+	WithAPINamespaceMap(nil)(r)
+	assert.Equal(t, "class-label-id", nilToEmpty(r.apiNamespaceFor(newIngressConfig(
+		nil, &networkingv1.IngressClass{ObjectMeta: labeled("class-label-id")}))), "class label")
+	assert.Equal(t, "default-id", nilToEmpty(r.apiNamespaceFor(newIngressConfig(nil, nil))), "default")
+
+	assert.Equal(t,
+		[]string{"class-label-id", "default-id", "mapped-id", "namespace-label-id"},
+		r.knownAPINamespaces(),
+		"all namespaces mapped to should be garbage collected")
+
+	r.namespaceID = nil
+	assert.Nil(t, r.knownAPINamespaces())
+}
@@ -308,11 +308,11 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.Equal(t, "new-policy-id", ic.Annotations["api.pomerium.io/policy-id"])
-		assert.Equal(t, "new-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "new-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 		assert.Contains(t, ic.Finalizers, apiFinalizer)
 	})
 
@@ -326,8 +326,8 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 		ingress.Annotations = map[string]string{
 			"a/policy": ppl,
 			// this route + policy have already been synced via the API
-			"api.pomerium.io/policy-id":  "existing-policy-id",
-			"api.pomerium.io/route-id-0": "existing-route-id",
+			"api.pomerium.io/policy-id":                                "existing-policy-id",
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
 		}
 		ic := &model.IngressConfig{
 			AnnotationPrefix: "a",
@@ -380,7 +380,7 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
 	})
@@ -389,7 +389,7 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 		// If a previous policy is no longer needed, it should be deleted.
 		ingress := ingressTemplate.DeepCopy()
 		ingress.Annotations = map[string]string{
-			"api.pomerium.io/route-id-0": "existing-route-id",
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
 			// Note: no policy rule annotations here, only a previous policy ID
 			"api.pomerium.io/policy-id": "existing-policy-id",
 		}
@@ -437,7 +437,7 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.NotContains(t, ic.Annotations, "api.pomerium.io/policy-id")
@@ -511,17 +511,17 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
-		assert.Equal(t, "new-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "new-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 		assert.Contains(t, ic.Finalizers, apiFinalizer)
 	})
 
 	t.Run("not found error on update", func(t *testing.T) {
 		ingress := ingressTemplate.DeepCopy()
 		ingress.Annotations = map[string]string{
-			"api.pomerium.io/route-id-0": "existing-route-id",
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
 		}
 		ic := &model.IngressConfig{
 			AnnotationPrefix: "a", Ingress: ingress,
@@ -550,16 +550,16 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		assert.NoError(t, err)
-		assert.Equal(t, "recreated-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "recreated-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 	})
 
 	t.Run("different route namespace", func(t *testing.T) {
 		ingress := ingressTemplate.DeepCopy()
 		ingress.Annotations = map[string]string{
-			"api.pomerium.io/route-id-0": "existing-route-id",
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
 		}
 		ic := &model.IngressConfig{
 			AnnotationPrefix: "a", Ingress: ingress,
@@ -588,12 +588,8 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 			},
 		}), nil)
 
-		// The existing route should be deleted and recreated.
-		apiClient.EXPECT().DeleteRoute(ctx, RequestEq(&configpb.DeleteRouteRequest{
-			Id: "existing-route-id",
-		})).Return(connect.NewResponse(&configpb.DeleteRouteResponse{}), nil)
-
-		apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		// The route should be recreated in the new namespace before the existing route is deleted.
+		createRoute := apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
 			Route: &configpb.Route{
 				OriginatorId: new("ingress-controller"),
 				Name:         new("test-my-ingress-a-localhost-pomerium-io"),
@@ -603,19 +599,23 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 				Prefix:       "/",
 			},
 		})).Return(createRouteResponseWithID("recreated-route-id"), nil)
+		deleteRoute := apiClient.EXPECT().DeleteRoute(ctx, RequestEq(&configpb.DeleteRouteRequest{
+			Id: "existing-route-id",
+		})).Return(connect.NewResponse(&configpb.DeleteRouteResponse{}), nil)
+		gomock.InOrder(createRoute, deleteRoute)
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		assert.NoError(t, err)
-		assert.Equal(t, "recreated-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "recreated-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 	})
 
 	t.Run("other error on update", func(t *testing.T) {
 		ingress := ingressTemplate.DeepCopy()
 		ingress.Annotations = map[string]string{
-			"api.pomerium.io/route-id-0": "existing-route-id",
+			routeKeyAnnotation("https://a.localhost.pomerium.io", "/"): "existing-route-id",
 		}
 		ic := &model.IngressConfig{
 			AnnotationPrefix: "a", Ingress: ingress,
@@ -634,7 +634,7 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 		})).Return(nil, connect.NewError(connect.CodeUnavailable, fmt.Errorf("unavailable")))
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		_, err := r.upsertOneIngress(ctx, ic)
+		_, err := r.upsertOneIngress(ctx, nil, ic)
 		require.Error(t, err)
 		assert.Equal(t, connect.CodeUnavailable, connect.CodeOf(err))
 	})
@@ -678,10 +678,10 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		assert.NoError(t, err)
-		assert.Equal(t, "missing-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "missing-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 	})
 
 	t.Run("failed precondition", func(t *testing.T) {
@@ -723,10 +723,10 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		assert.NoError(t, err)
-		assert.Equal(t, "overlapping-route-id", ic.Annotations["api.pomerium.io/route-id-0"])
+		assert.Equal(t, "overlapping-route-id", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
 	})
 
 	t.Run("patch error", func(t *testing.T) {
@@ -755,7 +755,7 @@ func TestAPIReconciler_upsertOneIngress(t *testing.T) {
 		patchErr := fmt.Errorf("failed to patch")
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(patchErr)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		assert.ErrorIs(t, err, patchErr)
 	})
@@ -823,12 +823,12 @@ func TestAPIReconciler_upsertOneIngress_multipleRoutes(t *testing.T) {
 		// new route ID annotations (verified below).
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.Len(t, allRouteIDAnnotations(ic.Annotations), 2)
-		assert.Equal(t, "new-route-id-A", ic.Annotations["api.pomerium.io/route-id-0"])
-		assert.Equal(t, "new-route-id-B", ic.Annotations["api.pomerium.io/route-id-1"])
+		assert.Equal(t, "new-route-id-A", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
+		assert.Equal(t, "new-route-id-B", ic.Annotations[routeKeyAnnotation("https://b.localhost.pomerium.io", "/")])
 		assert.Contains(t, ic.Finalizers, apiFinalizer)
 	})
 
@@ -849,6 +849,7 @@ func TestAPIReconciler_upsertOneIngress_multipleRoutes(t *testing.T) {
 			Services:         services,
 		}
 
+		// The routes are fetched once to migrate the legacy annotations, and again to sync them.
 		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
 			Id: "route-id-A",
 		})).Return(connect.NewResponse(&configpb.GetRouteResponse{
@@ -861,7 +862,7 @@ func TestAPIReconciler_upsertOneIngress_multipleRoutes(t *testing.T) {
 				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
 				Prefix:       "/",
 			},
-		}), nil)
+		}), nil).Times(2)
 		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
 			Id: "route-id-B",
 		})).Return(connect.NewResponse(&configpb.GetRouteResponse{
@@ -874,7 +875,17 @@ func TestAPIReconciler_upsertOneIngress_multipleRoutes(t *testing.T) {
 				To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
 				Prefix:       "/",
 			},
-		}), nil)
+		}), nil).Times(2)
+		for id, from := range map[string]string{
+			"route-id-C": "https://c.localhost.pomerium.io",
+			"route-id-D": "https://d.localhost.pomerium.io",
+		} {
+			apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+				Id: id,
+			})).Return(connect.NewResponse(&configpb.GetRouteResponse{
+				Route: &configpb.Route{Id: new(id), From: from, Prefix: "/"},
+			}), nil)
+		}
 
 		// If there are more route ID annotations than currently-needed routes,
 		// the unnecessary routes should be deleted.
@@ -889,12 +900,210 @@ func TestAPIReconciler_upsertOneIngress_multipleRoutes(t *testing.T) {
 		// annotations for the deleted routes.
 		k8sClient.EXPECT().Patch(ctx, ingress, gomock.Any()).Return(nil)
 
-		changed, err := r.upsertOneIngress(ctx, ic)
+		changed, err := r.upsertOneIngress(ctx, nil, ic)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.Len(t, allRouteIDAnnotations(ic.Annotations), 2)
-		assert.Equal(t, "route-id-A", ic.Annotations["api.pomerium.io/route-id-0"])
-		assert.Equal(t, "route-id-B", ic.Annotations["api.pomerium.io/route-id-1"])
+		assert.Equal(t, "route-id-A", ic.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "/")])
+		assert.Equal(t, "route-id-B", ic.Annotations[routeKeyAnnotation("https://b.localhost.pomerium.io", "/")])
+	})
+}
+
+func TestAPIReconciler_upsertOneIngress_policyRef(t *testing.T) {
+	// Verify that a PolicyFilter referenced by multiple Ingresses is synced to a single policy.
+	apiClient, k8sClient, r := setupReconciler(t)
+	ctx := t.Context()
+
+	examplePPL := `allow:
+  or:
+    - email:
+        is: "me@example.com"`
+	policyFilterObject := &icgv1alpha1.PolicyFilter{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "shared",
+			Namespace: "test",
+		},
+		Spec: icgv1alpha1.PolicyFilterSpec{
+			PPL: examplePPL,
+		},
+	}
+	ingressConfig := func(name, host string) *model.IngressConfig {
+		return &model.IngressConfig{
+			AnnotationPrefix: "a",
+			Ingress: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Namespace:   "test",
+					Annotations: map[string]string{"a/policy_ref": "shared"},
+				},
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: new("pomerium"),
+					Rules: []networkingv1.IngressRule{{
+						Host:             host,
+						IngressRuleValue: exampleIngressRuleValue,
+					}},
+				},
+			},
+			Services: map[types.NamespacedName]*corev1.Service{
+				{Name: "example-svc", Namespace: "test"}: {},
+			},
+			// each Ingress is fetched with its own copy of the PolicyFilter
+			PolicyFilters: map[types.NamespacedName]*icgv1alpha1.PolicyFilter{
+				{Name: "shared", Namespace: "test"}: policyFilterObject.DeepCopy(),
+			},
+		}
+	}
+	icA := ingressConfig("ingress-a", "a.localhost.pomerium.io")
+	icB := ingressConfig("ingress-b", "b.localhost.pomerium.io")
+
+	k8sClient.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// The first Ingress creates the shared policy.
+	apiClient.EXPECT().CreatePolicy(ctx, RequestEq(&configpb.CreatePolicyRequest{
+		Policy: &configpb.Policy{
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-shared"),
+			SourcePpl:    &examplePPL,
+		},
+	})).Return(createPolicyResponseWithID("shared-policy-id"), nil)
+	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		Route: &configpb.Route{
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-ingress-a-a-localhost-pomerium-io"),
+			From:         "https://a.localhost.pomerium.io",
+			To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+			Prefix:       "/",
+			PolicyIds:    []string{"shared-policy-id"},
+		},
+	})).Return(createRouteResponseWithID("route-id-A"), nil)
+
+	changed, err := r.upsertOneIngress(ctx, nil, icA)
+	assert.True(t, changed)
+	require.NoError(t, err)
+	pfKey := types.NamespacedName{Name: "shared", Namespace: "test"}
+	assert.Equal(t, "shared-policy-id", icA.PolicyFilters[pfKey].Annotations[apiPolicyIDAnnotation])
+	assert.NotContains(t, icA.PolicyFilters[pfKey].Finalizers, apiFinalizer)
+
+	// The second Ingress reuses it, even though its copy of the PolicyFilter has no policy ID.
+	apiClient.EXPECT().GetPolicy(ctx, RequestEq(&configpb.GetPolicyRequest{
+		Id: "shared-policy-id",
+	})).Return(connect.NewResponse(&configpb.GetPolicyResponse{
+		Policy: &configpb.Policy{
+			Id:           new("shared-policy-id"),
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-shared"),
+			SourcePpl:    &examplePPL,
+		},
+	}), nil)
+	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		Route: &configpb.Route{
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-ingress-b-b-localhost-pomerium-io"),
+			From:         "https://b.localhost.pomerium.io",
+			To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+			Prefix:       "/",
+			PolicyIds:    []string{"shared-policy-id"},
+		},
+	})).Return(createRouteResponseWithID("route-id-B"), nil)
+
+	changed, err = r.upsertOneIngress(ctx, nil, icB)
+	assert.True(t, changed)
+	require.NoError(t, err)
+	assert.Equal(t, "shared-policy-id", icB.PolicyFilters[pfKey].Annotations[apiPolicyIDAnnotation])
+
+	// An Ingress synced to another API namespace uses a copy of the policy in that namespace.
+	r.namespaces.byKubernetesNamespace = map[string]string{"test": "namespace-charlie"}
+	apiClient.EXPECT().CreatePolicy(ctx, RequestEq(&configpb.CreatePolicyRequest{
+		Policy: &configpb.Policy{
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-shared"),
+			NamespaceId:  new("namespace-charlie"),
+			SourcePpl:    &examplePPL,
+		},
+	})).Return(createPolicyResponseWithID("shared-policy-id-charlie"), nil)
+	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		Route: &configpb.Route{
+			OriginatorId: new("ingress-controller"),
+			Name:         new("test-ingress-d-d-localhost-pomerium-io"),
+			NamespaceId:  new("namespace-charlie"),
+			From:         "https://d.localhost.pomerium.io",
+			To:           []string{"http://example-svc.test.svc.cluster.local:8080"},
+			Prefix:       "/",
+			PolicyIds:    []string{"shared-policy-id-charlie"},
+		},
+	})).Return(createRouteResponseWithID("route-id-D"), nil)
+
+	icD := ingressConfig("ingress-d", "d.localhost.pomerium.io")
+	changed, err = r.upsertOneIngress(ctx, nil, icD)
+	assert.True(t, changed)
+	require.NoError(t, err)
+	assert.Equal(t, "shared-policy-id-charlie",
+		icD.PolicyFilters[pfKey].Annotations[r.policyFilterIDAnnotation(new("namespace-charlie"))])
+	assert.NotContains(t, icD.PolicyFilters[pfKey].Annotations, apiPolicyIDAnnotation)
+	r.namespaces.byKubernetesNamespace = nil
+
+	// A PolicyFilter that is being deleted can no longer be referenced.
+	icC := ingressConfig("ingress-c", "c.localhost.pomerium.io")
+	icC.PolicyFilters[pfKey].DeletionTimestamp = new(metav1.Now())
+	_, err = r.upsertOneIngress(ctx, nil, icC)
+	assert.ErrorContains(t, err, "filter was deleted")
+}
+
+func TestRouteIDAnnotations(t *testing.T) {
+	routeA := &configpb.Route{From: "https://a.localhost.pomerium.io", Prefix: "/"}
+	routeB := &configpb.Route{From: "https://b.localhost.pomerium.io", Prefix: "/"}
+	routeC := &configpb.Route{From: "https://b.localhost.pomerium.io", Prefix: "/"}
+
+	// Annotations should not depend on the route order.
+	keys := routeIDAnnotations([]*configpb.Route{routeA, routeB})
+	assert.Equal(t, []string{keys[1], keys[0]}, routeIDAnnotations([]*configpb.Route{routeB, routeA}))
+	assert.NotEqual(t, keys[0], keys[1])
+
+	// Routes with identical matches should still have distinct annotations.
+	keys = routeIDAnnotations([]*configpb.Route{routeA, routeB, routeC})
+	assert.Equal(t, keys[1]+"-2", keys[2])
+
+	t.Run("migrate", func(t *testing.T) {
+		apiClient, _, r := setupReconciler(t)
+		ctx := t.Context()
+
+		// The rules were reordered since the legacy annotations were recorded.
+		ingress := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"api.pomerium.io/route-id-0": "route-id-B",
+					"api.pomerium.io/route-id-1": "route-id-A",
+					"api.pomerium.io/route-id-2": "route-id-C",
+					"api.pomerium.io/route-id-3": "route-id-D",
+				},
+			},
+		}
+		for id, route := range map[string]*configpb.Route{
+			"route-id-A": routeA,
+			"route-id-B": routeB,
+			"route-id-C": {From: "https://c.localhost.pomerium.io", Prefix: "/"},
+		} {
+			apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{Id: id})).
+				Return(connect.NewResponse(&configpb.GetRouteResponse{Route: route}), nil).AnyTimes()
+		}
+		apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{Id: "route-id-D"})).
+			Return(nil, connect.NewError(connect.CodeNotFound, nil))
+
+		routes := []*configpb.Route{routeA, routeB}
+		keys := routeIDAnnotations(routes)
+		modified, err := r.migrateRouteIDAnnotations(ctx, ingress, routes, keys)
+		require.NoError(t, err)
+		assert.True(t, modified)
+		// The route that no longer matches any rule is left to be deleted as unused.
+		assert.Equal(t, map[string]string{
+			keys[0]:                      "route-id-A",
+			keys[1]:                      "route-id-B",
+			"api.pomerium.io/route-id-2": "route-id-C",
+		}, ingress.Annotations)
+
+		modified, err = r.migrateRouteIDAnnotations(ctx, ingress, routes, keys)
+		require.NoError(t, err)
+		assert.False(t, modified)
 	})
 }
 
@@ -951,8 +1160,12 @@ func TestAPIReconciler_SetGatewayConfig(t *testing.T) {
 
 	gc := &model.GatewayConfig{
 		Routes: []model.GatewayHTTPRouteConfig{{
-			HTTPRoute:        httpRouteObject,
-			Hostnames:        []gateway_v1.Hostname{"a.localhost.pomerium.io"},
+			HTTPRoute: httpRouteObject,
+			Addresses: []model.GatewayListenerAddress{{
+				Protocol: gateway_v1.HTTPSProtocolType,
+				Hostname: "a.localhost.pomerium.io",
+				Port:     443,
+			}},
 			ValidBackendRefs: noopBackendRefChecker{},
 			Services: map[types.NamespacedName]*corev1.Service{
 				{Name: "example-svc", Namespace: "test"}: {},
@@ -976,7 +1189,7 @@ func TestAPIReconciler_SetGatewayConfig(t *testing.T) {
 		},
 	})).Return(createPolicyResponseWithID("example-policy-id"), nil)
 	route := &configpb.Route{
-		OriginatorId:         new("ingress-controller"),
+		OriginatorId:         new("ingress-controller/gateway"),
 		Name:                 new("test-route-a-a-localhost-pomerium-io"),
 		NamespaceId:          new("api-namespace-id"),
 		From:                 "https://a.localhost.pomerium.io",
@@ -1014,7 +1227,7 @@ func TestAPIReconciler_SetGatewayConfig(t *testing.T) {
 	}), nil).Times(2)
 	apiClient.EXPECT().UpdateRoute(ctx, RequestEq(&configpb.UpdateRouteRequest{
 		Route: &configpb.Route{
-			OriginatorId:         new("ingress-controller"),
+			OriginatorId:         new("ingress-controller/gateway"),
 			Id:                   new("new-route-id-1"),
 			Name:                 new("test-route-a-a-localhost-pomerium-io"),
 			NamespaceId:          new("api-namespace-id"),
@@ -1037,7 +1250,7 @@ func TestAPIReconciler_SetGatewayConfig(t *testing.T) {
 
 	apiClient.EXPECT().UpdateRoute(ctx, RequestEq(&configpb.UpdateRouteRequest{
 		Route: &configpb.Route{
-			OriginatorId: new("ingress-controller"),
+			OriginatorId: new("ingress-controller/gateway"),
 			Id:           new("new-route-id-1"),
 			Name:         new("test-route-a-a-localhost-pomerium-io"),
 			NamespaceId:  new("api-namespace-id"),
@@ -1076,7 +1289,7 @@ func TestAPIReconciler_SetGatewayConfig_routeRecreated(t *testing.T) {
 			Name:      "route-a",
 			Namespace: "test",
 			Annotations: map[string]string{
-				"api.pomerium.io/route-id-0": "existing-route-id",
+				routeKeyAnnotation("https://a.localhost.pomerium.io", ""): "existing-route-id",
 			},
 		},
 		Spec: gateway_v1.HTTPRouteSpec{
@@ -1097,8 +1310,12 @@ func TestAPIReconciler_SetGatewayConfig_routeRecreated(t *testing.T) {
 
 	gc := &model.GatewayConfig{
 		Routes: []model.GatewayHTTPRouteConfig{{
-			HTTPRoute:        httpRouteObject,
-			Hostnames:        []gateway_v1.Hostname{"a.localhost.pomerium.io"},
+			HTTPRoute: httpRouteObject,
+			Addresses: []model.GatewayListenerAddress{{
+				Protocol: gateway_v1.HTTPSProtocolType,
+				Hostname: "a.localhost.pomerium.io",
+				Port:     443,
+			}},
 			ValidBackendRefs: noopBackendRefChecker{},
 			Services: map[types.NamespacedName]*corev1.Service{
 				{Name: "example-svc", Namespace: "test"}: {},
@@ -1116,7 +1333,7 @@ func TestAPIReconciler_SetGatewayConfig_routeRecreated(t *testing.T) {
 	})).Return(nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("not found")))
 	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
 		Route: &configpb.Route{
-			OriginatorId:         new("ingress-controller"),
+			OriginatorId:         new("ingress-controller/gateway"),
 			Name:                 new("test-route-a-a-localhost-pomerium-io"),
 			From:                 "https://a.localhost.pomerium.io",
 			To:                   []string{"http://example-svc.test.svc.cluster.local:8000"},
@@ -1130,7 +1347,85 @@ func TestAPIReconciler_SetGatewayConfig_routeRecreated(t *testing.T) {
 	changed, err := r.SetGatewayConfig(ctx, gc)
 	assert.True(t, changed)
 	require.NoError(t, err)
-	assert.Equal(t, "recreated-route-id", httpRouteObject.Annotations["api.pomerium.io/route-id-0"])
+	assert.Equal(t, "recreated-route-id", httpRouteObject.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "")])
+}
+
+func TestAPIReconciler_SetGatewayConfig_unchangedFingerprint(t *testing.T) {
+	httpRouteObject := &gateway_v1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "route-a",
+			Namespace: "test",
+		},
+		Spec: gateway_v1.HTTPRouteSpec{
+			Rules: []gateway_v1.HTTPRouteRule{{
+				BackendRefs: []gateway_v1.HTTPBackendRef{{
+					BackendRef: gateway_v1.BackendRef{
+						BackendObjectReference: gateway_v1.BackendObjectReference{
+							Name: "example-svc",
+							Port: new(gateway_v1.PortNumber(8000)),
+						},
+					},
+				}},
+			}},
+		},
+	}
+
+	gc := &model.GatewayConfig{
+		Routes: []model.GatewayHTTPRouteConfig{{
+			HTTPRoute: httpRouteObject,
+			Addresses: []model.GatewayListenerAddress{{
+				Protocol: gateway_v1.HTTPSProtocolType,
+				Hostname: "a.localhost.pomerium.io",
+				Port:     443,
+			}},
+			ValidBackendRefs: noopBackendRefChecker{},
+			Services: map[types.NamespacedName]*corev1.Service{
+				{Name: "example-svc", Namespace: "test"}: {},
+			},
+			Fingerprint: "fingerprint-1",
+		}},
+	}
+	expectedRoute := &configpb.Route{
+		OriginatorId:         new("ingress-controller/gateway"),
+		Name:                 new("test-route-a-a-localhost-pomerium-io"),
+		From:                 "https://a.localhost.pomerium.io",
+		To:                   []string{"http://example-svc.test.svc.cluster.local:8000"},
+		LoadBalancingWeights: []uint32{1},
+		PreserveHostHeader:   true,
+	}
+
+	apiClient, k8sClient, r := setupReconciler(t)
+	ctx := t.Context()
+
+	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		Route: expectedRoute,
+	})).Return(createRouteResponseWithID("route-id-1"), nil)
+	k8sClient.EXPECT().Patch(ctx, httpRouteObject, gomock.Any()).Return(nil)
+
+	changed, err := r.SetGatewayConfig(ctx, gc)
+	assert.True(t, changed)
+	require.NoError(t, err)
+
+	// A route with an unchanged fingerprint should not be synced again.
+	changed, err = r.SetGatewayConfig(ctx, gc)
+	assert.False(t, changed)
+	require.NoError(t, err)
+
+	// A changed fingerprint should cause the route to be synced again.
+	gc.Routes[0].Fingerprint = "fingerprint-2"
+
+	apiClient.EXPECT().GetRoute(ctx, RequestEq(&configpb.GetRouteRequest{
+		Id: "route-id-1",
+	})).Return(nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("not found")))
+	apiClient.EXPECT().CreateRoute(ctx, RequestEq(&configpb.CreateRouteRequest{
+		Route: expectedRoute,
+	})).Return(createRouteResponseWithID("route-id-2"), nil)
+	k8sClient.EXPECT().Patch(ctx, httpRouteObject, gomock.Any()).Return(nil)
+
+	changed, err = r.SetGatewayConfig(ctx, gc)
+	assert.True(t, changed)
+	require.NoError(t, err)
+	assert.Equal(t, "route-id-2", httpRouteObject.Annotations[routeKeyAnnotation("https://a.localhost.pomerium.io", "")])
 }
 
 func TestAPIReconciler_SetConfig(t *testing.T) {
@@ -1307,7 +1602,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 		// newly-assigned ID in the keypair ID annotation (verified below).
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.Equal(t, "new-keypair-id", secret.Annotations[apiKeyPairIDAnnotation])
@@ -1335,7 +1630,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		require.NoError(t, err)
 		assert.Equal(t, "new-keypair-id", secret.Annotations[apiKeyPairIDAnnotation])
@@ -1381,7 +1676,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 	})
@@ -1422,7 +1717,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 			},
 		})).Return(nil, connect.NewError(connect.CodeDeadlineExceeded, context.DeadlineExceeded))
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.False(t, changed)
 		assert.ErrorIs(t, err, context.DeadlineExceeded)
 	})
@@ -1460,7 +1755,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 			},
 		}, nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.False(t, changed)
 		assert.NoError(t, err)
 	})
@@ -1505,7 +1800,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 		assert.Equal(t, "missing-keypair-id", secret.Annotations["api.pomerium.io/keypair-id"])
@@ -1545,7 +1840,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 		assert.Equal(t, "new-keypair-id", secret.Annotations[apiKeyPairIDAnnotation])
@@ -1604,7 +1899,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(nil)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 		assert.Equal(t, "recreated-keypair-id", secret.Annotations[apiKeyPairIDAnnotation])
@@ -1636,7 +1931,7 @@ func TestAPIReconciler_syncOneSecret(t *testing.T) {
 		patchErr := fmt.Errorf("failed to patch")
 		k8sClient.EXPECT().Patch(ctx, secret, gomock.Any()).Return(patchErr)
 
-		changed, err := r.syncOneSecret(ctx, secret)
+		changed, err := r.syncOneSecret(ctx, nil, secret, r.namespaceID)
 		assert.True(t, changed)
 		require.ErrorIs(t, err, patchErr)
 	})
@@ -1704,7 +1999,7 @@ func TestAPIReconciler_upsertPolicy(t *testing.T) {
 			Policy: &configpb.Policy{},
 		})).Return(createPolicyResponseWithID("existing-policy-id"), nil)
 
-		changed, err := r.upsertPolicy(ctx, policy)
+		changed, err := r.upsertPolicy(ctx, nil, policy, nil)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 	})
@@ -1719,7 +2014,7 @@ func TestAPIReconciler_upsertPolicy(t *testing.T) {
 			Id: "existing-policy-id",
 		})).Return(nil, apiError)
 
-		changed, err := r.upsertPolicy(ctx, policy)
+		changed, err := r.upsertPolicy(ctx, nil, policy, nil)
 		assert.False(t, changed)
 		assert.Equal(t, apiError, err)
 	})
@@ -1749,7 +2044,7 @@ func TestAPIReconciler_upsertPolicy(t *testing.T) {
 
 		// No UpdatePolicy() call expected.
 
-		changed, err := r.upsertPolicy(ctx, policy)
+		changed, err := r.upsertPolicy(ctx, nil, policy, nil)
 		assert.False(t, changed)
 		assert.NoError(t, err)
 	})
@@ -1788,7 +2083,7 @@ func TestAPIReconciler_upsertPolicy(t *testing.T) {
 			},
 		})).Return(createPolicyResponseWithID("recreated-policy-id"), nil)
 
-		changed, err := r.upsertPolicy(ctx, policy)
+		changed, err := r.upsertPolicy(ctx, nil, policy, nil)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 		assert.Equal(t, "recreated-policy-id", policy.GetId())
@@ -1822,13 +2117,56 @@ func TestAPIReconciler_upsertPolicy(t *testing.T) {
 			}},
 		}), nil)
 
-		changed, err := r.upsertPolicy(ctx, policy)
+		changed, err := r.upsertPolicy(ctx, nil, policy, nil)
 		assert.True(t, changed)
 		assert.NoError(t, err)
 		assert.Equal(t, "missing-policy-id", policy.GetId())
 	})
 }
 
+func TestAPIReconciler_syncGatewayMatchPolicies(t *testing.T) {
+	apiClient, _, r := setupReconciler(t)
+	ctx := t.Context()
+
+	route := &gateway_v1.HTTPRoute{Spec: gateway_v1.HTTPRouteSpec{Rules: []gateway_v1.HTTPRouteRule{{
+		Matches: []gateway_v1.HTTPRouteMatch{{Method: new(gateway_v1.HTTPMethodGet)}},
+	}}}}
+	gatewayConfig := &model.GatewayConfig{Routes: []model.GatewayHTTPRouteConfig{{HTTPRoute: route}}}
+	policies := gateway.MethodMatchPolicies(route)
+	require.Len(t, policies, 1)
+	ppl := policies[0].GetSourcePpl()
+	name := gatewayMatchPolicyName(ppl)
+	existing := &configpb.Policy{
+		Id:           new("existing-policy-id"),
+		Name:         &name,
+		OriginatorId: new(defaultOriginatorID),
+		SourcePpl:    &ppl,
+	}
+
+	// The policy ID is not yet known, as after a restart, so the policy is looked up by name
+	// rather than created again.
+	apiClient.EXPECT().ListPolicies(ctx, RequestEq(&configpb.ListPoliciesRequest{
+		Filter: filterByName(t, name),
+	})).Return(connect.NewResponse(&configpb.ListPoliciesResponse{
+		Policies: []*configpb.Policy{existing},
+	}), nil)
+	apiClient.EXPECT().GetPolicy(ctx, RequestEq(&configpb.GetPolicyRequest{
+		Id: "existing-policy-id",
+	})).Return(connect.NewResponse(&configpb.GetPolicyResponse{Policy: existing}), nil).Times(2)
+
+	policyIDs := map[string]string{}
+	changed, err := r.syncGatewayMatchPolicies(ctx, nil, gatewayConfig, policyIDs)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, map[string]string{ppl: "existing-policy-id"}, policyIDs)
+
+	// Once known, the policy ID is reused.
+	policyIDs = map[string]string{}
+	_, err = r.syncGatewayMatchPolicies(ctx, nil, gatewayConfig, policyIDs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{ppl: "existing-policy-id"}, policyIDs)
+}
+
 func TestAPIReconciler_deletePolicy(t *testing.T) {
 	t.Run("not found error", func(t *testing.T) {
 		apiClient, _, r := setupReconciler(t)
@@ -1911,6 +2249,11 @@ func createPolicyResponseWithID(id string) *connect.Response[configpb.CreatePoli
 	}
 }
 
+// routeKeyAnnotation returns the route ID annotation for a route with the given matches.
+func routeKeyAnnotation(from, prefix string) string {
+	return routeIDAnnotations([]*configpb.Route{{From: from, Prefix: prefix}})[0]
+}
+
 func createRouteResponseWithID(id string) *connect.Response[configpb.CreateRouteResponse] {
 	return &connect.Response[configpb.CreateRouteResponse]{
 		Msg: &configpb.CreateRouteResponse{
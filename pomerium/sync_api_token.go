@@ -0,0 +1,77 @@
+package pomerium
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+var _ = APITokenUpdater((*APIReconciler)(nil))
+
+// apiCredentials holds a unified API token that may be replaced while the API client is in use,
+// along with the outcome of the most recent authenticated request.
+type apiCredentials struct {
+	// token, once set, replaces the token the API client was created with.
+	token atomic.Pointer[string]
+	// authErr holds the error for the most recent request rejected as unauthenticated, or nil if
+	// the most recent request was accepted.
+	authErr atomic.Pointer[error]
+
+	// loaded, if set, is closed once a token is available, either as the API client was created
+	// with one or when one is first set.
+	loaded     chan struct{}
+	loadedOnce sync.Once
+}
+
+// setLoaded records that a token is available.
+func (c *apiCredentials) setLoaded() {
+	if c.loaded != nil {
+		c.loadedOnce.Do(func() { close(c.loaded) })
+	}
+}
+
+// apiTokenTransport is an http.RoundTripper that sets the current unified API token on each
+// request, and records whether the token was accepted.
+type apiTokenTransport struct {
+	base  http.RoundTripper
+	creds *apiCredentials
+}
+
+func (t *apiTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if token := t.creds.token.Load(); token != nil {
+		// Replace the Authorization header set by the API client with the current token.
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+*token)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		err := fmt.Errorf("unified API rejected the token: %s", resp.Status)
+		t.creds.authErr.Store(&err)
+	case resp.StatusCode < 300:
+		t.creds.authErr.Store(nil)
+	}
+	return resp, nil
+}
+
+// SetAPIToken replaces the unified API token used for subsequent requests.
+func (r *APIReconciler) SetAPIToken(token string) {
+	r.creds.token.Store(&token)
+	// The new token has not yet been rejected.
+	r.creds.authErr.Store(nil)
+	r.creds.setLoaded()
+}
+
+// APIAuthError returns an error if the most recent unified API request was rejected as
+// unauthenticated.
+func (r *APIReconciler) APIAuthError() error {
+	if err := r.creds.authErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
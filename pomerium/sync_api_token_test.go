@@ -0,0 +1,52 @@
+package pomerium
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokenTransport(t *testing.T) {
+	var gotAuth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	r := &APIReconciler{}
+	c := &http.Client{Transport: &apiTokenTransport{base: http.DefaultTransport, creds: &r.creds}}
+	do := func() {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer initial-token")
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	do()
+	assert.Equal(t, "Bearer initial-token", gotAuth, "token should be unchanged until replaced")
+	assert.NoError(t, r.APIAuthError())
+
+	r.SetAPIToken("rotated-token")
+	status = http.StatusUnauthorized
+	do()
+	assert.Equal(t, "Bearer rotated-token", gotAuth)
+	assert.ErrorContains(t, r.APIAuthError(), "401")
+
+	status = http.StatusOK
+	do()
+	assert.NoError(t, r.APIAuthError(), "an accepted request should clear the error")
+
+	status = http.StatusForbidden
+	do()
+	require.Error(t, r.APIAuthError())
+	r.SetAPIToken("another-token")
+	assert.NoError(t, r.APIAuthError(), "a new token should clear the error")
+}
@@ -81,6 +81,9 @@ type DataBrokerReconciler struct {
 	DebugDumpConfigDiff bool
 	// RemoveUnreferencedCerts would strip any certs not matched by any of the Routes SNI
 	RemoveUnreferencedCerts bool
+
+	// gatewayRoutes caches translated Gateway API routes between SetGatewayConfig calls.
+	gatewayRoutes gatewayRouteCache
 }
 
 // Upsert should update or create the pomerium routes corresponding to this ingress
@@ -166,22 +169,72 @@ func (r *DataBrokerReconciler) SetGatewayConfig(
 	if err != nil {
 		return false, fmt.Errorf("get config: %w", err)
 	}
+	next := translateGatewayConfig(ctx, config, &r.gatewayRoutes)
+
+	changes, err = r.saveConfig(ctx, prev, next, r.ConfigID)
+	if err != nil {
+		r.gatewayRoutes.discard()
+		return changes, err
+	}
+	r.gatewayRoutes.commit()
+	return changes, nil
+}
+
+// translateGatewayConfig returns the Pomerium configuration for all Gateway-defined routes and
+// certificates, reusing the routes previously translated by cache where possible.
+func translateGatewayConfig(
+	ctx context.Context,
+	config *model.GatewayConfig,
+	cache *gatewayRouteCache,
+) *pb.Config {
 	next := new(pb.Config)
 
 	for i := range config.Routes {
-		r := &config.Routes[i]
-		if r.DeletionTimestamp != nil {
+		gr := &config.Routes[i]
+		if gr.DeletionTimestamp != nil {
 			// Ignore any deleted HTTPRoutes.
 			continue
 		}
-		next.Routes = append(next.Routes, gateway.TranslateRoutes(ctx, config, r)...)
+		next.Routes = append(next.Routes, cache.translate(gr.Fingerprint, func() []*pb.Route {
+			return gateway.TranslateRoutes(ctx, config, gr)
+		})...)
+	}
+	for i := range config.GRPCRoutes {
+		gr := &config.GRPCRoutes[i]
+		if gr.DeletionTimestamp != nil {
+			// Ignore any deleted GRPCRoutes.
+			continue
+		}
+		next.Routes = append(next.Routes, cache.translate(gr.Fingerprint, func() []*pb.Route {
+			return gateway.TranslateGRPCRoutes(ctx, config, gr)
+		})...)
+	}
+	for i := range config.TCPRoutes {
+		gr := &config.TCPRoutes[i]
+		if gr.DeletionTimestamp != nil {
+			// Ignore any deleted TCPRoutes.
+			continue
+		}
+		next.Routes = append(next.Routes, cache.translate(gr.Fingerprint, func() []*pb.Route {
+			return gateway.TranslateTCPRoutes(gr)
+		})...)
+	}
+	for i := range config.UDPRoutes {
+		gr := &config.UDPRoutes[i]
+		if gr.DeletionTimestamp != nil {
+			// Ignore any deleted UDPRoutes.
+			continue
+		}
+		next.Routes = append(next.Routes, cache.translate(gr.Fingerprint, func() []*pb.Route {
+			return gateway.TranslateUDPRoutes(gr)
+		})...)
 	}
 	next.Settings = new(pb.Settings)
 	for _, cert := range config.Certificates {
 		addTLSCert(next.Settings, cert)
 	}
 
-	return r.saveConfig(ctx, prev, next, r.ConfigID)
+	return next
 }
 
 // DeleteAll cleans pomerium configuration entirely
@@ -3,6 +3,7 @@ package pomerium
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"golang.org/x/net/nettest"
 
@@ -12,28 +13,13 @@ import (
 	"github.com/pomerium/pomerium/pkg/cryptutil"
 	pb "github.com/pomerium/pomerium/pkg/grpc/config"
 
+	"github.com/pomerium/ingress-controller/model"
 	"github.com/pomerium/ingress-controller/pomerium/envoy"
 )
 
 // validate validates pomerium config.
 func validate(ctx context.Context, cfg *pb.Config, id string) error {
-	options := config.NewDefaultOptions()
-	options.ApplySettings(ctx, cryptutil.NewCertificatesIndex(), cfg.GetSettings())
-	options.InsecureServer = true
-
-	for _, r := range cfg.GetRoutes() {
-		p, err := config.NewPolicyFromProto(r)
-		if err != nil {
-			return err
-		}
-		err = p.Validate()
-		if err != nil {
-			return err
-		}
-		options.Policies = append(options.Policies, *p)
-	}
-
-	err := options.Validate()
+	options, err := validateOptions(ctx, cfg)
 	if err != nil {
 		return err
 	}
@@ -64,3 +50,78 @@ func validate(ctx context.Context, cfg *pb.Config, id string) error {
 
 	return nil
 }
+
+// validateOptions validates pomerium config settings and routes, without building the envoy
+// configuration.
+func validateOptions(ctx context.Context, cfg *pb.Config) (*config.Options, error) {
+	options := config.NewDefaultOptions()
+	options.ApplySettings(ctx, cryptutil.NewCertificatesIndex(), cfg.GetSettings())
+	options.InsecureServer = true
+
+	for _, r := range cfg.GetRoutes() {
+		p, err := config.NewPolicyFromProto(r)
+		if err != nil {
+			return nil, err
+		}
+		err = p.Validate()
+		if err != nil {
+			return nil, err
+		}
+		options.Policies = append(options.Policies, *p)
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// ValidateIngress checks that an Ingress, with the objects it references, translates to valid
+// Pomerium routes, as it would be on reconcile.
+func ValidateIngress(ctx context.Context, ic *model.IngressConfig) error {
+	cfg := new(pb.Config)
+	if err := upsertRoutes(ctx, cfg, ic); err != nil {
+		return err
+	}
+	_, err := validateOptions(ctx, cfg)
+	return err
+}
+
+// ValidateIngressAnnotations checks the Pomerium annotations of an Ingress that do not depend on
+// any other object, for when the objects the Ingress references are not available.
+func ValidateIngressAnnotations(annotations map[string]string, prefix string) error {
+	kv, err := removeKeyPrefix(annotations, prefix)
+	if err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	r := new(pb.Route)
+	if err = unmarshalAnnotations(r, kv.Base); err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	if err = applyMCPAnnotations(r, kv.MCPServer, kv.MCPClient); err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	if err = applyUpstreamAnnotations(r, kv.UpstreamTunnel); err != nil {
+		return fmt.Errorf("annotations: %w", err)
+	}
+	if err = unmarshalPolicyAnnotations(new(pb.Policy), kv.Policy); err != nil {
+		return fmt.Errorf("annotations: applying policy annotations: %w", err)
+	}
+	if ref, ok := kv.Etc[model.PolicyRef]; ok {
+		if _, err = model.ParsePolicyRefs(ref); err != nil {
+			return fmt.Errorf("annotations: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateSettings checks that the Pomerium CRD, with the Secrets it references, translates to
+// valid Pomerium settings, as it would be on reconcile.
+func ValidateSettings(ctx context.Context, src *model.Config) error {
+	cfg := new(pb.Config)
+	if err := ApplyConfig(ctx, cfg, src); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	_, err := validateOptions(ctx, cfg)
+	return err
+}
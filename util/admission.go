@@ -0,0 +1,16 @@
+package util
+
+import "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+// AdmissionResult returns the response of a validating admission webhook for an object that
+// failed validation with err, if not nil: the object is rejected with the error, or if warnOnly
+// is set, admitted with the error as a warning.
+func AdmissionResult(err error, warnOnly bool, warnings ...string) (admission.Warnings, error) {
+	if err == nil {
+		return warnings, nil
+	}
+	if warnOnly {
+		return append(warnings, err.Error()), nil
+	}
+	return warnings, err
+}
@@ -0,0 +1,27 @@
+package util_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/pomerium/ingress-controller/util"
+)
+
+func TestAdmissionResult(t *testing.T) {
+	errInvalid := errors.New("invalid")
+
+	warnings, err := util.AdmissionResult(nil, false, "a")
+	assert.Equal(t, admission.Warnings{"a"}, warnings)
+	assert.NoError(t, err)
+
+	warnings, err = util.AdmissionResult(errInvalid, false, "a")
+	assert.Equal(t, admission.Warnings{"a"}, warnings)
+	assert.ErrorIs(t, err, errInvalid)
+
+	warnings, err = util.AdmissionResult(errInvalid, true, "a")
+	assert.Equal(t, admission.Warnings{"a", "invalid"}, warnings)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"net"
+	"sort"
+	"strconv"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+)
+
+// EndpointSliceHosts returns the "address:port" upstream hosts of all endpoint slices of a
+// service, for the endpoint ports selected by portMatch.
+// endpoints that are ready are preferred; if there are none, endpoints that are terminating
+// but still serving are used, so that in-flight traffic may drain gracefully
+func EndpointSliceHosts(
+	slices []*discoveryv1.EndpointSlice,
+	portMatch func(port discoveryv1.EndpointPort) bool,
+) []string {
+	ready := make(map[string]struct{})
+	terminating := make(map[string]struct{})
+	for _, slice := range slices {
+		var ports []int32
+		for _, endpointPort := range slice.Ports {
+			if endpointPort.Port != nil && portMatch(endpointPort) {
+				ports = append(ports, *endpointPort.Port)
+			}
+		}
+		if len(ports) == 0 {
+			continue
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			if !isEndpointServing(endpoint.Conditions) {
+				continue
+			}
+			dst := ready
+			if endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating {
+				dst = terminating
+			}
+			for _, address := range endpoint.Addresses {
+				for _, port := range ports {
+					dst[net.JoinHostPort(address, strconv.Itoa(int(port)))] = struct{}{}
+				}
+			}
+		}
+	}
+
+	if len(ready) == 0 {
+		ready = terminating
+	}
+	hosts := make([]string, 0, len(ready))
+	for host := range ready {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// isEndpointServing checks whether an endpoint may receive traffic.
+// serving was introduced after ready and may be absent, in which case ready is consulted,
+// and an unknown state should be interpreted as serving per the EndpointSlice API
+func isEndpointServing(cond discoveryv1.EndpointConditions) bool {
+	if cond.Serving != nil {
+		return *cond.Serving
+	}
+	if cond.Ready != nil {
+		return *cond.Ready
+	}
+	return true
+}
+
+// EndpointPortNameMatcher returns an EndpointSlice port matcher for the Service port with the
+// given name. EndpointSlice ports are always named after the Service port they were derived
+// from, with an empty name for a single unnamed Service port, and carry the already resolved
+// targetPort number.
+func EndpointPortNameMatcher(servicePortName string) func(port discoveryv1.EndpointPort) bool {
+	return func(port discoveryv1.EndpointPort) bool {
+		name := ""
+		if port.Name != nil {
+			name = *port.Name
+		}
+		return name == servicePortName
+	}
+}
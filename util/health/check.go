@@ -10,4 +10,6 @@ const (
 	SettingsBootstrapReconciler = health.Check("controller.settings.reconciler.bootstrap")
 	// SettingsReconciler checks that the leased settings reconciler has run
 	SettingsReconciler = health.Check("controller.settings.reconciler")
+	// SyncAPIClient checks that unified API requests are not failing repeatedly
+	SyncAPIClient = health.Check("controller.sync-api.client")
 )
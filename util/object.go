@@ -1,6 +1,10 @@
 package util
 
-import "sigs.k8s.io/controller-runtime/pkg/client"
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
 
 // SetAnnotation sets the given annotation key/value, making a new Annotations
 // map if one does not already exist.
@@ -12,3 +16,19 @@ func SetAnnotation(object client.Object, key, value string) {
 	m[key] = value
 	object.SetAnnotations(m)
 }
+
+// IsKindInstalled checks whether the API server serves the kind of obj, so that optional kinds
+// can be skipped if the corresponding CRD is not installed.
+func IsKindInstalled(c client.Client, obj client.Object) (bool, error) {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return false, err
+	}
+	_, err = c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}